@@ -0,0 +1,105 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// rfbKeyEvent is the type of the RFB client message carrying a key press or release.
+	rfbKeyEvent = 4
+	// rfbPointerEvent is the type of the RFB client message carrying a mouse movement or click.
+	rfbPointerEvent = 5
+	// rfbPointerEventLength is the minimum length of the RFB input messages.
+	rfbPointerEventLength = 6
+)
+
+// ActivityInfo summarizes the usage of the remote desktop, as exposed by the activity endpoint.
+type ActivityInfo struct {
+	// The last time a remote desktop session was open, or the current time if any is active.
+	LastActivity time.Time `json:"lastActivity"`
+	// The last time a keyboard or mouse input was received from a client, or the startup time if none yet.
+	LastInput time.Time `json:"lastInput"`
+	// The number of currently active remote desktop sessions.
+	ActiveSessions int32 `json:"activeSessions"`
+}
+
+// ActivityTracker keeps track of the last time the remote desktop has been used,
+// to allow detecting idle instances.
+type ActivityTracker struct {
+	lastSession    atomic.Int64
+	lastInput      atomic.Int64
+	activeSessions atomic.Int32
+}
+
+// NewActivityTracker returns a new ActivityTracker, considering the current time as the last activity.
+func NewActivityTracker() *ActivityTracker {
+	tracker := &ActivityTracker{}
+	now := time.Now().UnixNano()
+	tracker.lastSession.Store(now)
+	tracker.lastInput.Store(now)
+	return tracker
+}
+
+// SessionStarted records that a new remote desktop session has been opened.
+func (t *ActivityTracker) SessionStarted() {
+	t.activeSessions.Add(1)
+	t.lastSession.Store(time.Now().UnixNano())
+}
+
+// SessionEnded records that a remote desktop session has been closed.
+func (t *ActivityTracker) SessionEnded() {
+	t.activeSessions.Add(-1)
+	t.lastSession.Store(time.Now().UnixNano())
+}
+
+// ClientMessage inspects a message received from a client, recording it
+// in case it carries keyboard or mouse input. Since noVNC usually sends
+// each RFB message in a separate frame, only the first one is considered.
+func (t *ActivityTracker) ClientMessage(buffer []byte) {
+	if len(buffer) < rfbPointerEventLength {
+		return
+	}
+	if buffer[0] == rfbKeyEvent || buffer[0] == rfbPointerEvent {
+		t.lastInput.Store(time.Now().UnixNano())
+	}
+}
+
+// Info returns the current activity summary.
+func (t *ActivityTracker) Info() ActivityInfo {
+	info := ActivityInfo{
+		LastActivity:   time.Unix(0, t.lastSession.Load()),
+		LastInput:      time.Unix(0, t.lastInput.Load()),
+		ActiveSessions: t.activeSessions.Load(),
+	}
+	if info.ActiveSessions > 0 {
+		info.LastActivity = time.Now()
+	}
+	return info
+}
+
+// ServeHTTP exposes the current activity summary in JSON format.
+func (t *ActivityTracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	if err := json.NewEncoder(w).Encode(t.Info()); err != nil {
+		log.Println("activity write error:", err)
+	}
+}
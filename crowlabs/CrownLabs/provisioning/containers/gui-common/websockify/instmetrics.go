@@ -27,7 +27,11 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	pb "github.com/novnc/websockify-other/websockify/instmetrics"
 )
 
 // Resources utilization derived from ContainerMetrics.
@@ -53,6 +57,8 @@ type InstanceMetricsHandler struct {
 	// Last Resources extracted from CustomMetricsServer
 	cachedResources      *Resources
 	cachedResourcesMutex sync.RWMutex
+	// Whether cachedResources is kept updated by the metrics stream.
+	watchingResources atomic.Bool
 	// <UID, ConnInfo> map
 	connectionsTracking *sync.Map
 	connectionsCount    uint32
@@ -114,7 +120,7 @@ func (h *InstanceMetricsHandler) sendInstMetrics(ws *websocket.Conn, updatePerio
 	ctx, cancel := context.WithTimeout(context.Background(), updatePeriod)
 	defer cancel()
 
-	if !h.cachedResourcesIsUpdated(updatePeriod) {
+	if !h.watchingResources.Load() && !h.cachedResourcesIsUpdated(updatePeriod) {
 		if err := h.updateCachedResources(ctx, updatePeriod); err != nil {
 			return err
 		}
@@ -155,11 +161,47 @@ func (h *InstanceMetricsHandler) updateCachedResources(ctx context.Context, upda
 	}
 	log.Println("InstMetrics Response: ", response)
 
+	h.setCachedResources(response)
+	return nil
+}
+
+// watchCachedResources keeps cachedResources updated through the metrics stream, so that
+// the viewers do not need to poll the instMetrics server. In case the stream is interrupted,
+// it is established again after retryPeriod, while viewers fall back to polling.
+func (h *InstanceMetricsHandler) watchCachedResources(ctx context.Context, retryPeriod time.Duration) {
+	if h.podName == "" {
+		log.Println("[InstanceMetricsHandler] podName is required, metrics stream not started")
+		return
+	}
+
+	for {
+		err := h.instanceMetricsClient.WatchContainerMetrics(ctx, &h.podName, func(response *pb.ContainerMetricsResponse) {
+			h.setCachedResources(response)
+			h.watchingResources.Store(true)
+		})
+		h.watchingResources.Store(false)
+
+		if status.Code(err) == codes.Unimplemented {
+			log.Println("InstMetrics server does not support metrics streaming, falling back to polling")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryPeriod):
+			log.Printf("InstMetrics stream interrupted, reconnecting. Error: %v", err)
+		}
+	}
+}
+
+func (h *InstanceMetricsHandler) setCachedResources(response *pb.ContainerMetricsResponse) {
 	resources := percentagesFromContainerMetrics(response.CpuPerc, response.MemBytes, h.cpuLimit, h.memoryLimit)
 	resources.Timestamp = time.Now()
 
+	h.cachedResourcesMutex.Lock()
 	h.cachedResources = resources
-	return nil
+	h.cachedResourcesMutex.Unlock()
 }
 
 func (h *InstanceMetricsHandler) sendCachedResources(ws *websocket.Conn, updatePeriod time.Duration, connUID *string) error {
@@ -44,6 +44,9 @@ func main() {
 	podName := flag.String("pod-name", "", "instance podName")
 	cpuLimit := flag.String("cpu-limit", "", "application container resources.limits.cpu")
 	memLimit := flag.String("memory-limit", "", "application container resources.limits.memory")
+	recordingDir := flag.String("recording-dir", "", "directory where the sessions are recorded (recording is disabled if empty)")
+	recordingUploadURL := flag.String("recording-upload-url", "", "URL the session recordings are uploaded to once completed (kept in the recording directory if empty)")
+	viewerKeyFile := flag.String("viewer-key-file", "", "file containing the key view-only tokens are signed with (view-only access is disabled if empty)")
 
 	log.SetFlags(0)
 	flag.Parse()
@@ -64,31 +67,49 @@ func main() {
 		log.Println("Instance metrics will not be available")
 	}
 
-	go runMetricsServer(*metricsAddr, "/metrics")
+	activity := NewActivityTracker()
+	go runMetricsServer(*metricsAddr, "/metrics", "/activity", activity)
 
 	log.Printf("Websockify listening on %s%s", *httpAddr, *basePath)
 
 	// SyncMap shared between NoVncHandler and InstanceMetricsHandler
 	var connectionsTracking sync.Map
 
+	metricsHandler := &InstanceMetricsHandler{
+		cpuLimit:              *cpuLimit,
+		memoryLimit:           *memLimit,
+		podName:               *podName,
+		connectionsTracking:   &connectionsTracking,
+		cachedResourcesMutex:  sync.RWMutex{},
+		instanceMetricsClient: instMetricsClient,
+	}
+	if instMetricsClient != nil {
+		go metricsHandler.watchCachedResources(ctx, *instMetricsConnectionTimeout)
+	}
+
+	var recorder *SessionRecorder
+	if *recordingDir != "" {
+		log.Printf("Recording sessions in %s", *recordingDir)
+		recorder = &SessionRecorder{Dir: *recordingDir, UploadURL: *recordingUploadURL, Prefix: *podName}
+	}
+
 	mux := http.NewServeMux()
 
-	mux.Handle("/", &NoVncHandler{
+	noVncHandler := &NoVncHandler{
 		BasePath:            *basePath,
 		NoVncFS:             http.FileServer(http.FS(novncFS)),
 		ShowNoVncBar:        *showBar,
 		TargetSocket:        *targetAddr,
 		PingInterval:        time.Second * time.Duration(*pingInterval),
 		connectionsTracking: &connectionsTracking,
-		MetricsHandler: &InstanceMetricsHandler{
-			cpuLimit:              *cpuLimit,
-			memoryLimit:           *memLimit,
-			podName:               *podName,
-			connectionsTracking:   &connectionsTracking,
-			cachedResourcesMutex:  sync.RWMutex{},
-			instanceMetricsClient: instMetricsClient,
-		},
-	})
+		MetricsHandler:      metricsHandler,
+		Activity:            activity,
+		Recorder:            recorder,
+		Audit:               newAuditLogger(),
+		Viewer:              &ViewerTokenValidator{KeyFile: *viewerKeyFile},
+	}
+	mux.Handle("/", noVncHandler)
+	mux.Handle(viewerPathPrefix, &ViewerHandler{NoVncHandler: noVncHandler})
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -35,9 +35,10 @@ var (
 	)
 )
 
-func runMetricsServer(addr, metricsEndpoint string) {
+func runMetricsServer(addr, metricsEndpoint, activityEndpoint string, activity *ActivityTracker) {
 	mux := http.NewServeMux()
 	mux.Handle(metricsEndpoint, promhttp.Handler())
+	mux.Handle(activityEndpoint, activity)
 
 	server := &http.Server{
 		Addr:              addr,
@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +34,7 @@ const (
 	hideNovncBarStyle   = "<style>#noVNC_control_bar_anchor {display:none !important;}</style>\n"
 	websockifyPath      = "/websockify"
 	usagesPath          = "/usages"
+	viewerTokenParam    = "viewToken"
 )
 
 var (
@@ -47,6 +50,8 @@ type ConnInfo struct {
 	ConnTime    time.Time `json:"connTime,omitempty"`
 	DisconnTime time.Time `json:"disconnTime,omitempty"`
 	Active      bool      `json:"active"`
+	// whether the connection has been assigned to a view-only session.
+	viewOnly bool
 }
 
 // NoVncHandler is the main handler for the noVNC server.
@@ -59,6 +64,10 @@ type NoVncHandler struct {
 	ShowNoVncBar        bool
 	TargetSocket        string
 	MetricsHandler      *InstanceMetricsHandler
+	Activity            *ActivityTracker
+	Recorder            *SessionRecorder
+	Audit               *slog.Logger
+	Viewer              *ViewerTokenValidator
 }
 
 // ServeHTTP handles the HTTP request.
@@ -76,19 +85,9 @@ func (h *NoVncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "": // enforce slash terminated path.
 		http.Redirect(w, r, r.URL.Path+"/", http.StatusFound)
 	case "/": // serve vnc index on root.
-		h.serveNoVncHome(w, r)
+		h.serveNoVncHome(w, r, h.BasePath, "")
 	case websockifyPath:
-		// connUid is required for websockify connections.
-		if connUID, ok := r.URL.Query()["connUid"]; ok {
-			if _, ok := h.connectionsTracking.Load(connUID[0]); !ok {
-				http.Error(w, "received connUid queryParam was never assigned", http.StatusBadRequest)
-			} else {
-				log.Println("Connection UID: ", connUID[0])
-				h.serveWs(w, r)
-			}
-		} else {
-			http.Error(w, "connUid queryParam required", http.StatusBadRequest)
-		}
+		h.serveTrackedWs(w, r, false)
 	case usagesPath:
 		h.MetricsHandler.serveWs(w, r)
 	default:
@@ -97,7 +96,28 @@ func (h *NoVncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *NoVncHandler) serveNoVncHome(w http.ResponseWriter, r *http.Request) {
+// serveTrackedWs serves a websockify connection, provided that the connUid query parameter has been previously
+// assigned by serving the noVNC index, in the same (i.e., full control or view-only) mode.
+func (h *NoVncHandler) serveTrackedWs(w http.ResponseWriter, r *http.Request, viewOnly bool) {
+	// connUid is required for websockify connections.
+	connUID, ok := r.URL.Query()["connUid"]
+	if !ok {
+		http.Error(w, "connUid queryParam required", http.StatusBadRequest)
+		return
+	}
+	ci, ok := h.connectionsTracking.Load(connUID[0])
+	if !ok || ci.(ConnInfo).viewOnly != viewOnly {
+		http.Error(w, "received connUid queryParam was never assigned", http.StatusBadRequest)
+		return
+	}
+	log.Println("Connection UID: ", connUID[0])
+	h.serveWs(w, r, viewOnly)
+}
+
+// serveNoVncHome serves the noVNC index, configured to connect to the websockify endpoint under the given base path.
+// The session is view-only in case a view-only token is given, which is forwarded to the websockify endpoint.
+func (h *NoVncHandler) serveNoVncHome(w http.ResponseWriter, r *http.Request, basePath, viewToken string) {
+	viewOnly := viewToken != ""
 	data, err := novncFS.ReadFile("novnc/vnc.html")
 	if err != nil {
 		panic(err)
@@ -118,13 +138,17 @@ func (h *NoVncHandler) serveNoVncHome(w http.ResponseWriter, r *http.Request) {
 		injectStr += hideNovncBarStyle
 	}
 
-	vncEndpoint := strings.TrimPrefix(h.BasePath+websockifyPath, "/")
-	usagesEndpoint := strings.TrimPrefix(h.BasePath+usagesPath, "/")
+	vncEndpoint := strings.TrimPrefix(basePath+websockifyPath, "/")
+	usagesEndpoint := strings.TrimPrefix(basePath+usagesPath, "/")
 	uid := strings.ReplaceAll(uuid.New().String(), "-", "")
-	injectStr += fmt.Sprintf(`<script>window.websockifyTargetUrl='%s?connUid=%s';
-	window.metricsTargetUrl='%s?connUid=%s';</script>`, vncEndpoint, uid, usagesEndpoint, uid)
+	vncQuery := url.Values{"connUid": {uid}}
+	if viewOnly {
+		vncQuery.Set(viewerTokenParam, viewToken)
+	}
+	injectStr += fmt.Sprintf(`<script>window.websockifyTargetUrl='%s?%s';
+	window.metricsTargetUrl='%s?connUid=%s';</script>`, vncEndpoint, vncQuery.Encode(), usagesEndpoint, uid)
 
-	connectionInfo := &ConnInfo{IP: ip, UID: uid, Latency: 0, ConnTime: time.Now(), DisconnTime: time.Now(), Active: false}
+	connectionInfo := &ConnInfo{IP: ip, UID: uid, Latency: 0, ConnTime: time.Now(), DisconnTime: time.Now(), Active: false, viewOnly: viewOnly}
 	h.connectionsTracking.Store(connectionInfo.UID, *connectionInfo)
 
 	data = bytes.ReplaceAll(data, searchStringHeadBytes, []byte(injectStr))
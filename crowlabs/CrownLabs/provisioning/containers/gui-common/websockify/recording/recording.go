@@ -0,0 +1,143 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recording defines the format of the recordings of the remote desktop sessions, consisting of
+// a header followed by the sequence of timestamped RFB frames exchanged by the client and the server,
+// encoded as JSON lines.
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// FormatVersion is the version of the recording format.
+	FormatVersion = 1
+	// FileExtension is the extension of the recording files.
+	FileExtension = ".rec.jsonl"
+)
+
+// Source identifies the peer which sent a recorded frame.
+type Source string
+
+const (
+	// SourceServer identifies the frames sent by the VNC server.
+	SourceServer Source = "server"
+	// SourceClient identifies the frames sent by the noVNC client.
+	SourceClient Source = "client"
+)
+
+// Header describes the recorded session.
+type Header struct {
+	Version   int       `json:"version"`
+	StartTime time.Time `json:"startTime"`
+	ConnUID   string    `json:"connUid"`
+	ClientIP  string    `json:"clientIp"`
+}
+
+// Frame is a chunk of the RFB stream, as received from one of the peers.
+type Frame struct {
+	// The offset since the beginning of the session, in milliseconds.
+	Offset int64  `json:"t"`
+	Source Source `json:"src"`
+	Data   []byte `json:"data"`
+}
+
+// Writer records a session to a file, and it is safe for concurrent use.
+type Writer struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	start   time.Time
+}
+
+// Create creates a new recording in the given directory, named after the given prefix and the session.
+func Create(dir, prefix string, header Header) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed creating recordings directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s%s", prefix, header.StartTime.UTC().Format("20060102T150405Z"), header.ConnUID, FileExtension)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating recording file: %w", err)
+	}
+
+	header.Version = FormatVersion
+	w := &Writer{file: file, encoder: json.NewEncoder(file), start: header.StartTime}
+	if err := w.encoder.Encode(header); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed writing recording header: %w", err)
+	}
+	return w, nil
+}
+
+// Path returns the path of the recording file.
+func (w *Writer) Path() string {
+	return w.file.Name()
+}
+
+// Write records a frame received from the given source.
+func (w *Writer) Write(source Source, data []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.encoder.Encode(Frame{Offset: time.Since(w.start).Milliseconds(), Source: source, Data: data})
+}
+
+// Close completes the recording.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.file.Close()
+}
+
+// Reader reads a recording, frame by frame.
+type Reader struct {
+	Header  Header
+	decoder *json.Decoder
+}
+
+// NewReader returns a new Reader, after decoding the header of the recording.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{decoder: json.NewDecoder(r)}
+	if err := reader.decoder.Decode(&reader.Header); err != nil {
+		return nil, fmt.Errorf("failed reading recording header: %w", err)
+	}
+	if reader.Header.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", reader.Header.Version)
+	}
+	return reader, nil
+}
+
+// Next returns the next frame of the recording, or io.EOF once completed.
+func (r *Reader) Next() (*Frame, error) {
+	var frame Frame
+	if err := r.decoder.Decode(&frame); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// The last frame may be truncated, in case websockify terminated abruptly.
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return &frame, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	header := Header{StartTime: time.Now(), ConnUID: "conn-uid", ClientIP: "10.0.0.1"}
+
+	w, err := Create(dir, "pod", header)
+	if err != nil {
+		t.Fatalf("failed creating recording: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(w.Path()), "pod-") || !strings.HasSuffix(w.Path(), "-conn-uid"+FileExtension) {
+		t.Errorf("unexpected recording name %q", filepath.Base(w.Path()))
+	}
+
+	frames := []struct {
+		source Source
+		data   []byte
+	}{
+		{SourceServer, []byte("RFB 003.008\n")},
+		{SourceClient, []byte{0x00, 0xff, 0x10}},
+		{SourceServer, []byte{}},
+	}
+	for _, frame := range frames {
+		if err := w.Write(frame.source, frame.data); err != nil {
+			t.Fatalf("failed writing frame: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed closing recording: %v", err)
+	}
+
+	file, err := os.Open(w.Path())
+	if err != nil {
+		t.Fatalf("failed opening recording: %v", err)
+	}
+	defer file.Close()
+
+	r, err := NewReader(file)
+	if err != nil {
+		t.Fatalf("failed reading recording: %v", err)
+	}
+	if r.Header.Version != FormatVersion || r.Header.ConnUID != header.ConnUID || r.Header.ClientIP != header.ClientIP ||
+		!r.Header.StartTime.Equal(header.StartTime) {
+		t.Errorf("unexpected header %+v", r.Header)
+	}
+
+	var previous int64
+	for i, expected := range frames {
+		frame, err := r.Next()
+		if err != nil {
+			t.Fatalf("failed reading frame %d: %v", i, err)
+		}
+		if frame.Source != expected.source || !bytes.Equal(frame.Data, expected.data) {
+			t.Errorf("unexpected frame %d: %+v", i, frame)
+		}
+		if frame.Offset < previous {
+			t.Errorf("frame %d offset %d precedes the previous one (%d)", i, frame.Offset, previous)
+		}
+		previous = frame.Offset
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of the recording, got %v", err)
+	}
+}
+
+func TestCreateExisting(t *testing.T) {
+	dir := t.TempDir()
+	header := Header{StartTime: time.Now(), ConnUID: "conn-uid"}
+
+	w, err := Create(dir, "pod", header)
+	if err != nil {
+		t.Fatalf("failed creating recording: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := Create(dir, "pod", header); err == nil {
+		t.Error("expected an error overwriting an existing recording")
+	}
+}
+
+func TestReaderTruncatedFrame(t *testing.T) {
+	recording := `{"version":1,"startTime":"2024-01-01T00:00:00Z","connUid":"conn-uid","clientIp":"10.0.0.1"}
+{"t":10,"src":"server","data":"AAE="}
+{"t":20,"src":"cli`
+
+	r, err := NewReader(strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("failed reading recording: %v", err)
+	}
+	if frame, err := r.Next(); err != nil || frame.Offset != 10 || !bytes.Equal(frame.Data, []byte{0x00, 0x01}) {
+		t.Fatalf("unexpected frame %+v (error: %v)", frame, err)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF on the truncated frame, got %v", err)
+	}
+}
+
+func TestReaderUnsupportedVersion(t *testing.T) {
+	if _, err := NewReader(strings.NewReader(`{"version":42}`)); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+	if _, err := NewReader(strings.NewReader("")); err == nil {
+		t.Error("expected an error for an empty recording")
+	}
+}
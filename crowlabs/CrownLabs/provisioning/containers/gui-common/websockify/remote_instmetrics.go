@@ -70,3 +70,23 @@ func (c *InstanceMetricsClient) ContainerMetrics(ctx context.Context, podName *s
 	}
 	return resp, nil
 }
+
+// WatchContainerMetrics streams the containerMetrics given a podName, invoking the handler for each update.
+// It returns when the stream is terminated, either because of an error or by the server.
+func (c *InstanceMetricsClient) WatchContainerMetrics(ctx context.Context, podName *string, handler func(*pb.ContainerMetricsResponse)) error {
+	stream, err := c.client.WatchContainerMetrics(ctx, &pb.ContainerMetricsRequest{
+		PodName: *podName,
+	})
+	if err != nil {
+		log.Printf("WatchContainerMetrics with podName filter '%s' failed. Error: %v", *podName, err)
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		handler(resp)
+	}
+}
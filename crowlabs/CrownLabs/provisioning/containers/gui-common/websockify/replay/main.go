@@ -0,0 +1,189 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Replay renders a session recorded by websockify back through noVNC, by serving the noVNC
+// client and replaying the frames sent by the VNC server with their original timing.
+//
+// Usage:
+//
+//	go run ./replay --recording <file> --novnc-dir <dir>
+//
+// where the noVNC directory can be prepared through the prepare-novnc.sh script.
+// The recording is then available at http://127.0.0.1:8081/, and it is replayed
+// from the beginning at each connection (e.g., reloading the page).
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/novnc/websockify-other/websockify/recording"
+)
+
+const (
+	websockifyPath = "/websockify"
+	injectedScript = "<head>\n<script>window.websockifyTargetUrl='websockify';</script>"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+type replayHandler struct {
+	recordingPath string
+	novncDir      string
+	speed         float64
+	files         http.Handler
+}
+
+func main() {
+	httpAddr := flag.String("http-addr", "127.0.0.1:8081", "replay server listen address:port")
+	recordingPath := flag.String("recording", "", "path of the session recording to be replayed")
+	novncDir := flag.String("novnc-dir", "novnc", "directory containing the noVNC client")
+	speed := flag.Float64("speed", 1, "replay speed factor")
+
+	log.SetFlags(0)
+	flag.Parse()
+
+	if *recordingPath == "" {
+		log.Fatal("the recording to be replayed is required")
+	}
+	if *speed <= 0 {
+		log.Fatal("the replay speed must be positive")
+	}
+
+	file, err := os.Open(*recordingPath)
+	if err != nil {
+		log.Fatal("failed opening recording: ", err)
+	}
+	reader, err := recording.NewReader(file)
+	_ = file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Replaying session %s from IP=%s, started at %s", reader.Header.ConnUID, reader.Header.ClientIP, reader.Header.StartTime.Format(time.RFC3339))
+
+	handler := &replayHandler{
+		recordingPath: *recordingPath,
+		novncDir:      *novncDir,
+		speed:         *speed,
+		files:         http.FileServer(http.Dir(*novncDir)),
+	}
+
+	server := &http.Server{
+		Addr:              *httpAddr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           handler,
+	}
+
+	log.Printf("Replay available at http://%s/", *httpAddr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal("failed starting replay server", err)
+	}
+}
+
+// ServeHTTP serves the noVNC client, configured to connect to the replay endpoint.
+func (h *replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		data, err := os.ReadFile(filepath.Join(h.novncDir, "vnc.html"))
+		if err != nil {
+			http.Error(w, "noVNC client not found", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		if _, err := w.Write(bytes.Replace(data, []byte("<head>"), []byte(injectedScript), 1)); err != nil {
+			log.Println("index write error:", err)
+		}
+	case websockifyPath:
+		h.replay(w, r)
+	default:
+		h.files.ServeHTTP(w, r)
+	}
+}
+
+// replay sends the frames originally sent by the server to the client, discarding the client ones.
+func (h *replayHandler) replay(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("upgrade:", err)
+		return
+	}
+	defer ws.Close()
+
+	// Consume the client messages, which are not relevant for the replay, detecting the disconnection.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	file, err := os.Open(h.recordingPath)
+	if err != nil {
+		log.Println("failed opening recording:", err)
+		return
+	}
+	defer file.Close()
+
+	reader, err := recording.NewReader(file)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	start := time.Now()
+	for {
+		frame, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			log.Println("replay completed")
+			break
+		}
+		if err != nil {
+			log.Println("failed reading recording:", err)
+			return
+		}
+		if frame.Source != recording.SourceServer {
+			continue
+		}
+
+		delay := time.Duration(float64(frame.Offset)/h.speed)*time.Millisecond - time.Since(start)
+		select {
+		case <-closed:
+			return
+		case <-time.After(delay):
+		}
+
+		if err := ws.WriteMessage(websocket.BinaryMessage, frame.Data); err != nil {
+			log.Println("ws write error:", err)
+			return
+		}
+	}
+
+	// Keep the connection open, so that the last frame remains visible.
+	<-closed
+}
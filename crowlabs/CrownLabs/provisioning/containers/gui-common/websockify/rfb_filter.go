@@ -0,0 +1,172 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The stages of the client to server RFB stream.
+type rfbStage int
+
+const (
+	rfbStageVersion rfbStage = iota
+	rfbStageSecurity
+	rfbStageVNCAuth
+	rfbStageClientInit
+	rfbStageMessages
+)
+
+const (
+	rfbVersionLength     = 12
+	rfbVNCAuthLength     = 16
+	rfbSecurityNone      = 1
+	rfbSecurityVNCAuth   = 2
+	rfbMaxCutTextLength  = 1 << 20
+	rfbSetPixelFormat    = 0
+	rfbSetEncodings      = 2
+	rfbFramebufferUpdate = 3
+	rfbClientCutText     = 6
+	rfbContinuousUpdates = 150
+	rfbClientFence       = 248
+	rfbXvp               = 250
+	rfbSetDesktopSize    = 251
+	rfbQEMU              = 255
+	rfbQEMUExtendedKey   = 0
+)
+
+var errUnsupportedRFB = errors.New("unsupported RFB message for view-only sessions")
+
+// viewOnlyFilter parses the RFB stream sent by a client, dropping the messages carrying input
+// (e.g., key and pointer events) or altering the remote desktop, so that the client can only
+// observe the framebuffer. Since the stream is parsed statefully, messages may span multiple
+// websocket frames; unknown messages terminate the session, as they could not be skipped.
+type viewOnlyFilter struct {
+	stage   rfbStage
+	pending []byte
+}
+
+// Filter returns the portion of the given client data which can be forwarded to the server.
+func (f *viewOnlyFilter) Filter(data []byte) ([]byte, error) {
+	f.pending = append(f.pending, data...)
+
+	var forwarded []byte
+	for len(f.pending) > 0 {
+		length, forward, next, err := f.nextMessage()
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 || len(f.pending) < length {
+			// The message is not complete yet.
+			break
+		}
+
+		message := f.pending[:length]
+		if f.stage == rfbStageClientInit {
+			// Force the session to be shared, not to disconnect the other clients.
+			message = []byte{1}
+		}
+		if forward {
+			forwarded = append(forwarded, message...)
+		}
+		f.pending = f.pending[length:]
+		f.stage = next
+	}
+
+	f.pending = append([]byte(nil), f.pending...)
+	return forwarded, nil
+}
+
+// nextMessage returns the length of the next message, whether it has to be forwarded, and the next stage.
+// A zero length is returned in case more data is required to determine it.
+func (f *viewOnlyFilter) nextMessage() (length int, forward bool, next rfbStage, err error) {
+	p := f.pending
+	switch f.stage {
+	case rfbStageVersion:
+		if len(p) < rfbVersionLength {
+			return 0, false, f.stage, nil
+		}
+		if string(p[:rfbVersionLength]) < "RFB 003.007\n" {
+			return 0, false, f.stage, fmt.Errorf("%w: protocol version %q", errUnsupportedRFB, p[:rfbVersionLength])
+		}
+		return rfbVersionLength, true, rfbStageSecurity, nil
+	case rfbStageSecurity:
+		switch p[0] {
+		case rfbSecurityNone:
+			return 1, true, rfbStageClientInit, nil
+		case rfbSecurityVNCAuth:
+			return 1, true, rfbStageVNCAuth, nil
+		default:
+			return 0, false, f.stage, fmt.Errorf("%w: security type %d", errUnsupportedRFB, p[0])
+		}
+	case rfbStageVNCAuth:
+		return rfbVNCAuthLength, true, rfbStageClientInit, nil
+	case rfbStageClientInit:
+		return 1, true, rfbStageMessages, nil
+	}
+
+	switch p[0] {
+	case rfbSetPixelFormat:
+		return 20, true, f.stage, nil
+	case rfbSetEncodings:
+		if len(p) < 4 {
+			return 0, false, f.stage, nil
+		}
+		return 4 + 4*int(binary.BigEndian.Uint16(p[2:4])), true, f.stage, nil
+	case rfbFramebufferUpdate:
+		return 10, true, f.stage, nil
+	case rfbKeyEvent:
+		return 8, false, f.stage, nil
+	case rfbPointerEvent:
+		return rfbPointerEventLength, false, f.stage, nil
+	case rfbClientCutText:
+		if len(p) < 8 {
+			return 0, false, f.stage, nil
+		}
+		// Negative lengths identify the extended clipboard messages.
+		textLength := int64(int32(binary.BigEndian.Uint32(p[4:8])))
+		if textLength < 0 {
+			textLength = -textLength
+		}
+		if textLength > rfbMaxCutTextLength {
+			return 0, false, f.stage, fmt.Errorf("%w: cut text too long", errUnsupportedRFB)
+		}
+		return 8 + int(textLength), false, f.stage, nil
+	case rfbContinuousUpdates:
+		return 10, true, f.stage, nil
+	case rfbClientFence:
+		if len(p) < 9 {
+			return 0, false, f.stage, nil
+		}
+		return 9 + int(p[8]), true, f.stage, nil
+	case rfbXvp:
+		return 4, false, f.stage, nil
+	case rfbSetDesktopSize:
+		if len(p) < 8 {
+			return 0, false, f.stage, nil
+		}
+		return 8 + 16*int(p[6]), false, f.stage, nil
+	case rfbQEMU:
+		if len(p) < 2 {
+			return 0, false, f.stage, nil
+		}
+		if p[1] == rfbQEMUExtendedKey {
+			return 12, false, f.stage, nil
+		}
+	}
+	return 0, false, f.stage, fmt.Errorf("%w: message type %d", errUnsupportedRFB, p[0])
+}
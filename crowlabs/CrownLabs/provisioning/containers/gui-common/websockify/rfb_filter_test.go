@@ -0,0 +1,150 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// handshake is the client side of the RFB handshake, with no authentication and an exclusive session requested.
+var handshake = []byte("RFB 003.008\n\x01\x00")
+
+// cutText forges a ClientCutText message with the given length field and text.
+func cutText(length int32, text string) []byte {
+	message := []byte{rfbClientCutText, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(message[4:8], uint32(length))
+	return append(message, text...)
+}
+
+func TestViewOnlyFilterHandshake(t *testing.T) {
+	var f viewOnlyFilter
+	forwarded, err := f.Filter(handshake)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The session is forced to be shared.
+	if expected := []byte("RFB 003.008\n\x01\x01"); !bytes.Equal(forwarded, expected) {
+		t.Errorf("unexpected forwarded handshake %q, expected %q", forwarded, expected)
+	}
+	if f.stage != rfbStageMessages {
+		t.Errorf("unexpected stage %d after the handshake", f.stage)
+	}
+}
+
+func TestViewOnlyFilterVNCAuth(t *testing.T) {
+	var f viewOnlyFilter
+	auth := bytes.Repeat([]byte{0xaa}, rfbVNCAuthLength)
+	data := append(append([]byte("RFB 003.008\n\x02"), auth...), 0)
+
+	forwarded, err := f.Filter(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := append(append([]byte("RFB 003.008\n\x02"), auth...), 1)
+	if !bytes.Equal(forwarded, expected) {
+		t.Errorf("unexpected forwarded handshake %q, expected %q", forwarded, expected)
+	}
+}
+
+func TestViewOnlyFilterMessages(t *testing.T) {
+	setPixelFormat := append([]byte{rfbSetPixelFormat}, make([]byte, 19)...)
+	setEncodings := []byte{rfbSetEncodings, 0, 0, 2, 0, 0, 0, 7, 0xff, 0xff, 0xff, 0x21}
+	updateRequest := []byte{rfbFramebufferUpdate, 1, 0, 0, 0, 0, 4, 0, 3, 0}
+	fence := []byte{rfbClientFence, 0, 0, 0, 0, 0, 0, 0, 2, 'o', 'k'}
+	continuousUpdates := []byte{rfbContinuousUpdates, 1, 0, 0, 0, 0, 4, 0, 3, 0}
+
+	cases := []struct {
+		name     string
+		data     []byte
+		expected []byte
+	}{
+		{"set pixel format", setPixelFormat, setPixelFormat},
+		{"set encodings", setEncodings, setEncodings},
+		{"framebuffer update request", updateRequest, updateRequest},
+		{"client fence", fence, fence},
+		{"continuous updates", continuousUpdates, continuousUpdates},
+		{"key event", []byte{rfbKeyEvent, 1, 0, 0, 0, 0, 0, 'a'}, nil},
+		{"pointer event", []byte{rfbPointerEvent, 1, 0, 10, 0, 10}, nil},
+		{"cut text", cutText(5, "hello"), nil},
+		{"extended cut text", cutText(-4, "\x00\x00\x00\x01"), nil},
+		{"xvp", []byte{rfbXvp, 0, 1, 2}, nil},
+		{"set desktop size", []byte{rfbSetDesktopSize, 0, 4, 0, 3, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4, 0, 3, 0, 0, 0, 0, 0}, nil},
+		{"qemu extended key event", []byte{rfbQEMU, rfbQEMUExtendedKey, 0, 1, 0, 0, 0, 'a', 0, 0, 0, 0x1e}, nil},
+		{"mixed messages", append(append([]byte{rfbKeyEvent, 1, 0, 0, 0, 0, 0, 'a'}, updateRequest...), rfbPointerEvent, 1, 0, 10, 0, 10), updateRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := viewOnlyFilter{stage: rfbStageMessages}
+			forwarded, err := f.Filter(c.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(forwarded, c.expected) {
+				t.Errorf("unexpected forwarded data %v, expected %v", forwarded, c.expected)
+			}
+			if len(f.pending) != 0 {
+				t.Errorf("unexpected pending data %v", f.pending)
+			}
+		})
+	}
+}
+
+func TestViewOnlyFilterFragmented(t *testing.T) {
+	f := viewOnlyFilter{}
+	data := append(append(append([]byte{}, handshake...), rfbPointerEvent, 1, 0, 10, 0, 10), rfbFramebufferUpdate, 1, 0, 0, 0, 0, 4, 0, 3, 0)
+
+	// Feed the stream one byte at a time, as messages may span multiple websocket frames.
+	var forwarded []byte
+	for i := range data {
+		chunk, err := f.Filter(data[i : i+1])
+		if err != nil {
+			t.Fatalf("unexpected error at byte %d: %v", i, err)
+		}
+		forwarded = append(forwarded, chunk...)
+	}
+
+	expected := append([]byte("RFB 003.008\n\x01\x01"), rfbFramebufferUpdate, 1, 0, 0, 0, 0, 4, 0, 3, 0)
+	if !bytes.Equal(forwarded, expected) {
+		t.Errorf("unexpected forwarded data %q, expected %q", forwarded, expected)
+	}
+}
+
+func TestViewOnlyFilterErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		stage rfbStage
+		data  []byte
+	}{
+		{"old protocol version", rfbStageVersion, []byte("RFB 003.003\n")},
+		{"unsupported security type", rfbStageSecurity, []byte{16}},
+		{"unknown message", rfbStageMessages, []byte{42, 0, 0, 0}},
+		{"unknown qemu message", rfbStageMessages, []byte{rfbQEMU, 1, 0, 0}},
+		{"cut text too long", rfbStageMessages, cutText(rfbMaxCutTextLength+1, "")},
+		{"cut text with minimum length", rfbStageMessages, cutText(-1<<31, "")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := viewOnlyFilter{stage: c.stage}
+			if _, err := f.Filter(c.data); !errors.Is(err, errUnsupportedRFB) {
+				t.Errorf("expected an unsupported RFB error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/novnc/websockify-other/websockify/recording"
+)
+
+const (
+	uploadAttempts = 3
+	uploadTimeout  = 2 * time.Minute
+)
+
+// session tracks a remote desktop session, for auditing and recording purposes.
+type session struct {
+	connUID  string
+	ip       string
+	start    time.Time
+	recorder *recording.Writer
+	// viewOnly filters the client messages, if the session is view-only.
+	viewOnly *viewOnlyFilter
+}
+
+// record stores a frame of the session, if recording is enabled.
+func (s *session) record(source recording.Source, data []byte) {
+	if s.recorder == nil {
+		return
+	}
+	if err := s.recorder.Write(source, data); err != nil {
+		log.Println("recording write error:", err)
+	}
+}
+
+// SessionRecorder records the remote desktop sessions, storing them in a directory
+// and optionally uploading them to a remote endpoint once completed.
+type SessionRecorder struct {
+	Dir       string
+	UploadURL string
+	Prefix    string
+}
+
+// startSession starts tracking a new session, recording it if configured.
+// View-only sessions are never recorded, as they cannot alter the remote desktop.
+func (h *NoVncHandler) startSession(connUID, ip string, viewOnly bool) *session {
+	s := &session{connUID: connUID, ip: ip, start: time.Now()}
+	if viewOnly {
+		s.viewOnly = &viewOnlyFilter{}
+	}
+
+	if h.Recorder != nil && !viewOnly {
+		header := recording.Header{StartTime: s.start, ConnUID: connUID, ClientIP: ip}
+		recorder, err := recording.Create(h.Recorder.Dir, h.Recorder.Prefix, header)
+		if err != nil {
+			log.Println("failed starting session recording:", err)
+			h.Audit.Error("session recording failed", "connUid", connUID, "ip", ip, "error", err)
+		} else {
+			s.recorder = recorder
+		}
+	}
+
+	h.Audit.Info("session started", "connUid", connUID, "ip", ip, "viewOnly", viewOnly, "recorded", s.recorder != nil)
+	return s
+}
+
+// endSession completes the tracking of the given session, finalizing its recording.
+func (h *NoVncHandler) endSession(s *session) {
+	h.Audit.Info("session ended", "connUid", s.connUID, "ip", s.ip, "duration", time.Since(s.start).Round(time.Second).String())
+	if s.recorder == nil {
+		return
+	}
+
+	if err := s.recorder.Close(); err != nil {
+		log.Println("failed completing session recording:", err)
+	}
+	if h.Recorder.UploadURL == "" {
+		return
+	}
+
+	path := s.recorder.Path()
+	if err := h.Recorder.upload(path); err != nil {
+		log.Println("failed uploading session recording:", err)
+		h.Audit.Error("session recording upload failed", "connUid", s.connUID, "recording", filepath.Base(path), "error", err)
+		return
+	}
+
+	h.Audit.Info("session recording uploaded", "connUid", s.connUID, "recording", filepath.Base(path))
+	if err := os.Remove(path); err != nil {
+		log.Println("failed removing uploaded session recording:", err)
+	}
+}
+
+// upload uploads the given recording, through a multipart POST request (as for the instance content).
+func (r *SessionRecorder) upload(path string) error {
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		if err = r.uploadOnce(path); err == nil {
+			return nil
+		}
+		log.Printf("session recording upload attempt %d failed: %v", attempt, err)
+		time.Sleep(time.Duration(attempt) * 5 * time.Second)
+	}
+	return err
+}
+
+func (r *SessionRecorder) uploadOnce(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// The body is streamed through a pipe, not to keep the whole recording in memory.
+	body, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipartRecording(writer, file, filepath.Base(path)))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.UploadURL, body)
+	if err != nil {
+		_ = body.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// writeMultipartRecording writes the multipart body carrying the given recording.
+func writeMultipartRecording(writer *multipart.Writer, file io.Reader, name string) error {
+	part, err := writer.CreateFormFile("binfile", name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := writer.WriteField("filename", name); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// newAuditLogger returns the logger for the audit events, in JSON format on the standard output.
+func newAuditLogger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("log", "audit")
+}
@@ -0,0 +1,77 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionRecorderUpload(t *testing.T) {
+	content := []byte("{\"version\":1}\n{\"t\":0,\"src\":\"server\",\"data\":\"AA==\"}\n")
+	path := filepath.Join(t.TempDir(), "pod-session.rec.jsonl")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("failed writing recording: %v", err)
+	}
+
+	var received []byte
+	var filename string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("binfile")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		received, _ = io.ReadAll(file)
+		filename = r.FormValue("filename")
+	}))
+	defer server.Close()
+
+	recorder := SessionRecorder{UploadURL: server.URL}
+	if err := recorder.uploadOnce(path); err != nil {
+		t.Fatalf("failed uploading recording: %v", err)
+	}
+	if string(received) != string(content) {
+		t.Errorf("unexpected uploaded content %q", received)
+	}
+	if filename != filepath.Base(path) {
+		t.Errorf("unexpected uploaded filename %q", filename)
+	}
+}
+
+func TestSessionRecorderUploadFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pod-session.rec.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("failed writing recording: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	recorder := SessionRecorder{UploadURL: server.URL}
+	if err := recorder.uploadOnce(path); err == nil {
+		t.Error("expected an error for a non-2xx status code")
+	}
+	if err := recorder.uploadOnce(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing recording")
+	}
+}
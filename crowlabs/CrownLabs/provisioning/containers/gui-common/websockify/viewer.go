@@ -0,0 +1,148 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// viewerPathPrefix is the prefix of the path the view-only sessions are served at.
+	viewerPathPrefix = "/viewer/"
+	// viewerPathMessage is signed with the viewer key to derive the path of the view-only sessions (as the instance operator does).
+	viewerPathMessage = "viewer-path"
+)
+
+// ViewerTokenValidator validates the tokens granting view-only access to the remote desktop, generated by the
+// instance operator: the expiration (as Unix timestamp) and its HMAC-SHA256 signature, separated by a dot.
+type ViewerTokenValidator struct {
+	// The file holding the key the tokens are signed with. It is read at every validation,
+	// so that the rotation of the key (i.e., the revocation of the tokens) is immediately effective.
+	KeyFile string
+}
+
+// key returns the key the tokens are signed with.
+func (v *ViewerTokenValidator) key() ([]byte, error) {
+	if v == nil || v.KeyFile == "" {
+		return nil, errors.New("view-only access is not enabled")
+	}
+
+	key, err := os.ReadFile(v.KeyFile)
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("failed reading the viewer key: %w", err)
+	}
+	return key, nil
+}
+
+// PathID returns the identifier of the path the view-only sessions are served at, derived from the key.
+func (v *ViewerTokenValidator) PathID() (string, error) {
+	key, err := v.key()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(viewerPathMessage))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Validate returns an error in case the given token is not valid or expired.
+func (v *ViewerTokenValidator) Validate(token string) error {
+	key, err := v.key()
+	if err != nil {
+		return err
+	}
+
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return errors.New("malformed token")
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("malformed token signature")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	if !hmac.Equal(decoded, mac.Sum(nil)) {
+		return errors.New("invalid token signature")
+	}
+
+	expiration, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return errors.New("malformed token expiration")
+	}
+	if time.Now().After(time.Unix(expiration, 0)) {
+		return errors.New("token expired")
+	}
+	return nil
+}
+
+// ViewerHandler serves the view-only sessions, on a dedicated path (exposed through a separate ingress) which is
+// independent of the base path of the instance, and which never grants full control of the remote desktop.
+type ViewerHandler struct {
+	*NoVncHandler
+}
+
+// ServeHTTP handles the HTTP request.
+func (h *ViewerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, cleanedUpPath, found := strings.Cut(strings.TrimPrefix(r.URL.Path, viewerPathPrefix), "/")
+	expected, err := h.Viewer.PathID()
+	if err != nil || subtle.ConstantTimeCompare([]byte(id), []byte(expected)) != 1 {
+		http.NotFound(w, r)
+		log.Printf("not found: %s", r.URL.Path)
+		return
+	}
+
+	basePath := viewerPathPrefix + id
+	if !found {
+		// enforce slash terminated path.
+		target := *r.URL
+		target.Path += "/"
+		http.Redirect(w, r, target.RequestURI(), http.StatusFound)
+		return
+	}
+
+	switch cleanedUpPath = "/" + cleanedUpPath; cleanedUpPath {
+	case "/", websockifyPath:
+		token := r.URL.Query().Get(viewerTokenParam)
+		if err := h.Viewer.Validate(token); err != nil {
+			log.Println("invalid view-only token:", err)
+			http.Error(w, "invalid or expired view-only link", http.StatusForbidden)
+			return
+		}
+		if cleanedUpPath == "/" {
+			h.serveNoVncHome(w, r, basePath, token)
+		} else {
+			h.serveTrackedWs(w, r, true)
+		}
+	case usagesPath:
+		h.MetricsHandler.serveWs(w, r)
+	default:
+		r.URL.Path = "novnc" + cleanedUpPath
+		h.NoVncFS.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testViewerKey = []byte("0123456789abcdef0123456789abcdef")
+
+// viewerToken forges a view-only token, as the instance operator does.
+func viewerToken(key []byte, expiration time.Time) string {
+	payload := strconv.FormatInt(expiration.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestViewerValidator(t *testing.T) *ViewerTokenValidator {
+	t.Helper()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, testViewerKey, 0o600); err != nil {
+		t.Fatalf("failed writing viewer key: %v", err)
+	}
+	return &ViewerTokenValidator{KeyFile: keyFile}
+}
+
+func TestViewerTokenValidator(t *testing.T) {
+	validator := newTestViewerValidator(t)
+	valid := viewerToken(testViewerKey, time.Now().Add(time.Hour))
+
+	cases := []struct {
+		name      string
+		validator *ViewerTokenValidator
+		token     string
+		valid     bool
+	}{
+		{"valid token", validator, valid, true},
+		{"expired token", validator, viewerToken(testViewerKey, time.Now().Add(-time.Minute)), false},
+		{"token signed with a different key", validator, viewerToken([]byte("another-key"), time.Now().Add(time.Hour)), false},
+		{"tampered expiration", validator, strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10) + valid[strings.Index(valid, "."):], false},
+		{"malformed token", validator, "malformed", false},
+		{"empty token", validator, "", false},
+		{"view-only access disabled", &ViewerTokenValidator{}, valid, false},
+		{"missing key", &ViewerTokenValidator{KeyFile: filepath.Join(t.TempDir(), "missing")}, valid, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.validator.Validate(c.token); (err == nil) != c.valid {
+				t.Errorf("unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestViewerTokenValidatorPathID(t *testing.T) {
+	mac := hmac.New(sha256.New, testViewerKey)
+	mac.Write([]byte(viewerPathMessage))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	id, err := newTestViewerValidator(t).PathID()
+	if err != nil || id != expected {
+		t.Errorf("unexpected path identifier %q (error: %v), expected %q", id, err, expected)
+	}
+	if _, err := (&ViewerTokenValidator{}).PathID(); err == nil {
+		t.Error("expected an error with view-only access disabled")
+	}
+}
+
+func TestViewerHandler(t *testing.T) {
+	if _, err := novncFS.ReadFile("novnc/vnc.html"); err != nil {
+		t.Skip("noVNC has not been prepared")
+	}
+
+	validator := newTestViewerValidator(t)
+	id, _ := validator.PathID()
+	viewerPath := viewerPathPrefix + id
+	token := viewerToken(testViewerKey, time.Now().Add(time.Hour))
+
+	noVncHandler := &NoVncHandler{
+		connectionsTracking: &sync.Map{},
+		BasePath:            "/instance/uid/app",
+		Viewer:              validator,
+		Audit:               newAuditLogger(),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", noVncHandler)
+	mux.Handle(viewerPathPrefix, &ViewerHandler{NoVncHandler: noVncHandler})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return recorder
+	}
+	connUIDRegex := regexp.MustCompile(`connUid=([0-9a-f]+)`)
+	index := func(path string) string {
+		response := serve(path)
+		if response.Code != http.StatusOK {
+			t.Fatalf("unexpected status code %d serving the index at %s", response.Code, path)
+		}
+		return response.Body.String()
+	}
+	viewerQuery := "?" + url.Values{viewerTokenParam: {token}}.Encode()
+
+	t.Run("unknown viewer path", func(t *testing.T) {
+		if code := serve(viewerPathPrefix + "unknown/" + viewerQuery).Code; code != http.StatusNotFound {
+			t.Errorf("unexpected status code %d", code)
+		}
+	})
+
+	t.Run("missing trailing slash", func(t *testing.T) {
+		response := serve(viewerPath + viewerQuery)
+		if response.Code != http.StatusFound || response.Header().Get("Location") != viewerPath+"/"+viewerQuery {
+			t.Errorf("unexpected redirection %d to %q", response.Code, response.Header().Get("Location"))
+		}
+	})
+
+	t.Run("missing or invalid token", func(t *testing.T) {
+		for _, path := range []string{viewerPath + "/", viewerPath + websockifyPath + "?connUid=x", viewerPath + "/?" + viewerTokenParam + "=invalid"} {
+			if code := serve(path).Code; code != http.StatusForbidden {
+				t.Errorf("unexpected status code %d for %s", code, path)
+			}
+		}
+	})
+
+	t.Run("view-only index", func(t *testing.T) {
+		body := index(viewerPath + "/" + viewerQuery)
+		if !strings.Contains(body, strings.TrimPrefix(viewerPath+websockifyPath, "/")+"?") {
+			t.Errorf("the view-only index does not target the viewer websockify endpoint: %s", body)
+		}
+		if strings.Contains(body, noVncHandler.BasePath) {
+			t.Errorf("the view-only index discloses the base path of the instance: %s", body)
+		}
+
+		// The connection is assigned to a view-only session, hence it cannot be used on the full control endpoint.
+		connUID := connUIDRegex.FindStringSubmatch(body)[1]
+		if code := serve(noVncHandler.BasePath + websockifyPath + "?connUid=" + connUID).Code; code != http.StatusBadRequest {
+			t.Errorf("unexpected status code %d using a view-only connection for full control", code)
+		}
+	})
+
+	t.Run("full control index", func(t *testing.T) {
+		// The view-only token is ignored outside of the viewer path.
+		body := index(noVncHandler.BasePath + "/" + viewerQuery)
+		if strings.Contains(body, viewerTokenParam) {
+			t.Errorf("the full control index forwards the view-only token: %s", body)
+		}
+
+		connUID := connUIDRegex.FindStringSubmatch(body)[1]
+		if code := serve(viewerPath + websockifyPath + viewerQuery + "&connUid=" + connUID).Code; code != http.StatusBadRequest {
+			t.Errorf("unexpected status code %d using a full control connection for a view-only session", code)
+		}
+	})
+}
@@ -21,10 +21,13 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/novnc/websockify-other/websockify/recording"
 )
 
 var upgrader = websocket.Upgrader{
@@ -35,7 +38,7 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func forwardtcp(wsconn *websocket.Conn, conn net.Conn) {
+func forwardtcp(wsconn *websocket.Conn, conn net.Conn, s *session) {
 	var tcpbuffer [1024]byte
 	defer wsconn.Close()
 	defer conn.Close()
@@ -45,6 +48,7 @@ func forwardtcp(wsconn *websocket.Conn, conn net.Conn) {
 			log.Println("tcp read error:", err)
 			return
 		}
+		s.record(recording.SourceServer, tcpbuffer[0:n])
 
 		if err := wsconn.WriteMessage(websocket.BinaryMessage, tcpbuffer[0:n]); err != nil {
 			log.Println("ws write error:", err)
@@ -53,15 +57,28 @@ func forwardtcp(wsconn *websocket.Conn, conn net.Conn) {
 	}
 }
 
-func forwardweb(wsconn *websocket.Conn, conn net.Conn) {
+func forwardweb(wsconn *websocket.Conn, conn net.Conn, activity *ActivityTracker, s *session) {
 	defer wsconn.Close()
 	defer conn.Close()
+	activity.SessionStarted()
+	defer activity.SessionEnded()
 	for {
 		_, buffer, err := wsconn.ReadMessage()
 		if err != nil {
 			log.Println("ws read error:", err)
 			return
 		}
+		if s.viewOnly != nil {
+			if buffer, err = s.viewOnly.Filter(buffer); err != nil {
+				log.Println("view-only filter error:", err)
+				return
+			}
+			if len(buffer) == 0 {
+				continue
+			}
+		}
+		activity.ClientMessage(buffer)
+		s.record(recording.SourceClient, buffer)
 
 		if _, err := conn.Write(buffer); err != nil {
 			log.Println("tcp write error: ", err)
@@ -70,7 +87,7 @@ func forwardweb(wsconn *websocket.Conn, conn net.Conn) {
 	}
 }
 
-func (h *NoVncHandler) serveWs(w http.ResponseWriter, r *http.Request) {
+func (h *NoVncHandler) serveWs(w http.ResponseWriter, r *http.Request, viewOnly bool) {
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("upgrade:", err)
@@ -93,8 +110,21 @@ func (h *NoVncHandler) serveWs(w http.ResponseWriter, r *http.Request) {
 		connectionInfo := ci.(ConnInfo)
 		metric := makeLatencyObserver(ip, connUID)
 		log.Printf("Incoming websocket connection on path /websockify from IP=%s", ip)
-		go forwardtcp(ws, vnc)
-		go forwardweb(ws, vnc)
+		s := h.startSession(connUID, ip, viewOnly)
+		var forwarders sync.WaitGroup
+		forwarders.Add(2)
+		go func() {
+			defer forwarders.Done()
+			forwardtcp(ws, vnc, s)
+		}()
+		go func() {
+			defer forwarders.Done()
+			forwardweb(ws, vnc, h.Activity, s)
+		}()
+		go func() {
+			forwarders.Wait()
+			h.endSession(s)
+		}()
 		go h.pingCycle(ws, metric, &connectionInfo)
 	} else {
 		log.Println("received connUid queryParam was never assigned")
@@ -0,0 +1,84 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum="Pods";"Services"
+
+// NetworkAllocationType is an enumeration of the different networks a CIDR can be allocated for.
+type NetworkAllocationType string
+
+const (
+	// NetworkAllocationPods -> the CIDR is allocated to the pod network of a cluster.
+	NetworkAllocationPods NetworkAllocationType = "Pods"
+	// NetworkAllocationServices -> the CIDR is allocated to the service network of a cluster.
+	NetworkAllocationServices NetworkAllocationType = "Services"
+)
+
+// ClusterNetworkAllocationSpec is the specification of the desired state of the ClusterNetworkAllocation.
+type ClusterNetworkAllocationSpec struct {
+	// The allocated CIDR.
+	CIDR string `json:"cidr"`
+
+	// The pool the CIDR has been allocated from.
+	Pool string `json:"pool"`
+
+	// The network the CIDR is allocated for (i.e. Pods or Services).
+	Type NetworkAllocationType `json:"type"`
+
+	// The IP family of the allocated CIDR.
+	Family IPFamily `json:"family"`
+
+	// The reference to the Instance the CIDR is allocated to.
+	InstanceRef GenericRef `json:"instance.crownlabs.polito.it/InstanceRef"`
+}
+
+// ClusterNetworkAllocationStatus reflects the most recently observed status of the ClusterNetworkAllocation.
+type ClusterNetworkAllocationStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope="Cluster",shortName="cna"
+// +kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Family",type=string,JSONPath=`.spec.family`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterNetworkAllocation records the allocation of a CIDR to the cluster environment of an Instance.
+// Its name is derived from the CIDR itself, so that the same CIDR cannot be allocated twice.
+type ClusterNetworkAllocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterNetworkAllocationSpec   `json:"spec,omitempty"`
+	Status ClusterNetworkAllocationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterNetworkAllocationList contains a list of ClusterNetworkAllocation objects.
+type ClusterNetworkAllocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterNetworkAllocation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterNetworkAllocation{}, &ClusterNetworkAllocationList{})
+}
@@ -63,3 +63,6 @@ const TnOperatorFinalizerName = "crownlabs.polito.it/tenant-operator"
 
 // ShVolCtrlFinalizerName is the name of the finalizer for SharedVolume's PVC protection.
 const ShVolCtrlFinalizerName = "crownlabs.polito.it/shvolctrl-volume-protection"
+
+// ExamSessionFinalizerName is the name of the finalizer terminating the Instances of a deleted ExamSession.
+const ExamSessionFinalizerName = "crownlabs.polito.it/examsession-cleanup"
@@ -0,0 +1,159 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum="";"Scheduled";"Preparing";"Running";"Completed";"Failed"
+
+// ExamSessionPhase is an enumeration of the different phases associated with an ExamSession.
+type ExamSessionPhase string
+
+const (
+	// ExamSessionPhaseUnset -> the exam session phase is unknown.
+	ExamSessionPhaseUnset ExamSessionPhase = ""
+	// ExamSessionPhaseScheduled -> the exam session is waiting for its Instances to be prepared.
+	ExamSessionPhaseScheduled ExamSessionPhase = "Scheduled"
+	// ExamSessionPhasePreparing -> the Instances of the exam session are created, but not yet running.
+	ExamSessionPhasePreparing ExamSessionPhase = "Preparing"
+	// ExamSessionPhaseRunning -> the exam session started, and its Instances are running.
+	ExamSessionPhaseRunning ExamSessionPhase = "Running"
+	// ExamSessionPhaseCompleted -> the deadline of the exam session passed, and its Instances have been terminated.
+	ExamSessionPhaseCompleted ExamSessionPhase = "Completed"
+	// ExamSessionPhaseFailed -> the exam session cannot be carried out (e.g., a Template does not exist).
+	ExamSessionPhaseFailed ExamSessionPhase = "Failed"
+)
+
+// +kubebuilder:validation:XValidation:rule="self.endTime > self.startTime",message="endTime must follow startTime"
+// +kubebuilder:validation:XValidation:rule="has(self.tenants) || has(self.workspace)",message="at least one of tenants and workspace must be specified"
+
+// ExamSessionSpec is the specification of the desired state of the ExamSession.
+type ExamSessionSpec struct {
+	// The human-readable name of the ExamSession.
+	PrettyName string `json:"prettyName,omitempty"`
+
+	// +kubebuilder:validation:MinItems=1
+
+	// The references to the Templates each student is given an Instance of.
+	// The Templates must belong to the namespace of the ExamSession, which is the default if not specified.
+	Templates []GenericRef `json:"templates"`
+
+	// The Tenants taking the exam, which must be members of the Workspace of the Templates.
+	Tenants []string `json:"tenants,omitempty"`
+
+	// The Workspace whose users (i.e., the Tenants with the user role) take the exam,
+	// in addition to the ones explicitly listed. It must be the Workspace of the Templates.
+	Workspace string `json:"workspace,omitempty"`
+
+	// The time the exam starts at, when the Instances are set running.
+	StartTime metav1.Time `json:"startTime"`
+
+	// The deadline of the exam, when the Instances are terminated (and their content
+	// submitted, or graded, as for the Instances whose status check reports them as expired).
+	EndTime metav1.Time `json:"endTime"`
+
+	// +kubebuilder:default="10m"
+
+	// How long before the start time the Instances are created, not running, to be ready in time.
+	PreparationLeadTime metav1.Duration `json:"preparationLeadTime,omitempty"`
+
+	// Optional urls for advanced integration features, configured for all the Instances.
+	CustomizationUrls *InstanceCustomizationUrls `json:"customizationUrls,omitempty"`
+}
+
+// ExamSessionInstanceStatus reflects the status of an Instance of an ExamSession.
+type ExamSessionInstanceStatus struct {
+	// The name of the Instance.
+	Name string `json:"name"`
+
+	// The namespace of the Instance.
+	Namespace string `json:"namespace"`
+
+	// The name of the Template the Instance refers to.
+	Template string `json:"template"`
+
+	// The current phase of the Instance.
+	Phase EnvironmentPhase `json:"phase,omitempty"`
+
+	// The URL where it is possible to access the remote desktop of the Instance.
+	URL string `json:"url,omitempty"`
+
+	// The status of the automations of the Instance, including the deadline and the submission time.
+	Automation InstanceAutomationStatus `json:"automation,omitempty"`
+}
+
+// ExamSessionStudentStatus reflects the status of a student taking an ExamSession.
+type ExamSessionStudentStatus struct {
+	// The name of the Tenant.
+	Tenant string `json:"tenant"`
+
+	// The status of the Instances of the student.
+	Instances []ExamSessionInstanceStatus `json:"instances,omitempty"`
+
+	// The reason why the Instances of the student could not be enforced, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ExamSessionStatus reflects the most recently observed status of the ExamSession.
+type ExamSessionStatus struct {
+	// The current phase of the lifecycle of the ExamSession.
+	Phase ExamSessionPhase `json:"phase,omitempty"`
+
+	// A human-readable message describing the reason of the current phase, if any.
+	Message string `json:"message,omitempty"`
+
+	// The number of students whose Instances are all ready.
+	ReadyStudents int `json:"readyStudents,omitempty"`
+
+	// +listType=map
+	// +listMapKey=tenant
+
+	// The status of the students taking the exam.
+	Students []ExamSessionStudentStatus `json:"students,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName="exam"
+// +kubebuilder:printcolumn:name="Pretty Name",type=string,JSONPath=`.spec.prettyName`
+// +kubebuilder:printcolumn:name="Start",type=string,JSONPath=`.spec.startTime`
+// +kubebuilder:printcolumn:name="End",type=string,JSONPath=`.spec.endTime`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyStudents`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ExamSession describes a scheduled exam, i.e., the Instances given to a set of students during a time window.
+type ExamSession struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExamSessionSpec   `json:"spec,omitempty"`
+	Status ExamSessionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExamSessionList contains a list of ExamSession objects.
+type ExamSessionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExamSession `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExamSession{}, &ExamSessionList{})
+}
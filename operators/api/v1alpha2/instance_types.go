@@ -105,6 +105,14 @@ type InstanceAutomationStatus struct {
 
 	// The time the Instance content submission has been completed.
 	SubmissionTime metav1.Time `json:"submissionTime,omitempty"`
+
+	// The last time the Instance has been detected as being in use,
+	// in case it is subject to an idle policy.
+	LastActivityTime metav1.Time `json:"lastActivityTime,omitempty"`
+
+	// The number of failed attempts to set up the grading of the Instance
+	// (i.e., to grant the checker access to the workload cluster).
+	GradingSetupFailures int32 `json:"gradingSetupFailures,omitempty"`
 }
 
 // InstanceStatus reflects the most recently observed status of the Instance.
@@ -136,6 +144,253 @@ type InstanceStatus struct {
 
 	// The actual nodeSelector assigned to the Instance.
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// The pod and service CIDRs assigned to the cluster environment (if any).
+	ClusterNetwork *InstanceClusterNetworkStatus `json:"clusterNetwork,omitempty"`
+
+	// The nodes of the cluster environment (if any), which can be accessed through
+	// the SSH protocol leveraging the SSH bastion, with the tenant keys.
+	ClusterNodes []InstanceClusterNode `json:"clusterNodes,omitempty"`
+
+	// The outcome of the automated checks run against the cluster on submission
+	// (cluster environments with grading enabled only).
+	Grading *InstanceGradingStatus `json:"grading,omitempty"`
+
+	// The content applied to the cluster environment of the Instance (if any).
+	ClusterContent *InstanceClusterContentStatus `json:"clusterContent,omitempty"`
+
+	// The NFS volumes (i.e. MyDrive and SharedVolumes) exposed as PersistentVolumeClaims
+	// in the cluster environment of the Instance (if any).
+	ClusterVolumes []InstanceClusterVolume `json:"clusterVolumes,omitempty"`
+
+	// The provisioning stages completed by the cluster environment (if any),
+	// along with the time each of them required since the creation of the cluster.
+	ClusterStages []InstanceClusterStage `json:"clusterStages,omitempty"`
+
+	// The revision of the Template the Instance is pinned to, i.e., the one the
+	// Instance has been created from (or has been last upgraded to).
+	TemplateRevision *InstanceTemplateRevisionStatus `json:"templateRevision,omitempty"`
+
+	// The resets of the cluster environment of the Instance (if any) to its initial state.
+	ClusterReset *InstanceClusterResetStatus `json:"clusterReset,omitempty"`
+
+	// The view-only share link of the graphical environment of the Instance (if any).
+	ViewerShare *InstanceViewerShareStatus `json:"viewerShare,omitempty"`
+
+	// The conditions describing the state of the Instance which is not captured by
+	// the phase (e.g. the teardown of the associated cluster being stuck).
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// InstanceConditionDeletionStuck -> the type of the condition signaling that the teardown of the
+	// cluster environment associated with an Instance being deleted did not complete in time.
+	InstanceConditionDeletionStuck = "DeletionStuck"
+	// InstanceReasonClusterTeardownTimeout -> the reason of the DeletionStuck condition, in case the
+	// Cluster API did not tear the cluster down within the configured timeout.
+	InstanceReasonClusterTeardownTimeout = "ClusterTeardownTimeout"
+	// InstanceConditionClusterAddonFailed -> the type of the condition signaling that the installation of an add-on
+	// (i.e., the CNI or the lab content) of the cluster environment associated with an Instance failed. The reason
+	// identifies the add-on, and the condition is cleared once the installation succeeds.
+	InstanceConditionClusterAddonFailed = "ClusterAddonFailed"
+)
+
+// +kubebuilder:validation:Enum="ControlPlane";"Worker"
+
+// ClusterNodeRole is an enumeration of the roles of the nodes of a cluster environment.
+type ClusterNodeRole string
+
+const (
+	// ClusterNodeControlPlane -> the node is part of the control plane.
+	ClusterNodeControlPlane ClusterNodeRole = "ControlPlane"
+	// ClusterNodeWorker -> the node is a worker.
+	ClusterNodeWorker ClusterNodeRole = "Worker"
+)
+
+// InstanceClusterNode reflects a node of the cluster environment of the Instance.
+type InstanceClusterNode struct {
+	// The name of the node.
+	Name string `json:"name"`
+
+	// The role of the node in the cluster.
+	Role ClusterNodeRole `json:"role"`
+
+	// The internal IP address associated with the node.
+	IP string `json:"ip,omitempty"`
+
+	// Whether the node joined the cluster and is ready.
+	Ready bool `json:"ready,omitempty"`
+}
+
+// +kubebuilder:validation:Enum="Infrastructure";"ControlPlane";"Machines";"NodeJoin";"CNI"
+
+// ClusterStage is an enumeration of the provisioning stages of a cluster environment.
+type ClusterStage string
+
+const (
+	// ClusterStageInfrastructure -> the infrastructure of the cluster (e.g. the network) is ready.
+	ClusterStageInfrastructure ClusterStage = "Infrastructure"
+	// ClusterStageControlPlane -> the control plane of the cluster has been initialized.
+	ClusterStageControlPlane ClusterStage = "ControlPlane"
+	// ClusterStageMachines -> the machines (e.g. the VMs) of the cluster have been scheduled and provisioned.
+	ClusterStageMachines ClusterStage = "Machines"
+	// ClusterStageNodeJoin -> the machines of the cluster joined it as nodes.
+	ClusterStageNodeJoin ClusterStage = "NodeJoin"
+	// ClusterStageCNI -> the CNI has been rolled out, and all the nodes of the cluster are healthy.
+	ClusterStageCNI ClusterStage = "CNI"
+)
+
+// InstanceClusterStage reflects a provisioning stage completed by the cluster environment of the Instance.
+type InstanceClusterStage struct {
+	// The name of the stage.
+	Name ClusterStage `json:"name"`
+
+	// The time the stage was completed at.
+	CompletionTime metav1.Time `json:"completionTime"`
+
+	// The amount of time the stage required to complete, since the completion of the previous stage
+	// (or since the creation of the cluster, for the first one).
+	Duration string `json:"duration"`
+}
+
+// InstanceClusterVolume reflects an NFS volume exposed in the cluster environment of the Instance.
+type InstanceClusterVolume struct {
+	// The name of the PersistentVolumeClaim bound to the volume, in the default namespace of the cluster.
+	ClaimName string `json:"claimName"`
+
+	// The address of the NFS server exporting the volume.
+	Server string `json:"server"`
+
+	// The path of the volume exported by the NFS server.
+	Path string `json:"path"`
+
+	// Whether the volume is exposed with read-only permission.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// InstanceClusterContentStatus reflects the content applied to the cluster environment of the Instance.
+type InstanceClusterContentStatus struct {
+	// The origin the content has been retrieved from.
+	Source string `json:"source"`
+
+	// The revision (i.e., the digest of the manifests) of the applied content.
+	Revision string `json:"revision"`
+
+	// The number of applied objects.
+	Objects int `json:"objects"`
+
+	// The time the content has been applied.
+	AppliedTime metav1.Time `json:"appliedTime"`
+
+	// The last lab reset request handled, i.e., the value of the corresponding annotation.
+	ObservedReset string `json:"observedReset,omitempty"`
+}
+
+// InstanceTemplateRevisionStatus reflects the revision of the Template the Instance is pinned to.
+type InstanceTemplateRevisionStatus struct {
+	// The name of the TemplateRevision the Instance is pinned to.
+	Name string `json:"name"`
+
+	// The digest of the specification of the Template the Instance is pinned to.
+	Revision string `json:"revision"`
+
+	// The digest of the current specification of the Template, if different from the pinned one.
+	LatestRevision string `json:"latestRevision,omitempty"`
+
+	// The changes which would be applied by upgrading the Instance to the current specification of the Template.
+	PendingChanges []string `json:"pendingChanges,omitempty"`
+}
+
+// InstanceClusterResetStatus reflects the resets of the cluster environment of the Instance, i.e., the teardown and
+// re-provisioning of the workload cluster, requested through the corresponding annotation.
+type InstanceClusterResetStatus struct {
+	// The last cluster reset request handled, i.e., the value of the corresponding annotation. The value set
+	// when the cluster is first provisioned is recorded as well, as it does not request a reset.
+	ObservedReset string `json:"observedReset"`
+
+	// The number of resets completed.
+	Count int `json:"count"`
+
+	// The time the last reset has been completed at, if any.
+	// +optional
+	LastResetTime *metav1.Time `json:"lastResetTime,omitempty"`
+}
+
+// InstanceViewerShareStatus reflects the view-only share link of the graphical environment
+// of the Instance, generated upon request through the corresponding annotation.
+type InstanceViewerShareStatus struct {
+	// The last share request handled, i.e., the value of the corresponding annotation.
+	ObservedRequest string `json:"observedRequest"`
+
+	// The URL granting view-only access to the graphical environment, until the expiration time.
+	URL string `json:"url,omitempty"`
+
+	// The time the share link expires at.
+	ExpirationTime metav1.Time `json:"expirationTime,omitempty"`
+}
+
+// GradingOutcome is an enumeration of the possible outcomes of the grading of an Instance.
+// +kubebuilder:validation:Enum=Passed;Failed;Error
+type GradingOutcome string
+
+const (
+	// GradingPassed -> all the checks passed.
+	GradingPassed GradingOutcome = "Passed"
+	// GradingFailed -> at least one of the checks failed.
+	GradingFailed GradingOutcome = "Failed"
+	// GradingError -> the checker failed to run, or its report could not be retrieved.
+	GradingError GradingOutcome = "Error"
+)
+
+// InstanceGradingStatus reflects the outcome of the automated checks run against the cluster environment of the Instance.
+type InstanceGradingStatus struct {
+	// The overall outcome of the grading.
+	Outcome GradingOutcome `json:"outcome"`
+
+	// The time the grading has been completed.
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+
+	// The number of checks which passed.
+	Passed int `json:"passed"`
+
+	// The total number of checks.
+	Total int `json:"total"`
+
+	// A human-readable message detailing the outcome, in case of errors.
+	Message string `json:"message,omitempty"`
+
+	// The outcome of the single checks.
+	Checks []GradingCheckResult `json:"checks,omitempty"`
+}
+
+// GradingCheckResult reflects the outcome of a single grading check.
+type GradingCheckResult struct {
+	// The name of the check.
+	Name string `json:"name"`
+
+	// Whether the check passed.
+	Passed bool `json:"passed"`
+
+	// A human-readable message detailing the outcome of the check.
+	Message string `json:"message,omitempty"`
+}
+
+// InstanceClusterNetworkStatus reflects the CIDRs in use by the cluster environment of the Instance.
+type InstanceClusterNetworkStatus struct {
+	// The CIDR of the pod network (the primary one, in case of dual-stack clusters).
+	Pods string `json:"pods,omitempty"`
+
+	// The CIDRs of the pod network, one per IP family.
+	PodsCIDRs []string `json:"podsCIDRs,omitempty"`
+
+	// The CIDR of the service network (the primary one, in case of dual-stack clusters).
+	Services string `json:"services,omitempty"`
+
+	// The CIDRs of the service network, one per IP family.
+	ServicesCIDRs []string `json:"servicesCIDRs,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -72,6 +72,32 @@ type TemplateSpec struct {
 	// or stopped to save resources. If set to "never", the instance will not be
 	// automatically terminated.
 	DeleteAfter string `json:"deleteAfter,omitempty"`
+
+	// +listType=map
+	// +listMapKey=mode
+
+	// The policies to automatically stop the Instances referencing the current
+	// Template when left inactive, depending on the mode of their environment.
+	// Currently, inactivity can be detected only for graphical container environments.
+	IdlePolicies []IdlePolicy `json:"idlePolicies,omitempty"`
+}
+
+// IdlePolicy defines after how long the Instances running environments with
+// the given mode are considered inactive, and automatically stopped.
+type IdlePolicy struct {
+	// +kubebuilder:validation:Enum="Standard";"Exam";"Exercise"
+
+	// The mode of the environments the policy applies to.
+	Mode EnvironmentMode `json:"mode"`
+
+	// The period of inactivity after which the Instance is stopped.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// +kubebuilder:default=false
+
+	// Whether only keyboard and mouse input is considered as activity. Otherwise,
+	// the Instance is considered active as long as a remote desktop session is open.
+	InputOnly bool `json:"inputOnly,omitempty"`
 }
 
 // TemplateStatus reflects the most recently observed status of the Template.
@@ -129,6 +155,10 @@ type Environment struct {
 	// Options to customize container startup
 	ContainerStartupOptions *ContainerStartupOpts `json:"containerStartupOptions,omitempty"`
 
+	// Options to record the remote desktop sessions (e.g., in Exam mode), for
+	// graphical container environments only. Recording is disabled if not set.
+	SessionRecording *SessionRecording `json:"sessionRecording,omitempty"`
+
 	// Name of the storage class to be used for the persistent volume (when needed)
 	StorageClassName string `json:"storageClassName,omitempty"`
 
@@ -144,6 +174,26 @@ type Environment struct {
 	// In case it is present, the labels that are chosen are the ones present on the instance
 	NodeSelector *map[string]string `json:"nodeSelector,omitempty"`
 
+	// +kubebuilder:default=false
+	// Whether the environment is a node of a VM-based kubeadm cluster, bootstrapped by the
+	// instance operator without relying on the Cluster API. In this case, all the environments
+	// of the template must be nodes of the same cluster.
+	IsClusterNode bool `json:"isClusterNode,omitempty"`
+
+	// The name of the cluster the node belongs to
+	// +kubebuilder:validation:Optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// The role of the node in the cluster: the control plane node with ordinal 0
+	// initializes the cluster, while the other nodes join it
+	// +kubebuilder:validation:Optional
+	ClusterRole ClusterNodeRole `json:"clusterRole,omitempty"`
+
+	// The ordinal distinguishing the nodes of the cluster with the same role
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	Ordinal int `json:"ordinal,omitempty"`
+
 	//Cluster
 	Cluster *ClusterTemplate `json:"cluster,omitempty"`
 
@@ -171,8 +221,149 @@ type ClusterTemplate struct {
 
 	// The worker deployment rule sepcifying how to bootstrap
 	MachineDeploy MachineDeployment `json:"machineDeployment"`
+
+	// The infrastructure provider hosting the cluster nodes
+	// +kubebuilder:validation:Enum=kubevirt;inmemory
+	// +kubebuilder:default=kubevirt
+	Infrastructure InfrastructureProvider `json:"infrastructure,omitempty"`
+
+	// The customization of the node setup, applied to both control plane (kubeadm provider) and worker nodes
+	NodeBootstrap *NodeBootstrap `json:"nodeBootstrap,omitempty"`
+
+	// The automated checks run against the cluster when the instance is submitted
+	Grading *ClusterGrading `json:"grading,omitempty"`
+
+	// The content (e.g. the initial state of the lab) applied to the cluster once ready
+	Content *ClusterContent `json:"content,omitempty"`
+
+	// The destinations, outside the cluster, the nodes are allowed to reach (e.g. the Internet, for labs requiring outbound access).
+	// Any other traffic to and from the nodes is denied, except the one within the cluster and towards its API server.
+	AllowedEgress []ClusterEgressRule `json:"allowedEgress,omitempty"`
+}
+
+// The ClusterEgressRule defines a destination, outside the cluster, the nodes of a cluster environment are allowed to reach.
+type ClusterEgressRule struct {
+	// CIDR is the range of IP addresses of the destination (e.g. 0.0.0.0/0 to allow the access to the Internet)
+	CIDR string `json:"cidr"`
+	// Except is the list of ranges, within the CIDR, the nodes are not allowed to reach
+	Except []string `json:"except,omitempty"`
+	// Ports is the list of the destination ports the nodes are allowed to reach (all if empty)
+	Ports []ClusterEgressPort `json:"ports,omitempty"`
+}
+
+// The ClusterEgressPort defines a destination port the nodes of a cluster environment are allowed to reach.
+type ClusterEgressPort struct {
+	// Protocol is the protocol of the traffic towards the port
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +kubebuilder:default=TCP
+	Protocol string `json:"protocol,omitempty"`
+	// Port is the number of the destination port
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
+
+// The ClusterContent defines the manifests applied (through server-side apply) to the workload cluster once it is ready.
+// The content origin specified in the customization URLs of the Instance, if any, takes precedence.
+// +kubebuilder:validation:XValidation:rule="!(has(self.url) && has(self.configMap))",message="url and configMap are mutually exclusive"
+type ClusterContent struct {
+	// URL is the HTTP URL of either a manifest file (.yaml, .yml or .json), a tarball of manifests
+	// (.tar, .tar.gz or .tgz, possibly including a kustomization) or a remote kustomization
+	URL string `json:"url,omitempty"`
+	// ConfigMap is the name of the ConfigMap, in the namespace of the Template, whose values are the manifests to be applied
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// The ClusterGrading defines the checker run against the workload cluster to grade the instance on submission.
+// The checker is provided with a read-only kubeconfig (through the KUBECONFIG environment variable) and it is
+// expected to write a JSON report with the outcome of the checks (i.e., {"checks": [{"name": "...", "passed": true, "message": "..."}]})
+// to the path specified by GRADING_RESULTS_PATH, exiting successfully regardless of the outcome of the single checks.
+type ClusterGrading struct {
+	// Image is the container image of the checker
+	Image string `json:"image"`
+	// Command overrides the entrypoint of the checker image
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments passed to the checker
+	Args []string `json:"args,omitempty"`
+	// Script is an inline shell script executed by the checker, as an alternative to command and args
+	Script string `json:"script,omitempty"`
+	// TimeoutSeconds bounds the duration of the grading
+	// +kubebuilder:default=600
+	// +kubebuilder:validation:Minimum=30
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// The NodeBootstrap defines the node-level setup performed by the kubeadm bootstrap provider.
+type NodeBootstrap struct {
+	// Files are the files written on the nodes before running kubeadm
+	// +listType=map
+	// +listMapKey=path
+	Files []NodeFile `json:"files,omitempty"`
+	// Packages are the packages installed (through apt) before running the pre-kubeadm commands
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9.+:=~_-]*$`
+	Packages []string `json:"packages,omitempty"`
+	// PreKubeadmCommands are the commands executed before running kubeadm
+	PreKubeadmCommands []string `json:"preKubeadmCommands,omitempty"`
+	// PostKubeadmCommands are the commands executed after running kubeadm
+	PostKubeadmCommands []string `json:"postKubeadmCommands,omitempty"`
+	// NTP configures the time synchronization of the nodes
+	NTP *NodeNTP `json:"ntp,omitempty"`
+	// KubeletExtraArgs are the additional arguments passed to the kubelet (without the leading dashes)
+	KubeletExtraArgs map[string]string `json:"kubeletExtraArgs,omitempty"`
+}
+
+// The NodeFile defines a file written on the cluster nodes, whose content is either inline or retrieved from a ConfigMap or a Secret.
+type NodeFile struct {
+	// Path is the absolute path of the file on the nodes
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path"`
+	// Owner specifies the ownership of the file, e.g. "root:root"
+	Owner string `json:"owner,omitempty"`
+	// Permissions specifies the permissions of the file, e.g. "0640"
+	// +kubebuilder:validation:Pattern=`^0?[0-7]{3}$`
+	Permissions string `json:"permissions,omitempty"`
+	// Content is the inline content of the file
+	Content string `json:"content,omitempty"`
+	// ContentFrom references the ConfigMap or the Secret, in the namespace of the Template, holding the content of the file
+	ContentFrom *NodeFileSource `json:"contentFrom,omitempty"`
 }
 
+// The NodeFileSource references the key of a ConfigMap or of a Secret holding the content of a file.
+type NodeFileSource struct {
+	// ConfigMap references the ConfigMap key holding the content of the file
+	ConfigMap *NodeFileKeyRef `json:"configMap,omitempty"`
+	// Secret references the Secret key holding the content of the file, the Secret being
+	// required to be labeled with crownlabs.polito.it/node-bootstrap=true
+	Secret *NodeFileKeyRef `json:"secret,omitempty"`
+}
+
+// The NodeFileKeyRef references a key of a ConfigMap or of a Secret.
+type NodeFileKeyRef struct {
+	// Name is the name of the ConfigMap or Secret
+	Name string `json:"name"`
+	// Key is the key holding the content of the file
+	Key string `json:"key"`
+}
+
+// The NodeNTP defines the NTP configuration of the cluster nodes.
+type NodeNTP struct {
+	// Enabled specifies whether NTP is enabled
+	// +kubebuilder:default=true
+	Enabled *bool `json:"enabled,omitempty"`
+	// Servers are the NTP servers to synchronize with
+	Servers []string `json:"servers,omitempty"`
+}
+
+// InfrastructureProvider represents the Cluster API infrastructure provider choosen for the nodes, kubevirt or inmemory
+type InfrastructureProvider string
+
+const (
+	// InfraKubevirt -> the cluster nodes are KubeVirt virtual machines.
+	InfraKubevirt InfrastructureProvider = "kubevirt"
+	// InfraInMemory -> the cluster nodes are simulated by the Cluster API in-memory provider (testing only).
+	InfraInMemory InfrastructureProvider = "inmemory"
+)
+
 // +kubebuilder:validation:Optional
 // The VisualizationType defines the visual content
 type VisualizationType struct {
@@ -185,13 +376,30 @@ type VisualizationType struct {
 }
 
 // The ClusterNetwork defines corrlative network components
+// +kubebuilder:validation:XValidation:rule="!has(self.podsCIDRs) || !has(self.servicesCIDRs) || (size(self.podsCIDRs) == size(self.servicesCIDRs) && self.podsCIDRs[0].contains(':') == self.servicesCIDRs[0].contains(':'))",message="podsCIDRs and servicesCIDRs must belong to the same IP families, in the same order"
 type ClusterNetwork struct {
-	// Pods is the CIDR for pod network
+	// Pods is the CIDR for pod network, ignored in case of automatic allocation or if PodsCIDRs is set
 	// +kubebuilder:validation:Pattern=`^([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]+$`
-	Pods string `json:"pods"`
-	// Services is the CIDR for service network
+	Pods string `json:"pods,omitempty"`
+	// PodsCIDRs are the CIDRs for pod network (at most one per IP family, the first being the primary one),
+	// taking precedence over Pods and ignored in case of automatic allocation
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:items:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}|[0-9a-fA-F:]+/[0-9]{1,3})$`
+	// +kubebuilder:validation:XValidation:rule="size(self) < 2 || self[0].contains(':') != self[1].contains(':')",message="dual-stack CIDRs must belong to different IP families"
+	PodsCIDRs []string `json:"podsCIDRs,omitempty"`
+	// Services is the CIDR for service network, ignored in case of automatic allocation or if ServicesCIDRs is set
 	// +kubebuilder:validation:Pattern=`^([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]+$`
-	Services string `json:"services"`
+	Services string `json:"services,omitempty"`
+	// ServicesCIDRs are the CIDRs for service network (at most one per IP family, the first being the primary one),
+	// taking precedence over Services and ignored in case of automatic allocation
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:items:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}|[0-9a-fA-F:]+/[0-9]{1,3})$`
+	// +kubebuilder:validation:XValidation:rule="size(self) < 2 || self[0].contains(':') != self[1].contains(':')",message="dual-stack CIDRs must belong to different IP families"
+	ServicesCIDRs []string `json:"servicesCIDRs,omitempty"`
+	// Allocation requests a unique pod and service CIDR to be allocated to each instance
+	Allocation *NetworkAllocationPolicy `json:"allocation,omitempty"`
 	// Cni specifies the CNI provider to deploy
 	// +kubebuilder:validation:Enum=calico;cilium;flannel
 	// +kubebuilder:default=cilium
@@ -209,6 +417,57 @@ type ClusterNetwork struct {
 	CertSAN string `json:"certsan,omitempty"`
 }
 
+// The NetworkAllocationPolicy defines how the pod and service CIDRs of each instance are allocated.
+// Pools default to the ones configured in the instance operator when not specified.
+type NetworkAllocationPolicy struct {
+	// IPFamilies are the IP families CIDRs are allocated for, the first being the primary one
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="size(self) < 2 || self[0] != self[1]",message="IP families must be unique"
+	// +kubebuilder:default={"IPv4"}
+	IPFamilies []IPFamily `json:"ipFamilies,omitempty"`
+	// PodPools are the parent CIDRs the pod CIDRs are carved from (at most one per IP family)
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:items:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}|[0-9a-fA-F:]+/[0-9]{1,3})$`
+	PodPools []string `json:"podPools,omitempty"`
+	// PodPrefixLength is the prefix length of the IPv4 pod CIDR allocated to each instance
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=28
+	// +kubebuilder:default=16
+	PodPrefixLength uint32 `json:"podPrefixLength,omitempty"`
+	// PodIPv6PrefixLength is the prefix length of the IPv6 pod CIDR allocated to each instance
+	// +kubebuilder:validation:Minimum=48
+	// +kubebuilder:validation:Maximum=120
+	// +kubebuilder:default=64
+	PodIPv6PrefixLength uint32 `json:"podIPv6PrefixLength,omitempty"`
+	// ServicePools are the parent CIDRs the service CIDRs are carved from (at most one per IP family)
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:items:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}|[0-9a-fA-F:]+/[0-9]{1,3})$`
+	ServicePools []string `json:"servicePools,omitempty"`
+	// ServicePrefixLength is the prefix length of the IPv4 service CIDR allocated to each instance
+	// +kubebuilder:validation:Minimum=12
+	// +kubebuilder:validation:Maximum=28
+	// +kubebuilder:default=20
+	ServicePrefixLength uint32 `json:"servicePrefixLength,omitempty"`
+	// ServiceIPv6PrefixLength is the prefix length of the IPv6 service CIDR allocated to each instance
+	// +kubebuilder:validation:Minimum=108
+	// +kubebuilder:validation:Maximum=120
+	// +kubebuilder:default=112
+	ServiceIPv6PrefixLength uint32 `json:"serviceIPv6PrefixLength,omitempty"`
+}
+
+// +kubebuilder:validation:Enum="IPv4";"IPv6"
+
+// IPFamily is an enumeration of the IP families supported by cluster environments.
+type IPFamily string
+
+const (
+	// IPv4Family -> the IPv4 family.
+	IPv4Family IPFamily = "IPv4"
+	// IPv6Family -> the IPv6 family.
+	IPv6Family IPFamily = "IPv6"
+)
+
 // constrain the provider in callico, cilium and flannel
 type CniProvider string
 
@@ -278,6 +537,34 @@ type EnvironmentResources struct {
 	Disk resource.Quantity `json:"disk,omitempty"`
 }
 
+// +kubebuilder:validation:Enum="PersistentVolume";"Upload"
+
+// RecordingDestination is an enumeration of the destinations of the session recordings.
+type RecordingDestination string
+
+const (
+	// RecordingDestinationPersistentVolume -> the recordings are stored in the volume of the instance,
+	// which is also accessible (and writable) by the user of the instance.
+	RecordingDestinationPersistentVolume RecordingDestination = "PersistentVolume"
+	// RecordingDestinationUpload -> the recordings are uploaded to a remote endpoint once each session ends.
+	RecordingDestinationUpload RecordingDestination = "Upload"
+)
+
+// SessionRecording specifies how the remote desktop sessions of the instances are recorded.
+type SessionRecording struct {
+	// +kubebuilder:default="Upload"
+
+	// Where the recordings are stored: an upload endpoint, or the volume of the
+	// instance (in a hidden folder, and persisted only if the environment is persistent).
+	// Recordings stored in the volume can be accessed and modified by the user of the
+	// instance, hence they are not suitable as evidence of the sessions (e.g., of exams).
+	Destination RecordingDestination `json:"destination,omitempty"`
+
+	// The URL the recordings are uploaded to (through a multipart POST request,
+	// as for the content of the instances), in case of Upload destination.
+	UploadURL string `json:"uploadUrl,omitempty"`
+}
+
 // ContainerStartupOpts specifies custom startup options for the created container,
 // including the possibility to download and extract an archive to a given destination
 // and specifying the arguments that will be passed to the application container.
@@ -0,0 +1,67 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateRevisionSpec is the specification of the desired state of the TemplateRevision.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="TemplateRevisions are immutable"
+type TemplateRevisionSpec struct {
+	// The reference to the Template this revision has been taken from.
+	TemplateRef GenericRef `json:"template.crownlabs.polito.it/TemplateRef"`
+
+	// The digest of the specification of the Template, identifying the revision.
+	Revision string `json:"revision"`
+
+	// The specification of the Template at the time the revision has been taken.
+	Template TemplateSpec `json:"template"`
+}
+
+// TemplateRevisionStatus reflects the most recently observed status of the TemplateRevision.
+type TemplateRevisionStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName="tmplrev"
+// +kubebuilder:printcolumn:name="Template",type=string,JSONPath=`.spec.template\.crownlabs\.polito\.it/TemplateRef.name`
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TemplateRevision is an immutable snapshot of the specification of a Template, taken the first time an Instance
+// is created from that specification. Its name is derived from the digest of the specification, so that Instances
+// created from the same specification share the same revision.
+type TemplateRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TemplateRevisionSpec   `json:"spec,omitempty"`
+	Status TemplateRevisionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TemplateRevisionList contains a list of TemplateRevision objects.
+type TemplateRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TemplateRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TemplateRevision{}, &TemplateRevisionList{})
+}
@@ -19,12 +19,108 @@
 package v1alpha2
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterContent) DeepCopyInto(out *ClusterContent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterContent.
+func (in *ClusterContent) DeepCopy() *ClusterContent {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterContent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEgressPort) DeepCopyInto(out *ClusterEgressPort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEgressPort.
+func (in *ClusterEgressPort) DeepCopy() *ClusterEgressPort {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEgressPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEgressRule) DeepCopyInto(out *ClusterEgressRule) {
+	*out = *in
+	if in.Except != nil {
+		in, out := &in.Except, &out.Except
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]ClusterEgressPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEgressRule.
+func (in *ClusterEgressRule) DeepCopy() *ClusterEgressRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEgressRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterGrading) DeepCopyInto(out *ClusterGrading) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterGrading.
+func (in *ClusterGrading) DeepCopy() *ClusterGrading {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterGrading)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterNetwork) DeepCopyInto(out *ClusterNetwork) {
 	*out = *in
+	if in.PodsCIDRs != nil {
+		in, out := &in.PodsCIDRs, &out.PodsCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServicesCIDRs != nil {
+		in, out := &in.ServicesCIDRs, &out.ServicesCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Allocation != nil {
+		in, out := &in.Allocation, &out.Allocation
+		*out = new(NetworkAllocationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetwork.
@@ -37,12 +133,124 @@ func (in *ClusterNetwork) DeepCopy() *ClusterNetwork {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterNetworkAllocation) DeepCopyInto(out *ClusterNetworkAllocation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetworkAllocation.
+func (in *ClusterNetworkAllocation) DeepCopy() *ClusterNetworkAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterNetworkAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterNetworkAllocation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterNetworkAllocationList) DeepCopyInto(out *ClusterNetworkAllocationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterNetworkAllocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetworkAllocationList.
+func (in *ClusterNetworkAllocationList) DeepCopy() *ClusterNetworkAllocationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterNetworkAllocationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterNetworkAllocationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterNetworkAllocationSpec) DeepCopyInto(out *ClusterNetworkAllocationSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetworkAllocationSpec.
+func (in *ClusterNetworkAllocationSpec) DeepCopy() *ClusterNetworkAllocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterNetworkAllocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterNetworkAllocationStatus) DeepCopyInto(out *ClusterNetworkAllocationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetworkAllocationStatus.
+func (in *ClusterNetworkAllocationStatus) DeepCopy() *ClusterNetworkAllocationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterNetworkAllocationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterTemplate) DeepCopyInto(out *ClusterTemplate) {
 	*out = *in
-	out.ClusterNet = in.ClusterNet
+	in.ClusterNet.DeepCopyInto(&out.ClusterNet)
 	out.ControlPlane = in.ControlPlane
 	out.MachineDeploy = in.MachineDeploy
+	if in.NodeBootstrap != nil {
+		in, out := &in.NodeBootstrap, &out.NodeBootstrap
+		*out = new(NodeBootstrap)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Grading != nil {
+		in, out := &in.Grading, &out.Grading
+		*out = new(ClusterGrading)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Content != nil {
+		in, out := &in.Content, &out.Content
+		*out = new(ClusterContent)
+		**out = **in
+	}
+	if in.AllowedEgress != nil {
+		in, out := &in.AllowedEgress, &out.AllowedEgress
+		*out = make([]ClusterEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTemplate.
@@ -99,6 +307,11 @@ func (in *Environment) DeepCopyInto(out *Environment) {
 		*out = new(ContainerStartupOpts)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SessionRecording != nil {
+		in, out := &in.SessionRecording, &out.SessionRecording
+		*out = new(SessionRecording)
+		**out = **in
+	}
 	if in.SharedVolumeMounts != nil {
 		in, out := &in.SharedVolumeMounts, &out.SharedVolumeMounts
 		*out = make([]SharedVolumeMountInfo, len(*in))
@@ -118,7 +331,7 @@ func (in *Environment) DeepCopyInto(out *Environment) {
 	if in.Cluster != nil {
 		in, out := &in.Cluster, &out.Cluster
 		*out = new(ClusterTemplate)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Visulizer != nil {
 		in, out := &in.Visulizer, &out.Visulizer
@@ -154,6 +367,158 @@ func (in *EnvironmentResources) DeepCopy() *EnvironmentResources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSession) DeepCopyInto(out *ExamSession) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSession.
+func (in *ExamSession) DeepCopy() *ExamSession {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSession)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExamSession) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSessionInstanceStatus) DeepCopyInto(out *ExamSessionInstanceStatus) {
+	*out = *in
+	in.Automation.DeepCopyInto(&out.Automation)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSessionInstanceStatus.
+func (in *ExamSessionInstanceStatus) DeepCopy() *ExamSessionInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSessionInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSessionList) DeepCopyInto(out *ExamSessionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ExamSession, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSessionList.
+func (in *ExamSessionList) DeepCopy() *ExamSessionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSessionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExamSessionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSessionSpec) DeepCopyInto(out *ExamSessionSpec) {
+	*out = *in
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]GenericRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	out.PreparationLeadTime = in.PreparationLeadTime
+	if in.CustomizationUrls != nil {
+		in, out := &in.CustomizationUrls, &out.CustomizationUrls
+		*out = new(InstanceCustomizationUrls)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSessionSpec.
+func (in *ExamSessionSpec) DeepCopy() *ExamSessionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSessionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSessionStatus) DeepCopyInto(out *ExamSessionStatus) {
+	*out = *in
+	if in.Students != nil {
+		in, out := &in.Students, &out.Students
+		*out = make([]ExamSessionStudentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSessionStatus.
+func (in *ExamSessionStatus) DeepCopy() *ExamSessionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSessionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExamSessionStudentStatus) DeepCopyInto(out *ExamSessionStudentStatus) {
+	*out = *in
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]ExamSessionInstanceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExamSessionStudentStatus.
+func (in *ExamSessionStudentStatus) DeepCopy() *ExamSessionStudentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExamSessionStudentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GenericRef) DeepCopyInto(out *GenericRef) {
 	*out = *in
@@ -169,6 +534,37 @@ func (in *GenericRef) DeepCopy() *GenericRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GradingCheckResult) DeepCopyInto(out *GradingCheckResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GradingCheckResult.
+func (in *GradingCheckResult) DeepCopy() *GradingCheckResult {
+	if in == nil {
+		return nil
+	}
+	out := new(GradingCheckResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdlePolicy) DeepCopyInto(out *IdlePolicy) {
+	*out = *in
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdlePolicy.
+func (in *IdlePolicy) DeepCopy() *IdlePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(IdlePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Instance) DeepCopyInto(out *Instance) {
 	*out = *in
@@ -199,17 +595,124 @@ func (in *Instance) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceAutomationStatus) DeepCopyInto(out *InstanceAutomationStatus) {
 	*out = *in
-	in.LastCheckTime.DeepCopyInto(&out.LastCheckTime)
-	in.TerminationTime.DeepCopyInto(&out.TerminationTime)
-	in.SubmissionTime.DeepCopyInto(&out.SubmissionTime)
+	in.LastCheckTime.DeepCopyInto(&out.LastCheckTime)
+	in.TerminationTime.DeepCopyInto(&out.TerminationTime)
+	in.SubmissionTime.DeepCopyInto(&out.SubmissionTime)
+	in.LastActivityTime.DeepCopyInto(&out.LastActivityTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceAutomationStatus.
+func (in *InstanceAutomationStatus) DeepCopy() *InstanceAutomationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceAutomationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterContentStatus) DeepCopyInto(out *InstanceClusterContentStatus) {
+	*out = *in
+	in.AppliedTime.DeepCopyInto(&out.AppliedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterContentStatus.
+func (in *InstanceClusterContentStatus) DeepCopy() *InstanceClusterContentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceClusterContentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterNetworkStatus) DeepCopyInto(out *InstanceClusterNetworkStatus) {
+	*out = *in
+	if in.PodsCIDRs != nil {
+		in, out := &in.PodsCIDRs, &out.PodsCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServicesCIDRs != nil {
+		in, out := &in.ServicesCIDRs, &out.ServicesCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterNetworkStatus.
+func (in *InstanceClusterNetworkStatus) DeepCopy() *InstanceClusterNetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceClusterNetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterNode) DeepCopyInto(out *InstanceClusterNode) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterNode.
+func (in *InstanceClusterNode) DeepCopy() *InstanceClusterNode {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceClusterNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterResetStatus) DeepCopyInto(out *InstanceClusterResetStatus) {
+	*out = *in
+	if in.LastResetTime != nil {
+		in, out := &in.LastResetTime, &out.LastResetTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterResetStatus.
+func (in *InstanceClusterResetStatus) DeepCopy() *InstanceClusterResetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceClusterResetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterStage) DeepCopyInto(out *InstanceClusterStage) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterStage.
+func (in *InstanceClusterStage) DeepCopy() *InstanceClusterStage {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceClusterStage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceClusterVolume) DeepCopyInto(out *InstanceClusterVolume) {
+	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceAutomationStatus.
-func (in *InstanceAutomationStatus) DeepCopy() *InstanceAutomationStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceClusterVolume.
+func (in *InstanceClusterVolume) DeepCopy() *InstanceClusterVolume {
 	if in == nil {
 		return nil
 	}
-	out := new(InstanceAutomationStatus)
+	out := new(InstanceClusterVolume)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -229,6 +732,27 @@ func (in *InstanceCustomizationUrls) DeepCopy() *InstanceCustomizationUrls {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGradingStatus) DeepCopyInto(out *InstanceGradingStatus) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]GradingCheckResult, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGradingStatus.
+func (in *InstanceGradingStatus) DeepCopy() *InstanceGradingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGradingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceList) DeepCopyInto(out *InstanceList) {
 	*out = *in
@@ -392,6 +916,60 @@ func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.ClusterNetwork != nil {
+		in, out := &in.ClusterNetwork, &out.ClusterNetwork
+		*out = new(InstanceClusterNetworkStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterNodes != nil {
+		in, out := &in.ClusterNodes, &out.ClusterNodes
+		*out = make([]InstanceClusterNode, len(*in))
+		copy(*out, *in)
+	}
+	if in.Grading != nil {
+		in, out := &in.Grading, &out.Grading
+		*out = new(InstanceGradingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterContent != nil {
+		in, out := &in.ClusterContent, &out.ClusterContent
+		*out = new(InstanceClusterContentStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterVolumes != nil {
+		in, out := &in.ClusterVolumes, &out.ClusterVolumes
+		*out = make([]InstanceClusterVolume, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterStages != nil {
+		in, out := &in.ClusterStages, &out.ClusterStages
+		*out = make([]InstanceClusterStage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TemplateRevision != nil {
+		in, out := &in.TemplateRevision, &out.TemplateRevision
+		*out = new(InstanceTemplateRevisionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterReset != nil {
+		in, out := &in.ClusterReset, &out.ClusterReset
+		*out = new(InstanceClusterResetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ViewerShare != nil {
+		in, out := &in.ViewerShare, &out.ViewerShare
+		*out = new(InstanceViewerShareStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
@@ -404,6 +982,42 @@ func (in *InstanceStatus) DeepCopy() *InstanceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTemplateRevisionStatus) DeepCopyInto(out *InstanceTemplateRevisionStatus) {
+	*out = *in
+	if in.PendingChanges != nil {
+		in, out := &in.PendingChanges, &out.PendingChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTemplateRevisionStatus.
+func (in *InstanceTemplateRevisionStatus) DeepCopy() *InstanceTemplateRevisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTemplateRevisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceViewerShareStatus) DeepCopyInto(out *InstanceViewerShareStatus) {
+	*out = *in
+	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceViewerShareStatus.
+func (in *InstanceViewerShareStatus) DeepCopy() *InstanceViewerShareStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceViewerShareStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeconfigTemplate) DeepCopyInto(out *KubeconfigTemplate) {
 	*out = *in
@@ -449,6 +1063,185 @@ func (in *NameCreated) DeepCopy() *NameCreated {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkAllocationPolicy) DeepCopyInto(out *NetworkAllocationPolicy) {
+	*out = *in
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodPools != nil {
+		in, out := &in.PodPools, &out.PodPools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServicePools != nil {
+		in, out := &in.ServicePools, &out.ServicePools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkAllocationPolicy.
+func (in *NetworkAllocationPolicy) DeepCopy() *NetworkAllocationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkAllocationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeBootstrap) DeepCopyInto(out *NodeBootstrap) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]NodeFile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Packages != nil {
+		in, out := &in.Packages, &out.Packages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreKubeadmCommands != nil {
+		in, out := &in.PreKubeadmCommands, &out.PreKubeadmCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostKubeadmCommands != nil {
+		in, out := &in.PostKubeadmCommands, &out.PostKubeadmCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NTP != nil {
+		in, out := &in.NTP, &out.NTP
+		*out = new(NodeNTP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeletExtraArgs != nil {
+		in, out := &in.KubeletExtraArgs, &out.KubeletExtraArgs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeBootstrap.
+func (in *NodeBootstrap) DeepCopy() *NodeBootstrap {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeBootstrap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFile) DeepCopyInto(out *NodeFile) {
+	*out = *in
+	if in.ContentFrom != nil {
+		in, out := &in.ContentFrom, &out.ContentFrom
+		*out = new(NodeFileSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFile.
+func (in *NodeFile) DeepCopy() *NodeFile {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFileKeyRef) DeepCopyInto(out *NodeFileKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFileKeyRef.
+func (in *NodeFileKeyRef) DeepCopy() *NodeFileKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFileKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFileSource) DeepCopyInto(out *NodeFileSource) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(NodeFileKeyRef)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(NodeFileKeyRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFileSource.
+func (in *NodeFileSource) DeepCopy() *NodeFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNTP) DeepCopyInto(out *NodeNTP) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNTP.
+func (in *NodeNTP) DeepCopy() *NodeNTP {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionRecording) DeepCopyInto(out *SessionRecording) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionRecording.
+func (in *SessionRecording) DeepCopy() *SessionRecording {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionRecording)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SharedVolume) DeepCopyInto(out *SharedVolume) {
 	*out = *in
@@ -614,6 +1407,97 @@ func (in *TemplateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateRevision) DeepCopyInto(out *TemplateRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateRevision.
+func (in *TemplateRevision) DeepCopy() *TemplateRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TemplateRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateRevisionList) DeepCopyInto(out *TemplateRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TemplateRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateRevisionList.
+func (in *TemplateRevisionList) DeepCopy() *TemplateRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TemplateRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateRevisionSpec) DeepCopyInto(out *TemplateRevisionSpec) {
+	*out = *in
+	out.TemplateRef = in.TemplateRef
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateRevisionSpec.
+func (in *TemplateRevisionSpec) DeepCopy() *TemplateRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateRevisionStatus) DeepCopyInto(out *TemplateRevisionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateRevisionStatus.
+func (in *TemplateRevisionStatus) DeepCopy() *TemplateRevisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateRevisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplateSpec) DeepCopyInto(out *TemplateSpec) {
 	*out = *in
@@ -625,6 +1509,11 @@ func (in *TemplateSpec) DeepCopyInto(out *TemplateSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IdlePolicies != nil {
+		in, out := &in.IdlePolicies, &out.IdlePolicies
+		*out = make([]IdlePolicy, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSpec.
@@ -16,6 +16,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -47,12 +48,35 @@ func main() {
 		InstancesRoot = "/instances"
 		TemplateRoot  = "/template"
 		TemplatesRoot = "/templates"
+		SessionsRoot  = "/sessions"
+		StreamRoot    = "/watch/instances"
 		InstanceEP    = path.Join(examagent.Options.BasePath, InstanceRoot) + "/"
 		InstancesEP   = path.Join(examagent.Options.BasePath, InstancesRoot) + "/"
 		TemplateEP    = path.Join(examagent.Options.BasePath, TemplateRoot) + "/"
 		TemplatesEP   = path.Join(examagent.Options.BasePath, TemplatesRoot) + "/"
+		SessionsEP    = path.Join(examagent.Options.BasePath, SessionsRoot) + "/"
+		StreamEP      = path.Join(examagent.Options.BasePath, StreamRoot)
 	)
 
+	ctx := context.Background()
+	k8sCache, err := examagent.NewK8sCache(examagent.Options.Namespace)
+	if err != nil {
+		log.Error(err, "unable to prepare k8s cache")
+		os.Exit(1)
+	}
+	go func() {
+		if err := k8sCache.Start(ctx); err != nil {
+			log.Error(err, "unable to start k8s cache")
+			os.Exit(1)
+		}
+	}()
+
+	var auth *examagent.TokenAuthenticator
+	if examagent.Options.TokensNamespace != "" {
+		auth = &examagent.TokenAuthenticator{Client: k8sClient, Namespace: examagent.Options.TokensNamespace}
+	}
+	authorizer := examagent.Authorizer{Auth: auth, Audit: log.WithName("audit")}
+
 	handler := http.NewServeMux()
 	server := &http.Server{
 		Addr:              examagent.Options.ListenerAddr,
@@ -62,8 +86,14 @@ func main() {
 
 	handler.HandleFunc("/healthz", healthzHandler)
 
-	handler.Handle(InstanceEP, &examagent.InstanceHandler{Log: log.WithName("instance"), Client: k8sClient, AdapterEndpoint: InstanceRoot})
-	handler.Handle(InstancesEP, &examagent.InstanceHandler{Log: log.WithName("instance"), Client: k8sClient, AdapterEndpoint: InstancesRoot})
+	handler.Handle(InstanceEP, &examagent.InstanceHandler{Log: log.WithName("instance"), Client: k8sClient, AdapterEndpoint: InstanceRoot, Authorizer: authorizer})
+	handler.Handle(InstancesEP, &examagent.InstanceHandler{Log: log.WithName("instance"), Client: k8sClient, AdapterEndpoint: InstancesRoot, Authorizer: authorizer})
+
+	handler.Handle(SessionsEP, &examagent.SessionHandler{Log: log.WithName("session"), Client: k8sClient, AdapterEndpoint: SessionsRoot,
+		Concurrency: examagent.Options.SessionConcurrency, Authorizer: authorizer})
+
+	handler.Handle(StreamEP, &examagent.InstanceStreamHandler{Log: log.WithName("stream"), Cache: k8sCache,
+		HeartbeatInterval: examagent.Options.StreamHeartbeatInterval, Authorizer: authorizer})
 
 	handler.Handle(TemplateEP, &examagent.TemplateHandler{Log: log.WithName("template"), Client: k8sClient})
 	handler.Handle(TemplatesEP, &examagent.TemplateHandler{Log: log.WithName("template"), Client: k8sClient})
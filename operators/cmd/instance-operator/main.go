@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog/v2"
@@ -33,6 +34,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	crownlabsv1alpha1 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha1"
 	crownlabsv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
@@ -41,6 +43,7 @@ import (
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/shvolctrl"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/templatewh"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils/restcfg"
 
 	kamajiv1alpha1 "github.com/clastix/cluster-api-control-plane-provider-kamaji/api/v1alpha1"
@@ -50,6 +53,11 @@ import (
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 )
 
+const (
+	// TemplateValidatingWebhookPath -> path on which the template validating webhook will be bound. Has to match the one set in the ValidatingWebhookConfiguration.
+	TemplateValidatingWebhookPath = "/validate-v1alpha2-template"
+)
+
 var (
 	scheme = runtime.NewScheme()
 )
@@ -73,12 +81,15 @@ func init() {
 func main() {
 	containerEnvOpts := forge.ContainerEnvOpts{}
 	svcUrls := instctrl.ServiceUrls{}
+	clusterNetPools := instctrl.ClusterNetworkPools{}
 	instSnapOpts := instancesnapshot_controller.ContainersSnapshotOpts{}
 
 	metricsAddr := flag.String("metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	enableLeaderElection := flag.Bool("enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	maxConcurrentReconciles := flag.Int("max-concurrent-reconciles", 1, "The maximum number of concurrent Reconciles which can be run for the Instance controller")
+	enableTemplateWH := flag.Bool("enable-template-webhook", false, "Enable the webhook validating the configuration of the Templates")
+	webhookBypassGroups := flag.String("webhook-bypass-groups", "system:masters", "The list of groups which can skip webhooks checks, comma separated values")
 
 	namespaceWhiteList := flag.String("namespace-whitelist", "production=true", "The whitelist of the namespaces on "+
 		"which the controller will work. Different labels (key=value) can be specified, by separating them with a &"+
@@ -89,11 +100,24 @@ func main() {
 	maxConcurrentTerminationReconciles := flag.Int("max-concurrent-reconciles-termination", 1, "The maximum number of concurrent Reconciles which can be run for the Instance Termination controller")
 	instanceTerminationStatusCheckTimeout := flag.Duration("instance-termination-status-check-timeout", 3*time.Second, "The maximum time to wait for the status check for Instances that require it")
 	instanceTerminationStatusCheckInterval := flag.Duration("instance-termination-status-check-interval", 2*time.Minute, "The interval to check the status of Instances that require it")
+	maxConcurrentInactivityReconciles := flag.Int("max-concurrent-reconciles-inactivity", 1, "The maximum number of concurrent Reconciles which can be run for the Instance Inactivity controller")
+	instanceInactivityCheckTimeout := flag.Duration("instance-inactivity-check-timeout", 3*time.Second, "The maximum time to wait for the activity check of Instances subject to an idle policy")
+	instanceInactivityCheckInterval := flag.Duration("instance-inactivity-check-interval", 2*time.Minute, "The interval to check the activity of Instances subject to an idle policy")
+	maxConcurrentExamSessionReconciles := flag.Int("max-concurrent-reconciles-exam-session", 1, "The maximum number of concurrent Reconciles which can be run for the ExamSession controller")
+	gradingSetupMaxRetries := flag.Int("grading-setup-max-retries", instautoctrl.DefaultGradingSetupMaxRetries, "The maximum number of attempts to access the workload cluster to set up the grading of an Instance, before reporting a grading error")
 	maxConcurrentSubmissionReconciles := flag.Int("max-concurrent-reconciles-submission", 1, "The maximum number of concurrent Reconciles which can be run for the Instance Submission controller")
 
 	flag.StringVar(&svcUrls.WebsiteBaseURL, "website-base-url", "crownlabs.polito.it", "Base URL of crownlabs website instance")
 	flag.StringVar(&svcUrls.InstancesAuthURL, "instances-auth-url", "", "The base URL for user instances authentication (i.e., oauth2-proxy)")
 
+	flag.StringVar(&clusterNetPools.Pods, "cluster-pod-cidr-pool", "10.64.0.0/12", "The default pool the pod CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&clusterNetPools.Services, "cluster-service-cidr-pool", "10.112.0.0/12", "The default pool the service CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&clusterNetPools.PodsIPv6, "cluster-pod-cidr-pool-ipv6", "fd10:64::/48", "The default pool the IPv6 pod CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&clusterNetPools.ServicesIPv6, "cluster-service-cidr-pool-ipv6", "fd10:112::/96", "The default pool the IPv6 service CIDRs of cluster environments are automatically allocated from")
+	clusterTeardownTimeout := flag.Duration("cluster-teardown-timeout", instctrl.DefaultClusterTeardownTimeout, "The maximum time to wait for the teardown of the cluster of an Instance being deleted, before flagging the deletion as stuck")
+	nodeClusterCNIImage := flag.String("node-cluster-cni-image", instctrl.DefaultNodeClusterCNIImage, "The image of the Cilium CLI, used to install the CNI in the VM-based clusters")
+	viewerShareMaxValidity := flag.Duration("viewer-share-max-validity", 24*time.Hour, "The maximum validity of the view-only share links of graphical Instances")
+
 	flag.StringVar(&containerEnvOpts.ImagesTag, "container-env-sidecars-tag", "latest", "The tag for service containers (such as gui sidecar containers)")
 	flag.StringVar(&containerEnvOpts.XVncImg, "container-env-x-vnc-img", "crownlabs/tigervnc", "The image name for the vnc image (sidecar for graphical container environment)")
 	flag.StringVar(&containerEnvOpts.WebsockifyImg, "container-env-websockify-img", "crownlabs/websockify", "The image name for the websockify image (sidecar for graphical container environment)")
@@ -122,6 +146,7 @@ func main() {
 	mgr, err := ctrl.NewManager(restcfg.SetRateLimiter(ctrl.GetConfigOrDie()), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                server.Options{BindAddress: *metricsAddr},
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443}),
 		LeaderElection:         *enableLeaderElection,
 		HealthProbeBindAddress: ":8081",
 		LivenessEndpointName:   "/healthz",
@@ -134,15 +159,29 @@ func main() {
 
 	nsWhitelist := metav1.LabelSelector{MatchLabels: whiteListMap, MatchExpressions: []metav1.LabelSelectorRequirement{}}
 
+	if *enableTemplateWH {
+		mgr.GetWebhookServer().Register(
+			TemplateValidatingWebhookPath,
+			templatewh.MakeTemplateValidator(strings.Split(*webhookBypassGroups, ","), mgr.GetScheme()),
+		)
+	} else {
+		log.Info("Template webhook set up: operation skipped")
+	}
+
 	// Configure the Instance controller
 	const instanceCtrlName = "Instance"
 	if err = (&instctrl.InstanceReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		EventsRecorder:     mgr.GetEventRecorderFor(instanceCtrlName),
-		NamespaceWhitelist: nsWhitelist,
-		ServiceUrls:        svcUrls,
-		ContainerEnvOpts:   containerEnvOpts,
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		EventsRecorder:         mgr.GetEventRecorderFor(instanceCtrlName),
+		NamespaceWhitelist:     nsWhitelist,
+		ServiceUrls:            svcUrls,
+		ContainerEnvOpts:       containerEnvOpts,
+		ClusterNetworkPools:    clusterNetPools,
+		APIReader:              mgr.GetAPIReader(),
+		ClusterTeardownTimeout: *clusterTeardownTimeout,
+		NodeClusterCNIImage:    *nodeClusterCNIImage,
+		ViewerShareMaxValidity: *viewerShareMaxValidity,
 	}).SetupWithManager(mgr, *maxConcurrentReconciles); err != nil {
 		log.Error(err, "unable to create controller", "controller", instanceCtrlName)
 		os.Exit(1)
@@ -175,6 +214,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Configure the Instance inactivity controller
+	instanceInactivity := "InstanceInactivity"
+	if err := (&instautoctrl.InstanceInactivityReconciler{
+		Client:                        mgr.GetClient(),
+		Scheme:                        mgr.GetScheme(),
+		EventsRecorder:                mgr.GetEventRecorderFor(instanceInactivity),
+		NamespaceWhitelist:            nsWhitelist,
+		ActivityCheckRequestTimeout:   *instanceInactivityCheckTimeout,
+		InstanceActivityCheckInterval: *instanceInactivityCheckInterval,
+	}).SetupWithManager(mgr, *maxConcurrentInactivityReconciles); err != nil {
+		log.Error(err, "unable to create controller", "controller", instanceInactivity)
+		os.Exit(1)
+	}
+
 	// Configure the Instance submission controller
 	instanceSubmission := "InstanceSubmission"
 	if err := (&instautoctrl.InstanceSubmissionReconciler{
@@ -183,11 +236,26 @@ func main() {
 		EventsRecorder:     mgr.GetEventRecorderFor(instanceSubmission),
 		ContainerEnvOpts:   containerEnvOpts,
 		NamespaceWhitelist: nsWhitelist,
+		// The grading reports are retrieved from the logs, hence through a direct client.
+		PodLogs:                instautoctrl.NewPodLogsReader(kubernetes.NewForConfigOrDie(mgr.GetConfig())),
+		GradingSetupMaxRetries: int32(*gradingSetupMaxRetries),
 	}).SetupWithManager(mgr, *maxConcurrentSubmissionReconciles); err != nil {
 		log.Error(err, "unable to create controller", "controller", instanceSubmission)
 		os.Exit(1)
 	}
 
+	// Configure the ExamSession controller
+	const examSessionCtrl = "ExamSession"
+	if err := (&instautoctrl.ExamSessionReconciler{
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		EventsRecorder:     mgr.GetEventRecorderFor(examSessionCtrl),
+		NamespaceWhitelist: nsWhitelist,
+	}).SetupWithManager(mgr, *maxConcurrentExamSessionReconciles); err != nil {
+		log.Error(err, "unable to create controller", "controller", examSessionCtrl)
+		os.Exit(1)
+	}
+
 	// Configure the SharedVolume controller
 	const sharedVolumeCtrl = "SharedVolume"
 	if err := (&shvolctrl.SharedVolumeReconciler{
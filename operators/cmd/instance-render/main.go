@@ -0,0 +1,125 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the entrypoint for the offline renderer of the objects forged by the instance operator.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/textlogger"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instrender"
+)
+
+const (
+	// exitDifferences is the exit code in case differences are detected in diff mode (consistently with diff).
+	exitDifferences = 1
+	// exitFailure is the exit code in case of errors.
+	exitFailure = 2
+)
+
+func main() {
+	opts := instrender.Options{}
+
+	diffWith := flag.String("diff", "", "The file containing a previous render to be compared with the current one, printing only the differences")
+
+	flag.StringVar(&opts.ServiceUrls.WebsiteBaseURL, "website-base-url", "crownlabs.polito.it", "Base URL of crownlabs website instance")
+	flag.StringVar(&opts.ServiceUrls.InstancesAuthURL, "instances-auth-url", "", "The base URL for user instances authentication (i.e., oauth2-proxy)")
+
+	flag.StringVar(&opts.ClusterNetworkPools.Pods, "cluster-pod-cidr-pool", "10.64.0.0/12", "The default pool the pod CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&opts.ClusterNetworkPools.Services, "cluster-service-cidr-pool", "10.112.0.0/12", "The default pool the service CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&opts.ClusterNetworkPools.PodsIPv6, "cluster-pod-cidr-pool-ipv6", "fd10:64::/48", "The default pool the IPv6 pod CIDRs of cluster environments are automatically allocated from")
+	flag.StringVar(&opts.ClusterNetworkPools.ServicesIPv6, "cluster-service-cidr-pool-ipv6", "fd10:112::/96", "The default pool the IPv6 service CIDRs of cluster environments are automatically allocated from")
+
+	flag.StringVar(&opts.ContainerEnvOpts.ImagesTag, "container-env-sidecars-tag", "latest", "The tag for service containers (such as gui sidecar containers)")
+	flag.StringVar(&opts.ContainerEnvOpts.XVncImg, "container-env-x-vnc-img", "crownlabs/tigervnc", "The image name for the vnc image (sidecar for graphical container environment)")
+	flag.StringVar(&opts.ContainerEnvOpts.WebsockifyImg, "container-env-websockify-img", "crownlabs/websockify", "The image name for the websockify image (sidecar for graphical container environment)")
+	flag.StringVar(&opts.ContainerEnvOpts.ContentDownloaderImg, "container-env-content-downloader-img", "latest", "The image name for the init-container to download and unarchive initial content to the instance volume.")
+	flag.StringVar(&opts.ContainerEnvOpts.ContentUploaderImg, "container-env-content-uploader-img", "latest", "The image name for the job to compress and upload instance content from a persistent instance.")
+	flag.StringVar(&opts.ContainerEnvOpts.InstMetricsEndpoint, "container-env-instmetrics-server-endpoint", "instmetrics:9090", "The endpoint of the InstMetrics gRPC server")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] manifest.yaml...\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Prints the objects the instance operator would create for the Instance in the given manifests, which must")
+		fmt.Fprintln(flag.CommandLine.Output(), "also include the corresponding Template and Tenant. No cluster access is required.")
+		fmt.Fprintln(flag.CommandLine.Output())
+		flag.PrintDefaults()
+	}
+
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	// Logs are written to the standard error, to keep the render on the standard output.
+	ctrl.SetLogger(textlogger.NewLogger(textlogger.NewConfig()))
+	log := ctrl.Log.WithName("instance-render")
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(exitFailure)
+	}
+
+	scheme := instrender.NewScheme()
+	var objects []client.Object
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Error(err, "failed to read manifest", "path", path)
+			os.Exit(exitFailure)
+		}
+		decoded, err := instrender.Decode(scheme, data)
+		if err != nil {
+			log.Error(err, "failed to decode manifest", "path", path)
+			os.Exit(exitFailure)
+		}
+		objects = append(objects, decoded...)
+	}
+
+	rendered, err := instrender.Render(ctrl.LoggerInto(context.Background(), log), scheme, objects, &opts)
+	if err != nil {
+		log.Error(err, "failed to render the instance")
+		os.Exit(exitFailure)
+	}
+	output, err := instrender.Encode(rendered)
+	if err != nil {
+		log.Error(err, "failed to encode the rendered objects")
+		os.Exit(exitFailure)
+	}
+
+	if *diffWith == "" {
+		_, _ = os.Stdout.Write(output)
+		return
+	}
+
+	previous, err := os.ReadFile(*diffWith)
+	if err != nil {
+		log.Error(err, "failed to read the previous render", "path", *diffWith)
+		os.Exit(exitFailure)
+	}
+	diff, err := instrender.Diff(previous, output)
+	if err != nil {
+		log.Error(err, "failed to compare the renders")
+		os.Exit(exitFailure)
+	}
+	if diff != "" {
+		fmt.Print(diff)
+		os.Exit(exitDifferences)
+	}
+}
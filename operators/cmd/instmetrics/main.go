@@ -22,8 +22,12 @@ import (
 	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/textlogger"
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/instmetrics"
@@ -34,6 +38,15 @@ func main() {
 	runtimeEndpoint := flag.String("runtime-endpoint", "unix:///run/containerd/containerd.sock", "Container runtime endpoint for CRI-API")
 	connectionTimeout := flag.Duration("connection-timeout", 5*time.Second, "Timeout of connection to the CRI-API")
 	updatePeriod := flag.Duration("update-period", 1*time.Second, "Metrics update period and timeout in seconds for requests to CRI-API")
+	metricsAddr := flag.String("metrics-addr", ":8082", "The address the Prometheus metrics are exposed on (empty to disable)")
+	guestAgentMetrics := flag.Bool("guest-agent-metrics", false, "Retrieve the disk usage of VMs from the KubeVirt guest agent, "+
+		"and their CPU and memory usage from the virt-handler of the node (requires access to the Kubernetes API)")
+	guestAgentPeriod := flag.Duration("guest-agent-period", 30*time.Second, "Minimum interval between two queries to the guest agent of the same VM")
+	nodeName := flag.String("node-name", os.Getenv("NODE_NAME"), "The name of the node the daemon is running on, whose virt-handler is queried for the VM metrics")
+	virtHandlerNamespace := flag.String("virt-handler-namespace", "kubevirt", "The namespace the KubeVirt virt-handler pods run in")
+	instanceInfo := flag.Bool("instance-info", false, "Retrieve the workspace and the environment type the exported metrics are labelled by "+
+		"from the Instances and their Templates (requires access to the Kubernetes API)")
+	instanceInfoPeriod := flag.Duration("instance-info-period", 5*time.Minute, "Minimum interval between two queries for the attributes of the same instance")
 
 	klog.InitFlags(nil)
 	flag.Parse()
@@ -49,6 +62,31 @@ func main() {
 
 	var statsScraper instmetrics.StatsScraper = instmetrics.CRIMetricsScraper{RuntimeClient: remoteRuntimeClient}
 
+	var restConfig *rest.Config
+	if *guestAgentMetrics || *instanceInfo {
+		if restConfig, err = ctrl.GetConfig(); err != nil {
+			log.Error(err, "Error retrieving the Kubernetes configuration")
+			os.Exit(1)
+		}
+	}
+
+	var guestAgent instmetrics.GuestAgentClient
+	if *guestAgentMetrics {
+		if guestAgent, err = instmetrics.NewKubeVirtGuestAgentClient(restConfig,
+			*guestAgentPeriod, *updatePeriod/2, *nodeName, *virtHandlerNamespace); err != nil {
+			log.Error(err, "Error creating the guest agent client")
+			os.Exit(1)
+		}
+	}
+
+	var instanceInfoClient instmetrics.InstanceInfoClient
+	if *instanceInfo {
+		if instanceInfoClient, err = instmetrics.NewKubernetesInstanceInfoClient(restConfig, *instanceInfoPeriod); err != nil {
+			log.Error(err, "Error creating the instance info client")
+			os.Exit(1)
+		}
+	}
+
 	go func() {
 		http.Handle("/ready", &instmetrics.ReadinessProbeHandler{RuntimeClient: remoteRuntimeClient, Log: log.WithName("probeHandler"), Ready: false})
 		//nolint:gosec // The server is meant to be accessed only by well behaving clients, hence there are no issues with timeouts.
@@ -57,12 +95,28 @@ func main() {
 		}
 	}()
 
+	var metricsRegisterer prometheus.Registerer
+	if *metricsAddr != "" {
+		metricsRegisterer = prometheus.DefaultRegisterer
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			//nolint:gosec // The server is meant to be accessed only by well behaving clients, hence there are no issues with timeouts.
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Error(err, "Error serving metrics")
+			}
+		}()
+	}
+
 	err = (&instmetrics.Server{
 		MetricsScraperPeriod: *updatePeriod,
 		Log:                  log.WithName("gRPCServer"),
 		Port:                 *grpcPort,
 		RuntimeClient:        remoteRuntimeClient,
 		StatsScraper:         &statsScraper,
+		GuestAgent:           guestAgent,
+		InstanceInfo:         instanceInfoClient,
+		MetricsRegisterer:    metricsRegisterer,
 	}).Start(ctx)
 	if err != nil {
 		log.Error(err, "Unable to initialize gRPC server")
@@ -66,3 +66,10 @@ Metrics selector additional labels
 {{- define "exam-agent.metricsAdditionalLabels" -}}
 app.kubernetes.io/component: metrics
 {{- end }}
+
+{{/*
+Namespace of the Secrets holding the authentication tokens
+*/}}
+{{- define "exam-agent.tokensNamespace" -}}
+{{- .Values.configurations.tokenAuthentication.namespace | default .Release.Namespace }}
+{{- end }}
@@ -79,4 +79,10 @@ The tag to be used for image exporter container for VM snapshots
 */}}
 {{- define "instance-operator.containerExportImageTag" -}}
 {{- .Values.configurations.containerVmSnapshots.exportImageTag | default ( include "instance-operator.version" . ) }}
-{{- end }}
\ No newline at end of file
+{{- end }}
+{{/*
+The name of the resources associated with the template webhook
+*/}}
+{{- define "instance-operator.webhookname" -}}
+{{ .Values.rbacResourcesName }}-webhook
+{{- end }}
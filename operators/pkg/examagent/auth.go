@@ -0,0 +1,212 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// TokenSecretPrefix is the prefix of the name of the Secrets holding the exam agent tokens.
+	TokenSecretPrefix = "exam-agent-token-"
+	// TokenSecretType is the type of the Secrets holding the exam agent tokens.
+	TokenSecretType v1.SecretType = "crownlabs.polito.it/exam-agent-token"
+	// TokenSecretKey is the key of the Secret containing the secret part of the token.
+	TokenSecretKey = "token"
+	// TokenTemplatesKey is the key of the Secret containing the comma separated list of Templates
+	// the token grants access to ("*" for all the Templates).
+	TokenTemplatesKey = "templates"
+	// TokenNotBeforeKey is the key of the Secret containing the (optional) RFC3339 time the token is valid from.
+	TokenNotBeforeKey = "notBefore"
+	// TokenNotAfterKey is the key of the Secret containing the (optional) RFC3339 time the token is valid until.
+	TokenNotAfterKey = "notAfter"
+	// TokenAllTemplates is the wildcard granting access to all the Templates.
+	TokenAllTemplates = "*"
+
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+var (
+	// ErrMissingToken is returned when the request does not include a bearer token.
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken is returned when the bearer token does not match any valid exam agent token.
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// TokenScope represents the operations granted to the caller of the exam agent.
+// A nil scope corresponds to an unrestricted caller (i.e., when token authentication is disabled).
+type TokenScope struct {
+	ID        string
+	Templates []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// Authorizer authorizes the requests altering or listing the instances, recording their outcome.
+type Authorizer struct {
+	// Auth authenticates the requests (token authentication is disabled if nil).
+	Auth *TokenAuthenticator
+	// Audit records the outcome of the requests.
+	Audit logr.Logger
+}
+
+// TokenAuthenticator authenticates the exam agent requests, through bearer tokens in the
+// form "<id>.<secret>", stored in the Secrets named TokenSecretPrefix + <id>.
+type TokenAuthenticator struct {
+	Client    client.Client
+	Namespace string
+}
+
+// AllowsTemplate returns whether the scope grants access to the instances of the given Template.
+func (s *TokenScope) AllowsTemplate(template string) bool {
+	if s == nil {
+		return true
+	}
+	return slices.Contains(s.Templates, TokenAllTemplates) || slices.Contains(s.Templates, template)
+}
+
+// AllowsAllTemplates returns whether the scope grants access to the instances of any Template.
+func (s *TokenScope) AllowsAllTemplates() bool {
+	return s == nil || slices.Contains(s.Templates, TokenAllTemplates)
+}
+
+// CallerID returns the identifier of the caller, for auditing purposes.
+func (s *TokenScope) CallerID() string {
+	if s == nil {
+		return "anonymous"
+	}
+	return s.ID
+}
+
+// Authenticate validates the bearer token included in the request, returning the associated scope.
+// Requests are not authenticated (i.e., a nil scope is returned) if the authenticator is nil.
+func (a *TokenAuthenticator) Authenticate(ctx context.Context, r *http.Request, now time.Time) (*TokenScope, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	header := r.Header.Get(authorizationHeader)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return nil, ErrMissingToken
+	}
+	id, secret, found := strings.Cut(strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix)), ".")
+	if !found || id == "" || secret == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var token v1.Secret
+	if err := a.Client.Get(ctx, types.NamespacedName{Namespace: a.Namespace, Name: TokenSecretPrefix + id}, &token); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed retrieving token %q: %w", id, err)
+	}
+
+	if token.Type != TokenSecretType || len(token.Data[TokenSecretKey]) == 0 ||
+		subtle.ConstantTimeCompare(token.Data[TokenSecretKey], []byte(secret)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	scope, err := ScopeFromSecret(id, &token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if !scope.NotBefore.IsZero() && now.Before(scope.NotBefore) {
+		return nil, fmt.Errorf("%w: token %q not yet valid", ErrInvalidToken, id)
+	}
+	if !scope.NotAfter.IsZero() && now.After(scope.NotAfter) {
+		return nil, fmt.Errorf("%w: token %q expired", ErrInvalidToken, id)
+	}
+	return scope, nil
+}
+
+// ScopeFromSecret parses the scope of the token stored in the given Secret.
+func ScopeFromSecret(id string, token *v1.Secret) (*TokenScope, error) {
+	scope := &TokenScope{ID: id}
+
+	for _, template := range strings.Split(string(token.Data[TokenTemplatesKey]), ",") {
+		if template = strings.TrimSpace(template); template != "" {
+			scope.Templates = append(scope.Templates, template)
+		}
+	}
+
+	for key, target := range map[string]*time.Time{TokenNotBeforeKey: &scope.NotBefore, TokenNotAfterKey: &scope.NotAfter} {
+		if value := strings.TrimSpace(string(token.Data[key])); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s of token %q: %w", key, id, err)
+			}
+			*target = parsed
+		}
+	}
+
+	return scope, nil
+}
+
+// Authorize checks whether the request is allowed, according to the IP allow-list and to the bearer token, returning
+// the scope of the caller. Otherwise, the error is written to the response, and the returned boolean is false.
+func (a *Authorizer) Authorize(w http.ResponseWriter, r *http.Request, log logr.Logger) (*TokenScope, bool) {
+	if err := Options.CheckAllowedIP(r.Header.Get(XForwardedFor)); err != nil {
+		log.Error(err, "unauthorized")
+		a.AuditRequest(r, nil, "", "", err)
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "Forbidden")
+		return nil, false
+	}
+
+	scope, err := a.Auth.Authenticate(r.Context(), r, time.Now())
+	switch {
+	case errors.Is(err, ErrMissingToken), errors.Is(err, ErrInvalidToken):
+		log.Error(err, "unauthenticated")
+		a.AuditRequest(r, nil, "", "", err)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, "Unauthorized")
+		return nil, false
+	case err != nil:
+		log.Error(err, "failed authenticating request")
+		a.AuditRequest(r, nil, "", "", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "Internal server error")
+		return nil, false
+	}
+	return scope, true
+}
+
+// AuditRequest records the outcome of a request altering or listing the instances.
+func (a *Authorizer) AuditRequest(r *http.Request, scope *TokenScope, instance, template string, err error) {
+	values := []interface{}{
+		"caller", scope.CallerID(), "remote-ip", r.Header.Get(XForwardedFor), "method", r.Method,
+		"path", r.URL.Path, "instance", instance, "template", template, "allowed", err == nil,
+	}
+	if err != nil {
+		values = append(values, "reason", err.Error())
+	}
+	a.Audit.Info("exam agent request", values...)
+}
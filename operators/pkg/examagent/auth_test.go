@@ -0,0 +1,253 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/examagent"
+)
+
+const tokensNamespace = "exam-agent"
+
+// tokenSecret forges the Secret of an exam agent token, with the given data.
+func tokenSecret(id string, data map[string]string) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: examagent.TokenSecretPrefix + id, Namespace: tokensNamespace},
+		Type:       examagent.TokenSecretType,
+		Data:       map[string][]byte{},
+	}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+	return secret
+}
+
+var _ = Describe("Token authentication", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	})
+
+	Describe("The examagent.ScopeFromSecret function", func() {
+		It("Should parse the templates, ignoring the empty entries", func() {
+			scope, err := examagent.ScopeFromSecret("exam", tokenSecret("exam", map[string]string{
+				examagent.TokenTemplatesKey: " exam-networks, ,exam-databases,",
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scope.ID).To(Equal("exam"))
+			Expect(scope.Templates).To(Equal([]string{"exam-networks", "exam-databases"}))
+			Expect(scope.NotBefore.IsZero()).To(BeTrue())
+			Expect(scope.NotAfter.IsZero()).To(BeTrue())
+		})
+
+		It("Should parse the validity window", func() {
+			scope, err := examagent.ScopeFromSecret("exam", tokenSecret("exam", map[string]string{
+				examagent.TokenNotBeforeKey: "2026-06-01T08:00:00Z",
+				examagent.TokenNotAfterKey:  " 2026-06-01T20:00:00Z ",
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scope.Templates).To(BeEmpty())
+			Expect(scope.NotBefore).To(BeTemporally("==", time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)))
+			Expect(scope.NotAfter).To(BeTemporally("==", time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC)))
+		})
+
+		It("Should fail with an invalid validity window", func() {
+			_, err := examagent.ScopeFromSecret("exam", tokenSecret("exam", map[string]string{examagent.TokenNotAfterKey: "tomorrow"}))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The TokenScope.AllowsTemplate function", func() {
+		It("Should allow any template to unrestricted callers", func() {
+			var scope *examagent.TokenScope
+			Expect(scope.AllowsTemplate("exam-networks")).To(BeTrue())
+			Expect(scope.CallerID()).To(Equal("anonymous"))
+		})
+
+		It("Should allow only the templates in the scope", func() {
+			scope := &examagent.TokenScope{ID: "exam", Templates: []string{"exam-networks"}}
+			Expect(scope.AllowsTemplate("exam-networks")).To(BeTrue())
+			Expect(scope.AllowsTemplate("exam-databases")).To(BeFalse())
+			Expect(scope.CallerID()).To(Equal("exam"))
+		})
+
+		It("Should allow any template with the wildcard", func() {
+			scope := &examagent.TokenScope{Templates: []string{examagent.TokenAllTemplates}}
+			Expect(scope.AllowsTemplate("exam-databases")).To(BeTrue())
+		})
+	})
+
+	Describe("The TokenAuthenticator.Authenticate function", func() {
+		var (
+			authenticator *examagent.TokenAuthenticator
+			header        string
+			scope         *examagent.TokenScope
+			err           error
+		)
+
+		BeforeEach(func() {
+			valid := tokenSecret("exam", map[string]string{
+				examagent.TokenSecretKey:    "s3cr3t",
+				examagent.TokenTemplatesKey: "exam-networks",
+				examagent.TokenNotBeforeKey: "2026-06-01T08:00:00Z",
+				examagent.TokenNotAfterKey:  "2026-06-01T20:00:00Z",
+			})
+			untyped := tokenSecret("untyped", map[string]string{examagent.TokenSecretKey: "s3cr3t"})
+			untyped.Type = v1.SecretTypeOpaque
+			empty := tokenSecret("empty", map[string]string{examagent.TokenSecretKey: ""})
+			malformed := tokenSecret("malformed", map[string]string{examagent.TokenSecretKey: "s3cr3t", examagent.TokenNotBeforeKey: "now"})
+
+			authenticator = &examagent.TokenAuthenticator{
+				Client:    fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(valid, untyped, empty, malformed).Build(),
+				Namespace: tokensNamespace,
+			}
+			header = "Bearer exam.s3cr3t"
+		})
+
+		JustBeforeEach(func() {
+			request := httptest.NewRequest(http.MethodGet, "/api/instances", http.NoBody)
+			if header != "" {
+				request.Header.Set("Authorization", header)
+			}
+			scope, err = authenticator.Authenticate(context.Background(), request, now)
+		})
+
+		When("the token is valid", func() {
+			It("Should return the scope of the token", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(scope.ID).To(Equal("exam"))
+				Expect(scope.Templates).To(ConsistOf("exam-networks"))
+			})
+		})
+
+		When("the authenticator is nil", func() {
+			BeforeEach(func() { authenticator = nil })
+
+			It("Should return an unrestricted scope", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(scope).To(BeNil())
+			})
+		})
+
+		DescribeTable("Should reject the request",
+			func(value string, expected error) {
+				header = value
+				request := httptest.NewRequest(http.MethodGet, "/api/instances", http.NoBody)
+				if header != "" {
+					request.Header.Set("Authorization", header)
+				}
+				scope, err = authenticator.Authenticate(context.Background(), request, now)
+				Expect(err).To(MatchError(expected))
+				Expect(scope).To(BeNil())
+			},
+			Entry("when the token is missing", "", examagent.ErrMissingToken),
+			Entry("when the scheme is not bearer", "Basic ZXhhbTpzM2NyM3Q=", examagent.ErrMissingToken),
+			Entry("when the token is malformed", "Bearer exam", examagent.ErrInvalidToken),
+			Entry("when the secret is empty", "Bearer exam.", examagent.ErrInvalidToken),
+			Entry("when the token does not exist", "Bearer unknown.s3cr3t", examagent.ErrInvalidToken),
+			Entry("when the secret does not match", "Bearer exam.wrong", examagent.ErrInvalidToken),
+			Entry("when the Secret is not of the token type", "Bearer untyped.s3cr3t", examagent.ErrInvalidToken),
+			Entry("when the Secret holds an empty token", "Bearer empty.", examagent.ErrInvalidToken),
+			Entry("when the scope of the token is malformed", "Bearer malformed.s3cr3t", examagent.ErrInvalidToken),
+		)
+
+		When("the token is not yet valid", func() {
+			BeforeEach(func() { now = time.Date(2026, 6, 1, 7, 59, 59, 0, time.UTC) })
+
+			It("Should reject the request", func() { Expect(err).To(MatchError(examagent.ErrInvalidToken)) })
+		})
+
+		When("the token is expired", func() {
+			BeforeEach(func() { now = time.Date(2026, 6, 1, 20, 0, 1, 0, time.UTC) })
+
+			It("Should reject the request", func() { Expect(err).To(MatchError(examagent.ErrInvalidToken)) })
+		})
+
+		When("the validity window boundaries are reached", func() {
+			It("Should accept the token at the start of the window", func() {
+				request := httptest.NewRequest(http.MethodGet, "/api/instances", http.NoBody)
+				request.Header.Set("Authorization", header)
+				_, err := authenticator.Authenticate(context.Background(), request, time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("Should accept the token at the end of the window", func() {
+				request := httptest.NewRequest(http.MethodGet, "/api/instances", http.NoBody)
+				request.Header.Set("Authorization", header)
+				_, err := authenticator.Authenticate(context.Background(), request, time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("The Authorizer.Authorize function", func() {
+		var (
+			authorizer *examagent.Authorizer
+			audited    []string
+			recorder   *httptest.ResponseRecorder
+			ok         bool
+		)
+
+		BeforeEach(func() {
+			audited = nil
+			authorizer = &examagent.Authorizer{
+				Auth: &examagent.TokenAuthenticator{
+					Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+						Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+							return errors.New("connection refused")
+						},
+					}).Build(),
+					Namespace: tokensNamespace,
+				},
+				Audit: funcr.New(func(_, args string) { audited = append(audited, args) }, funcr.Options{}),
+			}
+		})
+
+		JustBeforeEach(func() {
+			request := httptest.NewRequest(http.MethodGet, "/api/instances", http.NoBody)
+			request.Header.Set("Authorization", "Bearer exam.s3cr3t")
+			recorder = httptest.NewRecorder()
+			_, ok = authorizer.Authorize(recorder, request, logr.Discard())
+		})
+
+		When("the token cannot be validated", func() {
+			It("Should reject the request with an internal error", func() {
+				Expect(ok).To(BeFalse())
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("Should audit the request as not allowed", func() {
+				Expect(audited).To(ConsistOf(And(ContainSubstring(`"allowed"=false`), ContainSubstring("connection refused"))))
+			})
+		})
+	})
+})
@@ -21,6 +21,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
@@ -29,14 +30,31 @@ import (
 
 // NewK8sClient initializes the global k8s client.
 func NewK8sClient() (client.Client, error) {
-	scheme := runtime.NewScheme()
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-	utilruntime.Must(clv1alpha2.AddToScheme(scheme))
+	kubeconfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("k8s config error: %w", err)
+	}
 
+	return client.New(restcfg.SetRateLimiter(kubeconfig), client.Options{Scheme: newScheme()})
+}
+
+// NewK8sCache initializes the cache backing the informers on the objects of the given namespace.
+// The cache needs to be started before being used.
+func NewK8sCache(namespace string) (cache.Cache, error) {
 	kubeconfig, err := ctrl.GetConfig()
 	if err != nil {
 		return nil, fmt.Errorf("k8s config error: %w", err)
 	}
 
-	return client.New(restcfg.SetRateLimiter(kubeconfig), client.Options{Scheme: scheme})
+	return cache.New(restcfg.SetRateLimiter(kubeconfig), cache.Options{
+		Scheme:            newScheme(),
+		DefaultNamespaces: map[string]cache.Config{namespace: {}},
+	})
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(clv1alpha2.AddToScheme(scheme))
+	return scheme
 }
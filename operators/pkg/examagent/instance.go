@@ -18,7 +18,9 @@ package examagent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -43,10 +45,12 @@ import (
 type InstanceAdapter struct {
 	ID                string                               `json:"id"`
 	Template          string                               `json:"template"`
+	TemplateRevision  string                               `json:"templateRevision,omitempty"`
 	Running           *bool                                `json:"running,omitempty"`
 	CustomizationUrls clv1alpha2.InstanceCustomizationUrls `json:"customizationUrls"`
 	Phase             string                               `json:"phase"`
 	URL               string                               `json:"url,omitempty"`
+	IP                string                               `json:"ip,omitempty"`
 	Labels            map[string]string                    `json:"labels"`
 }
 
@@ -55,6 +59,7 @@ type InstanceHandler struct {
 	Log             logr.Logger
 	Client          client.Client
 	AdapterEndpoint string
+	Authorizer
 }
 
 const (
@@ -62,6 +67,8 @@ const (
 	XForwardedFor = "X-Forwarded-For"
 )
 
+var errTemplateNotAllowed = stderrors.New("template not allowed by the token scope")
+
 // ServeHTTP is the Instance handler for the examagent.
 func (ih *InstanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log := ih.Log.WithValues("remote-ip", r.Header.Get(XForwardedFor), "method", r.Method, "path", r.URL.Path)
@@ -86,17 +93,39 @@ func (ih *InstanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleGet handles the GET request for the examagent.
+// HandleGet handles the GET request for the examagent. The requests of the exam systems (i.e., for the JSON
+// representation of the instance, or including a bearer token) are authorized and checked against the scope of the
+// caller, while the ones of the browsers of the students (who are redirected to their instance) are not.
 func (ih *InstanceHandler) HandleGet(w http.ResponseWriter, r *http.Request, log logr.Logger) {
+	var scope *TokenScope
+	authorized := !AcceptsHTML(r) || r.Header.Get(authorizationHeader) != ""
+	if authorized {
+		var ok bool
+		if scope, ok = ih.Authorize(w, r, log); !ok {
+			return
+		}
+	}
+
 	inst := ih.EmptyInstanceFromRequest(r)
 	log = log.WithValues("instance", inst.Name)
+	if authorized {
+		log = log.WithValues("caller", scope.CallerID())
+	}
 
-	if err := ih.Client.Get(r.Context(), forge.NamespacedName(inst), inst); err != nil {
-		if errors.IsNotFound(err) {
-			log.Error(err, "instance not found")
-			WriteError(w, r, log, http.StatusNotFound, "The requested Instance does not exist.")
-			return
-		}
+	err := ih.retrieveScopedInstance(r.Context(), scope, inst)
+	if authorized {
+		ih.AuditRequest(r, scope, inst.Name, inst.Spec.Template.Name, err)
+	}
+	switch {
+	case stderrors.Is(err, errTemplateNotAllowed):
+		log.Error(err, "unauthorized", "template", inst.Spec.Template.Name)
+		WriteError(w, r, log, http.StatusForbidden, "The requested Instance is not in the scope of the token.")
+		return
+	case errors.IsNotFound(err):
+		log.Error(err, "instance not found")
+		WriteError(w, r, log, http.StatusNotFound, "The requested Instance does not exist.")
+		return
+	case err != nil:
 		log.Error(err, "error retrieving instance")
 		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the requested instance.")
 		return
@@ -136,10 +165,8 @@ func (ih *InstanceHandler) HandleGet(w http.ResponseWriter, r *http.Request, log
 
 // HandlePut handles the PUT request for a InstanceAdapter api call.
 func (ih *InstanceHandler) HandlePut(w http.ResponseWriter, r *http.Request, log logr.Logger) {
-	if err := Options.CheckAllowedIP(r.Header.Get(XForwardedFor)); err != nil {
-		log.Error(err, "unauthorized")
-		w.WriteHeader(http.StatusForbidden)
-		fmt.Fprint(w, "Forbidden")
+	scope, ok := ih.Authorize(w, r, log)
+	if !ok {
 		return
 	}
 
@@ -153,16 +180,35 @@ func (ih *InstanceHandler) HandlePut(w http.ResponseWriter, r *http.Request, log
 	}
 
 	instance := ih.EmptyInstanceFromRequest(r)
-	log = log.WithValues("instance", instance.Name)
+	log = log.WithValues("instance", instance.Name, "caller", scope.CallerID())
+
+	if !scope.AllowsTemplate(adapter.Template) {
+		log.Error(errTemplateNotAllowed, "unauthorized", "template", adapter.Template)
+		ih.AuditRequest(r, scope, instance.Name, adapter.Template, errTemplateNotAllowed)
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "Forbidden")
+		return
+	}
 
 	op, err := ctrl.CreateOrUpdate(r.Context(), ih.Client, instance, func() error {
+		// Prevent the caller from taking over instances of templates outside its scope.
+		if !instance.CreationTimestamp.IsZero() && !scope.AllowsTemplate(instance.Spec.Template.Name) {
+			return errTemplateNotAllowed
+		}
 		instance.Spec = InstanceSpecFromAdapter(&adapter)
 		instance.SetLabels(labels.Merge(instance.GetLabels(), adapter.Labels))
 		return nil
 	})
 
 	log = log.WithValues("operation", op)
+	ih.AuditRequest(r, scope, instance.Name, adapter.Template, err)
 
+	if stderrors.Is(err, errTemplateNotAllowed) {
+		log.Error(err, "unauthorized")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "Forbidden")
+		return
+	}
 	if err != nil {
 		log.Error(err, "failed performing operation")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -191,10 +237,8 @@ func (ih *InstanceHandler) HandlePut(w http.ResponseWriter, r *http.Request, log
 
 // HandleGetAll handles the GET request for all the instances.
 func (ih *InstanceHandler) HandleGetAll(w http.ResponseWriter, r *http.Request, log logr.Logger) {
-	if err := Options.CheckAllowedIP(r.Header.Get(XForwardedFor)); err != nil {
-		log.Error(err, "unauthorized")
-		w.WriteHeader(http.StatusForbidden)
-		fmt.Fprint(w, "Forbidden")
+	scope, ok := ih.Authorize(w, r, log)
+	if !ok {
 		return
 	}
 
@@ -204,16 +248,21 @@ func (ih *InstanceHandler) HandleGetAll(w http.ResponseWriter, r *http.Request,
 	}
 
 	var instances clv1alpha2.InstanceList
-	if err := ih.Client.List(r.Context(), &instances, clientOptions...); err != nil {
+	err := ih.Client.List(r.Context(), &instances, clientOptions...)
+	ih.AuditRequest(r, scope, "", "", err)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, "Error retrieving instances")
 		log.Error(err, "error retrieving instances")
 		return
 	}
 
-	adapters := make([]InstanceAdapter, len(instances.Items))
+	// Only the instances of the templates in the scope of the caller are returned.
+	adapters := make([]InstanceAdapter, 0, len(instances.Items))
 	for i := range instances.Items {
-		adapters[i] = *AdapterFromInstance(&instances.Items[i])
+		if scope.AllowsTemplate(instances.Items[i].Spec.Template.Name) {
+			adapters = append(adapters, *AdapterFromInstance(&instances.Items[i]))
+		}
 	}
 
 	if err := WriteJSON(w, adapters); err != nil {
@@ -226,16 +275,42 @@ func (ih *InstanceHandler) HandleGetAll(w http.ResponseWriter, r *http.Request,
 
 // HandleDelete handles the DELETE request for a InstanceAdapter api call.
 func (ih *InstanceHandler) HandleDelete(w http.ResponseWriter, r *http.Request, log logr.Logger) {
-	if err := Options.CheckAllowedIP(r.Header.Get(XForwardedFor)); err != nil {
-		log.Error(err, "unauthorized")
-		w.WriteHeader(http.StatusForbidden)
-		fmt.Fprint(w, "Forbidden")
+	scope, ok := ih.Authorize(w, r, log)
+	if !ok {
 		return
 	}
 
 	inst := ih.EmptyInstanceFromRequest(r)
-	log = log.WithValues("instance", inst.Name, "operation", "delete")
-	if err := ih.Client.Delete(r.Context(), inst); err != nil {
+	log = log.WithValues("instance", inst.Name, "operation", "delete", "caller", scope.CallerID())
+
+	var deleteOptions []client.DeleteOption
+	if scope != nil {
+		// The template of the instance is checked against the scope of the caller,
+		// ensuring the deleted instance is the same that has been checked.
+		if err := ih.retrieveScopedInstance(r.Context(), scope, inst); err != nil {
+			ih.AuditRequest(r, scope, inst.Name, inst.Spec.Template.Name, err)
+			switch {
+			case stderrors.Is(err, errTemplateNotAllowed):
+				log.Error(err, "unauthorized", "template", inst.Spec.Template.Name)
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, "Forbidden")
+			case errors.IsNotFound(err):
+				log.Error(err, "instance not found")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, "Instance not found")
+			default:
+				log.Error(err, "failed retrieving instance")
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, "Error deleting instance")
+			}
+			return
+		}
+		deleteOptions = append(deleteOptions, client.Preconditions{UID: &inst.UID})
+	}
+
+	err := ih.Client.Delete(r.Context(), inst, deleteOptions...)
+	ih.AuditRequest(r, scope, inst.Name, inst.Spec.Template.Name, err)
+	if err != nil {
 		log.Error(err, "failed performing operation")
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, "Error deleting instance")
@@ -245,6 +320,23 @@ func (ih *InstanceHandler) HandleDelete(w http.ResponseWriter, r *http.Request,
 	log.Info("success")
 }
 
+// retrieveScopedInstance retrieves the given instance, returning errTemplateNotAllowed if it is not in the scope of the caller.
+// Missing instances are reported as outside the scope as well to the callers restricted to a subset of the templates, so
+// that they cannot tell apart the instances of the other templates from the ones which do not exist.
+func (ih *InstanceHandler) retrieveScopedInstance(ctx context.Context, scope *TokenScope, inst *clv1alpha2.Instance) error {
+	if err := ih.Client.Get(ctx, forge.NamespacedName(inst), inst); err != nil {
+		if errors.IsNotFound(err) && !scope.AllowsAllTemplates() {
+			return errTemplateNotAllowed
+		}
+		return err
+	}
+
+	if !scope.AllowsTemplate(inst.Spec.Template.Name) {
+		return errTemplateNotAllowed
+	}
+	return nil
+}
+
 // GetInstanceIDFromRequest returns the instance id from the request.
 func (ih *InstanceHandler) GetInstanceIDFromRequest(r *http.Request) string {
 	InstanceEP := path.Join(Options.BasePath, ih.AdapterEndpoint) + "/"
@@ -297,6 +389,7 @@ func AdapterFromInstance(inst *clv1alpha2.Instance) *InstanceAdapter {
 		Template: inst.Spec.Template.Name,
 		Running:  ptr.To(inst.Spec.Running),
 		URL:      inst.Status.URL,
+		IP:       inst.Status.IP,
 		Phase:    string(inst.Status.Phase),
 		Labels:   inst.GetLabels(),
 	}
@@ -305,6 +398,11 @@ func AdapterFromInstance(inst *clv1alpha2.Instance) *InstanceAdapter {
 		adapter.CustomizationUrls = *inst.Spec.CustomizationUrls
 	}
 
+	// The revision the instance is pinned to, which may differ from the current specification of the template.
+	if inst.Status.TemplateRevision != nil {
+		adapter.TemplateRevision = inst.Status.TemplateRevision.Revision
+	}
+
 	return adapter
 }
 
@@ -0,0 +1,187 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/examagent"
+	. "github.com/netgroup-polito/CrownLabs/operators/pkg/utils/tests"
+)
+
+var _ = Describe("Scope checks of the instance handler", func() {
+	const (
+		examNamespace = "crownlabs-exam"
+		allowed       = "exam-networks"
+		forbidden     = "exam-databases"
+	)
+
+	var (
+		k8sClient client.Client
+		handler   *examagent.InstanceHandler
+	)
+
+	instance := func(name, template string) *clv1alpha2.Instance {
+		return &clv1alpha2.Instance{
+			// The creation timestamp is set explicitly, as the fake client does not.
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: examNamespace, CreationTimestamp: metav1.Now()},
+			Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: template, Namespace: examNamespace}},
+			Status:     clv1alpha2.InstanceStatus{Phase: clv1alpha2.EnvironmentPhaseReady, URL: "https://exam.crownlabs.example.com/instance/" + name},
+		}
+	}
+
+	serve := func(method, path, body, token string, html bool) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		if html {
+			request.Header.Set("Accept", "text/html")
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	BeforeEach(func() {
+		examagent.Options.Namespace = examNamespace
+		examagent.Options.BasePath = "/api"
+
+		token := tokenSecret("exam", map[string]string{examagent.TokenSecretKey: "s3cr3t", examagent.TokenTemplatesKey: allowed})
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(token, instance("student-allowed", allowed), instance("student-forbidden", forbidden)).Build()
+
+		handler = &examagent.InstanceHandler{
+			Log:             logr.Discard(),
+			Client:          k8sClient,
+			AdapterEndpoint: "instance",
+			Authorizer: examagent.Authorizer{
+				Auth:  &examagent.TokenAuthenticator{Client: k8sClient, Namespace: tokensNamespace},
+				Audit: logr.Discard(),
+			},
+		}
+	})
+
+	Describe("Retrieving an instance", func() {
+		It("Should reject the JSON requests without a token", func() {
+			Expect(serve(http.MethodGet, "/api/instance/student-allowed", "", "", false).Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("Should return the instances in the scope of the token", func() {
+			response := serve(http.MethodGet, "/api/instance/student-allowed", "", "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			var adapter examagent.InstanceAdapter
+			Expect(json.Unmarshal(response.Body.Bytes(), &adapter)).To(Succeed())
+			Expect(adapter.ID).To(Equal("student-allowed"))
+		})
+
+		It("Should reject the requests for the instances outside the scope of the token", func() {
+			response := serve(http.MethodGet, "/api/instance/student-forbidden", "", "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+			Expect(response.Body.String()).ToNot(ContainSubstring("student-forbidden"))
+		})
+
+		It("Should not tell apart the missing instances from the ones outside the scope of the token", func() {
+			missing := serve(http.MethodGet, "/api/instance/student-missing", "", "exam.s3cr3t", false)
+			forbidden := serve(http.MethodGet, "/api/instance/student-forbidden", "", "exam.s3cr3t", false)
+			Expect(missing.Code).To(Equal(http.StatusForbidden))
+			Expect(missing.Body.String()).To(Equal(forbidden.Body.String()))
+		})
+
+		It("Should report the missing instances to the browsers of the students without a token", func() {
+			Expect(serve(http.MethodGet, "/api/instance/student-missing", "", "", true).Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("Should redirect the browsers of the students without a token", func() {
+			response := serve(http.MethodGet, "/api/instance/student-forbidden", "", "", true)
+			Expect(response.Code).To(Equal(http.StatusFound))
+			Expect(response.Header().Get("Location")).To(HaveSuffix("/instance/student-forbidden"))
+		})
+
+		It("Should check the scope of the token included by a browser", func() {
+			Expect(serve(http.MethodGet, "/api/instance/student-forbidden", "", "exam.s3cr3t", true).Code).To(Equal(http.StatusForbidden))
+			Expect(serve(http.MethodGet, "/api/instance/student-allowed", "", "invalid.token", true).Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("Listing the instances", func() {
+		It("Should return only the instances in the scope of the token", func() {
+			response := serve(http.MethodGet, "/api/instance/", "", "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			var adapters []examagent.InstanceAdapter
+			Expect(json.Unmarshal(response.Body.Bytes(), &adapters)).To(Succeed())
+			Expect(adapters).To(HaveLen(1))
+			Expect(adapters[0].ID).To(Equal("student-allowed"))
+		})
+	})
+
+	Describe("Creating an instance", func() {
+		It("Should create the instances of the templates in the scope of the token", func() {
+			response := serve(http.MethodPut, "/api/instance/student-new", `{"template":"`+allowed+`"}`, "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusCreated))
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "student-new"}, &clv1alpha2.Instance{})).To(Succeed())
+		})
+
+		It("Should reject the instances of the templates outside the scope of the token", func() {
+			response := serve(http.MethodPut, "/api/instance/student-new", `{"template":"`+forbidden+`"}`, "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "student-new"}, &clv1alpha2.Instance{})).To(FailBecauseNotFound())
+		})
+
+		It("Should prevent taking over the instances outside the scope of the token", func() {
+			response := serve(http.MethodPut, "/api/instance/student-forbidden", `{"template":"`+allowed+`"}`, "exam.s3cr3t", false)
+			Expect(response.Code).To(Equal(http.StatusForbidden))
+
+			var current clv1alpha2.Instance
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "student-forbidden"}, &current)).To(Succeed())
+			Expect(current.Spec.Template.Name).To(Equal(forbidden))
+		})
+	})
+
+	Describe("Deleting an instance", func() {
+		It("Should delete the instances in the scope of the token", func() {
+			Expect(serve(http.MethodDelete, "/api/instance/student-allowed", "", "exam.s3cr3t", false).Code).To(Equal(http.StatusOK))
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "student-allowed"}, &clv1alpha2.Instance{})).To(FailBecauseNotFound())
+		})
+
+		It("Should reject the deletion of the instances outside the scope of the token", func() {
+			Expect(serve(http.MethodDelete, "/api/instance/student-forbidden", "", "exam.s3cr3t", false).Code).To(Equal(http.StatusForbidden))
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "student-forbidden"}, &clv1alpha2.Instance{})).To(Succeed())
+		})
+
+		It("Should not tell apart the missing instances from the ones outside the scope of the token", func() {
+			Expect(serve(http.MethodDelete, "/api/instance/student-missing", "", "exam.s3cr3t", false).Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should reject the requests without a token", func() {
+			Expect(serve(http.MethodDelete, "/api/instance/student-allowed", "", "", false).Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -27,12 +28,15 @@ import (
 )
 
 type options struct {
-	AllowedIPs       string
-	Namespace        string
-	BasePath         string
-	ListenerAddr     string
-	PrintRequestBody bool
-	ipNets           []*net.IPNet
+	AllowedIPs              string
+	Namespace               string
+	BasePath                string
+	ListenerAddr            string
+	PrintRequestBody        bool
+	TokensNamespace         string
+	SessionConcurrency      int
+	StreamHeartbeatInterval time.Duration
+	ipNets                  []*net.IPNet
 }
 
 // Options object holds all the examagent parameters.
@@ -44,6 +48,9 @@ func (o *options) Init() {
 	flag.StringVar(&o.Namespace, "namespace", "", "Namespace in which Templates are stored and instances will be created")
 	flag.StringVar(&o.AllowedIPs, "allowed-ips", "", "Comma separated list of CIDRs that are allowed to create new instances")
 	flag.StringVar(&o.BasePath, "base-path", "/api", "Base path of the Exam Agent API")
+	flag.StringVar(&o.TokensNamespace, "tokens-namespace", "", "Namespace in which the Secrets of the bearer tokens are stored (token authentication is disabled if empty)")
+	flag.IntVar(&o.SessionConcurrency, "session-concurrency", 10, "Maximum number of instances concurrently altered by a single exam session request")
+	flag.DurationVar(&o.StreamHeartbeatInterval, "stream-heartbeat-interval", 30*time.Second, "Interval between the heartbeats sent on the idle instance streams")
 	flag.BoolVar(&o.PrintRequestBody, "print-request-body", false, "Print the request body (WARNING: might be unstable)")
 
 	restcfg.InitFlags(nil)
@@ -73,6 +80,18 @@ func (o *options) Parse() error {
 		}
 	}
 
+	if o.TokensNamespace == "" {
+		klog.Infoln("No tokens namespace has been specified: token authentication is disabled")
+	}
+
+	if o.SessionConcurrency <= 0 {
+		return errors.New("invalid argument: session-concurrency must be positive")
+	}
+
+	if o.StreamHeartbeatInterval <= 0 {
+		return errors.New("invalid argument: stream-heartbeat-interval must be positive")
+	}
+
 	if o.BasePath == "" {
 		return errors.New("missing argument: base-path")
 	}
@@ -0,0 +1,432 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// SessionLabel is the label identifying the exam session an instance belongs to.
+	SessionLabel = "crownlabs.polito.it/exam-session"
+
+	// SessionActionStart is the action starting all the instances of an exam session.
+	SessionActionStart = "start"
+	// SessionActionStop is the action stopping all the instances of an exam session.
+	SessionActionStop = "stop"
+
+	// sessionPhasePending is the phase reported for the instances not yet processed by the instance operator.
+	sessionPhasePending = "Pending"
+)
+
+var errNotInSession = errors.New("instance belongs to a different exam session")
+
+// SessionRequest represents the declaration of an exam session within the examagent.
+type SessionRequest struct {
+	Template string `json:"template"`
+	// Running defaults to true, i.e., the instances are started as soon as they are created.
+	Running *bool `json:"running,omitempty"`
+	// Concurrency limits the instances concurrently altered, in addition to the limit of the examagent.
+	Concurrency int               `json:"concurrency,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Instances   []SessionInstance `json:"instances"`
+}
+
+// SessionInstance represents an instance of an exam session within the examagent.
+type SessionInstance struct {
+	ID                string                               `json:"id"`
+	CustomizationUrls clv1alpha2.InstanceCustomizationUrls `json:"customizationUrls"`
+	Labels            map[string]string                    `json:"labels,omitempty"`
+}
+
+// SessionStatus represents the aggregated status of an exam session within the examagent.
+type SessionStatus struct {
+	Name      string            `json:"name"`
+	Total     int               `json:"total"`
+	Ready     int               `json:"ready"`
+	AllReady  bool              `json:"allReady"`
+	Phases    map[string]int    `json:"phases"`
+	Instances []InstanceAdapter `json:"instances"`
+	// Errors maps the instances which could not be processed to the corresponding error.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// SessionHandler is the handler for the exam sessions, i.e., the groups of instances managed at once.
+type SessionHandler struct {
+	Log             logr.Logger
+	Client          client.Client
+	AdapterEndpoint string
+	// Concurrency is the maximum number of instances concurrently altered by a single request.
+	Concurrency int
+	Authorizer
+}
+
+// ServeHTTP is the exam session handler for the examagent.
+func (sh *SessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := sh.Log.WithValues("remote-ip", r.Header.Get(XForwardedFor), "method", r.Method, "path", r.URL.Path)
+
+	log.Info("processing request", "query", r.URL.RawQuery)
+
+	name, action := sh.GetSessionFromRequest(r)
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		log.Error(errors.New(strings.Join(errs, ", ")), "invalid session name")
+		WriteError(w, r, log, http.StatusBadRequest, "Invalid session name.")
+		return
+	}
+	log = log.WithValues("session", name)
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		sh.HandleGet(w, r, log, name)
+	case action == "" && r.Method == http.MethodPut:
+		sh.HandlePut(w, r, log, name)
+	case action == "" && r.Method == http.MethodDelete:
+		sh.HandleDelete(w, r, log, name)
+	case (action == SessionActionStart || action == SessionActionStop) && r.Method == http.MethodPost:
+		sh.HandleAction(w, r, log, name, action == SessionActionStart)
+	case action == "" || action == SessionActionStart || action == SessionActionStop:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "Method not allowed")
+	default:
+		WriteError(w, r, log, http.StatusNotFound, "Unknown session action.")
+	}
+}
+
+// HandleGet handles the GET request for the status of an exam session.
+func (sh *SessionHandler) HandleGet(w http.ResponseWriter, r *http.Request, log logr.Logger, name string) {
+	scope, ok := sh.Authorize(w, r, log)
+	if !ok {
+		return
+	}
+
+	instances, err := sh.ListSessionInstances(r.Context(), name)
+	if err != nil {
+		log.Error(err, "error retrieving session instances")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the requested session.")
+		return
+	}
+
+	// Only the instances of the templates in the scope of the caller are considered.
+	instances = slices.DeleteFunc(instances, func(inst clv1alpha2.Instance) bool {
+		return !scope.AllowsTemplate(inst.Spec.Template.Name)
+	})
+	if len(instances) == 0 {
+		WriteError(w, r, log, http.StatusNotFound, "The requested session does not exist.")
+		return
+	}
+
+	if err := WriteJSON(w, SessionStatusFromInstances(name, instances, nil)); err != nil {
+		log.Error(err, "cannot encode session")
+	}
+}
+
+// HandlePut handles the PUT request declaring an exam session: the instances included in the request are created
+// or updated to match it. The instances of the session no longer included in the request are left untouched, since
+// their teardown requires an explicit DELETE request (either of the single instances, or of the whole session).
+func (sh *SessionHandler) HandlePut(w http.ResponseWriter, r *http.Request, log logr.Logger, name string) {
+	scope, ok := sh.Authorize(w, r, log)
+	if !ok {
+		return
+	}
+	log = log.WithValues("caller", scope.CallerID())
+
+	var request SessionRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error(err, "cannot parse request")
+		WriteError(w, r, log, http.StatusBadRequest, "Bad request.")
+		return
+	}
+	if err := ValidateSessionRequest(&request); err != nil {
+		log.Error(err, "invalid request")
+		WriteError(w, r, log, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v.", err))
+		return
+	}
+	log = log.WithValues("template", request.Template, "instances", len(request.Instances))
+
+	if !scope.AllowsTemplate(request.Template) {
+		log.Error(errTemplateNotAllowed, "unauthorized")
+		sh.AuditRequest(r, scope, "", request.Template, errTemplateNotAllowed)
+		WriteError(w, r, log, http.StatusForbidden, "Forbidden.")
+		return
+	}
+
+	running := ptr.Deref(request.Running, true)
+	concurrency := sh.concurrency(request.Concurrency)
+
+	errs := forEach(len(request.Instances), concurrency, func(i int) (string, error) {
+		instance := &request.Instances[i]
+		err := sh.EnforceSessionInstance(r.Context(), name, &request, instance, running, scope)
+		sh.AuditRequest(r, scope, instance.ID, request.Template, err)
+		return instance.ID, err
+	})
+
+	sh.writeStatus(w, r, log, name, errs)
+}
+
+// HandleDelete handles the DELETE request for all the instances of an exam session.
+func (sh *SessionHandler) HandleDelete(w http.ResponseWriter, r *http.Request, log logr.Logger, name string) {
+	scope, ok := sh.Authorize(w, r, log)
+	if !ok {
+		return
+	}
+	log = log.WithValues("caller", scope.CallerID(), "operation", "delete")
+
+	instances, err := sh.ListSessionInstances(r.Context(), name)
+	if err != nil {
+		log.Error(err, "error retrieving session instances")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the requested session.")
+		return
+	}
+
+	errs := forEach(len(instances), sh.concurrency(0), func(i int) (string, error) {
+		err := sh.DeleteSessionInstance(r.Context(), &instances[i], scope)
+		sh.AuditRequest(r, scope, instances[i].Name, instances[i].Spec.Template.Name, err)
+		return instances[i].Name, err
+	})
+
+	sh.writeStatus(w, r, log, name, errs)
+}
+
+// HandleAction handles the POST request starting or stopping all the instances of an exam session.
+func (sh *SessionHandler) HandleAction(w http.ResponseWriter, r *http.Request, log logr.Logger, name string, running bool) {
+	scope, ok := sh.Authorize(w, r, log)
+	if !ok {
+		return
+	}
+	log = log.WithValues("caller", scope.CallerID(), "running", running)
+
+	instances, err := sh.ListSessionInstances(r.Context(), name)
+	if err != nil {
+		log.Error(err, "error retrieving session instances")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the requested session.")
+		return
+	}
+	if len(instances) == 0 {
+		WriteError(w, r, log, http.StatusNotFound, "The requested session does not exist.")
+		return
+	}
+
+	errs := forEach(len(instances), sh.concurrency(0), func(i int) (string, error) {
+		instance := &instances[i]
+		err := errTemplateNotAllowed
+		if scope.AllowsTemplate(instance.Spec.Template.Name) {
+			err = SetSessionInstanceRunning(r.Context(), sh.Client, instance, running)
+		}
+		sh.AuditRequest(r, scope, instance.Name, instance.Spec.Template.Name, err)
+		return instance.Name, err
+	})
+
+	sh.writeStatus(w, r, log, name, errs)
+}
+
+// GetSessionFromRequest returns the name of the exam session and the (optional) action from the request.
+func (sh *SessionHandler) GetSessionFromRequest(r *http.Request) (name, action string) {
+	sessionEP := path.Join(Options.BasePath, sh.AdapterEndpoint) + "/"
+	name, action, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, sessionEP), "/")
+	return name, action
+}
+
+// ListSessionInstances returns the instances belonging to the given exam session.
+func (sh *SessionHandler) ListSessionInstances(ctx context.Context, name string) ([]clv1alpha2.Instance, error) {
+	var instances clv1alpha2.InstanceList
+	if err := sh.Client.List(ctx, &instances, client.InNamespace(Options.Namespace), client.MatchingLabels{SessionLabel: name}); err != nil {
+		return nil, err
+	}
+	return instances.Items, nil
+}
+
+// EnforceSessionInstance creates or updates an instance of an exam session, according to the request.
+func (sh *SessionHandler) EnforceSessionInstance(ctx context.Context, name string, request *SessionRequest,
+	si *SessionInstance, running bool, scope *TokenScope) error {
+	instance := &clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: si.ID, Namespace: Options.Namespace}}
+
+	_, err := ctrl.CreateOrUpdate(ctx, sh.Client, instance, func() error {
+		// Prevent the caller from taking over instances of other sessions or outside its scope.
+		if !instance.CreationTimestamp.IsZero() {
+			if instance.GetLabels()[SessionLabel] != name {
+				return errNotInSession
+			}
+			if !scope.AllowsTemplate(instance.Spec.Template.Name) {
+				return errTemplateNotAllowed
+			}
+		}
+
+		adapter := InstanceAdapter{ID: si.ID, Template: request.Template, Running: ptr.To(running), CustomizationUrls: si.CustomizationUrls}
+		instance.Spec = InstanceSpecFromAdapter(&adapter)
+		instance.SetLabels(labels.Merge(labels.Merge(instance.GetLabels(), labels.Merge(request.Labels, si.Labels)),
+			map[string]string{SessionLabel: name}))
+		return nil
+	})
+	return err
+}
+
+// DeleteSessionInstance deletes an instance of an exam session, if allowed by the scope.
+func (sh *SessionHandler) DeleteSessionInstance(ctx context.Context, instance *clv1alpha2.Instance, scope *TokenScope) error {
+	if !scope.AllowsTemplate(instance.Spec.Template.Name) {
+		return errTemplateNotAllowed
+	}
+	if err := sh.Client.Delete(ctx, instance, client.Preconditions{UID: &instance.UID}); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// SetSessionInstanceRunning starts or stops an instance of an exam session.
+func SetSessionInstanceRunning(ctx context.Context, c client.Client, instance *clv1alpha2.Instance, running bool) error {
+	original := instance.DeepCopy()
+	instance.Spec.Running = running
+	return c.Patch(ctx, instance, client.MergeFrom(original))
+}
+
+// ValidateSessionRequest checks that the request declares a valid exam session.
+func ValidateSessionRequest(request *SessionRequest) error {
+	if request.Template == "" {
+		return errors.New("missing template")
+	}
+	if len(request.Instances) == 0 {
+		return errors.New("missing instances")
+	}
+
+	ids := make(map[string]struct{}, len(request.Instances))
+	for i := range request.Instances {
+		id := request.Instances[i].ID
+		if errs := validation.IsDNS1123Subdomain(id); len(errs) > 0 {
+			return fmt.Errorf("invalid instance id %q", id)
+		}
+		if _, found := ids[id]; found {
+			return fmt.Errorf("duplicated instance id %q", id)
+		}
+		ids[id] = struct{}{}
+	}
+	return nil
+}
+
+// SessionStatusFromInstances aggregates the status of the instances of an exam session.
+func SessionStatusFromInstances(name string, instances []clv1alpha2.Instance, errs map[string]error) *SessionStatus {
+	status := &SessionStatus{
+		Name:      name,
+		Total:     len(instances),
+		Phases:    make(map[string]int),
+		Instances: make([]InstanceAdapter, len(instances)),
+	}
+
+	for i := range instances {
+		inst := &instances[i]
+		status.Instances[i] = *AdapterFromInstance(inst)
+
+		phase := string(inst.Status.Phase)
+		if inst.Status.Phase == clv1alpha2.EnvironmentPhaseUnset {
+			phase = sessionPhasePending
+		}
+		status.Phases[phase]++
+		if inst.Status.Phase == clv1alpha2.EnvironmentPhaseReady {
+			status.Ready++
+		}
+	}
+	status.AllReady = status.Total > 0 && status.Ready == status.Total
+
+	slices.SortFunc(status.Instances, func(a, b InstanceAdapter) int { return strings.Compare(a.ID, b.ID) })
+
+	for id, err := range errs {
+		if err != nil {
+			if status.Errors == nil {
+				status.Errors = make(map[string]string)
+			}
+			status.Errors[id] = err.Error()
+		}
+	}
+	return status
+}
+
+// writeStatus writes the current status of the exam session, along with the errors occurred while processing the request.
+func (sh *SessionHandler) writeStatus(w http.ResponseWriter, r *http.Request, log logr.Logger, name string, errs map[string]error) {
+	instances, err := sh.ListSessionInstances(r.Context(), name)
+	if err != nil {
+		log.Error(err, "error retrieving session instances")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the requested session.")
+		return
+	}
+
+	status := SessionStatusFromInstances(name, instances, errs)
+	if len(status.Errors) > 0 {
+		log.Error(errors.New("operation partially failed"), "failed processing session instances", "errors", status.Errors)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		log.Info("success")
+	}
+
+	if err := WriteJSON(w, status); err != nil {
+		log.Error(err, "cannot encode session")
+	}
+}
+
+// concurrency returns the maximum number of instances concurrently altered, given the one requested.
+func (sh *SessionHandler) concurrency(requested int) int {
+	limit := max(sh.Concurrency, 1)
+	if requested > 0 {
+		return min(requested, limit)
+	}
+	return limit
+}
+
+// forEach executes the given function for the items in [0, n), with at most concurrency concurrent executions,
+// returning the resulting errors keyed by the identifier returned by the function.
+func forEach(n, concurrency int, fn func(i int) (string, error)) map[string]error {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		semaphore = make(chan struct{}, concurrency)
+		errs      = make(map[string]error, n)
+	)
+
+	for i := range n {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			id, err := fn(i)
+			mu.Lock()
+			errs[id] = err
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
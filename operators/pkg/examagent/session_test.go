@@ -0,0 +1,205 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+var _ = Describe("Exam sessions", func() {
+	Describe("The forEach function", func() {
+		It("Should execute the function for all the items, collecting the errors", func() {
+			failure := errors.New("failure")
+			errs := forEach(10, 3, func(i int) (string, error) {
+				if i%2 == 0 {
+					return string(rune('a' + i)), failure
+				}
+				return string(rune('a' + i)), nil
+			})
+
+			Expect(errs).To(HaveLen(10))
+			Expect(errs).To(HaveKeyWithValue("a", failure))
+			Expect(errs).To(HaveKeyWithValue("b", BeNil()))
+		})
+
+		It("Should limit the concurrent executions", func() {
+			var running, peak atomic.Int32
+			forEach(20, 4, func(i int) (string, error) {
+				current := running.Add(1)
+				for {
+					previous := peak.Load()
+					if current <= previous || peak.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				return string(rune('a' + i)), nil
+			})
+			Expect(peak.Load()).To(BeNumerically("<=", 4))
+		})
+
+		It("Should return an empty map with no items", func() {
+			Expect(forEach(0, 1, func(int) (string, error) { panic("unexpected call") })).To(BeEmpty())
+		})
+	})
+
+	Describe("The ValidateSessionRequest function", func() {
+		var request SessionRequest
+
+		BeforeEach(func() {
+			request = SessionRequest{
+				Template:  "exam-networks",
+				Instances: []SessionInstance{{ID: "exam-s123456"}, {ID: "exam-s654321"}},
+			}
+		})
+
+		It("Should accept a valid request", func() {
+			Expect(ValidateSessionRequest(&request)).To(Succeed())
+		})
+
+		DescribeTable("Should reject an invalid request",
+			func(mutate func(*SessionRequest), message string) {
+				mutate(&request)
+				Expect(ValidateSessionRequest(&request)).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("when the template is missing", func(r *SessionRequest) { r.Template = "" }, "missing template"),
+			Entry("when the instances are missing", func(r *SessionRequest) { r.Instances = nil }, "missing instances"),
+			Entry("when an instance id is invalid", func(r *SessionRequest) { r.Instances[1].ID = "Exam_S654321" }, "invalid instance id"),
+			Entry("when an instance id is empty", func(r *SessionRequest) { r.Instances[1].ID = "" }, "invalid instance id"),
+			Entry("when an instance id is duplicated", func(r *SessionRequest) { r.Instances[1].ID = r.Instances[0].ID }, "duplicated instance id"),
+		)
+	})
+
+	Describe("The SessionStatusFromInstances function", func() {
+		instance := func(name string, phase clv1alpha2.EnvironmentPhase) clv1alpha2.Instance {
+			return clv1alpha2.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: "exam-networks"}},
+				Status:     clv1alpha2.InstanceStatus{Phase: phase},
+			}
+		}
+
+		It("Should aggregate the phases of the instances", func() {
+			status := SessionStatusFromInstances("networks", []clv1alpha2.Instance{
+				instance("exam-c", clv1alpha2.EnvironmentPhaseReady),
+				instance("exam-a", clv1alpha2.EnvironmentPhaseUnset),
+				instance("exam-b", clv1alpha2.EnvironmentPhaseReady),
+			}, map[string]error{"exam-a": errors.New("failure"), "exam-b": nil})
+
+			Expect(status.Name).To(Equal("networks"))
+			Expect(status.Total).To(Equal(3))
+			Expect(status.Ready).To(Equal(2))
+			Expect(status.AllReady).To(BeFalse())
+			Expect(status.Phases).To(Equal(map[string]int{string(clv1alpha2.EnvironmentPhaseReady): 2, sessionPhasePending: 1}))
+			Expect(status.Instances).To(HaveLen(3))
+			Expect(status.Instances[0].ID).To(Equal("exam-a"))
+			Expect(status.Instances[2].ID).To(Equal("exam-c"))
+			Expect(status.Errors).To(Equal(map[string]string{"exam-a": "failure"}))
+		})
+
+		It("Should report all the instances ready", func() {
+			status := SessionStatusFromInstances("networks", []clv1alpha2.Instance{instance("exam-a", clv1alpha2.EnvironmentPhaseReady)}, nil)
+			Expect(status.AllReady).To(BeTrue())
+			Expect(status.Errors).To(BeNil())
+		})
+
+		It("Should not report an empty session as ready", func() {
+			status := SessionStatusFromInstances("networks", nil, nil)
+			Expect(status.Total).To(BeZero())
+			Expect(status.AllReady).To(BeFalse())
+		})
+	})
+
+	Describe("The declaration of an exam session", func() {
+		const examNamespace = "crownlabs-exam"
+
+		var (
+			k8sClient client.Client
+			handler   *SessionHandler
+		)
+
+		put := func(body string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/api/sessions/networks", strings.NewReader(body)))
+			return recorder
+		}
+
+		BeforeEach(func() {
+			Options.Namespace = examNamespace
+			Options.BasePath = "/api"
+
+			existing := &clv1alpha2.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "exam-s000000", Namespace: examNamespace, Labels: map[string]string{SessionLabel: "networks"}},
+				Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: "exam-networks", Namespace: examNamespace}},
+			}
+			k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+			handler = &SessionHandler{Log: logr.Discard(), Client: k8sClient, AdapterEndpoint: "sessions", Concurrency: 2,
+				Authorizer: Authorizer{Audit: logr.Discard()}}
+		})
+
+		It("Should create the listed instances, without deleting the unlisted ones", func() {
+			response := put(`{"template":"exam-networks","instances":[{"id":"exam-s123456"}]}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			var status SessionStatus
+			Expect(json.Unmarshal(response.Body.Bytes(), &status)).To(Succeed())
+			Expect(status.Total).To(Equal(2))
+
+			var instance clv1alpha2.Instance
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "exam-s123456"}, &instance)).To(Succeed())
+			Expect(instance.GetLabels()).To(HaveKeyWithValue(SessionLabel, "networks"))
+			Expect(instance.Spec.Running).To(BeTrue())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "exam-s000000"}, &instance)).To(Succeed())
+		})
+
+		It("Should reject a request without instances", func() {
+			Expect(put(`{"template":"exam-networks","instances":[]}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(put(`{"template":"exam-networks"}`).Code).To(Equal(http.StatusBadRequest))
+
+			var instance clv1alpha2.Instance
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "exam-s000000"}, &instance)).To(Succeed())
+		})
+
+		It("Should reject a request with unknown fields", func() {
+			Expect(put(`{"template":"exam-networks","instance":[{"id":"exam-s123456"}]}`).Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("Should create the listed instances stopped if requested", func() {
+			Expect(put(`{"template":"exam-networks","running":false,"instances":[{"id":"exam-s123456"}]}`).Code).To(Equal(http.StatusOK))
+
+			var instance clv1alpha2.Instance
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: examNamespace, Name: "exam-s123456"}, &instance)).To(Succeed())
+			Expect(instance.Spec.Running).To(BeFalse())
+		})
+	})
+})
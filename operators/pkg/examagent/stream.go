@@ -0,0 +1,306 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// InstanceEventAdded is the type of the events related to instances created, existing when the stream
+	// starts, or starting to match the filter of the stream (e.g., due to a change of their labels).
+	InstanceEventAdded = "ADDED"
+	// InstanceEventModified is the type of the events related to instances whose phase, URL or IP changed.
+	InstanceEventModified = "MODIFIED"
+	// InstanceEventDeleted is the type of the events related to instances deleted, or no longer matching
+	// the filter of the stream (e.g., due to a change of their labels).
+	InstanceEventDeleted = "DELETED"
+
+	streamFormatSSE        = "sse"
+	streamFormatJSONLines  = "jsonl"
+	contentTypeEventStream = "text/event-stream"
+	contentTypeJSONLines   = "application/jsonl"
+
+	// streamBufferSize is the number of events buffered for each stream, before the slow client is disconnected.
+	// The instances existing when the stream starts are not buffered, as they are written before any other event.
+	streamBufferSize = 256
+)
+
+// InstanceEvent represents a change of an instance, as pushed by the InstanceStreamHandler.
+type InstanceEvent struct {
+	Type     string           `json:"type"`
+	Instance *InstanceAdapter `json:"instance"`
+}
+
+// InstanceStreamFilter selects the instances whose events are streamed.
+type InstanceStreamFilter struct {
+	Prefix   string
+	Selector labels.Selector
+	Scope    *TokenScope
+}
+
+// InstanceStreamHandler streams the changes of the instances, as Server-Sent Events or JSON lines.
+type InstanceStreamHandler struct {
+	Log   logr.Logger
+	Cache cache.Cache
+	// HeartbeatInterval is the interval between the heartbeats keeping the idle streams alive.
+	HeartbeatInterval time.Duration
+	Authorizer
+}
+
+// ServeHTTP is the instance stream handler for the examagent.
+func (sh *InstanceStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := sh.Log.WithValues("remote-ip", r.Header.Get(XForwardedFor), "method", r.Method, "path", r.URL.Path)
+
+	log.Info("processing request", "query", r.URL.RawQuery)
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "Method not allowed")
+		return
+	}
+
+	scope, ok := sh.Authorize(w, r, log)
+	if !ok {
+		return
+	}
+	log = log.WithValues("caller", scope.CallerID())
+
+	filter, err := InstanceStreamFilterFromRequest(r, scope)
+	if err != nil {
+		log.Error(err, "invalid filter")
+		WriteError(w, r, log, http.StatusBadRequest, "Invalid label selector.")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error(errors.New("response writer does not support flushing"), "cannot stream")
+		WriteError(w, r, log, http.StatusInternalServerError, "Streaming not supported.")
+		return
+	}
+
+	informer, err := sh.Cache.GetInformer(r.Context(), &clv1alpha2.Instance{})
+	if err != nil {
+		log.Error(err, "cannot retrieve instances informer")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the instances.")
+		return
+	}
+
+	events := make(chan InstanceEvent, streamBufferSize)
+	overflowed := make(chan struct{})
+	var overflow sync.Once
+	send := func(eventType string, instance *clv1alpha2.Instance) {
+		select {
+		case events <- InstanceEvent{Type: eventType, Instance: AdapterFromInstance(instance)}:
+		default:
+			overflow.Do(func() { close(overflowed) })
+		}
+	}
+
+	// The instances existing when the handler is registered are skipped, as they are notified from the snapshot below.
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if instance, ok := obj.(*clv1alpha2.Instance); ok && !isInInitialList && filter.Matches(instance) {
+				send(InstanceEventAdded, instance)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldInstance, okOld := oldObj.(*clv1alpha2.Instance)
+			newInstance, okNew := newObj.(*clv1alpha2.Instance)
+			if !okOld || !okNew {
+				return
+			}
+			if eventType := InstanceUpdateEventType(filter, oldInstance, newInstance); eventType != "" {
+				send(eventType, newInstance)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if instance, ok := obj.(*clv1alpha2.Instance); ok && filter.Matches(instance) {
+				send(InstanceEventDeleted, instance)
+			}
+		},
+	})
+	if err != nil {
+		log.Error(err, "cannot register instances event handler")
+		WriteError(w, r, log, http.StatusInternalServerError, "Cannot retrieve the instances.")
+		return
+	}
+	defer func() {
+		if err := informer.RemoveEventHandler(registration); err != nil {
+			log.Error(err, "cannot remove instances event handler")
+		}
+	}()
+
+	sse := StreamFormatFromRequest(r) == streamFormatSSE
+	if sse {
+		w.Header().Set("Content-Type", contentTypeEventStream)
+	} else {
+		w.Header().Set("Content-Type", contentTypeJSONLines)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Prevent the ingress controller from buffering the stream.
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	log.Info("streaming instances", "sse", sse, "prefix", filter.Prefix, "selector", filter.Selector.String())
+
+	// The existing instances are retrieved after the registration of the handler, not to miss any change:
+	// the events buffered in the meanwhile are streamed afterwards, possibly repeating the current status.
+	if err := sh.writeSnapshot(r, w, sse, filter); err != nil {
+		log.Error(err, "cannot write the existing instances to the stream")
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sh.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			log.Info("stream closed by the client")
+			return
+		case <-overflowed:
+			log.Error(errors.New("too many pending events"), "slow client, closing stream")
+			return
+		case <-heartbeat.C:
+			err = WriteStreamHeartbeat(w, sse)
+		case event := <-events:
+			err = WriteStreamEvent(w, sse, &event)
+		}
+
+		if err != nil {
+			log.Error(err, "cannot write to the stream")
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSnapshot writes the instances matching the filter to the stream, as added.
+func (sh *InstanceStreamHandler) writeSnapshot(r *http.Request, w http.ResponseWriter, sse bool, filter *InstanceStreamFilter) error {
+	var instances clv1alpha2.InstanceList
+	if err := sh.Cache.List(r.Context(), &instances, client.InNamespace(Options.Namespace), client.MatchingLabelsSelector{Selector: filter.Selector}); err != nil {
+		return err
+	}
+
+	slices.SortFunc(instances.Items, func(a, b clv1alpha2.Instance) int { return strings.Compare(a.Name, b.Name) })
+	for i := range instances.Items {
+		if filter.Matches(&instances.Items[i]) {
+			if err := WriteStreamEvent(w, sse, &InstanceEvent{Type: InstanceEventAdded, Instance: AdapterFromInstance(&instances.Items[i])}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InstanceStreamFilterFromRequest parses the filter of the streamed instances from the request,
+// i.e., the "prefix" of the instance IDs and the "labelSelector" query parameters.
+func InstanceStreamFilterFromRequest(r *http.Request, scope *TokenScope) (*InstanceStreamFilter, error) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceStreamFilter{Prefix: r.URL.Query().Get("prefix"), Selector: selector, Scope: scope}, nil
+}
+
+// Matches returns whether the events of the given instance are streamed.
+func (f *InstanceStreamFilter) Matches(instance *clv1alpha2.Instance) bool {
+	return strings.HasPrefix(instance.Name, f.Prefix) &&
+		f.Selector.Matches(labels.Set(instance.GetLabels())) &&
+		f.Scope.AllowsTemplate(instance.Spec.Template.Name)
+}
+
+// InstanceUpdateEventType returns the type of the event to be streamed following the update of an instance (if any),
+// depending on whether it matches the filter before and after the update, and on whether its status changed.
+func InstanceUpdateEventType(filter *InstanceStreamFilter, oldInstance, newInstance *clv1alpha2.Instance) string {
+	oldMatches, newMatches := filter.Matches(oldInstance), filter.Matches(newInstance)
+	switch {
+	case oldMatches && !newMatches:
+		return InstanceEventDeleted
+	case !oldMatches && newMatches:
+		return InstanceEventAdded
+	case newMatches && InstanceStatusChanged(oldInstance, newInstance):
+		return InstanceEventModified
+	}
+	return ""
+}
+
+// InstanceStatusChanged returns whether the phase, the URL or the IP of the instance changed.
+func InstanceStatusChanged(oldInstance, newInstance *clv1alpha2.Instance) bool {
+	return oldInstance.Status.Phase != newInstance.Status.Phase ||
+		oldInstance.Status.URL != newInstance.Status.URL ||
+		oldInstance.Status.IP != newInstance.Status.IP
+}
+
+// StreamFormatFromRequest returns the format of the stream, either specified through the "format"
+// query parameter, or Server-Sent Events if accepted by the client, and JSON lines otherwise.
+func StreamFormatFromRequest(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case streamFormatSSE, streamFormatJSONLines:
+		return format
+	}
+	if strings.Contains(r.Header.Get("Accept"), contentTypeEventStream) {
+		return streamFormatSSE
+	}
+	return streamFormatJSONLines
+}
+
+// WriteStreamEvent writes the given event to the stream, in the given format.
+func WriteStreamEvent(w http.ResponseWriter, sse bool, event *InstanceEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if sse {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", strings.ToLower(event.Type), data)
+	} else {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	}
+	return err
+}
+
+// WriteStreamHeartbeat writes a heartbeat to the stream, ignored by the clients (i.e., a comment
+// for Server-Sent Events, and an empty line for JSON lines).
+func WriteStreamHeartbeat(w http.ResponseWriter, sse bool) error {
+	var err error
+	if sse {
+		_, err = fmt.Fprint(w, ": heartbeat\n\n")
+	} else {
+		_, err = fmt.Fprint(w, "\n")
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examagent_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/examagent"
+)
+
+// fakeInformer records the event handler registered by the stream, to trigger the events manually.
+type fakeInformer struct {
+	cache.Informer
+	mutex   sync.Mutex
+	handler toolscache.ResourceEventHandler
+}
+
+func (fi *fakeInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	fi.handler = handler
+	return nil, nil
+}
+
+func (fi *fakeInformer) RemoveEventHandler(_ toolscache.ResourceEventHandlerRegistration) error {
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	fi.handler = nil
+	return nil
+}
+
+func (fi *fakeInformer) registered() toolscache.ResourceEventHandler {
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	return fi.handler
+}
+
+// fakeCache serves the lists from a fake client, and the instances informer from a fakeInformer.
+type fakeCache struct {
+	cache.Cache
+	client.Reader
+	informer *fakeInformer
+}
+
+func (fc *fakeCache) GetInformer(_ context.Context, _ client.Object, _ ...cache.InformerGetOption) (cache.Informer, error) {
+	return fc.informer, nil
+}
+
+func (fc *fakeCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return fc.Reader.Get(ctx, key, obj, opts...)
+}
+
+func (fc *fakeCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return fc.Reader.List(ctx, list, opts...)
+}
+
+var _ = Describe("The instance stream handler", func() {
+	const (
+		examNamespace = "crownlabs-exam"
+		allowed       = "exam-networks"
+		forbidden     = "exam-databases"
+		sessionLabel  = "crownlabs.polito.it/exam-session"
+		existing      = 300 // More than the events buffered for each stream.
+	)
+
+	var (
+		informer *fakeInformer
+		server   *httptest.Server
+	)
+
+	instance := func(name, template, session string) *clv1alpha2.Instance {
+		return &clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: examNamespace, Labels: map[string]string{sessionLabel: session}},
+			Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: template, Namespace: examNamespace}},
+			Status:     clv1alpha2.InstanceStatus{Phase: clv1alpha2.EnvironmentPhaseReady},
+		}
+	}
+
+	stream := func(query string) (*bufio.Scanner, func()) {
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/api/watch/instances?"+query, http.NoBody)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Authorization", "Bearer exam.s3cr3t")
+
+		response, err := http.DefaultClient.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		return bufio.NewScanner(response.Body), func() { response.Body.Close() }
+	}
+
+	next := func(scanner *bufio.Scanner) examagent.InstanceEvent {
+		for scanner.Scan() {
+			if scanner.Text() == "" {
+				continue // Heartbeat
+			}
+			var event examagent.InstanceEvent
+			Expect(json.Unmarshal(scanner.Bytes(), &event)).To(Succeed())
+			return event
+		}
+		Fail("stream closed unexpectedly")
+		return examagent.InstanceEvent{}
+	}
+
+	BeforeEach(func() {
+		examagent.Options.Namespace = examNamespace
+
+		objects := []client.Object{
+			tokenSecret("exam", map[string]string{examagent.TokenSecretKey: "s3cr3t", examagent.TokenTemplatesKey: allowed}),
+			instance("student-forbidden", forbidden, "networks"),
+			instance("student-other-session", allowed, "other"),
+		}
+		for i := range existing {
+			objects = append(objects, instance(fmt.Sprintf("student-%03d", i), allowed, "networks"))
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+
+		informer = &fakeInformer{}
+		handler := &examagent.InstanceStreamHandler{
+			Log:               logr.Discard(),
+			Cache:             &fakeCache{Reader: k8sClient, informer: informer},
+			HeartbeatInterval: time.Hour,
+			Authorizer: examagent.Authorizer{
+				Auth:  &examagent.TokenAuthenticator{Client: k8sClient, Namespace: tokensNamespace},
+				Audit: logr.Discard(),
+			},
+		}
+		server = httptest.NewServer(handler)
+		DeferCleanup(server.Close)
+	})
+
+	It("Should stream all the existing instances matching the filter, even if more than the buffered events", func() {
+		scanner, closer := stream("labelSelector=" + sessionLabel + "%3Dnetworks")
+		defer closer()
+
+		for i := range existing {
+			event := next(scanner)
+			Expect(event.Type).To(Equal(examagent.InstanceEventAdded))
+			Expect(event.Instance.ID).To(Equal(fmt.Sprintf("student-%03d", i)))
+		}
+	})
+
+	It("Should ignore the instances notified by the informer as part of the initial list", func() {
+		scanner, closer := stream("prefix=student-001")
+		defer closer()
+		Expect(next(scanner).Instance.ID).To(Equal("student-001"))
+
+		handler := informer.registered()
+		Expect(handler).ToNot(BeNil())
+		handler.OnAdd(instance("student-002", allowed, "networks"), true)
+		handler.OnAdd(instance("student-001", allowed, "networks"), true)
+		handler.OnAdd(instance("student-0010", allowed, "networks"), false)
+
+		event := next(scanner)
+		Expect(event.Type).To(Equal(examagent.InstanceEventAdded))
+		Expect(event.Instance.ID).To(Equal("student-0010"))
+	})
+
+	It("Should notify the instances starting or stopping to match the label selector", func() {
+		scanner, closer := stream("prefix=student-other&labelSelector=" + sessionLabel + "%3Dnetworks")
+		defer closer()
+
+		handler := informer.registered()
+		Expect(handler).ToNot(BeNil())
+
+		outside, inside := instance("student-other-session", allowed, "other"), instance("student-other-session", allowed, "networks")
+		handler.OnUpdate(outside, inside)
+		event := next(scanner)
+		Expect(event.Type).To(Equal(examagent.InstanceEventAdded))
+		Expect(event.Instance.ID).To(Equal("student-other-session"))
+
+		handler.OnUpdate(inside, outside)
+		event = next(scanner)
+		Expect(event.Type).To(Equal(examagent.InstanceEventDeleted))
+		Expect(event.Instance.ID).To(Equal("student-other-session"))
+	})
+})
+
+var _ = Describe("The InstanceUpdateEventType function", func() {
+	filter := &examagent.InstanceStreamFilter{Selector: labels.SelectorFromSet(labels.Set{"session": "networks"})}
+
+	instance := func(session string, phase clv1alpha2.EnvironmentPhase) *clv1alpha2.Instance {
+		return &clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "student", Labels: map[string]string{"session": session}},
+			Status:     clv1alpha2.InstanceStatus{Phase: phase},
+		}
+	}
+
+	DescribeTable("Should return the type of the event to be streamed",
+		func(oldInstance, newInstance *clv1alpha2.Instance, expected string) {
+			Expect(examagent.InstanceUpdateEventType(filter, oldInstance, newInstance)).To(Equal(expected))
+		},
+		Entry("When the status changes", instance("networks", clv1alpha2.EnvironmentPhaseStarting),
+			instance("networks", clv1alpha2.EnvironmentPhaseReady), examagent.InstanceEventModified),
+		Entry("When nothing relevant changes", instance("networks", clv1alpha2.EnvironmentPhaseReady),
+			instance("networks", clv1alpha2.EnvironmentPhaseReady), ""),
+		Entry("When the instance stops matching", instance("networks", clv1alpha2.EnvironmentPhaseReady),
+			instance("other", clv1alpha2.EnvironmentPhaseReady), examagent.InstanceEventDeleted),
+		Entry("When the instance starts matching", instance("other", clv1alpha2.EnvironmentPhaseReady),
+			instance("networks", clv1alpha2.EnvironmentPhaseReady), examagent.InstanceEventAdded),
+		Entry("When the instance never matches", instance("other", clv1alpha2.EnvironmentPhaseStarting),
+			instance("other", clv1alpha2.EnvironmentPhaseReady), ""),
+	)
+})
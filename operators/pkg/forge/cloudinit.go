@@ -27,6 +27,15 @@ type userdata struct {
 	Network           network    `yaml:"network"`
 	Mounts            [][]string `yaml:"mounts"`
 	SSHAuthorizedKeys []string   `yaml:"ssh_authorized_keys,omitempty"`
+	WriteFiles        []file     `yaml:"write_files,omitempty"`
+	RunCmd            []string   `yaml:"runcmd,omitempty"`
+}
+
+// file is a helper structure to marshal the userdata configuration to write a given file.
+type file struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Permissions string `yaml:"permissions,omitempty"`
 }
 
 // user is a helper structure to marshal the userdata configuration to configure users.
@@ -61,6 +70,11 @@ func CloudInitUserScriptData() ([]byte, error) {
 
 // CloudInitUserData forges the yaml manifest representing the cloud-init userdata configuration.
 func CloudInitUserData(publicKeys []string, mountInfos []NFSVolumeMountInfo) ([]byte, error) {
+	return marshalUserData(cloudInitConfig(publicKeys, mountInfos))
+}
+
+// cloudInitConfig forges the cloud-init userdata configuration shared by all VMs.
+func cloudInitConfig(publicKeys []string, mountInfos []NFSVolumeMountInfo) userdata {
 	config := userdata{
 		Users: []user{{
 			Name:       "crownlabs",
@@ -84,7 +98,11 @@ func CloudInitUserData(publicKeys []string, mountInfos []NFSVolumeMountInfo) ([]
 		config.Mounts = append(config.Mounts, NFSVolumeMount(mountInfo.ServerAddress, mountInfo.ExportPath, mountInfo.MountPath, mountInfo.ReadOnly))
 	}
 	config.Mounts = append(config.Mounts, CommentMount("If you change mount options from here, not even Santa will give you 18."))
+	return config
+}
 
+// marshalUserData marshals the given cloud-init userdata configuration.
+func marshalUserData(config userdata) ([]byte, error) {
 	output, err := yaml.Marshal(config)
 	if err != nil {
 		return []byte{}, err
@@ -0,0 +1,125 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+// NodeBootstrapSecretLabel -> the label flagging the Secrets whose content can be copied to the nodes of cluster environments.
+const NodeBootstrapSecretLabel = "crownlabs.polito.it/node-bootstrap"
+
+// NodeBootstrapSecretAllowed returns whether the content of the given Secret can be copied to the nodes of cluster
+// environments, i.e. it is an opaque Secret explicitly flagged for this purpose by the workspace managers.
+// This prevents templates from exposing arbitrary Secrets (e.g. service account tokens) of the template namespace to tenants.
+func NodeBootstrapSecretAllowed(secret *corev1.Secret) bool {
+	return secret.GetLabels()[NodeBootstrapSecretLabel] == "true" &&
+		(secret.Type == "" || secret.Type == corev1.SecretTypeOpaque)
+}
+
+// NodeBootstrapSecretName returns the name of the secret holding the content of the node files retrieved from Secrets.
+func NodeBootstrapSecretName(instance *clv1alpha2.Instance) string {
+	return fmt.Sprintf("%s-node-files", instance.Name)
+}
+
+// NodeBootstrapSecretKey returns the key of the node bootstrap secret holding the content of the i-th file.
+func NodeBootstrapSecretKey(index int) string {
+	return fmt.Sprintf("file-%d", index)
+}
+
+// NodeBootstrapFile forges the i-th file of the node bootstrap configuration. The content of files retrieved from
+// ConfigMaps is inlined, while the one of files retrieved from Secrets is referenced from the node bootstrap secret.
+func NodeBootstrapFile(instance *clv1alpha2.Instance, file *clv1alpha2.NodeFile, index int, content string) bootstrapv1.File {
+	forged := bootstrapv1.File{
+		Path:        file.Path,
+		Owner:       file.Owner,
+		Permissions: file.Permissions,
+		Content:     content,
+	}
+
+	if file.ContentFrom != nil && file.ContentFrom.Secret != nil {
+		forged.Content = ""
+		forged.ContentFrom = &bootstrapv1.FileSource{
+			Secret: bootstrapv1.SecretFileSource{
+				Name: NodeBootstrapSecretName(instance),
+				Key:  NodeBootstrapSecretKey(index),
+			},
+		}
+	}
+	return forged
+}
+
+// WorkerKubeadmConfigSpec forges the specification of the kubeadm configuration of the worker nodes.
+func WorkerKubeadmConfigSpec(environment *clv1alpha2.Environment, publicKeys []string, files []bootstrapv1.File) bootstrapv1.KubeadmConfigSpec {
+	spec := bootstrapv1.KubeadmConfigSpec{
+		JoinConfiguration: &bootstrapv1.JoinConfiguration{
+			NodeRegistration: bootstrapv1.NodeRegistrationOptions{
+				KubeletExtraArgs: map[string]string{},
+			},
+		},
+		Users: ClusterNodeUsers(publicKeys),
+	}
+	ApplyNodeBootstrap(&spec, environment.Cluster.NodeBootstrap, files)
+	return spec
+}
+
+// ApplyNodeBootstrap merges the node bootstrap customization defined by the template into the given kubeadm configuration.
+func ApplyNodeBootstrap(spec *bootstrapv1.KubeadmConfigSpec, bootstrap *clv1alpha2.NodeBootstrap, files []bootstrapv1.File) {
+	if bootstrap == nil {
+		return
+	}
+
+	spec.Files = append(spec.Files, files...)
+	if len(bootstrap.Packages) > 0 {
+		spec.PreKubeadmCommands = append(spec.PreKubeadmCommands, NodePackagesCommand(bootstrap.Packages))
+	}
+	spec.PreKubeadmCommands = append(spec.PreKubeadmCommands, bootstrap.PreKubeadmCommands...)
+	spec.PostKubeadmCommands = append(spec.PostKubeadmCommands, bootstrap.PostKubeadmCommands...)
+
+	if bootstrap.NTP != nil {
+		spec.NTP = &bootstrapv1.NTP{Servers: bootstrap.NTP.Servers, Enabled: bootstrap.NTP.Enabled}
+	}
+
+	if spec.InitConfiguration != nil {
+		mergeKubeletExtraArgs(&spec.InitConfiguration.NodeRegistration, bootstrap.KubeletExtraArgs)
+	}
+	if spec.JoinConfiguration != nil {
+		mergeKubeletExtraArgs(&spec.JoinConfiguration.NodeRegistration, bootstrap.KubeletExtraArgs)
+	}
+}
+
+// NodePackagesCommand forges the command installing the given packages on the cluster nodes.
+func NodePackagesCommand(packages []string) string {
+	return "apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y " + strings.Join(packages, " ")
+}
+
+// mergeKubeletExtraArgs adds the given arguments to the kubelet ones of the node registration options.
+func mergeKubeletExtraArgs(registration *bootstrapv1.NodeRegistrationOptions, args map[string]string) {
+	if len(args) == 0 {
+		return
+	}
+	if registration.KubeletExtraArgs == nil {
+		registration.KubeletExtraArgs = make(map[string]string, len(args))
+	}
+	for key, value := range args {
+		registration.KubeletExtraArgs[key] = value
+	}
+}
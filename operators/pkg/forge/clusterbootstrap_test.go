@@ -0,0 +1,127 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster node bootstrap forging", func() {
+	var bootstrap *clv1alpha2.NodeBootstrap
+
+	BeforeEach(func() {
+		bootstrap = &clv1alpha2.NodeBootstrap{
+			Packages:            []string{"jq", "nfs-common"},
+			PreKubeadmCommands:  []string{"echo pre"},
+			PostKubeadmCommands: []string{"echo post"},
+			NTP:                 &clv1alpha2.NodeNTP{Enabled: ptr.To(true), Servers: []string{"ntp.polito.it"}},
+			KubeletExtraArgs:    map[string]string{"max-pods": "50"},
+		}
+	})
+
+	Describe("The forge.NodeBootstrapFile function", func() {
+		instance := &clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000"}}
+
+		When("the content is inlined or retrieved from a configmap", func() {
+			It("Should inline the content", func() {
+				file := &clv1alpha2.NodeFile{Path: "/etc/motd", Permissions: "0644", ContentFrom: &clv1alpha2.NodeFileSource{
+					ConfigMap: &clv1alpha2.NodeFileKeyRef{Name: "motd", Key: "motd"},
+				}}
+				Expect(forge.NodeBootstrapFile(instance, file, 0, "Welcome")).To(Equal(bootstrapv1.File{
+					Path: "/etc/motd", Permissions: "0644", Content: "Welcome",
+				}))
+			})
+		})
+
+		When("the content is retrieved from a secret", func() {
+			It("Should reference the node bootstrap secret of the instance", func() {
+				file := &clv1alpha2.NodeFile{Path: "/etc/token", ContentFrom: &clv1alpha2.NodeFileSource{
+					Secret: &clv1alpha2.NodeFileKeyRef{Name: "token", Key: "token"},
+				}}
+				forged := forge.NodeBootstrapFile(instance, file, 2, "secret")
+				Expect(forged.Content).To(BeEmpty())
+				Expect(forged.ContentFrom).To(PointTo(Equal(bootstrapv1.FileSource{
+					Secret: bootstrapv1.SecretFileSource{Name: "kubernetes-0000-node-files", Key: "file-2"},
+				})))
+			})
+		})
+	})
+
+	Describe("The forge.ApplyNodeBootstrap function", func() {
+		var spec bootstrapv1.KubeadmConfigSpec
+
+		BeforeEach(func() {
+			spec = bootstrapv1.KubeadmConfigSpec{
+				InitConfiguration: &bootstrapv1.InitConfiguration{NodeRegistration: bootstrapv1.NodeRegistrationOptions{
+					KubeletExtraArgs: map[string]string{"eviction-hard": "memory.available<100Mi"},
+				}},
+				JoinConfiguration:  &bootstrapv1.JoinConfiguration{},
+				PreKubeadmCommands: []string{"echo existing"},
+			}
+		})
+
+		It("Should merge the customization into the kubeadm configuration", func() {
+			files := []bootstrapv1.File{{Path: "/etc/motd", Content: "Welcome"}}
+			forge.ApplyNodeBootstrap(&spec, bootstrap, files)
+
+			Expect(spec.Files).To(Equal(files))
+			Expect(spec.PreKubeadmCommands).To(Equal([]string{"echo existing", forge.NodePackagesCommand(bootstrap.Packages), "echo pre"}))
+			Expect(spec.PostKubeadmCommands).To(Equal([]string{"echo post"}))
+			Expect(spec.NTP).To(PointTo(Equal(bootstrapv1.NTP{Enabled: ptr.To(true), Servers: []string{"ntp.polito.it"}})))
+			Expect(spec.InitConfiguration.NodeRegistration.KubeletExtraArgs).To(Equal(map[string]string{
+				"eviction-hard": "memory.available<100Mi", "max-pods": "50",
+			}))
+			Expect(spec.JoinConfiguration.NodeRegistration.KubeletExtraArgs).To(Equal(map[string]string{"max-pods": "50"}))
+		})
+
+		It("Should not modify the configuration if no customization is specified", func() {
+			expected := spec.DeepCopy()
+			forge.ApplyNodeBootstrap(&spec, nil, nil)
+			Expect(spec).To(Equal(*expected))
+		})
+	})
+
+	Describe("The forge.WorkerKubeadmConfigSpec function", func() {
+		It("Should configure the users and the node bootstrap customization", func() {
+			environment := &clv1alpha2.Environment{Cluster: &clv1alpha2.ClusterTemplate{NodeBootstrap: bootstrap}}
+			spec := forge.WorkerKubeadmConfigSpec(environment, []string{"ssh-ed25519 key"}, nil)
+
+			Expect(spec.Users).To(Equal(forge.ClusterNodeUsers([]string{"ssh-ed25519 key"})))
+			Expect(spec.PostKubeadmCommands).To(Equal([]string{"echo post"}))
+			Expect(spec.JoinConfiguration.NodeRegistration.KubeletExtraArgs).To(HaveKeyWithValue("max-pods", "50"))
+		})
+	})
+
+	DescribeTable("The forge.NodeBootstrapSecretAllowed function",
+		func(labels map[string]string, secretType corev1.SecretType, expected bool) {
+			secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: labels}, Type: secretType}
+			Expect(forge.NodeBootstrapSecretAllowed(&secret)).To(Equal(expected))
+		},
+		Entry("Flagged opaque secret", map[string]string{forge.NodeBootstrapSecretLabel: "true"}, corev1.SecretTypeOpaque, true),
+		Entry("Flagged secret without type", map[string]string{forge.NodeBootstrapSecretLabel: "true"}, corev1.SecretType(""), true),
+		Entry("Unflagged secret", nil, corev1.SecretTypeOpaque, false),
+		Entry("Secret flagged with a different value", map[string]string{forge.NodeBootstrapSecretLabel: "false"}, corev1.SecretTypeOpaque, false),
+		Entry("Flagged service account token", map[string]string{forge.NodeBootstrapSecretLabel: "true"}, corev1.SecretTypeServiceAccountToken, false),
+	)
+})
@@ -0,0 +1,178 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ClusterContentResetAnnotation -> annotation requesting the content of the cluster to be applied again (any new value triggers a reset).
+	ClusterContentResetAnnotation = "crownlabs.polito.it/reset-lab"
+	// ClusterContentFieldManager -> field manager used when applying the content to the workload cluster.
+	ClusterContentFieldManager = "crownlabs-instance-operator"
+	// ClusterContentDefaultNamespace -> namespace assigned to the namespaced objects of the content not specifying one.
+	ClusterContentDefaultNamespace = "default"
+)
+
+// ClusterContentFormat is an enumeration of the formats of the content applied to cluster environments.
+type ClusterContentFormat string
+
+const (
+	// ClusterContentManifest -> a file containing one or more manifests.
+	ClusterContentManifest ClusterContentFormat = "manifest"
+	// ClusterContentTarball -> a (possibly compressed) tarball of manifests, possibly including a kustomization.
+	ClusterContentTarball ClusterContentFormat = "tarball"
+	// ClusterContentKustomization -> a remote kustomization.
+	ClusterContentKustomization ClusterContentFormat = "kustomization"
+)
+
+// ClusterContentURL returns the URL the content of the cluster environment is retrieved from,
+// giving precedence to the content origin of the instance over the one specified by the template.
+func ClusterContentURL(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) string {
+	if instance.Spec.CustomizationUrls != nil && instance.Spec.CustomizationUrls.ContentOrigin != "" {
+		return instance.Spec.CustomizationUrls.ContentOrigin
+	}
+	if environment.Cluster.Content != nil {
+		return environment.Cluster.Content.URL
+	}
+	return ""
+}
+
+// ClusterContentSource returns a description of the source of the content of the cluster environment, if any.
+func ClusterContentSource(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) string {
+	if contentURL := ClusterContentURL(instance, environment); contentURL != "" {
+		return contentURL
+	}
+	if environment.Cluster.Content != nil && environment.Cluster.Content.ConfigMap != "" {
+		return fmt.Sprintf("configmap/%s", environment.Cluster.Content.ConfigMap)
+	}
+	return ""
+}
+
+// ClusterContentFormatFor returns the format of the content retrieved from the given URL, based on its extension.
+func ClusterContentFormatFor(contentURL string) ClusterContentFormat {
+	p := contentURL
+	if parsed, err := url.Parse(contentURL); err == nil {
+		p = parsed.Path
+	}
+
+	switch {
+	case strings.HasSuffix(p, ".tar"), strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return ClusterContentTarball
+	case path.Ext(p) == ".yaml", path.Ext(p) == ".yml", path.Ext(p) == ".json":
+		return ClusterContentManifest
+	default:
+		return ClusterContentKustomization
+	}
+}
+
+// ClusterContentApplyRequired returns whether the content has to be applied to the cluster environment,
+// i.e., it has not been applied yet, or a new reset has been requested.
+func ClusterContentApplyRequired(instance *clv1alpha2.Instance) bool {
+	status := instance.Status.ClusterContent
+	if status == nil {
+		return true
+	}
+	reset := instance.GetAnnotations()[ClusterContentResetAnnotation]
+	return reset != "" && reset != status.ObservedReset
+}
+
+// DecodeManifests decodes the (YAML or JSON) manifests contained in the given data, expanding lists and skipping empty documents.
+func DecodeManifests(data []byte) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var obj unstructured.Unstructured
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to decode the manifests: %w", err)
+		}
+
+		switch {
+		case len(obj.Object) == 0:
+			continue
+		case obj.IsList():
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode the manifests: %w", err)
+			}
+			objects = append(objects, list.Items...)
+		case obj.GetKind() == "" || obj.GetAPIVersion() == "":
+			return nil, fmt.Errorf("invalid manifest %q: missing apiVersion or kind", obj.GetName())
+		default:
+			objects = append(objects, obj)
+		}
+	}
+}
+
+// SortClusterContent sorts the given objects so that namespaces and custom resource definitions are applied first.
+func SortClusterContent(objects []unstructured.Unstructured) {
+	priority := func(obj *unstructured.Unstructured) int {
+		switch obj.GetKind() {
+		case "Namespace":
+			return 0
+		case "CustomResourceDefinition":
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return priority(&objects[i]) < priority(&objects[j])
+	})
+}
+
+// ClusterContentRevision returns the revision of the given content, i.e., the digest of the (ordered) objects.
+func ClusterContentRevision(objects []unstructured.Unstructured) (string, error) {
+	hash := sha256.New()
+	for i := range objects {
+		data, err := objects[i].MarshalJSON()
+		if err != nil {
+			return "", err
+		}
+		hash.Write(data)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// ClusterContentStatus forges the status of the content applied to the cluster environment.
+func ClusterContentStatus(instance *clv1alpha2.Instance, source, revision string, objects int) *clv1alpha2.InstanceClusterContentStatus {
+	return &clv1alpha2.InstanceClusterContentStatus{
+		Source:        source,
+		Revision:      revision,
+		Objects:       objects,
+		AppliedTime:   metav1.Now(),
+		ObservedReset: instance.GetAnnotations()[ClusterContentResetAnnotation],
+	}
+}
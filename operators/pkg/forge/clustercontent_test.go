@@ -0,0 +1,161 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster content forging", func() {
+	const manifests = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: lab
+---
+# an empty document
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: lab
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: first
+    namespace: lab
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: second
+    namespace: lab
+`
+
+	var (
+		instance    clv1alpha2.Instance
+		environment clv1alpha2.Environment
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000"}}
+		environment = clv1alpha2.Environment{Cluster: &clv1alpha2.ClusterTemplate{
+			Content: &clv1alpha2.ClusterContent{URL: "https://example.com/lab.tar.gz"},
+		}}
+	})
+
+	Describe("The forge.ClusterContentSource function", func() {
+		It("Should return the URL configured in the template", func() {
+			Expect(forge.ClusterContentSource(&instance, &environment)).To(Equal("https://example.com/lab.tar.gz"))
+		})
+
+		It("Should give precedence to the content origin of the instance", func() {
+			instance.Spec.CustomizationUrls = &clv1alpha2.InstanceCustomizationUrls{ContentOrigin: "https://example.com/custom.yaml"}
+			Expect(forge.ClusterContentSource(&instance, &environment)).To(Equal("https://example.com/custom.yaml"))
+		})
+
+		It("Should return the configmap, if configured", func() {
+			environment.Cluster.Content = &clv1alpha2.ClusterContent{ConfigMap: "lab"}
+			Expect(forge.ClusterContentSource(&instance, &environment)).To(Equal("configmap/lab"))
+		})
+
+		It("Should return an empty source if no content is configured", func() {
+			environment.Cluster.Content = nil
+			Expect(forge.ClusterContentSource(&instance, &environment)).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("The forge.ClusterContentFormatFor function",
+		func(url string, expected forge.ClusterContentFormat) {
+			Expect(forge.ClusterContentFormatFor(url)).To(Equal(expected))
+		},
+		Entry("a YAML manifest", "https://example.com/lab.yaml?token=abc", forge.ClusterContentManifest),
+		Entry("a JSON manifest", "https://example.com/lab.json", forge.ClusterContentManifest),
+		Entry("a compressed tarball", "https://example.com/lab.tar.gz", forge.ClusterContentTarball),
+		Entry("a tarball", "https://example.com/lab.tgz", forge.ClusterContentTarball),
+		Entry("a remote kustomization", "https://github.com/example/lab//overlays/exam?ref=v1", forge.ClusterContentKustomization),
+	)
+
+	Describe("The forge.ClusterContentApplyRequired function", func() {
+		It("Should return true if the content has not been applied yet", func() {
+			Expect(forge.ClusterContentApplyRequired(&instance)).To(BeTrue())
+		})
+
+		When("the content has already been applied", func() {
+			BeforeEach(func() {
+				instance.Status.ClusterContent = &clv1alpha2.InstanceClusterContentStatus{Revision: "sha256:0123", ObservedReset: "1"}
+			})
+
+			It("Should return false if no reset is requested", func() {
+				Expect(forge.ClusterContentApplyRequired(&instance)).To(BeFalse())
+			})
+
+			It("Should return false if the reset has already been handled", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterContentResetAnnotation: "1"})
+				Expect(forge.ClusterContentApplyRequired(&instance)).To(BeFalse())
+			})
+
+			It("Should return true if a new reset is requested", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterContentResetAnnotation: "2"})
+				Expect(forge.ClusterContentApplyRequired(&instance)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("The forge.DecodeManifests and forge.SortClusterContent functions", func() {
+		It("Should decode the manifests, expanding lists and applying namespaces first", func() {
+			objects, err := forge.DecodeManifests([]byte(manifests))
+			Expect(err).ToNot(HaveOccurred())
+			forge.SortClusterContent(objects)
+
+			names := []string{}
+			for i := range objects {
+				names = append(names, objects[i].GetKind()+"/"+objects[i].GetName())
+			}
+			Expect(names).To(Equal([]string{"Namespace/lab", "Deployment/nginx", "ConfigMap/first", "ConfigMap/second"}))
+		})
+
+		It("Should fail if a manifest lacks the kind", func() {
+			_, err := forge.DecodeManifests([]byte("apiVersion: v1\nmetadata:\n  name: invalid\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The forge.ClusterContentRevision function", func() {
+		It("Should return the same revision for the same content", func() {
+			first, err := forge.DecodeManifests([]byte(manifests))
+			Expect(err).ToNot(HaveOccurred())
+			second, err := forge.DecodeManifests([]byte(manifests))
+			Expect(err).ToNot(HaveOccurred())
+
+			revision, err := forge.ClusterContentRevision(first)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revision).To(HavePrefix("sha256:"))
+			Expect(forge.ClusterContentRevision(second)).To(Equal(revision))
+
+			second[0].SetName("changed")
+			Expect(forge.ClusterContentRevision(second)).ToNot(Equal(revision))
+		})
+	})
+})
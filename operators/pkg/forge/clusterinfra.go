@@ -0,0 +1,216 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	infrav1 "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// InfrastructureAPIVersion -> the API version shared by the supported Cluster API infrastructure providers.
+	InfrastructureAPIVersion = "infrastructure.cluster.x-k8s.io/v1alpha1"
+
+	// InMemoryVMStartupDuration -> the simulated startup duration of the in-memory VMs.
+	InMemoryVMStartupDuration = "10s"
+	// InMemoryComponentStartupDuration -> the simulated startup duration of the in-memory node components (node, API server, etcd).
+	InMemoryComponentStartupDuration = "2s"
+	// InMemoryStartupJitter -> the jitter applied to the simulated startup durations of the in-memory provider.
+	InMemoryStartupJitter = "0.2"
+
+	kubevirtClusterKind         = "KubevirtCluster"
+	kubevirtMachineTemplateKind = "KubevirtMachineTemplate"
+	inMemoryClusterKind         = "InMemoryCluster"
+	inMemoryMachineTemplateKind = "InMemoryMachineTemplate"
+)
+
+// ClusterInfrastructure abstracts the forging of the infrastructure-specific objects
+// of a cluster environment, i.e. the infrastructure cluster and the machine templates.
+// Objects are returned with their name and namespace only, and their specifications
+// are configured by the corresponding Mutate function at creation time.
+type ClusterInfrastructure interface {
+	// InfraCluster returns the infrastructure cluster object associated with the environment.
+	InfraCluster(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) client.Object
+	// MutateInfraCluster configures the specifications of the infrastructure cluster object.
+	MutateInfraCluster(obj client.Object, environment *clv1alpha2.Environment) error
+	// InfraClusterRef returns the reference to the infrastructure cluster object.
+	InfraClusterRef(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) corev1.ObjectReference
+
+	// MachineTemplate returns the machine template object with the given name.
+	MachineTemplate(instance *clv1alpha2.Instance, name string) client.Object
+	// MutateMachineTemplate configures the specifications of the machine template object.
+	MutateMachineTemplate(obj client.Object, environment *clv1alpha2.Environment) error
+	// MachineTemplateRef returns the reference to the machine template object with the given name.
+	MachineTemplateRef(instance *clv1alpha2.Instance, name string) corev1.ObjectReference
+
+	// RunsWorkloads returns whether the nodes can actually run workloads (e.g. the CNI).
+	RunsWorkloads() bool
+}
+
+// ClusterInfrastructureFor returns the ClusterInfrastructure selected by the given environment,
+// defaulting to KubeVirt in case none is specified.
+func ClusterInfrastructureFor(environment *clv1alpha2.Environment) ClusterInfrastructure {
+	if environment.Cluster != nil && environment.Cluster.Infrastructure == clv1alpha2.InfraInMemory {
+		return inMemoryInfrastructure{}
+	}
+	return kubevirtInfrastructure{}
+}
+
+// InfraClusterName returns the name of the infrastructure cluster object of the given environment.
+func InfraClusterName(environment *clv1alpha2.Environment) string {
+	return fmt.Sprintf("%s-infra", environment.Cluster.Name)
+}
+
+// kubevirtInfrastructure forges the objects of the KubeVirt infrastructure provider.
+type kubevirtInfrastructure struct{}
+
+func (kubevirtInfrastructure) InfraCluster(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) client.Object {
+	return &infrav1.KubevirtCluster{ObjectMeta: infraObjectMeta(instance, InfraClusterName(environment))}
+}
+
+func (kubevirtInfrastructure) MutateInfraCluster(obj client.Object, environment *clv1alpha2.Environment) error {
+	infra, ok := obj.(*infrav1.KubevirtCluster)
+	if !ok {
+		return fmt.Errorf("unexpected infrastructure cluster type %T", obj)
+	}
+	// The control plane Service is managed by Kamaji, when chosen as control plane provider.
+	if environment.Cluster.ControlPlane.Provider == clv1alpha2.ProviderKamaji {
+		annotations := deepCopyLabels(infra.GetAnnotations())
+		annotations["cluster.x-k8s.io/managed-by"] = "kamaji"
+		infra.SetAnnotations(annotations)
+		return nil
+	}
+	infra.Spec.ControlPlaneServiceTemplate.Spec.Type = corev1.ServiceType(environment.Cluster.ServiceType)
+	return nil
+}
+
+func (kubevirtInfrastructure) InfraClusterRef(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) corev1.ObjectReference {
+	return infraObjectRef(instance, kubevirtClusterKind, InfraClusterName(environment))
+}
+
+func (kubevirtInfrastructure) MachineTemplate(instance *clv1alpha2.Instance, name string) client.Object {
+	return &infrav1.KubevirtMachineTemplate{ObjectMeta: infraObjectMeta(instance, name)}
+}
+
+func (kubevirtInfrastructure) MutateMachineTemplate(obj client.Object, environment *clv1alpha2.Environment) error {
+	tmpl, ok := obj.(*infrav1.KubevirtMachineTemplate)
+	if !ok {
+		return fmt.Errorf("unexpected machine template type %T", obj)
+	}
+	tmpl.Spec.Template.Spec.BootstrapCheckSpec.CheckStrategy = "ssh"
+	tmpl.Spec.Template.Spec.VirtualMachineTemplate.Spec = ClusterVMSpec(environment)
+	return nil
+}
+
+func (kubevirtInfrastructure) MachineTemplateRef(instance *clv1alpha2.Instance, name string) corev1.ObjectReference {
+	return infraObjectRef(instance, kubevirtMachineTemplateKind, name)
+}
+
+func (kubevirtInfrastructure) RunsWorkloads() bool {
+	return true
+}
+
+// inMemoryInfrastructure forges the objects of the Cluster API in-memory infrastructure provider,
+// which simulates the machines and the workload cluster components without starting any VM.
+// Its types are not vendored, hence the objects are represented as unstructured.
+type inMemoryInfrastructure struct{}
+
+func (inMemoryInfrastructure) InfraCluster(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) client.Object {
+	return inMemoryObject(instance, inMemoryClusterKind, InfraClusterName(environment))
+}
+
+func (inMemoryInfrastructure) MutateInfraCluster(obj client.Object, _ *clv1alpha2.Environment) error {
+	infra, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected infrastructure cluster type %T", obj)
+	}
+	// The control plane endpoint is populated by the provider itself.
+	return unstructured.SetNestedMap(infra.Object, map[string]interface{}{}, "spec")
+}
+
+func (inMemoryInfrastructure) InfraClusterRef(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) corev1.ObjectReference {
+	return infraObjectRef(instance, inMemoryClusterKind, InfraClusterName(environment))
+}
+
+func (inMemoryInfrastructure) MachineTemplate(instance *clv1alpha2.Instance, name string) client.Object {
+	return inMemoryObject(instance, inMemoryMachineTemplateKind, name)
+}
+
+func (inMemoryInfrastructure) MutateMachineTemplate(obj client.Object, _ *clv1alpha2.Environment) error {
+	tmpl, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected machine template type %T", obj)
+	}
+	return unstructured.SetNestedMap(tmpl.Object, InMemoryMachineBehaviour(), "spec", "template", "spec", "behaviour")
+}
+
+func (inMemoryInfrastructure) MachineTemplateRef(instance *clv1alpha2.Instance, name string) corev1.ObjectReference {
+	return infraObjectRef(instance, inMemoryMachineTemplateKind, name)
+}
+
+func (inMemoryInfrastructure) RunsWorkloads() bool {
+	return false
+}
+
+// InMemoryMachineBehaviour forges the simulated provisioning behaviour of the in-memory machines.
+func InMemoryMachineBehaviour() map[string]interface{} {
+	provisioning := func(duration string) map[string]interface{} {
+		return map[string]interface{}{
+			"provisioning": map[string]interface{}{
+				"startupDuration": duration,
+				"startupJitter":   InMemoryStartupJitter,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"vm":        provisioning(InMemoryVMStartupDuration),
+		"node":      provisioning(InMemoryComponentStartupDuration),
+		"apiServer": provisioning(InMemoryComponentStartupDuration),
+		"etcd":      provisioning(InMemoryComponentStartupDuration),
+	}
+}
+
+// inMemoryObject returns an unstructured object of the in-memory provider, with the given kind and name.
+func inMemoryObject(instance *clv1alpha2.Instance, kind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(InfrastructureAPIVersion, kind))
+	obj.SetName(name)
+	obj.SetNamespace(instance.Namespace)
+	return obj
+}
+
+// infraObjectMeta returns the ObjectMeta of an infrastructure object, given its name.
+func infraObjectMeta(instance *clv1alpha2.Instance, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: instance.Namespace}
+}
+
+// infraObjectRef returns the reference to an infrastructure object, given its kind and name.
+func infraObjectRef(instance *clv1alpha2.Instance, kind, name string) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: InfrastructureAPIVersion,
+		Kind:       kind,
+		Name:       name,
+		Namespace:  instance.Namespace,
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	infrav1 "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster infrastructure forging", func() {
+	var (
+		instance       clv1alpha2.Instance
+		environment    clv1alpha2.Environment
+		infrastructure forge.ClusterInfrastructure
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		clusterName       = "demo"
+		templateName      = "demo-md-worker"
+		image             = "internal/registry/image:v1.0"
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+		}
+		environment = clv1alpha2.Environment{
+			Image:           image,
+			EnvironmentType: clv1alpha2.ClassCluster,
+			Cluster: &clv1alpha2.ClusterTemplate{
+				Name:        clusterName,
+				ServiceType: string(corev1.ServiceTypeClusterIP),
+				ControlPlane: clv1alpha2.ControlPlaneRef{
+					Provider: clv1alpha2.ProviderKubeadm,
+					Replicas: 1,
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		infrastructure = forge.ClusterInfrastructureFor(&environment)
+	})
+
+	Describe("The forge.InfraClusterName function", func() {
+		It("Should return the expected name", func() {
+			Expect(forge.InfraClusterName(&environment)).To(Equal("demo-infra"))
+		})
+	})
+
+	Context("The infrastructure provider is not specified", func() {
+		It("Should default to KubeVirt", func() {
+			Expect(infrastructure.InfraCluster(&instance, &environment)).To(BeAssignableToTypeOf(&infrav1.KubevirtCluster{}))
+		})
+	})
+
+	Context("The infrastructure provider is KubeVirt", func() {
+		BeforeEach(func() { environment.Cluster.Infrastructure = clv1alpha2.InfraKubevirt })
+
+		It("Should run workloads", func() {
+			Expect(infrastructure.RunsWorkloads()).To(BeTrue())
+		})
+
+		It("Should forge the correct infrastructure cluster reference", func() {
+			Expect(infrastructure.InfraClusterRef(&instance, &environment)).To(Equal(corev1.ObjectReference{
+				APIVersion: forge.InfrastructureAPIVersion,
+				Kind:       "KubevirtCluster",
+				Name:       "demo-infra",
+				Namespace:  instanceNamespace,
+			}))
+		})
+
+		It("Should forge the correct machine template reference", func() {
+			Expect(infrastructure.MachineTemplateRef(&instance, templateName)).To(Equal(corev1.ObjectReference{
+				APIVersion: forge.InfrastructureAPIVersion,
+				Kind:       "KubevirtMachineTemplate",
+				Name:       templateName,
+				Namespace:  instanceNamespace,
+			}))
+		})
+
+		Describe("The infrastructure cluster", func() {
+			var infra client.Object
+
+			JustBeforeEach(func() {
+				infra = infrastructure.InfraCluster(&instance, &environment)
+				Expect(infrastructure.MutateInfraCluster(infra, &environment)).To(Succeed())
+			})
+
+			It("Should have the correct name and namespace", func() {
+				Expect(infra.GetName()).To(Equal("demo-infra"))
+				Expect(infra.GetNamespace()).To(Equal(instanceNamespace))
+			})
+
+			When("the control plane provider is kubeadm", func() {
+				It("Should configure the control plane service type", func() {
+					Expect(infra.(*infrav1.KubevirtCluster).Spec.ControlPlaneServiceTemplate.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+				})
+			})
+
+			When("the control plane provider is kamaji", func() {
+				BeforeEach(func() { environment.Cluster.ControlPlane.Provider = clv1alpha2.ProviderKamaji })
+
+				It("Should flag the cluster as managed by kamaji", func() {
+					Expect(infra.GetAnnotations()).To(HaveKeyWithValue("cluster.x-k8s.io/managed-by", "kamaji"))
+				})
+
+				It("Should preserve the existing annotations", func() {
+					infra.SetAnnotations(map[string]string{"foo": "bar"})
+					Expect(infrastructure.MutateInfraCluster(infra, &environment)).To(Succeed())
+					Expect(infra.GetAnnotations()).To(HaveKeyWithValue("foo", "bar"))
+					Expect(infra.GetAnnotations()).To(HaveKeyWithValue("cluster.x-k8s.io/managed-by", "kamaji"))
+				})
+			})
+		})
+
+		Describe("The machine template", func() {
+			var tmpl client.Object
+
+			JustBeforeEach(func() {
+				tmpl = infrastructure.MachineTemplate(&instance, templateName)
+				Expect(infrastructure.MutateMachineTemplate(tmpl, &environment)).To(Succeed())
+			})
+
+			It("Should configure the ssh bootstrap check strategy", func() {
+				Expect(tmpl.(*infrav1.KubevirtMachineTemplate).Spec.Template.Spec.BootstrapCheckSpec.CheckStrategy).To(Equal("ssh"))
+			})
+			It("Should configure the virtual machine spec", func() {
+				Expect(tmpl.(*infrav1.KubevirtMachineTemplate).Spec.Template.Spec.VirtualMachineTemplate.Spec).To(Equal(forge.ClusterVMSpec(&environment)))
+			})
+		})
+	})
+
+	Context("The infrastructure provider is in-memory", func() {
+		BeforeEach(func() { environment.Cluster.Infrastructure = clv1alpha2.InfraInMemory })
+
+		It("Should not run workloads", func() {
+			Expect(infrastructure.RunsWorkloads()).To(BeFalse())
+		})
+
+		It("Should forge the correct infrastructure cluster reference", func() {
+			Expect(infrastructure.InfraClusterRef(&instance, &environment)).To(Equal(corev1.ObjectReference{
+				APIVersion: forge.InfrastructureAPIVersion,
+				Kind:       "InMemoryCluster",
+				Name:       "demo-infra",
+				Namespace:  instanceNamespace,
+			}))
+		})
+
+		It("Should forge the correct machine template reference", func() {
+			Expect(infrastructure.MachineTemplateRef(&instance, templateName)).To(Equal(corev1.ObjectReference{
+				APIVersion: forge.InfrastructureAPIVersion,
+				Kind:       "InMemoryMachineTemplate",
+				Name:       templateName,
+				Namespace:  instanceNamespace,
+			}))
+		})
+
+		Describe("The infrastructure cluster", func() {
+			var infra client.Object
+
+			JustBeforeEach(func() {
+				infra = infrastructure.InfraCluster(&instance, &environment)
+				Expect(infrastructure.MutateInfraCluster(infra, &environment)).To(Succeed())
+			})
+
+			It("Should have the correct kind, name and namespace", func() {
+				Expect(infra.GetObjectKind().GroupVersionKind().Kind).To(Equal("InMemoryCluster"))
+				Expect(infra.GetName()).To(Equal("demo-infra"))
+				Expect(infra.GetNamespace()).To(Equal(instanceNamespace))
+			})
+			It("Should have an empty spec", func() {
+				Expect(infra.(*unstructured.Unstructured).Object).To(HaveKeyWithValue("spec", BeEmpty()))
+			})
+		})
+
+		Describe("The machine template", func() {
+			var tmpl client.Object
+
+			JustBeforeEach(func() {
+				tmpl = infrastructure.MachineTemplate(&instance, templateName)
+				Expect(infrastructure.MutateMachineTemplate(tmpl, &environment)).To(Succeed())
+			})
+
+			It("Should have the correct kind", func() {
+				Expect(tmpl.GetObjectKind().GroupVersionKind().Kind).To(Equal("InMemoryMachineTemplate"))
+			})
+			It("Should configure the simulated provisioning behaviour", func() {
+				behaviour, found, err := unstructured.NestedMap(tmpl.(*unstructured.Unstructured).Object, "spec", "template", "spec", "behaviour")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(behaviour).To(Equal(forge.InMemoryMachineBehaviour()))
+			})
+		})
+	})
+
+	Describe("The forge.InMemoryMachineBehaviour function", func() {
+		It("Should configure the startup duration of the VMs", func() {
+			duration, _, err := unstructured.NestedString(forge.InMemoryMachineBehaviour(), "vm", "provisioning", "startupDuration")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(duration).To(Equal(forge.InMemoryVMStartupDuration))
+		})
+		It("Should configure the startup duration of the node components", func() {
+			for _, component := range []string{"node", "apiServer", "etcd"} {
+				duration, _, err := unstructured.NestedString(forge.InMemoryMachineBehaviour(), component, "provisioning", "startupDuration")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(duration).To(Equal(forge.InMemoryComponentStartupDuration))
+			}
+		})
+	})
+})
@@ -0,0 +1,221 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ClusterNodesNetworkPolicySuffix -> the suffix of the NetworkPolicy isolating the nodes of a cluster environment.
+	ClusterNodesNetworkPolicySuffix = "cluster-nodes"
+	// ClusterControlPlaneNetworkPolicySuffix -> the suffix of the NetworkPolicy isolating the hosted control plane of a cluster environment.
+	ClusterControlPlaneNetworkPolicySuffix = "cluster-control-plane"
+
+	// InstanceAccessNamespaceLabel -> the label identifying the namespaces allowed to access the instances (e.g. the ingress controller).
+	InstanceAccessNamespaceLabel = "crownlabs.polito.it/allow-instance-access"
+	// ClusterManagementNamespaceLabel -> the label identifying the namespaces allowed to access the API server of
+	// cluster environments to manage them (e.g. the Cluster API controllers and the instance operator).
+	ClusterManagementNamespaceLabel = "crownlabs.polito.it/allow-cluster-management"
+	// KamajiTenantControlPlaneLabel -> the label identifying the pods of the control planes hosted by Kamaji.
+	KamajiTenantControlPlaneLabel = "kamaji.clastix.io/name"
+
+	clusterAPIServerPort = 6443
+	clusterSSHPort       = 22
+	clusterDNSPort       = 53
+	clusterNFSPort       = 2049
+)
+
+// ClusterNodesSelector returns the selector matching the pods backing the nodes (i.e. the VMs) of the cluster environment.
+func ClusterNodesSelector(environment *clv1alpha2.Environment) metav1.LabelSelector {
+	return metav1.LabelSelector{MatchLabels: map[string]string{
+		capiv1.ClusterNameLabel: fmt.Sprintf("%s-cluster", environment.Cluster.Name),
+	}}
+}
+
+// ClusterControlPlaneSelector returns the selector matching the pods of the control plane hosted by Kamaji for the cluster environment.
+func ClusterControlPlaneSelector(environment *clv1alpha2.Environment) metav1.LabelSelector {
+	return metav1.LabelSelector{MatchLabels: map[string]string{
+		KamajiTenantControlPlaneLabel: fmt.Sprintf("%s-control-plane", environment.Cluster.Name),
+	}}
+}
+
+// ClusterHostedControlPlane returns whether the control plane of the cluster environment is hosted in the tenant namespace as pods.
+func ClusterHostedControlPlane(environment *clv1alpha2.Environment) bool {
+	return environment.Cluster.ControlPlane.Provider == clv1alpha2.ProviderKamaji
+}
+
+// ClusterNodesNetworkPolicySpec forges the specification of the NetworkPolicy isolating the nodes of the cluster environment.
+// Nodes are allowed to communicate with each other, with the control plane, with the DNS, with the NFS servers
+// of the given volumes (if exposed in the cluster) and with the destinations allowed by the template, while the API server
+// (if running on the nodes) can be reached by the management namespaces, and SSH by the instance access ones (e.g. the bastion).
+func ClusterNodesNetworkPolicySpec(environment *clv1alpha2.Environment, volumes []clv1alpha2.InstanceClusterVolume) netv1.NetworkPolicySpec {
+	cluster := clusterPeers(environment)
+
+	ingress := []netv1.NetworkPolicyIngressRule{{From: cluster}}
+	if !ClusterHostedControlPlane(environment) {
+		ingress = append(ingress, clusterAPIServerIngressRule())
+	}
+	ingress = append(ingress, clusterSSHIngressRule())
+
+	egress := []netv1.NetworkPolicyEgressRule{{To: cluster}, clusterDNSEgressRule()}
+	if peers := clusterNFSPeers(volumes); ClusterVolumesRequired(environment) && len(peers) > 0 {
+		egress = append(egress, clusterNFSEgressRule(peers))
+	}
+	egress = append(egress, ClusterEgressPolicyRules(environment.Cluster.AllowedEgress)...)
+
+	return netv1.NetworkPolicySpec{
+		PodSelector: ClusterNodesSelector(environment),
+		Ingress:     ingress,
+		Egress:      egress,
+		PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeIngress, netv1.PolicyTypeEgress},
+	}
+}
+
+// ClusterControlPlaneNetworkPolicySpec forges the specification of the NetworkPolicy isolating the control plane hosted by Kamaji,
+// which can be reached by the nodes of the cluster, and on the API server port by the management namespaces. The egress traffic
+// is not restricted, as the control plane needs to reach its datastore.
+func ClusterControlPlaneNetworkPolicySpec(environment *clv1alpha2.Environment) netv1.NetworkPolicySpec {
+	nodes := ClusterNodesSelector(environment)
+
+	return netv1.NetworkPolicySpec{
+		PodSelector: ClusterControlPlaneSelector(environment),
+		Ingress: []netv1.NetworkPolicyIngressRule{
+			{From: []netv1.NetworkPolicyPeer{{PodSelector: &nodes}}},
+			clusterAPIServerIngressRule(),
+		},
+		PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeIngress},
+	}
+}
+
+// ClusterEgressPolicyRules forges the egress rules corresponding to the destinations the nodes are allowed to reach by the template.
+func ClusterEgressPolicyRules(rules []clv1alpha2.ClusterEgressRule) []netv1.NetworkPolicyEgressRule {
+	egress := make([]netv1.NetworkPolicyEgressRule, 0, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+
+		ports := make([]netv1.NetworkPolicyPort, 0, len(rule.Ports))
+		for _, port := range rule.Ports {
+			protocol := corev1.ProtocolTCP
+			if port.Protocol != "" {
+				protocol = corev1.Protocol(port.Protocol)
+			}
+			ports = append(ports, netv1.NetworkPolicyPort{Protocol: ptr.To(protocol), Port: ptr.To(intstr.FromInt32(port.Port))})
+		}
+
+		egress = append(egress, netv1.NetworkPolicyEgressRule{
+			To:    []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: rule.CIDR, Except: rule.Except}}},
+			Ports: ports,
+		})
+	}
+	return egress
+}
+
+// clusterPeers returns the peers corresponding to the pods of the cluster environment (i.e., nodes and hosted control plane).
+func clusterPeers(environment *clv1alpha2.Environment) []netv1.NetworkPolicyPeer {
+	nodes := ClusterNodesSelector(environment)
+	peers := []netv1.NetworkPolicyPeer{{PodSelector: &nodes}}
+	if ClusterHostedControlPlane(environment) {
+		controlPlane := ClusterControlPlaneSelector(environment)
+		peers = append(peers, netv1.NetworkPolicyPeer{PodSelector: &controlPlane})
+	}
+	return peers
+}
+
+// clusterAPIServerIngressRule returns the rule allowing the management namespaces to reach the API server of the cluster.
+func clusterAPIServerIngressRule() netv1.NetworkPolicyIngressRule {
+	return netv1.NetworkPolicyIngressRule{
+		From: []netv1.NetworkPolicyPeer{
+			{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{InstanceAccessNamespaceLabel: "true"}}},
+			{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{ClusterManagementNamespaceLabel: "true"}}},
+		},
+		Ports: []netv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(clusterAPIServerPort))}},
+	}
+}
+
+// clusterSSHIngressRule returns the rule allowing the instance access namespaces (e.g. the bastion) to reach the nodes through SSH.
+func clusterSSHIngressRule() netv1.NetworkPolicyIngressRule {
+	return netv1.NetworkPolicyIngressRule{
+		From: []netv1.NetworkPolicyPeer{
+			{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{InstanceAccessNamespaceLabel: "true"}}},
+		},
+		Ports: []netv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(clusterSSHPort))}},
+	}
+}
+
+// clusterDNSEgressRule returns the rule allowing the nodes to reach the DNS of the hosting cluster.
+func clusterDNSEgressRule() netv1.NetworkPolicyEgressRule {
+	return netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+		}},
+		Ports: []netv1.NetworkPolicyPort{
+			{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(clusterDNSPort))},
+			{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(clusterDNSPort))},
+		},
+	}
+}
+
+// clusterNFSEgressRule returns the rule allowing the nodes to reach the given NFS servers (e.g. MyDrive and SharedVolumes).
+func clusterNFSEgressRule(peers []netv1.NetworkPolicyPeer) netv1.NetworkPolicyEgressRule {
+	return netv1.NetworkPolicyEgressRule{
+		To:    peers,
+		Ports: []netv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(clusterNFSPort))}},
+	}
+}
+
+// clusterNFSPeers returns the peers corresponding to the NFS servers of the given volumes. Servers identified by IP address
+// are matched exactly, while servers identified by the name of a Service of the hosting cluster (i.e. <name>.<namespace>.svc)
+// are matched through the corresponding namespace. Other servers are not matched, and they are to be allowed through the
+// egress rules of the template.
+func clusterNFSPeers(volumes []clv1alpha2.InstanceClusterVolume) []netv1.NetworkPolicyPeer {
+	var peers []netv1.NetworkPolicyPeer
+	seen := make(map[string]bool)
+	for i := range volumes {
+		server := volumes[i].Server
+		if seen[server] {
+			continue
+		}
+		seen[server] = true
+
+		if ip := net.ParseIP(server); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			peers = append(peers, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: fmt.Sprintf("%s/%d", ip, bits)}})
+			continue
+		}
+
+		if labels := strings.Split(server, "."); len(labels) >= 3 && labels[2] == "svc" {
+			peers = append(peers, netv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{corev1.LabelMetadataName: labels[1]},
+			}})
+		}
+	}
+	return peers
+}
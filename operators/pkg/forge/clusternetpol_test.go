@@ -0,0 +1,155 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster network policies forging", func() {
+	var environment clv1alpha2.Environment
+
+	nodes := metav1.LabelSelector{MatchLabels: map[string]string{capiv1.ClusterNameLabel: "demo-cluster"}}
+	controlPlane := metav1.LabelSelector{MatchLabels: map[string]string{forge.KamajiTenantControlPlaneLabel: "demo-control-plane"}}
+	apiServerPort := netv1.NetworkPolicyPort{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(6443))}
+
+	BeforeEach(func() {
+		environment = clv1alpha2.Environment{Cluster: &clv1alpha2.ClusterTemplate{
+			Name:         "demo",
+			ControlPlane: clv1alpha2.ControlPlaneRef{Provider: clv1alpha2.ProviderKamaji, Replicas: 1},
+		}}
+	})
+
+	Describe("The forge.ClusterNodesNetworkPolicySpec function", func() {
+		var (
+			spec    netv1.NetworkPolicySpec
+			volumes []clv1alpha2.InstanceClusterVolume
+		)
+
+		BeforeEach(func() { volumes = nil })
+
+		JustBeforeEach(func() {
+			spec = forge.ClusterNodesNetworkPolicySpec(&environment, volumes)
+		})
+
+		It("Should select the nodes of the cluster, restricting both directions", func() {
+			Expect(spec.PodSelector).To(Equal(nodes))
+			Expect(spec.PolicyTypes).To(ConsistOf(netv1.PolicyTypeIngress, netv1.PolicyTypeEgress))
+		})
+
+		It("Should allow the traffic within the cluster, including the hosted control plane", func() {
+			peers := []netv1.NetworkPolicyPeer{{PodSelector: &nodes}, {PodSelector: &controlPlane}}
+			Expect(spec.Ingress).To(HaveLen(2))
+			Expect(spec.Ingress[0]).To(Equal(netv1.NetworkPolicyIngressRule{From: peers}))
+			Expect(spec.Egress).To(ContainElement(netv1.NetworkPolicyEgressRule{To: peers}))
+		})
+
+		It("Should allow the instance access namespaces to reach the nodes through SSH", func() {
+			Expect(spec.Ingress).To(ContainElement(netv1.NetworkPolicyIngressRule{
+				From: []netv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{forge.InstanceAccessNamespaceLabel: "true"},
+				}}},
+				Ports: []netv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(22))}},
+			}))
+		})
+
+		It("Should allow the DNS traffic", func() {
+			Expect(spec.Egress).To(HaveLen(2))
+			Expect(spec.Egress[1].To[0].PodSelector.MatchLabels).To(HaveKeyWithValue("k8s-app", "kube-dns"))
+			Expect(spec.Egress[1].Ports).To(HaveLen(2))
+		})
+
+		When("volumes are exposed in the cluster", func() {
+			BeforeEach(func() {
+				environment.MountMyDriveVolume = true
+				volumes = []clv1alpha2.InstanceClusterVolume{
+					{ClaimName: "mydrive", Server: "rook-ceph-nfs-my-nfs-a.rook-ceph.svc.cluster.local", Path: "/tester"},
+					{ClaimName: "workspace-netgroup-datasets", Server: "10.0.0.10", Path: "/datasets"},
+					{ClaimName: "workspace-netgroup-models", Server: "10.0.0.10", Path: "/models"},
+					{ClaimName: "workspace-netgroup-external", Server: "nfs.example.com", Path: "/external"},
+				}
+			})
+
+			It("Should allow the NFS traffic towards the servers of the volumes only", func() {
+				Expect(spec.Egress).To(HaveLen(3))
+				Expect(spec.Egress[2].Ports).To(ConsistOf(netv1.NetworkPolicyPort{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(2049))}))
+				Expect(spec.Egress[2].To).To(ConsistOf(
+					netv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: "rook-ceph"}}},
+					netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.10/32"}},
+				))
+			})
+
+			When("the volumes have not been provisioned yet", func() {
+				BeforeEach(func() { volumes = nil })
+
+				It("Should not allow any NFS traffic", func() {
+					Expect(spec.Egress).To(HaveLen(2))
+				})
+			})
+		})
+
+		When("the control plane runs on the nodes", func() {
+			BeforeEach(func() { environment.Cluster.ControlPlane.Provider = clv1alpha2.ProviderKubeadm })
+
+			It("Should allow the management namespaces to reach the API server", func() {
+				Expect(spec.Ingress).To(HaveLen(3))
+				Expect(spec.Ingress[0].From).To(ConsistOf(netv1.NetworkPolicyPeer{PodSelector: &nodes}))
+				Expect(spec.Ingress[1].Ports).To(ConsistOf(apiServerPort))
+				Expect(spec.Ingress[1].From).To(HaveLen(2))
+			})
+		})
+
+		When("the template allows additional destinations", func() {
+			BeforeEach(func() {
+				environment.Cluster.AllowedEgress = []clv1alpha2.ClusterEgressRule{{
+					CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8"},
+					Ports: []clv1alpha2.ClusterEgressPort{{Port: 443}, {Protocol: "UDP", Port: 123}},
+				}}
+			})
+
+			It("Should allow the egress traffic towards them", func() {
+				Expect(spec.Egress).To(HaveLen(3))
+				Expect(spec.Egress[2]).To(Equal(netv1.NetworkPolicyEgressRule{
+					To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8"}}}},
+					Ports: []netv1.NetworkPolicyPort{
+						{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(443))},
+						{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(123))},
+					},
+				}))
+			})
+		})
+	})
+
+	Describe("The forge.ClusterControlPlaneNetworkPolicySpec function", func() {
+		It("Should allow the nodes and the management namespaces to reach the hosted control plane", func() {
+			spec := forge.ClusterControlPlaneNetworkPolicySpec(&environment)
+			Expect(spec.PodSelector).To(Equal(controlPlane))
+			Expect(spec.PolicyTypes).To(ConsistOf(netv1.PolicyTypeIngress))
+			Expect(spec.Ingress).To(HaveLen(2))
+			Expect(spec.Ingress[0].From).To(ConsistOf(netv1.NetworkPolicyPeer{PodSelector: &nodes}))
+			Expect(spec.Ingress[1].Ports).To(ConsistOf(apiServerPort))
+		})
+	})
+})
@@ -0,0 +1,163 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+// NetworkAllocationName returns the name of the ClusterNetworkAllocation corresponding to the given CIDR.
+func NetworkAllocationName(cidr string) string {
+	return strings.NewReplacer(".", StringSeparator, ":", StringSeparator, "/", StringSeparator).Replace(cidr)
+}
+
+// ClusterNetworkAllocationSpec forges the specification of a ClusterNetworkAllocation object.
+func ClusterNetworkAllocationSpec(instance *clv1alpha2.Instance, cidr, pool string,
+	allocationType clv1alpha2.NetworkAllocationType, family clv1alpha2.IPFamily) clv1alpha2.ClusterNetworkAllocationSpec {
+	return clv1alpha2.ClusterNetworkAllocationSpec{
+		CIDR:   cidr,
+		Pool:   pool,
+		Type:   allocationType,
+		Family: family,
+		InstanceRef: clv1alpha2.GenericRef{
+			Name:      instance.Name,
+			Namespace: instance.Namespace,
+		},
+	}
+}
+
+// IsNetworkAllocationOf returns whether the given ClusterNetworkAllocation belongs to the given instance.
+func IsNetworkAllocationOf(allocation *clv1alpha2.ClusterNetworkAllocation, instance *clv1alpha2.Instance) bool {
+	return allocation.Spec.InstanceRef.Name == instance.Name && allocation.Spec.InstanceRef.Namespace == instance.Namespace
+}
+
+// ClusterNetworkStatus forges the instance status entry reflecting the CIDRs of the given cluster environment.
+func ClusterNetworkStatus(environment *clv1alpha2.Environment) *clv1alpha2.InstanceClusterNetworkStatus {
+	pods, services := ClusterPodCIDRs(&environment.Cluster.ClusterNet), ClusterServiceCIDRs(&environment.Cluster.ClusterNet)
+	return &clv1alpha2.InstanceClusterNetworkStatus{
+		Pods:          primaryCIDR(pods),
+		PodsCIDRs:     pods,
+		Services:      primaryCIDR(services),
+		ServicesCIDRs: services,
+	}
+}
+
+// ClusterPodCIDRs returns the pod CIDRs of the given cluster network, the list taking precedence over the single CIDR.
+func ClusterPodCIDRs(network *clv1alpha2.ClusterNetwork) []string {
+	return cidrsOrSingle(network.PodsCIDRs, network.Pods)
+}
+
+// ClusterServiceCIDRs returns the service CIDRs of the given cluster network, the list taking precedence over the single CIDR.
+func ClusterServiceCIDRs(network *clv1alpha2.ClusterNetwork) []string {
+	return cidrsOrSingle(network.ServicesCIDRs, network.Services)
+}
+
+// SetClusterCIDRs configures the given pod and service CIDRs in the cluster network, setting the single CIDRs to the primary ones.
+func SetClusterCIDRs(network *clv1alpha2.ClusterNetwork, pods, services []string) {
+	network.PodsCIDRs, network.Pods = pods, primaryCIDR(pods)
+	network.ServicesCIDRs, network.Services = services, primaryCIDR(services)
+}
+
+// cidrsOrSingle returns the given list of CIDRs if not empty, otherwise a list containing the single CIDR, if set.
+func cidrsOrSingle(cidrs []string, single string) []string {
+	if len(cidrs) > 0 {
+		return cidrs
+	}
+	if single != "" {
+		return []string{single}
+	}
+	return nil
+}
+
+// primaryCIDR returns the first CIDR of the list, if any.
+func primaryCIDR(cidrs []string) string {
+	if len(cidrs) == 0 {
+		return ""
+	}
+	return cidrs[0]
+}
+
+// CIDRFamily returns the IP family the given CIDR belongs to.
+func CIDRFamily(cidr string) (clv1alpha2.IPFamily, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	if prefix.Addr().Unmap().Is4() {
+		return clv1alpha2.IPv4Family, nil
+	}
+	return clv1alpha2.IPv6Family, nil
+}
+
+// ClusterIPFamilies returns the IP families of the given cluster environment, the first being the primary one.
+func ClusterIPFamilies(environment *clv1alpha2.Environment) []clv1alpha2.IPFamily {
+	services := ClusterServiceCIDRs(&environment.Cluster.ClusterNet)
+	families := make([]clv1alpha2.IPFamily, 0, len(services))
+	for _, cidr := range services {
+		if family, err := CIDRFamily(cidr); err == nil {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// IsDualStack returns whether the given cluster environment is configured in dual-stack mode.
+func IsDualStack(environment *clv1alpha2.Environment) bool {
+	return len(ClusterServiceCIDRs(&environment.Cluster.ClusterNet)) > 1
+}
+
+// ValidateClusterNetwork checks that the pod and service CIDRs of the given cluster network are valid,
+// and belong to the same IP families, in the same order.
+func ValidateClusterNetwork(network *clv1alpha2.ClusterNetwork) error {
+	pods, err := dualStackFamilies(ClusterPodCIDRs(network))
+	if err != nil {
+		return fmt.Errorf("invalid pod CIDRs: %w", err)
+	}
+	services, err := dualStackFamilies(ClusterServiceCIDRs(network))
+	if err != nil {
+		return fmt.Errorf("invalid service CIDRs: %w", err)
+	}
+
+	if strings.Join(pods, ",") != strings.Join(services, ",") {
+		return fmt.Errorf("pod CIDRs %v and service CIDRs %v belong to different IP families", ClusterPodCIDRs(network), ClusterServiceCIDRs(network))
+	}
+	return nil
+}
+
+// dualStackFamilies returns the IP families of the given CIDRs, checking that the list contains
+// either a single CIDR, or two CIDRs belonging to different IP families (i.e. a dual-stack configuration).
+func dualStackFamilies(cidrs []string) ([]string, error) {
+	if len(cidrs) == 0 || len(cidrs) > 2 {
+		return nil, fmt.Errorf("expected one or two CIDRs, found %d", len(cidrs))
+	}
+
+	families := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		family, err := CIDRFamily(cidr)
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, string(family))
+	}
+
+	if len(families) == 2 && families[0] == families[1] {
+		return nil, fmt.Errorf("CIDRs %v belong to the same IP family", cidrs)
+	}
+	return families, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster network forging", func() {
+	var instance clv1alpha2.Instance
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: "tenant-tester"}}
+	})
+
+	DescribeTable("The forge.NetworkAllocationName function",
+		func(cidr, expected string) { Expect(forge.NetworkAllocationName(cidr)).To(Equal(expected)) },
+		Entry("IPv4 CIDR", "10.64.0.0/16", "10-64-0-0-16"),
+		Entry("IPv6 CIDR", "fd00:10::/56", "fd00-10---56"),
+	)
+
+	Describe("The forge.IsNetworkAllocationOf function", func() {
+		var allocation clv1alpha2.ClusterNetworkAllocation
+
+		BeforeEach(func() {
+			allocation = clv1alpha2.ClusterNetworkAllocation{
+				Spec: forge.ClusterNetworkAllocationSpec(&instance, "10.64.0.0/16", "10.64.0.0/12", clv1alpha2.NetworkAllocationPods, clv1alpha2.IPv4Family),
+			}
+		})
+
+		It("Should match the owner instance", func() {
+			Expect(forge.IsNetworkAllocationOf(&allocation, &instance)).To(BeTrue())
+		})
+
+		It("Should not match a different instance", func() {
+			instance.Namespace = "tenant-other"
+			Expect(forge.IsNetworkAllocationOf(&allocation, &instance)).To(BeFalse())
+		})
+	})
+	DescribeTable("The forge.CIDRFamily function",
+		func(cidr string, expected clv1alpha2.IPFamily) {
+			Expect(forge.CIDRFamily(cidr)).To(Equal(expected))
+		},
+		Entry("IPv4 CIDR", "10.64.0.0/16", clv1alpha2.IPv4Family),
+		Entry("IPv6 CIDR", "fd00:10::/56", clv1alpha2.IPv6Family),
+	)
+
+	DescribeTable("The forge.ValidateClusterNetwork function",
+		func(pods, services []string, valid bool) {
+			err := forge.ValidateClusterNetwork(&clv1alpha2.ClusterNetwork{PodsCIDRs: pods, ServicesCIDRs: services})
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("IPv4 single-stack", []string{"10.80.0.0/16"}, []string{"10.95.0.0/16"}, true),
+		Entry("IPv6 single-stack", []string{"fd10:64::/64"}, []string{"fd10:112::/112"}, true),
+		Entry("Dual-stack", []string{"10.80.0.0/16", "fd10:64::/64"}, []string{"10.95.0.0/16", "fd10:112::/112"}, true),
+		Entry("Missing CIDRs", nil, []string{"10.95.0.0/16"}, false),
+		Entry("Invalid CIDR", []string{"10.80.0.0"}, []string{"10.95.0.0/16"}, false),
+		Entry("Same family twice", []string{"10.80.0.0/16", "10.81.0.0/16"}, []string{"10.95.0.0/16", "10.96.0.0/16"}, false),
+		Entry("Mismatching families", []string{"fd10:64::/64"}, []string{"10.95.0.0/16"}, false),
+		Entry("Mismatching order", []string{"10.80.0.0/16", "fd10:64::/64"}, []string{"fd10:112::/112", "10.95.0.0/16"}, false),
+	)
+
+	Describe("The forge.ClusterPodCIDRs and forge.ClusterServiceCIDRs functions", func() {
+		var network clv1alpha2.ClusterNetwork
+
+		BeforeEach(func() {
+			network = clv1alpha2.ClusterNetwork{Pods: "10.80.0.0/16", Services: "10.95.0.0/16"}
+		})
+
+		It("Should fall back to the single CIDRs", func() {
+			Expect(forge.ClusterPodCIDRs(&network)).To(ConsistOf("10.80.0.0/16"))
+			Expect(forge.ClusterServiceCIDRs(&network)).To(ConsistOf("10.95.0.0/16"))
+		})
+
+		It("Should prefer the lists of CIDRs, when set", func() {
+			network.PodsCIDRs = []string{"fd10:64::/64", "10.81.0.0/16"}
+			network.ServicesCIDRs = []string{"fd10:112::/112", "10.96.0.0/16"}
+			Expect(forge.ClusterPodCIDRs(&network)).To(Equal([]string{"fd10:64::/64", "10.81.0.0/16"}))
+			Expect(forge.ClusterServiceCIDRs(&network)).To(Equal([]string{"fd10:112::/112", "10.96.0.0/16"}))
+		})
+
+		It("Should return no CIDRs, when none is set", func() {
+			Expect(forge.ClusterPodCIDRs(&clv1alpha2.ClusterNetwork{})).To(BeEmpty())
+		})
+	})
+
+	Describe("The forge.SetClusterCIDRs function", func() {
+		It("Should configure both the lists and the primary CIDRs", func() {
+			var network clv1alpha2.ClusterNetwork
+			forge.SetClusterCIDRs(&network, []string{"fd10:64::/64", "10.81.0.0/16"}, []string{"fd10:112::/112", "10.96.0.0/16"})
+			Expect(network.Pods).To(Equal("fd10:64::/64"))
+			Expect(network.PodsCIDRs).To(Equal([]string{"fd10:64::/64", "10.81.0.0/16"}))
+			Expect(network.Services).To(Equal("fd10:112::/112"))
+			Expect(network.ServicesCIDRs).To(Equal([]string{"fd10:112::/112", "10.96.0.0/16"}))
+		})
+	})
+})
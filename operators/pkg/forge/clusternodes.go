@@ -0,0 +1,70 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"sort"
+
+	"k8s.io/utils/ptr"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+// ClusterNodeUser is the name of the user configured on the nodes of cluster environments, consistently with VMs.
+const ClusterNodeUser = "crownlabs"
+
+// ClusterNodeUsers forges the users configured on the nodes of cluster environments, authorized with the given public keys.
+func ClusterNodeUsers(publicKeys []string) []bootstrapv1.User {
+	return []bootstrapv1.User{{
+		Name:              ClusterNodeUser,
+		Shell:             ptr.To("/bin/bash"),
+		LockPassword:      ptr.To(true),
+		Sudo:              ptr.To("ALL=(ALL) NOPASSWD:ALL"),
+		SSHAuthorizedKeys: publicKeys,
+	}}
+}
+
+// ClusterNodes forges the status entries reflecting the nodes of a cluster environment, given the corresponding machines.
+func ClusterNodes(machines []capiv1.Machine) []clv1alpha2.InstanceClusterNode {
+	nodes := make([]clv1alpha2.InstanceClusterNode, 0, len(machines))
+	for i := range machines {
+		machine := &machines[i]
+
+		node := clv1alpha2.InstanceClusterNode{Name: machine.Name, Role: clv1alpha2.ClusterNodeWorker}
+		if machine.Status.NodeRef != nil {
+			node.Name = machine.Status.NodeRef.Name
+		}
+		if _, found := machine.GetLabels()[capiv1.MachineControlPlaneLabel]; found {
+			node.Role = clv1alpha2.ClusterNodeControlPlane
+		}
+		for _, address := range machine.Status.Addresses {
+			if address.Type == capiv1.MachineInternalIP {
+				node.IP = address.Address
+				break
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Role != nodes[j].Role {
+			return nodes[i].Role == clv1alpha2.ClusterNodeControlPlane
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
@@ -0,0 +1,68 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster nodes forging", func() {
+	Describe("The forge.ClusterNodeUsers function", func() {
+		It("Should authorize the given keys for the crownlabs user", func() {
+			users := forge.ClusterNodeUsers([]string{"ssh-ed25519 key-1", "ssh-ed25519 key-2"})
+			Expect(users).To(HaveLen(1))
+			Expect(users[0].Name).To(Equal(forge.ClusterNodeUser))
+			Expect(users[0].SSHAuthorizedKeys).To(ConsistOf("ssh-ed25519 key-1", "ssh-ed25519 key-2"))
+			Expect(*users[0].Sudo).To(Equal("ALL=(ALL) NOPASSWD:ALL"))
+		})
+	})
+
+	Describe("The forge.ClusterNodes function", func() {
+		machine := func(name string, controlPlane bool, nodeName string, addresses ...capiv1.MachineAddress) capiv1.Machine {
+			m := capiv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: capiv1.MachineStatus{Addresses: addresses}}
+			if controlPlane {
+				m.SetLabels(map[string]string{capiv1.MachineControlPlaneLabel: ""})
+			}
+			if nodeName != "" {
+				m.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+			}
+			return m
+		}
+
+		It("Should return the nodes, control plane first, with their internal IPs", func() {
+			nodes := forge.ClusterNodes([]capiv1.Machine{
+				machine("demo-md-abcde", false, "worker-1",
+					capiv1.MachineAddress{Type: capiv1.MachineExternalIP, Address: "192.168.0.1"},
+					capiv1.MachineAddress{Type: capiv1.MachineInternalIP, Address: "10.0.0.2"}),
+				machine("demo-md-fghij", false, ""),
+				machine("demo-control-plane-xyz", true, "control-plane-1",
+					capiv1.MachineAddress{Type: capiv1.MachineInternalIP, Address: "10.0.0.1"}),
+			})
+
+			Expect(nodes).To(Equal([]clv1alpha2.InstanceClusterNode{
+				{Name: "control-plane-1", Role: clv1alpha2.ClusterNodeControlPlane, IP: "10.0.0.1"},
+				{Name: "demo-md-fghij", Role: clv1alpha2.ClusterNodeWorker},
+				{Name: "worker-1", Role: clv1alpha2.ClusterNodeWorker, IP: "10.0.0.2"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+// ClusterResetAnnotation -> annotation requesting the cluster of the instance to be torn down and provisioned again
+// from scratch (any new value triggers a reset, e.g., an increasing counter).
+const ClusterResetAnnotation = "crownlabs.polito.it/reset-cluster"
+
+// IsClusterTemplate returns whether the environments of the given template are backed by a cluster,
+// either provisioned through the Cluster API or composed of VM-based nodes.
+func IsClusterTemplate(template *clv1alpha2.Template) bool {
+	if IsNodeClusterTemplate(template) {
+		return true
+	}
+	for i := range template.Spec.EnvironmentList {
+		if template.Spec.EnvironmentList[i].EnvironmentType == clv1alpha2.ClassCluster {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterResetRequired returns whether the cluster of the instance has to be reset, i.e., the template is backed
+// by a cluster and a new reset has been requested, compared to the one observed when the cluster has been provisioned.
+func ClusterResetRequired(instance *clv1alpha2.Instance, template *clv1alpha2.Template) bool {
+	reset := instance.GetAnnotations()[ClusterResetAnnotation]
+	if reset == "" || !IsClusterTemplate(template) || instance.Status.ClusterReset == nil {
+		return false
+	}
+	return reset != instance.Status.ClusterReset.ObservedReset
+}
+
+// ClusterResetInitialStatus forges the status of the resets of the cluster of the instance when it is first provisioned,
+// recording the current value of the annotation, so that an instance created with it is not reset straight away.
+func ClusterResetInitialStatus(instance *clv1alpha2.Instance) *clv1alpha2.InstanceClusterResetStatus {
+	return &clv1alpha2.InstanceClusterResetStatus{ObservedReset: instance.GetAnnotations()[ClusterResetAnnotation]}
+}
+
+// ClusterResetStatus forges the status of the resets of the cluster of the instance, once the requested one has been completed.
+func ClusterResetStatus(instance *clv1alpha2.Instance) *clv1alpha2.InstanceClusterResetStatus {
+	count := 1
+	if instance.Status.ClusterReset != nil {
+		count = instance.Status.ClusterReset.Count + 1
+	}
+	return &clv1alpha2.InstanceClusterResetStatus{
+		ObservedReset: instance.GetAnnotations()[ClusterResetAnnotation],
+		Count:         count,
+		LastResetTime: ptr.To(metav1.Now()),
+	}
+}
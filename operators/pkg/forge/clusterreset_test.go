@@ -0,0 +1,136 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster reset forging", func() {
+	var (
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "cluster-0000", Namespace: "tenant-tester"}}
+		template = clv1alpha2.Template{Spec: clv1alpha2.TemplateSpec{EnvironmentList: []clv1alpha2.Environment{{
+			Name: "cluster", EnvironmentType: clv1alpha2.ClassCluster, Cluster: &clv1alpha2.ClusterTemplate{Name: "lab"},
+		}}}}
+	})
+
+	DescribeTable("The forge.IsClusterTemplate function",
+		func(environment clv1alpha2.Environment, expected bool) {
+			template.Spec.EnvironmentList = []clv1alpha2.Environment{environment}
+			Expect(forge.IsClusterTemplate(&template)).To(Equal(expected))
+		},
+		Entry("a Cluster API environment", clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassCluster}, true),
+		Entry("a VM-based cluster node", clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassVM, IsClusterNode: true}, true),
+		Entry("a VM environment", clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassVM}, false),
+		Entry("a container environment", clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassContainer}, false),
+	)
+
+	Describe("The forge.ClusterResetRequired function", func() {
+		It("Should return false if no reset is requested", func() {
+			Expect(forge.ClusterResetRequired(&instance, &template)).To(BeFalse())
+		})
+
+		It("Should return false if the cluster has not been provisioned yet", func() {
+			instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+			Expect(forge.ClusterResetRequired(&instance, &template)).To(BeFalse())
+		})
+
+		It("Should return false if the template is not backed by a cluster", func() {
+			instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+			instance.Status.ClusterReset = &clv1alpha2.InstanceClusterResetStatus{}
+			template.Spec.EnvironmentList[0].EnvironmentType = clv1alpha2.ClassContainer
+			Expect(forge.ClusterResetRequired(&instance, &template)).To(BeFalse())
+		})
+
+		When("the cluster has been provisioned without requesting a reset", func() {
+			BeforeEach(func() { instance.Status.ClusterReset = forge.ClusterResetInitialStatus(&instance) })
+
+			It("Should return true if a reset is requested for the first time", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+				Expect(forge.ClusterResetRequired(&instance, &template)).To(BeTrue())
+			})
+		})
+
+		When("the instance has been created with a reset annotation", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+				instance.Status.ClusterReset = forge.ClusterResetInitialStatus(&instance)
+			})
+
+			It("Should return false, as the cluster has just been provisioned", func() {
+				Expect(forge.ClusterResetRequired(&instance, &template)).To(BeFalse())
+			})
+
+			It("Should return true if a new reset is requested", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "2"})
+				Expect(forge.ClusterResetRequired(&instance, &template)).To(BeTrue())
+			})
+		})
+
+		When("a reset has already been completed", func() {
+			BeforeEach(func() {
+				instance.Status.ClusterReset = &clv1alpha2.InstanceClusterResetStatus{ObservedReset: "1", Count: 1}
+			})
+
+			It("Should return false if the reset has already been handled", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+				Expect(forge.ClusterResetRequired(&instance, &template)).To(BeFalse())
+			})
+
+			It("Should return true if a new reset is requested", func() {
+				instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "2"})
+				Expect(forge.ClusterResetRequired(&instance, &template)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("The forge.ClusterResetInitialStatus function", func() {
+		It("Should record the current value of the annotation, without any reset", func() {
+			instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "1"})
+			status := forge.ClusterResetInitialStatus(&instance)
+			Expect(status.ObservedReset).To(Equal("1"))
+			Expect(status.Count).To(BeZero())
+			Expect(status.LastResetTime).To(BeNil())
+		})
+	})
+
+	Describe("The forge.ClusterResetStatus function", func() {
+		BeforeEach(func() {
+			instance.SetAnnotations(map[string]string{forge.ClusterResetAnnotation: "2"})
+		})
+
+		It("Should record the first reset", func() {
+			status := forge.ClusterResetStatus(&instance)
+			Expect(status.ObservedReset).To(Equal("2"))
+			Expect(status.Count).To(Equal(1))
+			Expect(status.LastResetTime.IsZero()).To(BeFalse())
+		})
+
+		It("Should increase the number of resets", func() {
+			instance.Status.ClusterReset = &clv1alpha2.InstanceClusterResetStatus{ObservedReset: "1", Count: 3}
+			Expect(forge.ClusterResetStatus(&instance).Count).To(Equal(4))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+// ClusterStages returns the provisioning stages of cluster environments, in the order they are expected to complete.
+func ClusterStages() []clv1alpha2.ClusterStage {
+	return []clv1alpha2.ClusterStage{
+		clv1alpha2.ClusterStageInfrastructure,
+		clv1alpha2.ClusterStageMachines,
+		clv1alpha2.ClusterStageControlPlane,
+		clv1alpha2.ClusterStageNodeJoin,
+		clv1alpha2.ClusterStageCNI,
+	}
+}
+
+// ClusterStageCompleted returns whether the given provisioning stage is recorded as completed in the given status entries.
+func ClusterStageCompleted(stages []clv1alpha2.InstanceClusterStage, stage clv1alpha2.ClusterStage) bool {
+	return slices.ContainsFunc(stages, func(s clv1alpha2.InstanceClusterStage) bool { return s.Name == stage })
+}
+
+// ExpectedClusterMachines returns the number of machines composing the cluster of the given environment.
+// Kamaji control planes are hosted in the management cluster, hence they are not backed by any machine.
+func ExpectedClusterMachines(environment *clv1alpha2.Environment) int {
+	expected := int(environment.Cluster.MachineDeploy.Replicas)
+	if environment.Cluster.ControlPlane.Provider == clv1alpha2.ProviderKubeadm {
+		expected += int(environment.Cluster.ControlPlane.Replicas)
+	}
+	return expected
+}
+
+// ClusterStageCompletions returns the completion times of the provisioning stages completed by the given cluster, based on
+// the conditions reported by the Cluster API for the cluster and its machines. The join of the nodes, which is not tracked
+// by any condition, is considered completed when the last machine transitioned to the running phase (i.e., got its node).
+func ClusterStageCompletions(cluster *capiv1.Cluster, machines []capiv1.Machine, expected int) map[clv1alpha2.ClusterStage]time.Time {
+	completions := make(map[clv1alpha2.ClusterStage]time.Time)
+
+	if condition := conditions.Get(cluster, capiv1.InfrastructureReadyCondition); isTrue(condition) {
+		completions[clv1alpha2.ClusterStageInfrastructure] = condition.LastTransitionTime.Time
+	}
+	if condition := conditions.Get(cluster, capiv1.ControlPlaneInitializedCondition); isTrue(condition) {
+		completions[clv1alpha2.ClusterStageControlPlane] = condition.LastTransitionTime.Time
+	}
+
+	if len(machines) == 0 || len(machines) < expected {
+		return completions
+	}
+
+	if completion, ok := machinesCompletion(machines, capiv1.InfrastructureReadyCondition); ok {
+		completions[clv1alpha2.ClusterStageMachines] = completion
+	}
+
+	completion, joined := machinesJoinCompletion(machines)
+	if !joined {
+		return completions
+	}
+	completions[clv1alpha2.ClusterStageNodeJoin] = completion
+
+	// Nodes become healthy (i.e., ready) only once the CNI has been rolled out.
+	if completion, ok := machinesCompletion(machines, capiv1.MachineNodeHealthyCondition); ok {
+		completions[clv1alpha2.ClusterStageCNI] = completion
+	}
+	return completions
+}
+
+// ClusterStagesStatus returns the status entries reflecting the completed provisioning stages of a cluster, given the current
+// ones and the completion times, along with the entries corresponding to the newly completed stages. Stages are recorded
+// only once, hence the existing entries are never updated. The duration of each stage is measured since the completion of
+// the latest stage completed before it, or since the creation of the cluster for the first one.
+func ClusterStagesStatus(current []clv1alpha2.InstanceClusterStage, cluster *capiv1.Cluster,
+	completions map[clv1alpha2.ClusterStage]time.Time) (updated, added []clv1alpha2.InstanceClusterStage) {
+	recorded := make(map[clv1alpha2.ClusterStage]bool, len(current))
+	completionTimes := make([]time.Time, 0, len(current)+len(completions))
+	for i := range current {
+		recorded[current[i].Name] = true
+		completionTimes = append(completionTimes, current[i].CompletionTime.Time)
+	}
+	for stage, completion := range completions {
+		if !recorded[stage] {
+			completionTimes = append(completionTimes, completion)
+		}
+	}
+
+	updated = append(updated, current...)
+	for _, stage := range ClusterStages() {
+		completion, completed := completions[stage]
+		if !completed || recorded[stage] {
+			continue
+		}
+
+		duration := completion.Sub(previousCompletion(cluster.CreationTimestamp.Time, completion, completionTimes)).Truncate(time.Second)
+		if duration < 0 {
+			duration = 0
+		}
+
+		entry := clv1alpha2.InstanceClusterStage{
+			Name:           stage,
+			CompletionTime: metav1.NewTime(completion),
+			Duration:       duration.String(),
+		}
+		updated = append(updated, entry)
+		added = append(added, entry)
+	}
+	return updated, added
+}
+
+// previousCompletion returns the latest of the given completion times preceding the given one, or the start time if none.
+func previousCompletion(start, completion time.Time, completionTimes []time.Time) time.Time {
+	previous := start
+	for _, other := range completionTimes {
+		if other.Before(completion) && other.After(previous) {
+			previous = other
+		}
+	}
+	return previous
+}
+
+// machinesJoinCompletion returns the time the last of the given machines joined the cluster, if all of them did.
+// Machines transition to the running phase as soon as the corresponding node is registered.
+func machinesJoinCompletion(machines []capiv1.Machine) (time.Time, bool) {
+	var completion time.Time
+	for i := range machines {
+		status := &machines[i].Status
+		if status.NodeRef == nil || status.LastUpdated == nil || status.Phase != string(capiv1.MachinePhaseRunning) {
+			return time.Time{}, false
+		}
+		if status.LastUpdated.After(completion) {
+			completion = status.LastUpdated.Time
+		}
+	}
+	return completion, true
+}
+
+// machinesCompletion returns the time all the given machines reported the given condition as true, if that is the case.
+func machinesCompletion(machines []capiv1.Machine, conditionType capiv1.ConditionType) (time.Time, bool) {
+	var completion time.Time
+	for i := range machines {
+		condition := conditions.Get(&machines[i], conditionType)
+		if !isTrue(condition) {
+			return time.Time{}, false
+		}
+		if condition.LastTransitionTime.After(completion) {
+			completion = condition.LastTransitionTime.Time
+		}
+	}
+	return completion, true
+}
+
+// isTrue returns whether the given condition is set and true.
+func isTrue(condition *capiv1.Condition) bool {
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
@@ -0,0 +1,197 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster stages forging", func() {
+	created := time.Date(2025, time.March, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return created.Add(time.Duration(seconds) * time.Second) }
+
+	condition := func(conditionType capiv1.ConditionType, seconds int) capiv1.Condition {
+		return capiv1.Condition{Type: conditionType, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(at(seconds))}
+	}
+
+	Describe("The forge.ExpectedClusterMachines function", func() {
+		DescribeTable("Correctly returns the expected number of machines",
+			func(provider clv1alpha2.ControlPlaneProvider, expected int) {
+				environment := clv1alpha2.Environment{Cluster: &clv1alpha2.ClusterTemplate{
+					ControlPlane:  clv1alpha2.ControlPlaneRef{Provider: provider, Replicas: 3},
+					MachineDeploy: clv1alpha2.MachineDeployment{Replicas: 2},
+				}}
+				Expect(forge.ExpectedClusterMachines(&environment)).To(Equal(expected))
+			},
+			Entry("When the control plane is kubeadm", clv1alpha2.ProviderKubeadm, 5),
+			Entry("When the control plane is kamaji", clv1alpha2.ProviderKamaji, 2),
+		)
+	})
+
+	Describe("The forge.ClusterStageCompletions function", func() {
+		var (
+			cluster  capiv1.Cluster
+			machines []capiv1.Machine
+
+			completions map[clv1alpha2.ClusterStage]time.Time
+		)
+
+		machine := func(nodeName string, joined int, conditions ...capiv1.Condition) capiv1.Machine {
+			m := capiv1.Machine{Status: capiv1.MachineStatus{Conditions: conditions, Phase: string(capiv1.MachinePhaseProvisioned)}}
+			if nodeName != "" {
+				m.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+				m.Status.Phase = string(capiv1.MachinePhaseRunning)
+				m.Status.LastUpdated = ptr.To(metav1.NewTime(at(joined)))
+			}
+			return m
+		}
+
+		BeforeEach(func() {
+			cluster = capiv1.Cluster{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+			machines = nil
+		})
+
+		JustBeforeEach(func() {
+			completions = forge.ClusterStageCompletions(&cluster, machines, 2)
+		})
+
+		When("the cluster has just been created", func() {
+			It("Should not report any completed stage", func() { Expect(completions).To(BeEmpty()) })
+		})
+
+		When("the infrastructure and the control plane are ready", func() {
+			BeforeEach(func() {
+				cluster.Status.Conditions = capiv1.Conditions{
+					condition(capiv1.InfrastructureReadyCondition, 20),
+					condition(capiv1.ControlPlaneInitializedCondition, 120),
+				}
+			})
+
+			It("Should report the corresponding stages", func() {
+				Expect(completions).To(Equal(map[clv1alpha2.ClusterStage]time.Time{
+					clv1alpha2.ClusterStageInfrastructure: at(20),
+					clv1alpha2.ClusterStageControlPlane:   at(120),
+				}))
+			})
+		})
+
+		When("only part of the machines exist", func() {
+			BeforeEach(func() {
+				machines = []capiv1.Machine{machine("node-1", 200, condition(capiv1.InfrastructureReadyCondition, 60))}
+			})
+
+			It("Should not report the machine stages", func() { Expect(completions).To(BeEmpty()) })
+		})
+
+		When("the machines are provisioned, but not yet joined", func() {
+			BeforeEach(func() {
+				machines = []capiv1.Machine{
+					machine("node-1", 200, condition(capiv1.InfrastructureReadyCondition, 60)),
+					machine("", 0, condition(capiv1.InfrastructureReadyCondition, 90)),
+				}
+			})
+
+			It("Should report the machines stage, as of the last machine", func() {
+				Expect(completions).To(Equal(map[clv1alpha2.ClusterStage]time.Time{clv1alpha2.ClusterStageMachines: at(90)}))
+			})
+		})
+
+		When("the machines joined the cluster", func() {
+			BeforeEach(func() {
+				machines = []capiv1.Machine{
+					machine("node-1", 200, condition(capiv1.InfrastructureReadyCondition, 60), condition(capiv1.MachineNodeHealthyCondition, 300)),
+					machine("node-2", 240, condition(capiv1.InfrastructureReadyCondition, 90)),
+				}
+			})
+
+			It("Should report the node join stage, as of the last machine", func() {
+				Expect(completions).To(HaveKeyWithValue(clv1alpha2.ClusterStageNodeJoin, at(240)))
+			})
+
+			It("Should not report the CNI stage, until all the nodes are healthy", func() {
+				Expect(completions).ToNot(HaveKey(clv1alpha2.ClusterStageCNI))
+			})
+
+			When("all the nodes are healthy", func() {
+				BeforeEach(func() {
+					machines[1].Status.Conditions = append(machines[1].Status.Conditions, condition(capiv1.MachineNodeHealthyCondition, 330))
+				})
+
+				It("Should report the CNI stage, as of the last node", func() {
+					Expect(completions).To(HaveKeyWithValue(clv1alpha2.ClusterStageCNI, at(330)))
+				})
+			})
+		})
+	})
+
+	Describe("The forge.ClusterStagesStatus function", func() {
+		var cluster capiv1.Cluster
+
+		BeforeEach(func() {
+			cluster = capiv1.Cluster{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+		})
+
+		It("Should append the newly completed stages, in order, with their duration since the previous stage", func() {
+			current := []clv1alpha2.InstanceClusterStage{{
+				Name: clv1alpha2.ClusterStageInfrastructure, CompletionTime: metav1.NewTime(at(15)), Duration: "15s",
+			}}
+			updated, added := forge.ClusterStagesStatus(current, &cluster, map[clv1alpha2.ClusterStage]time.Time{
+				clv1alpha2.ClusterStageInfrastructure: at(20),
+				clv1alpha2.ClusterStageControlPlane:   at(150),
+				clv1alpha2.ClusterStageMachines:       at(90),
+			})
+
+			Expect(added).To(Equal([]clv1alpha2.InstanceClusterStage{
+				{Name: clv1alpha2.ClusterStageMachines, CompletionTime: metav1.NewTime(at(90)), Duration: "1m15s"},
+				{Name: clv1alpha2.ClusterStageControlPlane, CompletionTime: metav1.NewTime(at(150)), Duration: "1m0s"},
+			}))
+			Expect(updated).To(Equal(append(current, added...)))
+		})
+
+		It("Should not add any stage, if already recorded", func() {
+			current := []clv1alpha2.InstanceClusterStage{{
+				Name: clv1alpha2.ClusterStageInfrastructure, CompletionTime: metav1.NewTime(at(15)), Duration: "15s",
+			}}
+			updated, added := forge.ClusterStagesStatus(current, &cluster, map[clv1alpha2.ClusterStage]time.Time{
+				clv1alpha2.ClusterStageInfrastructure: at(20),
+			})
+			Expect(added).To(BeEmpty())
+			Expect(updated).To(Equal(current))
+		})
+	})
+
+	Describe("The forge.ClusterStageCompleted function", func() {
+		stages := []clv1alpha2.InstanceClusterStage{{Name: clv1alpha2.ClusterStageInfrastructure}, {Name: clv1alpha2.ClusterStageNodeJoin}}
+
+		It("Should return true if the stage is recorded", func() {
+			Expect(forge.ClusterStageCompleted(stages, clv1alpha2.ClusterStageNodeJoin)).To(BeTrue())
+		})
+
+		It("Should return false if the stage is not recorded", func() {
+			Expect(forge.ClusterStageCompleted(stages, clv1alpha2.ClusterStageCNI)).To(BeFalse())
+			Expect(forge.ClusterStageCompleted(nil, clv1alpha2.ClusterStageCNI)).To(BeFalse())
+		})
+	})
+})
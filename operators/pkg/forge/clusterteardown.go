@@ -0,0 +1,40 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	controlplanekamajiv1 "github.com/clastix/cluster-api-control-plane-provider-kamaji/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+)
+
+// ClusterComponentKinds returns the kinds of the objects composing a cluster environment, other than the
+// Cluster itself, in the order they are removed once the Cluster has been torn down by the Cluster API.
+func ClusterComponentKinds() []schema.GroupVersionKind {
+	infrastructure := schema.FromAPIVersionAndKind(InfrastructureAPIVersion, "").GroupVersion()
+
+	return []schema.GroupVersionKind{
+		capiv1.GroupVersion.WithKind("MachineDeployment"),
+		controlplanev1.GroupVersion.WithKind("KubeadmControlPlane"),
+		controlplanekamajiv1.GroupVersion.WithKind("KamajiControlPlane"),
+		bootstrapv1.GroupVersion.WithKind("KubeadmConfigTemplate"),
+		infrastructure.WithKind(kubevirtMachineTemplateKind),
+		infrastructure.WithKind(inMemoryMachineTemplateKind),
+		infrastructure.WithKind(kubevirtClusterKind),
+		infrastructure.WithKind(inMemoryClusterKind),
+	}
+}
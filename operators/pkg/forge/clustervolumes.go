@@ -0,0 +1,126 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ClusterVolumesNamespace -> the namespace of the workload cluster hosting the claims bound to the NFS volumes.
+	ClusterVolumesNamespace = "default"
+	// ClusterVolumeNominalCapacity -> the capacity of the volumes exposed in the workload cluster, which is not enforced by NFS.
+	ClusterVolumeNominalCapacity = "1Gi"
+	// ClusterVolumesFieldManager -> field manager used when applying the volumes to the workload cluster.
+	ClusterVolumesFieldManager = ClusterContentFieldManager
+
+	clusterVolumePrefix = "crownlabs"
+)
+
+// ClusterVolumesRequired returns whether any NFS volume is to be exposed in the cluster of the given environment.
+func ClusterVolumesRequired(environment *clv1alpha2.Environment) bool {
+	return environment.MountMyDriveVolume || len(environment.SharedVolumeMounts) > 0
+}
+
+// MyDriveClusterVolume forges the status entry of the MyDrive volume exposed in the workload cluster.
+func MyDriveClusterVolume(serverAddress, exportPath string) clv1alpha2.InstanceClusterVolume {
+	return clv1alpha2.InstanceClusterVolume{ClaimName: MyDriveVolumeName, Server: serverAddress, Path: exportPath}
+}
+
+// SharedClusterVolume forges the status entry of a SharedVolume exposed in the workload cluster,
+// given the NFS server and export path published in its status (see NFSShVolSpec). The claim name
+// includes the namespace of the SharedVolume, as SharedVolumes from different namespaces may share the name.
+func SharedClusterVolume(shvol *clv1alpha2.SharedVolume, mount clv1alpha2.SharedVolumeMountInfo) clv1alpha2.InstanceClusterVolume {
+	return clv1alpha2.InstanceClusterVolume{
+		ClaimName: fmt.Sprintf("%s-%s", shvol.Namespace, shvol.Name),
+		Server:    shvol.Status.ServerAddress,
+		Path:      shvol.Status.ExportPath,
+		ReadOnly:  mount.ReadOnly,
+	}
+}
+
+// ClusterPersistentVolume forges the NFS-backed PersistentVolume exposing the given volume in the workload cluster,
+// pre-bound to the corresponding PersistentVolumeClaim.
+func ClusterPersistentVolume(instance *clv1alpha2.Instance, volume *clv1alpha2.InstanceClusterVolume) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "PersistentVolume"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ClusterPersistentVolumeName(volume),
+			Labels: InstanceObjectLabels(nil, instance),
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:    corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(ClusterVolumeNominalCapacity)},
+			AccessModes: clusterVolumeAccessModes(volume),
+			PersistentVolumeSource: corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{
+				Server:   volume.Server,
+				Path:     volume.Path,
+				ReadOnly: volume.ReadOnly,
+			}},
+			ClaimRef: &corev1.ObjectReference{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "PersistentVolumeClaim",
+				Namespace:  ClusterVolumesNamespace,
+				Name:       volume.ClaimName,
+			},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			StorageClassName:              "",
+			MountOptions:                  []string{"hard", "tcp"},
+		},
+	}
+}
+
+// ClusterPersistentVolumeClaim forges the PersistentVolumeClaim bound to the given volume in the workload cluster.
+func ClusterPersistentVolumeClaim(instance *clv1alpha2.Instance, volume *clv1alpha2.InstanceClusterVolume) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      volume.ClaimName,
+			Namespace: ClusterVolumesNamespace,
+			Labels:    InstanceObjectLabels(nil, instance),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: clusterVolumeAccessModes(volume),
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(ClusterVolumeNominalCapacity)},
+			},
+			VolumeName:       ClusterPersistentVolumeName(volume),
+			StorageClassName: ptr.To(""),
+		},
+	}
+}
+
+// ClusterPersistentVolumeName returns the name of the PersistentVolume exposing the given volume in the workload cluster,
+// which includes a digest of the NFS export, to prevent collisions between volumes exposed under the same claim name.
+func ClusterPersistentVolumeName(volume *clv1alpha2.InstanceClusterVolume) string {
+	digest := sha256.Sum256([]byte(volume.Server + ":" + volume.Path))
+	return fmt.Sprintf("%s-%s-%s", clusterVolumePrefix, volume.ClaimName, hex.EncodeToString(digest[:])[:8])
+}
+
+// clusterVolumeAccessModes returns the access modes of the given volume, depending on whether it is read-only.
+func clusterVolumeAccessModes(volume *clv1alpha2.InstanceClusterVolume) []corev1.PersistentVolumeAccessMode {
+	if volume.ReadOnly {
+		return []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany}
+	}
+	return []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+}
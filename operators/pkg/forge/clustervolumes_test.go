@@ -0,0 +1,96 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Cluster volumes forging", func() {
+	var (
+		instance clv1alpha2.Instance
+		volume   clv1alpha2.InstanceClusterVolume
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: "tenant-tester"},
+			Spec: clv1alpha2.InstanceSpec{
+				Template: clv1alpha2.GenericRef{Name: "kubernetes", Namespace: "workspace-netgroup"},
+				Tenant:   clv1alpha2.GenericRef{Name: "tester"},
+			},
+		}
+		volume = forge.MyDriveClusterVolume("nfs.example.com", "/tester")
+	})
+
+	Describe("The forge.SharedClusterVolume function", func() {
+		It("Should forge the volume from the status of the SharedVolume, with the mount permissions and a namespaced claim", func() {
+			shvol := clv1alpha2.SharedVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "datasets", Namespace: "workspace-netgroup"},
+				Status:     clv1alpha2.SharedVolumeStatus{ServerAddress: "nfs.example.com", ExportPath: "/datasets"},
+			}
+			Expect(forge.SharedClusterVolume(&shvol, clv1alpha2.SharedVolumeMountInfo{ReadOnly: true})).To(Equal(clv1alpha2.InstanceClusterVolume{
+				ClaimName: "workspace-netgroup-datasets", Server: "nfs.example.com", Path: "/datasets", ReadOnly: true,
+			}))
+		})
+	})
+
+	Describe("The forge.ClusterPersistentVolume function", func() {
+		var pv *corev1.PersistentVolume
+
+		JustBeforeEach(func() {
+			pv = forge.ClusterPersistentVolume(&instance, &volume)
+		})
+
+		It("Should forge an NFS volume, pre-bound to the claim", func() {
+			Expect(pv.GetName()).To(Equal("crownlabs-mydrive-d8d0648e"))
+			Expect(pv.Spec.NFS).To(Equal(&corev1.NFSVolumeSource{Server: "nfs.example.com", Path: "/tester"}))
+			Expect(pv.Spec.ClaimRef.Namespace).To(Equal(forge.ClusterVolumesNamespace))
+			Expect(pv.Spec.ClaimRef.Name).To(Equal("mydrive"))
+			Expect(pv.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+			Expect(pv.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteMany))
+			Expect(pv.Kind).To(Equal("PersistentVolume"))
+		})
+
+		When("the volume is read-only", func() {
+			BeforeEach(func() { volume.ReadOnly = true })
+
+			It("Should forge a read-only volume", func() {
+				Expect(pv.Spec.NFS.ReadOnly).To(BeTrue())
+				Expect(pv.Spec.AccessModes).To(ConsistOf(corev1.ReadOnlyMany))
+			})
+		})
+	})
+
+	Describe("The forge.ClusterPersistentVolumeClaim function", func() {
+		It("Should forge a claim bound to the volume", func() {
+			pvc := forge.ClusterPersistentVolumeClaim(&instance, &volume)
+			Expect(pvc.GetName()).To(Equal("mydrive"))
+			Expect(pvc.GetNamespace()).To(Equal(forge.ClusterVolumesNamespace))
+			Expect(pvc.Spec.VolumeName).To(Equal(forge.ClusterPersistentVolumeName(&volume)))
+			Expect(*pvc.Spec.StorageClassName).To(BeEmpty())
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteMany))
+			Expect(pvc.Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, resource.MustParse(forge.ClusterVolumeNominalCapacity)))
+			Expect(pvc.GetLabels()).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+		})
+	})
+})
@@ -2,6 +2,8 @@ package forge
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	controlplanekamajiv1 "github.com/clastix/cluster-api-control-plane-provider-kamaji/api/v1alpha1"
 	"github.com/clastix/kamaji/api/v1alpha1"
@@ -40,6 +42,12 @@ func KamajiControlPlaneFields(environment *clv1alpha2.Environment, host string)
 				"ExternalIP",
 			},
 		},
+		ApiServer: controlplanekamajiv1.ControlPlaneComponent{
+			ExtraArgs: kamajiExtraArgs(APIServerExtraArgs(environment)),
+		},
+		ControllerManager: controlplanekamajiv1.ControlPlaneComponent{
+			ExtraArgs: kamajiExtraArgs(ControllerManagerExtraArgs(environment)),
+		},
 		Network: controlplanekamajiv1.NetworkComponent{
 			ServiceType: v1alpha1.ServiceType(environment.Cluster.ServiceType),
 			CertSANs: []string{
@@ -118,12 +126,7 @@ func BootstrapConfigRef(instance *clv1alpha2.Instance, environment *clv1alpha2.E
 
 // MachineInfrastructureRef forges the specification of a Macine infrastructure reference
 func MachineInfrastructureRef(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, Name string) corev1.ObjectReference {
-	return corev1.ObjectReference{
-		Name:       Name,
-		Namespace:  instance.Namespace,
-		APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha1",
-		Kind:       "KubevirtMachineTemplate",
-	}
+	return ClusterInfrastructureFor(environment).MachineTemplateRef(instance, Name)
 }
 
 // ClusterControlPlaneSepc forges the specification of a cluster controlplane spec
@@ -155,36 +158,72 @@ func ControlPlaneClusterConfiguration(instance *clv1alpha2.Instance, environment
 	return bootstrapv1.ClusterConfiguration{
 		Networking: ControlPlaneNetworking(instance, environment),
 		APIServer: bootstrapv1.APIServer{
+			ControlPlaneComponent: bootstrapv1.ControlPlaneComponent{
+				ExtraArgs: APIServerExtraArgs(environment),
+			},
 			CertSANs: []string{
 				host,
 				"ingress.local",
 				environment.Cluster.ClusterNet.CertSAN,
 			},
 		},
+		ControllerManager: bootstrapv1.ControlPlaneComponent{
+			ExtraArgs: ControllerManagerExtraArgs(environment),
+		},
+	}
+}
+
+// APIServerExtraArgs forges the extra arguments of the API server, configuring the dual-stack service IP families (if necessary)
+func APIServerExtraArgs(environment *clv1alpha2.Environment) map[string]string {
+	if !IsDualStack(environment) {
+		return nil
+	}
+	return map[string]string{
+		"service-cluster-ip-range": strings.Join(ClusterServiceCIDRs(&environment.Cluster.ClusterNet), ","),
 	}
 }
 
+// ControllerManagerExtraArgs forges the extra arguments of the controller manager, configuring the dual-stack networks (if necessary)
+func ControllerManagerExtraArgs(environment *clv1alpha2.Environment) map[string]string {
+	if !IsDualStack(environment) {
+		return nil
+	}
+	return map[string]string{
+		"cluster-cidr":             strings.Join(ClusterPodCIDRs(&environment.Cluster.ClusterNet), ","),
+		"service-cluster-ip-range": strings.Join(ClusterServiceCIDRs(&environment.Cluster.ClusterNet), ","),
+	}
+}
+
+// kamajiExtraArgs converts the given arguments in the flag format expected by Kamaji
+func kamajiExtraArgs(args map[string]string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	flags := make([]string, 0, len(args))
+	for key, value := range args {
+		flags = append(flags, fmt.Sprintf("--%s=%s", key, value))
+	}
+	sort.Strings(flags)
+	return flags
+}
+
 // ControlPlaneNetworking forges the spcification of controlplane network configuration
 func ControlPlaneNetworking(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) bootstrapv1.Networking {
 	return bootstrapv1.Networking{
 		DNSDomain:     fmt.Sprintf("%s.%s.local", environment.Cluster.Name, instance.Namespace),
-		PodSubnet:     environment.Cluster.ClusterNet.Pods,
-		ServiceSubnet: environment.Cluster.ClusterNet.Services,
+		PodSubnet:     strings.Join(ClusterPodCIDRs(&environment.Cluster.ClusterNet), ","),
+		ServiceSubnet: strings.Join(ClusterServiceCIDRs(&environment.Cluster.ClusterNet), ","),
 	}
 }
 
 // ClusterSpec forges the specification of a cluster object
 func ClusterSpec(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) capiv1.ClusterSpec {
 	Provider := environment.Cluster.ControlPlane.Provider
+	infrastructure := ClusterInfrastructureFor(environment)
 	if Provider == clv1alpha2.ProviderKubeadm {
 		return capiv1.ClusterSpec{
-			ClusterNetwork: ptr.To(ClusterNetworking(environment)),
-			InfrastructureRef: ptr.To(corev1.ObjectReference{
-				APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha1",
-				Kind:       "KubevirtCluster",
-				Name:       fmt.Sprintf("%s-infra", environment.Cluster.Name),
-				Namespace:  instance.Namespace,
-			}),
+			ClusterNetwork:    ptr.To(ClusterNetworking(environment)),
+			InfrastructureRef: ptr.To(infrastructure.InfraClusterRef(instance, environment)),
 			ControlPlaneRef: ptr.To(corev1.ObjectReference{
 				APIVersion: "controlplane.cluster.x-k8s.io/v1beta1",
 				Kind:       "KubeadmControlPlane",
@@ -194,13 +233,8 @@ func ClusterSpec(instance *clv1alpha2.Instance, environment *clv1alpha2.Environm
 		}
 	} else {
 		return capiv1.ClusterSpec{
-			ClusterNetwork: ptr.To(ClusterNetworking(environment)),
-			InfrastructureRef: ptr.To(corev1.ObjectReference{
-				APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha1",
-				Kind:       "KubevirtCluster",
-				Name:       fmt.Sprintf("%s-infra", environment.Cluster.Name),
-				Namespace:  instance.Namespace,
-			}),
+			ClusterNetwork:    ptr.To(ClusterNetworking(environment)),
+			InfrastructureRef: ptr.To(infrastructure.InfraClusterRef(instance, environment)),
 			ControlPlaneRef: ptr.To(corev1.ObjectReference{
 				APIVersion: "controlplane.cluster.x-k8s.io/v1alpha1",
 				Kind:       "KamajiControlPlane",
@@ -216,10 +250,10 @@ func ClusterSpec(instance *clv1alpha2.Instance, environment *clv1alpha2.Environm
 func ClusterNetworking(environment *clv1alpha2.Environment) capiv1.ClusterNetwork {
 	return capiv1.ClusterNetwork{
 		Pods: ptr.To(capiv1.NetworkRanges{
-			CIDRBlocks: []string{environment.Cluster.ClusterNet.Pods},
+			CIDRBlocks: ClusterPodCIDRs(&environment.Cluster.ClusterNet),
 		}),
 		Services: ptr.To(capiv1.NetworkRanges{
-			CIDRBlocks: []string{environment.Cluster.ClusterNet.Services},
+			CIDRBlocks: ClusterServiceCIDRs(&environment.Cluster.ClusterNet),
 		}),
 	}
 }
@@ -0,0 +1,249 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Clusters forging", func() {
+	var (
+		instance    clv1alpha2.Instance
+		environment clv1alpha2.Environment
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		clusterName       = "demo"
+		version           = "v1.30.2"
+		podCIDR           = "10.80.0.0/16"
+		serviceCIDR       = "10.95.0.0/16"
+		podCIDRv6         = "fd10:64::/64"
+		serviceCIDRv6     = "fd10:112::/112"
+		host              = "crownlabs.example.com"
+		image             = "internal/registry/image:v1.0"
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+		}
+		environment = clv1alpha2.Environment{
+			Image:           image,
+			EnvironmentType: clv1alpha2.ClassCluster,
+			Resources: clv1alpha2.EnvironmentResources{
+				CPU:    2,
+				Memory: resource.MustParse("4G"),
+			},
+			Cluster: &clv1alpha2.ClusterTemplate{
+				Name:    clusterName,
+				Version: version,
+				ClusterNet: clv1alpha2.ClusterNetwork{
+					Pods:     podCIDR,
+					Services: serviceCIDR,
+					CertSAN:  "cluster.example.com",
+				},
+				ControlPlane:  clv1alpha2.ControlPlaneRef{Provider: clv1alpha2.ProviderKamaji, Replicas: 2},
+				MachineDeploy: clv1alpha2.MachineDeployment{Replicas: 3},
+			},
+		}
+	})
+
+	Describe("The forge.ClusterSpec function", func() {
+		var spec capiv1.ClusterSpec
+
+		JustBeforeEach(func() {
+			spec = forge.ClusterSpec(&instance, &environment)
+		})
+
+		It("Should configure the cluster network", func() {
+			Expect(*spec.ClusterNetwork).To(Equal(forge.ClusterNetworking(&environment)))
+		})
+
+		When("the control plane provider is kamaji", func() {
+			It("Should reference the KamajiControlPlane", func() {
+				Expect(spec.ControlPlaneRef.Kind).To(Equal("KamajiControlPlane"))
+				Expect(spec.ControlPlaneRef.Name).To(Equal("demo-control-plane"))
+			})
+		})
+
+		When("the control plane provider is kubeadm", func() {
+			BeforeEach(func() { environment.Cluster.ControlPlane.Provider = clv1alpha2.ProviderKubeadm })
+
+			It("Should reference the KubeadmControlPlane", func() {
+				Expect(spec.ControlPlaneRef.Kind).To(Equal("KubeadmControlPlane"))
+				Expect(spec.ControlPlaneRef.Name).To(Equal("demo-control-plane"))
+			})
+		})
+
+		When("the infrastructure provider is KubeVirt", func() {
+			It("Should reference the KubevirtCluster", func() {
+				Expect(spec.InfrastructureRef.Kind).To(Equal("KubevirtCluster"))
+				Expect(spec.InfrastructureRef.Name).To(Equal("demo-infra"))
+				Expect(spec.InfrastructureRef.Namespace).To(Equal(instanceNamespace))
+			})
+		})
+
+		When("the infrastructure provider is in-memory", func() {
+			BeforeEach(func() { environment.Cluster.Infrastructure = clv1alpha2.InfraInMemory })
+
+			It("Should reference the InMemoryCluster", func() {
+				Expect(spec.InfrastructureRef.Kind).To(Equal("InMemoryCluster"))
+				Expect(spec.InfrastructureRef.Name).To(Equal("demo-infra"))
+			})
+		})
+	})
+
+	Describe("The forge.ClusterNetworking function", func() {
+		It("Should configure the pods and services CIDRs", func() {
+			network := forge.ClusterNetworking(&environment)
+			Expect(network.Pods.CIDRBlocks).To(ConsistOf(podCIDR))
+			Expect(network.Services.CIDRBlocks).To(ConsistOf(serviceCIDR))
+		})
+	})
+
+	Describe("The forge.ControlPlaneNetworking function", func() {
+		It("Should configure the expected networking", func() {
+			network := forge.ControlPlaneNetworking(&instance, &environment)
+			Expect(network.DNSDomain).To(Equal("demo.tenant-tester.local"))
+			Expect(network.PodSubnet).To(Equal(podCIDR))
+			Expect(network.ServiceSubnet).To(Equal(serviceCIDR))
+		})
+
+		When("the cluster is dual-stack", func() {
+			BeforeEach(func() {
+				environment.Cluster.ClusterNet.PodsCIDRs = []string{podCIDR, podCIDRv6}
+				environment.Cluster.ClusterNet.ServicesCIDRs = []string{serviceCIDR, serviceCIDRv6}
+			})
+
+			It("Should configure comma-separated subnets", func() {
+				network := forge.ControlPlaneNetworking(&instance, &environment)
+				Expect(network.PodSubnet).To(Equal(podCIDR + "," + podCIDRv6))
+				Expect(network.ServiceSubnet).To(Equal(serviceCIDR + "," + serviceCIDRv6))
+			})
+		})
+	})
+
+	Describe("The forge.APIServerExtraArgs and forge.ControllerManagerExtraArgs functions", func() {
+		When("the cluster is single-stack", func() {
+			It("Should not configure any argument", func() {
+				Expect(forge.APIServerExtraArgs(&environment)).To(BeEmpty())
+				Expect(forge.ControllerManagerExtraArgs(&environment)).To(BeEmpty())
+			})
+		})
+
+		When("the cluster is dual-stack", func() {
+			BeforeEach(func() {
+				environment.Cluster.ClusterNet.PodsCIDRs = []string{podCIDR, podCIDRv6}
+				environment.Cluster.ClusterNet.ServicesCIDRs = []string{serviceCIDR, serviceCIDRv6}
+			})
+
+			It("Should configure the dual-stack service IP ranges on the API server", func() {
+				Expect(forge.APIServerExtraArgs(&environment)).To(HaveKeyWithValue("service-cluster-ip-range", serviceCIDR+","+serviceCIDRv6))
+			})
+
+			It("Should configure the dual-stack networks on the controller manager", func() {
+				args := forge.ControllerManagerExtraArgs(&environment)
+				Expect(args).To(HaveKeyWithValue("cluster-cidr", podCIDR+","+podCIDRv6))
+				Expect(args).To(HaveKeyWithValue("service-cluster-ip-range", serviceCIDR+","+serviceCIDRv6))
+			})
+
+			It("Should propagate the arguments to the Kamaji control plane", func() {
+				fields := forge.KamajiControlPlaneFields(&environment, host)
+				Expect(fields.ApiServer.ExtraArgs).To(ConsistOf("--service-cluster-ip-range=" + serviceCIDR + "," + serviceCIDRv6))
+				Expect(fields.ControllerManager.ExtraArgs).To(HaveLen(2))
+			})
+		})
+	})
+
+	DescribeTable("The forge.CiliumIPAMArgs function",
+		func(podCIDRs []string, expected []string) {
+			Expect(forge.CiliumIPAMArgs(podCIDRs)).To(Equal(expected))
+		},
+		Entry("IPv4 only", []string{podCIDR}, []string{
+			"--set", "ipv4.enabled=true", "--set", "ipv6.enabled=false",
+			"--set", "ipam.operator.clusterPoolIPv4PodCIDRList={" + podCIDR + "}",
+		}),
+		Entry("IPv6 only", []string{podCIDRv6}, []string{
+			"--set", "ipv4.enabled=false", "--set", "ipv6.enabled=true",
+			"--set", "ipam.operator.clusterPoolIPv6PodCIDRList={" + podCIDRv6 + "}",
+		}),
+		Entry("Dual-stack", []string{podCIDRv6, podCIDR}, []string{
+			"--set", "ipv4.enabled=true", "--set", "ipv6.enabled=true",
+			"--set", "ipam.operator.clusterPoolIPv4PodCIDRList={" + podCIDR + "}",
+			"--set", "ipam.operator.clusterPoolIPv6PodCIDRList={" + podCIDRv6 + "}",
+		}),
+	)
+
+	Describe("The forge.KamajiControlPlaneSpec function", func() {
+		It("Should configure the replicas, version and certificate SANs", func() {
+			spec := forge.KamajiControlPlaneSpec(&environment, host)
+			Expect(*spec.Replicas).To(BeNumerically("==", 2))
+			Expect(spec.Version).To(Equal(version))
+			Expect(spec.Network.CertSANs).To(ContainElements(host, "cluster.example.com"))
+		})
+	})
+
+	Describe("The forge.MachineDeploymentSepc function", func() {
+		var spec capiv1.MachineSpec
+
+		JustBeforeEach(func() {
+			spec = forge.MachineDeploymentSepc(&instance, &environment)
+		})
+
+		It("Should reference the cluster and the version", func() {
+			Expect(spec.ClusterName).To(Equal("demo-cluster"))
+			Expect(*spec.Version).To(Equal(version))
+		})
+		It("Should reference the bootstrap configuration", func() {
+			Expect(*spec.Bootstrap.ConfigRef).To(Equal(forge.BootstrapConfigRef(&instance, &environment)))
+		})
+
+		When("the infrastructure provider is KubeVirt", func() {
+			It("Should reference the KubevirtMachineTemplate", func() {
+				Expect(spec.InfrastructureRef.Kind).To(Equal("KubevirtMachineTemplate"))
+				Expect(spec.InfrastructureRef.Name).To(Equal("demo-md-worker"))
+			})
+		})
+
+		When("the infrastructure provider is in-memory", func() {
+			BeforeEach(func() { environment.Cluster.Infrastructure = clv1alpha2.InfraInMemory })
+
+			It("Should reference the InMemoryMachineTemplate", func() {
+				Expect(spec.InfrastructureRef.Kind).To(Equal("InMemoryMachineTemplate"))
+				Expect(spec.InfrastructureRef.Name).To(Equal("demo-md-worker"))
+			})
+		})
+	})
+
+	Describe("The forge.ClusterControlPlaneSepc function", func() {
+		BeforeEach(func() { environment.Cluster.ControlPlane.Provider = clv1alpha2.ProviderKubeadm })
+
+		It("Should reference the control plane machine template", func() {
+			spec := forge.ClusterControlPlaneSepc(&instance, &environment, host)
+			Expect(spec.MachineTemplate.InfrastructureRef).To(Equal(
+				forge.MachineInfrastructureRef(&instance, &environment, "demo-control-plane-machine")))
+			Expect(spec.Version).To(Equal(version))
+		})
+	})
+})
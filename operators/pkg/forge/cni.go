@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
 	"k8s.io/client-go/tools/clientcmd"
@@ -37,19 +38,9 @@ func insertKubeConfig(instance *clv1alpha2.Instance, environment *clv1alpha2.Env
 }
 func Insinstallcni(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, host string) error {
 	cluster := environment.Cluster
-	clustername := fmt.Sprintf("%s-cluster", cluster.Name)
-	namespace := instance.Namespace
 	cni := cluster.ClusterNet.Cni
-	podCIDR := cluster.ClusterNet.Pods
+	podCIDRs := ClusterPodCIDRs(&cluster.ClusterNet)
 	kubeconfigPath := fmt.Sprintf("./kubeconfigs/%s-instance.kubeconfig", instance.Name)
-	// "Waiting for cluster to be ready"
-	exec.Command(
-		"kubectl", "wait",
-		"--for=condition=Ready=true",
-		"-n", namespace,
-		fmt.Sprintf("clusters.cluster.x-k8s.io/%s", clustername),
-		"--timeout=2m",
-	)
 	// insert relative KUBECONFIG files into local folder ./kubeconfigs
 	insertKubeConfig(instance, environment, host)
 	//Installing CNI on cluster
@@ -57,38 +48,68 @@ func Insinstallcni(instance *clv1alpha2.Instance, environment *clv1alpha2.Enviro
 	case clv1alpha2.CniCalico:
 
 	case clv1alpha2.CniCilium:
-		installCilium(kubeconfigPath, podCIDR)
-		waitCilium(kubeconfigPath)
+		if err := installCilium(kubeconfigPath, podCIDRs); err != nil {
+			return err
+		}
+		return waitCilium(kubeconfigPath)
 	case clv1alpha2.CniFlannel:
 
 	}
 	return nil
 }
 
-func installCilium(kubeconfig string, podCIDR string) error {
-	args := []string{
-		"install",
-		"--set", fmt.Sprintf("ipam.operator.clusterPoolIPv4PodCIDRList=%s", podCIDR),
+func installCilium(kubeconfig string, podCIDRs []string) error {
+	cmd := exec.Command("cilium", append([]string{"install"}, CiliumInstallArgs(podCIDRs)...)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cilium installation failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// CiliumInstallArgs forges the parameters of the Cilium CLI installing Cilium with the given pod CIDRs, and waiting for it to be ready.
+func CiliumInstallArgs(podCIDRs []string) []string {
+	args := CiliumIPAMArgs(podCIDRs)
+	return append(args,
 		"--set", "affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0].key=liqo.io/type",
 		"--set", "affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0].operator=DoesNotExist",
 		"--set", "encryption.enabled=true",
 		"--set", "encryption.type=wireguard",
 		"--wait",
-	}
-
-	cmd := exec.Command("cilium", args...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+	)
+}
 
-	_, _ = cmd.CombinedOutput()
+// CiliumIPAMArgs forges the Cilium installation parameters enabling the IP families of the given pod CIDRs
+func CiliumIPAMArgs(podCIDRs []string) []string {
+	var ipv4, ipv6 []string
+	for _, cidr := range podCIDRs {
+		if family, err := CIDRFamily(cidr); err == nil && family == clv1alpha2.IPv6Family {
+			ipv6 = append(ipv6, cidr)
+		} else {
+			ipv4 = append(ipv4, cidr)
+		}
+	}
 
-	return nil
+	args := []string{
+		"--set", fmt.Sprintf("ipv4.enabled=%t", len(ipv4) > 0),
+		"--set", fmt.Sprintf("ipv6.enabled=%t", len(ipv6) > 0),
+	}
+	if len(ipv4) > 0 {
+		args = append(args, "--set", fmt.Sprintf("ipam.operator.clusterPoolIPv4PodCIDRList={%s}", strings.Join(ipv4, ",")))
+	}
+	if len(ipv6) > 0 {
+		args = append(args, "--set", fmt.Sprintf("ipam.operator.clusterPoolIPv6PodCIDRList={%s}", strings.Join(ipv6, ",")))
+	}
+	return args
 }
 
 func waitCilium(kubeconfig string) error {
 	cmd := exec.Command("cilium", "status", "--wait")
 	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
 
-	_, _ = cmd.CombinedOutput()
-
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cilium not ready: %w: %s", err, strings.TrimSpace(string(output)))
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ package forge
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -63,6 +64,10 @@ const (
 	MyDriveVolumeName = "mydrive"
 	// MyDriveVolumeMountPath -> Mount path for the NFS personal volume.
 	MyDriveVolumeMountPath = "/media/mydrive"
+	// RecordingsDirName -> name of the hidden folder of the persistent volume storing the session recordings.
+	RecordingsDirName = ".crownlabs-recordings"
+	// RecordingsTemporaryPath -> path storing the session recordings before they are uploaded.
+	RecordingsTemporaryPath = "/tmp/recordings"
 
 	containersTerminationGracePeriod = 10
 )
@@ -226,10 +231,30 @@ func WebsockifyContainer(opts *ContainerEnvOpts, environment *clv1alpha2.Environ
 	AddContainerArg(&websockifyContainer, "pod-name", fmt.Sprintf("$(%s)", PodNameEnvName))
 	AddContainerArg(&websockifyContainer, "cpu-limit", fmt.Sprintf("$(%s)", AppCPULimitsEnvName))
 	AddContainerArg(&websockifyContainer, "memory-limit", fmt.Sprintf("$(%s)", AppMEMLimitsEnvName))
+	AddContainerVolumeMount(&websockifyContainer, ViewerVolumeName, ViewerKeyMountPath)
+	AddContainerArg(&websockifyContainer, "viewer-key-file", ViewerKeyPath())
+	SetContainerSessionRecording(&websockifyContainer, environment.SessionRecording)
 	SetContainerReadinessHTTPProbe(&websockifyContainer, GUIPortName, HealthzEndpoint)
 	return websockifyContainer
 }
 
+// SetContainerSessionRecording configures the websockify container to record the remote desktop
+// sessions, either uploading them once completed (default) or in the persistent volume of the instance.
+func SetContainerSessionRecording(c *corev1.Container, recording *clv1alpha2.SessionRecording) {
+	if recording == nil {
+		return
+	}
+
+	if recording.Destination == clv1alpha2.RecordingDestinationPersistentVolume {
+		AddContainerVolumeMount(c, PersistentVolumeName, PersistentDefaultMountPath)
+		AddContainerArg(c, "recording-dir", filepath.Join(PersistentDefaultMountPath, RecordingsDirName))
+		return
+	}
+
+	AddContainerArg(c, "recording-dir", RecordingsTemporaryPath)
+	AddContainerArg(c, "recording-upload-url", recording.UploadURL)
+}
+
 // XVncContainer forges the sidecar container which holds the desktop environment through a X+VNC server.
 func XVncContainer(opts *ContainerEnvOpts) corev1.Container {
 	xVncContainer := GenericContainer(XVncName, fmt.Sprintf("%s:%s", opts.XVncImg, opts.ImagesTag))
@@ -450,6 +475,10 @@ func ContainerVolumes(instance *clv1alpha2.Instance, environment *clv1alpha2.Env
 		vols = append(vols, NFSVolume(mountInfo))
 	}
 
+	if ViewerSharingSupported(environment) {
+		vols = append(vols, ViewerVolume(instance))
+	}
+
 	return vols
 }
 
@@ -409,6 +409,9 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 			forge.SetContainerReadinessHTTPProbe(&expected, "gui", forge.HealthzEndpoint)
 			Expect(actual.ReadinessProbe).To(Equal(expected.ReadinessProbe))
 		})
+		It("Should mount the key to validate the share links", func() {
+			Expect(actual.VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: forge.ViewerVolumeName, MountPath: forge.ViewerKeyMountPath}))
+		})
 		It("Should set the env varibles", func() {
 			expected.Name = forge.WebsockifyName
 			forge.AddEnvVariableFromFieldToContainer(&expected, forge.PodNameEnvName, "metadata.name")
@@ -451,6 +454,7 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 					fmt.Sprintf("--pod-name=$(%s)", forge.PodNameEnvName),
 					fmt.Sprintf("--cpu-limit=$(%s)", forge.AppCPULimitsEnvName),
 					fmt.Sprintf("--memory-limit=$(%s)", forge.AppMEMLimitsEnvName),
+					fmt.Sprintf("--viewer-key-file=%s", forge.ViewerKeyPath()),
 				}))
 			})
 		})
@@ -470,9 +474,49 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 					fmt.Sprintf("--pod-name=$(%s)", forge.PodNameEnvName),
 					fmt.Sprintf("--cpu-limit=$(%s)", forge.AppCPULimitsEnvName),
 					fmt.Sprintf("--memory-limit=$(%s)", forge.AppMEMLimitsEnvName),
+					fmt.Sprintf("--viewer-key-file=%s", forge.ViewerKeyPath()),
 				}))
 			})
 		})
+
+		When("the session recording is enabled with the PersistentVolume destination", func() {
+			BeforeEach(func() {
+				environment.SessionRecording = &clv1alpha2.SessionRecording{Destination: clv1alpha2.RecordingDestinationPersistentVolume}
+			})
+			It("Should store the recordings in the persistent volume", func() {
+				Expect(actual.Args).To(ContainElement(fmt.Sprintf("--recording-dir=%s/%s", forge.PersistentDefaultMountPath, forge.RecordingsDirName)))
+				Expect(actual.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: forge.PersistentVolumeName, MountPath: forge.PersistentDefaultMountPath}))
+			})
+		})
+
+		When("the session recording is enabled with the default destination", func() {
+			BeforeEach(func() {
+				environment.SessionRecording = &clv1alpha2.SessionRecording{UploadURL: "https://recordings.example.com/upload"}
+			})
+			It("Should upload the recordings to the given URL", func() {
+				Expect(actual.Args).To(ContainElements(
+					fmt.Sprintf("--recording-dir=%s", forge.RecordingsTemporaryPath),
+					"--recording-upload-url=https://recordings.example.com/upload",
+				))
+				Expect(actual.VolumeMounts).NotTo(ContainElement(HaveField("Name", forge.PersistentVolumeName)))
+			})
+		})
+
+		When("the session recording is enabled with the Upload destination", func() {
+			BeforeEach(func() {
+				environment.SessionRecording = &clv1alpha2.SessionRecording{
+					Destination: clv1alpha2.RecordingDestinationUpload,
+					UploadURL:   "https://recordings.example.com/upload",
+				}
+			})
+			It("Should upload the recordings to the given URL", func() {
+				Expect(actual.Args).To(ContainElements(
+					fmt.Sprintf("--recording-dir=%s", forge.RecordingsTemporaryPath),
+					"--recording-upload-url=https://recordings.example.com/upload",
+				))
+				Expect(actual.VolumeMounts).NotTo(ContainElement(HaveField("Name", forge.PersistentVolumeName)))
+			})
+		})
 	})
 
 	Describe("The forge.XVncContainer function forges a x-vnc sidecar container", func() {
@@ -1014,6 +1058,8 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 
 		type ContainerVolumesCase struct {
 			Persistent          bool
+			EnvironmentType     clv1alpha2.EnvironmentType
+			GuiEnabled          bool
 			MountPersonalVolume bool
 			MountInfos          []forge.NFSVolumeMountInfo
 			Mode                clv1alpha2.EnvironmentMode
@@ -1028,6 +1074,8 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 					environment.ContainerStartupOptions = c.StartupOpts
 					environment.Mode = c.Mode
 					environment.MountMyDriveVolume = c.MountPersonalVolume
+					environment.EnvironmentType = c.EnvironmentType
+					environment.GuiEnabled = c.GuiEnabled
 				})
 
 				JustBeforeEach(func() {
@@ -1097,6 +1145,21 @@ var _ = Describe("Containers and Deployment spec forging", func() {
 			},
 		}))
 
+		When("the environment is a graphical container", WhenBody(ContainerVolumesCase{
+			EnvironmentType: clv1alpha2.ClassContainer,
+			GuiEnabled:      true,
+			ExpectedOutputVSs: func(e *clv1alpha2.Environment) []corev1.Volume {
+				return []corev1.Volume{forge.ContainerVolume(forge.PersistentVolumeName, instanceName, e), forge.ViewerVolume(&instance)}
+			},
+		}))
+
+		When("the environment is a container without GUI", WhenBody(ContainerVolumesCase{
+			EnvironmentType: clv1alpha2.ClassContainer,
+			ExpectedOutputVSs: func(e *clv1alpha2.Environment) []corev1.Volume {
+				return []corev1.Volume{forge.ContainerVolume(forge.PersistentVolumeName, instanceName, e)}
+			},
+		}))
+
 		When("the environment has the mount personal volume option", WhenBody(ContainerVolumesCase{
 			MountPersonalVolume: true,
 			MountInfos: []forge.NFSVolumeMountInfo{
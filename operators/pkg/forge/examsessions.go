@@ -0,0 +1,130 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"time"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ExamSessionNameLabel -> the label identifying the name of the ExamSession an Instance belongs to.
+	ExamSessionNameLabel = "crownlabs.polito.it/examsession-name"
+	// ExamSessionNamespaceLabel -> the label identifying the namespace of the ExamSession an Instance belongs to.
+	ExamSessionNamespaceLabel = "crownlabs.polito.it/examsession-namespace"
+	// ExamSessionStageAnnotation -> the annotation tracking the last stage of the ExamSession applied to an Instance,
+	// so that it is not applied again (e.g., if the student stops the Instance before the deadline).
+	ExamSessionStageAnnotation = "crownlabs.polito.it/examsession-stage"
+
+	// ExamSessionStageStarted -> the Instance has been set running at the start of the ExamSession.
+	ExamSessionStageStarted = "started"
+	// ExamSessionStageTerminated -> the Instance has been terminated at the deadline of the ExamSession.
+	ExamSessionStageTerminated = "terminated"
+)
+
+// ExamSessionPhaseAt returns the phase of the given ExamSession at the given time, depending on its schedule,
+// along with the time remaining until the next phase (zero if the ExamSession is completed).
+func ExamSessionPhaseAt(session *clv1alpha2.ExamSession, now time.Time) (clv1alpha2.ExamSessionPhase, time.Duration) {
+	preparation := session.Spec.StartTime.Add(-session.Spec.PreparationLeadTime.Duration)
+
+	switch {
+	case now.Before(preparation):
+		return clv1alpha2.ExamSessionPhaseScheduled, preparation.Sub(now)
+	case now.Before(session.Spec.StartTime.Time):
+		return clv1alpha2.ExamSessionPhasePreparing, session.Spec.StartTime.Sub(now)
+	case now.Before(session.Spec.EndTime.Time):
+		return clv1alpha2.ExamSessionPhaseRunning, session.Spec.EndTime.Sub(now)
+	default:
+		return clv1alpha2.ExamSessionPhaseCompleted, 0
+	}
+}
+
+// ExamSessionTemplateRef returns the reference to the given Template of the ExamSession, defaulting its namespace.
+func ExamSessionTemplateRef(session *clv1alpha2.ExamSession, ref clv1alpha2.GenericRef) clv1alpha2.GenericRef {
+	if ref.Namespace == "" {
+		ref.Namespace = session.Namespace
+	}
+	return ref
+}
+
+// ExamSessionInstanceName returns the name of the Instance of the given Template, created for each student of the ExamSession.
+func ExamSessionInstanceName(session *clv1alpha2.ExamSession, template string) string {
+	return fmt.Sprintf("%s-%s", session.Name, template)
+}
+
+// ExamSessionInstanceLabels returns the labels identifying the Instances belonging to the given ExamSession.
+func ExamSessionInstanceLabels(labels map[string]string, session *clv1alpha2.ExamSession) map[string]string {
+	labels = deepCopyLabels(labels)
+	labels[ExamSessionNameLabel] = session.Name
+	labels[ExamSessionNamespaceLabel] = session.Namespace
+	return labels
+}
+
+// IsExamSessionInstance returns whether the given Instance belongs to the given ExamSession.
+func IsExamSessionInstance(instance *clv1alpha2.Instance, session *clv1alpha2.ExamSession) bool {
+	labels := instance.GetLabels()
+	return labels[ExamSessionNameLabel] == session.Name && labels[ExamSessionNamespaceLabel] == session.Namespace
+}
+
+// ExamSessionInstanceSpec returns the specification of the Instance of the given Template, created for a student of the ExamSession.
+func ExamSessionInstanceSpec(session *clv1alpha2.ExamSession, template clv1alpha2.GenericRef, tenant string, running bool) clv1alpha2.InstanceSpec {
+	prettyName := session.Spec.PrettyName
+	if prettyName == "" {
+		prettyName = session.Name
+	}
+
+	spec := clv1alpha2.InstanceSpec{
+		Template:   ExamSessionTemplateRef(session, template),
+		Tenant:     clv1alpha2.GenericRef{Name: tenant},
+		Running:    running,
+		PrettyName: fmt.Sprintf("%s (%s)", prettyName, template.Name),
+	}
+	if session.Spec.CustomizationUrls != nil {
+		spec.CustomizationUrls = session.Spec.CustomizationUrls.DeepCopy()
+	}
+	return spec
+}
+
+// ExamSessionInstanceStatus returns the status of the given Instance, as reported by the ExamSession.
+// The deadline of the ExamSession is reported as termination time, unless the Instance expires earlier.
+func ExamSessionInstanceStatus(session *clv1alpha2.ExamSession, instance *clv1alpha2.Instance) clv1alpha2.ExamSessionInstanceStatus {
+	status := clv1alpha2.ExamSessionInstanceStatus{
+		Name:       instance.Name,
+		Namespace:  instance.Namespace,
+		Template:   instance.Spec.Template.Name,
+		Phase:      instance.Status.Phase,
+		URL:        instance.Status.URL,
+		Automation: instance.Status.Automation,
+	}
+	if status.Automation.TerminationTime.IsZero() || session.Spec.EndTime.Before(&status.Automation.TerminationTime) {
+		status.Automation.TerminationTime = session.Spec.EndTime
+	}
+	return status
+}
+
+// ExamSessionStudentReady returns whether all the Instances of the given student are ready.
+func ExamSessionStudentReady(student *clv1alpha2.ExamSessionStudentStatus, templates int) bool {
+	if student.Error != "" || len(student.Instances) != templates {
+		return false
+	}
+	for i := range student.Instances {
+		if student.Instances[i].Phase != clv1alpha2.EnvironmentPhaseReady {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("ExamSession forging", func() {
+	var (
+		session clv1alpha2.ExamSession
+		start   time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Date(2026, 1, 13, 9, 0, 0, 0, time.UTC)
+		session = clv1alpha2.ExamSession{
+			ObjectMeta: metav1.ObjectMeta{Name: "networks-jan", Namespace: "workspace-netgroup"},
+			Spec: clv1alpha2.ExamSessionSpec{
+				Templates:           []clv1alpha2.GenericRef{{Name: "desktop"}},
+				StartTime:           metav1.NewTime(start),
+				EndTime:             metav1.NewTime(start.Add(2 * time.Hour)),
+				PreparationLeadTime: metav1.Duration{Duration: 10 * time.Minute},
+			},
+		}
+	})
+
+	Describe("The forge.ExamSessionPhaseAt function", func() {
+		type PhaseAtCase struct {
+			Offset          time.Duration
+			ExpectedPhase   clv1alpha2.ExamSessionPhase
+			ExpectedRequeue time.Duration
+		}
+
+		DescribeTable("Correctly returns the expected phase and requeue time",
+			func(c PhaseAtCase) {
+				phase, requeue := forge.ExamSessionPhaseAt(&session, start.Add(c.Offset))
+				Expect(phase).To(Equal(c.ExpectedPhase))
+				Expect(requeue).To(Equal(c.ExpectedRequeue))
+			},
+			Entry("Before the preparation", PhaseAtCase{
+				Offset: -time.Hour, ExpectedPhase: clv1alpha2.ExamSessionPhaseScheduled, ExpectedRequeue: 50 * time.Minute,
+			}),
+			Entry("During the preparation", PhaseAtCase{
+				Offset: -10 * time.Minute, ExpectedPhase: clv1alpha2.ExamSessionPhasePreparing, ExpectedRequeue: 10 * time.Minute,
+			}),
+			Entry("At the start time", PhaseAtCase{
+				Offset: 0, ExpectedPhase: clv1alpha2.ExamSessionPhaseRunning, ExpectedRequeue: 2 * time.Hour,
+			}),
+			Entry("After the deadline", PhaseAtCase{
+				Offset: 3 * time.Hour, ExpectedPhase: clv1alpha2.ExamSessionPhaseCompleted, ExpectedRequeue: 0,
+			}),
+		)
+	})
+
+	Describe("The forge.ExamSessionInstanceSpec function", func() {
+		It("Should refer to the template in the namespace of the session, if not specified", func() {
+			spec := forge.ExamSessionInstanceSpec(&session, session.Spec.Templates[0], "tester", false)
+			Expect(spec.Template).To(Equal(clv1alpha2.GenericRef{Name: "desktop", Namespace: "workspace-netgroup"}))
+			Expect(spec.Tenant).To(Equal(clv1alpha2.GenericRef{Name: "tester"}))
+			Expect(spec.Running).To(BeFalse())
+			Expect(spec.PrettyName).To(Equal("networks-jan (desktop)"))
+			Expect(spec.CustomizationUrls).To(BeNil())
+		})
+
+		It("Should configure the customization urls of the session", func() {
+			session.Spec.CustomizationUrls = &clv1alpha2.InstanceCustomizationUrls{ContentDestination: "https://example.com/upload"}
+			session.Spec.Templates[0].Namespace = "workspace-other"
+
+			spec := forge.ExamSessionInstanceSpec(&session, session.Spec.Templates[0], "tester", true)
+			Expect(spec.Template.Namespace).To(Equal("workspace-other"))
+			Expect(spec.Running).To(BeTrue())
+			Expect(spec.CustomizationUrls).To(Equal(session.Spec.CustomizationUrls))
+			Expect(spec.CustomizationUrls).ToNot(BeIdenticalTo(session.Spec.CustomizationUrls))
+		})
+	})
+
+	Describe("The forge.ExamSessionInstanceLabels and forge.IsExamSessionInstance functions", func() {
+		It("Should identify the instances of the session", func() {
+			instance := clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}}}
+			Expect(forge.IsExamSessionInstance(&instance, &session)).To(BeFalse())
+
+			instance.SetLabels(forge.ExamSessionInstanceLabels(instance.GetLabels(), &session))
+			Expect(instance.GetLabels()).To(HaveKeyWithValue("foo", "bar"))
+			Expect(forge.IsExamSessionInstance(&instance, &session)).To(BeTrue())
+
+			other := session.DeepCopy()
+			other.Namespace = "workspace-other"
+			Expect(forge.IsExamSessionInstance(&instance, other)).To(BeFalse())
+		})
+	})
+
+	Describe("The forge.ExamSessionInstanceStatus function", func() {
+		var instance clv1alpha2.Instance
+
+		BeforeEach(func() {
+			instance = clv1alpha2.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "networks-jan-desktop", Namespace: "tenant-tester"},
+				Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: "desktop", Namespace: "workspace-netgroup"}},
+				Status:     clv1alpha2.InstanceStatus{Phase: clv1alpha2.EnvironmentPhaseReady},
+			}
+		})
+
+		It("Should report the deadline of the session as termination time", func() {
+			status := forge.ExamSessionInstanceStatus(&session, &instance)
+			Expect(status.Name).To(Equal("networks-jan-desktop"))
+			Expect(status.Template).To(Equal("desktop"))
+			Expect(status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseReady))
+			Expect(status.Automation.TerminationTime).To(Equal(session.Spec.EndTime))
+			Expect(instance.Status.Automation.TerminationTime.IsZero()).To(BeTrue())
+		})
+
+		It("Should report the termination time of the instance, if earlier than the deadline", func() {
+			instance.Status.Automation.TerminationTime = metav1.NewTime(start.Add(time.Hour))
+			Expect(forge.ExamSessionInstanceStatus(&session, &instance).Automation.TerminationTime).To(Equal(instance.Status.Automation.TerminationTime))
+		})
+	})
+
+	Describe("The forge.ExamSessionStudentReady function", func() {
+		var student clv1alpha2.ExamSessionStudentStatus
+
+		BeforeEach(func() {
+			student = clv1alpha2.ExamSessionStudentStatus{Tenant: "tester", Instances: []clv1alpha2.ExamSessionInstanceStatus{
+				{Name: "networks-jan-desktop", Phase: clv1alpha2.EnvironmentPhaseReady},
+			}}
+		})
+
+		It("Should return true if all the instances are ready", func() {
+			Expect(forge.ExamSessionStudentReady(&student, 1)).To(BeTrue())
+		})
+
+		It("Should return false if an instance is missing", func() {
+			Expect(forge.ExamSessionStudentReady(&student, 2)).To(BeFalse())
+		})
+
+		It("Should return false if an instance is not ready", func() {
+			student.Instances[0].Phase = clv1alpha2.EnvironmentPhaseStarting
+			Expect(forge.ExamSessionStudentReady(&student, 1)).To(BeFalse())
+		})
+
+		It("Should return false in case of errors", func() {
+			student.Error = "tenant not found"
+			Expect(forge.ExamSessionStudentReady(&student, 1)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,210 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// GradingCheckerName -> name of the grading checker initcontainer.
+	GradingCheckerName = "grading-checker"
+	// GradingReporterName -> name of the container publishing the grading report through its logs.
+	GradingReporterName = "grading-reporter"
+	// GradingKubeconfigVolumeName -> name of the volume holding the kubeconfig of the workload cluster.
+	GradingKubeconfigVolumeName = "grading-kubeconfig"
+	// GradingKubeconfigMountPath -> mount path of the volume holding the kubeconfig of the workload cluster.
+	GradingKubeconfigMountPath = "/etc/crownlabs/grading"
+	// GradingKubeconfigKey -> key of the grading secret holding the kubeconfig of the workload cluster.
+	GradingKubeconfigKey = "kubeconfig"
+	// GradingResultsFileName -> name of the file the checker is expected to write the report to.
+	GradingResultsFileName = "grading.json"
+	// GradingServiceAccountName -> name of the service account used by the checker in the workload cluster.
+	GradingServiceAccountName = "crownlabs-grader"
+	// GradingServiceAccountNamespace -> namespace of the service account used by the checker in the workload cluster.
+	GradingServiceAccountNamespace = "kube-system"
+	// GradingClusterRoleName -> name of the (read-only) cluster role granted to the checker in the workload cluster.
+	GradingClusterRoleName = "view"
+	// GradingTokenExpirationMarginSeconds -> margin added to the grading timeout to compute the validity of the checker token.
+	GradingTokenExpirationMarginSeconds = 600
+	// GradingReportMaxBytes -> the maximum size of the grading report, as it is stored in the status of the instance.
+	GradingReportMaxBytes = 256 * 1024
+)
+
+// GradingReport is the report the checker is expected to write, in JSON format, to the GRADING_RESULTS_PATH file.
+type GradingReport struct {
+	Checks []clv1alpha2.GradingCheckResult `json:"checks"`
+}
+
+// GradingRequired returns whether the given environment defines automated grading checks.
+func GradingRequired(environment *clv1alpha2.Environment) bool {
+	return environment.EnvironmentType == clv1alpha2.ClassCluster && environment.Cluster != nil && environment.Cluster.Grading != nil
+}
+
+// GradingKubeconfigSecretName returns the name of the secret holding the kubeconfig used by the checker.
+func GradingKubeconfigSecretName(instance *clv1alpha2.Instance) string {
+	return fmt.Sprintf("%s-grading-kubeconfig", instance.Name)
+}
+
+// GradingTokenExpirationSeconds returns the validity of the token granted to the checker, based on the grading timeout.
+func GradingTokenExpirationSeconds(grading *clv1alpha2.ClusterGrading) int64 {
+	return grading.TimeoutSeconds + GradingTokenExpirationMarginSeconds
+}
+
+// GradingResultsPath returns the path of the file the checker is expected to write the report to.
+func GradingResultsPath() string {
+	return filepath.Join(PersistentDefaultMountPath, GradingResultsFileName)
+}
+
+// GradingJobSpec forges the specification of the job running the checker against the workload cluster of the instance.
+// The report is published through the logs of the reporter container (as the termination message would be truncated
+// to 4KB) and, if a content destination is configured, it is also uploaded there.
+func GradingJobSpec(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, opts *ContainerEnvOpts) batchv1.JobSpec {
+	grading := environment.Cluster.Grading
+
+	containers := []corev1.Container{GradingReporterContainer(opts)}
+	if instance.Spec.CustomizationUrls != nil && instance.Spec.CustomizationUrls.ContentDestination != "" {
+		containers = append(containers, ContentUploaderJobContainer(instance.Spec.CustomizationUrls.ContentDestination,
+			fmt.Sprintf("%s-grading", instance.Name), opts))
+	}
+
+	spec := batchv1.JobSpec{
+		BackoffLimit:            ptr.To[int32](0),
+		TTLSecondsAfterFinished: ptr.To[int32](SubmissionJobTTLSeconds),
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{GradingCheckerContainer(grading)},
+				Containers:     containers,
+				Volumes: []corev1.Volume{
+					{Name: PersistentVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					{Name: GradingKubeconfigVolumeName, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+						SecretName: GradingKubeconfigSecretName(instance),
+					}}},
+				},
+				SecurityContext:              PodSecurityContext(),
+				AutomountServiceAccountToken: ptr.To(false),
+				EnableServiceLinks:           ptr.To(false),
+				RestartPolicy:                corev1.RestartPolicyNever,
+			},
+		},
+	}
+
+	if grading.TimeoutSeconds > 0 {
+		spec.ActiveDeadlineSeconds = ptr.To(grading.TimeoutSeconds)
+	}
+	return spec
+}
+
+// GradingCheckerContainer forges the container running the checks defined by the template.
+func GradingCheckerContainer(grading *clv1alpha2.ClusterGrading) corev1.Container {
+	checker := GenericContainer(GradingCheckerName, grading.Image)
+	SetContainerResources(&checker, 0.1, 1, 128, 512)
+	checker.Command, checker.Args = grading.Command, grading.Args
+	if grading.Script != "" {
+		checker.Command, checker.Args = []string{"/bin/sh", "-c", grading.Script}, nil
+	}
+
+	AddContainerVolumeMount(&checker, PersistentVolumeName, PersistentDefaultMountPath)
+	AddContainerVolumeMount(&checker, GradingKubeconfigVolumeName, GradingKubeconfigMountPath)
+	AddEnvVariableToContainer(&checker, "KUBECONFIG", filepath.Join(GradingKubeconfigMountPath, GradingKubeconfigKey))
+	AddEnvVariableToContainer(&checker, "GRADING_RESULTS_PATH", GradingResultsPath())
+	return checker
+}
+
+// GradingReporterContainer forges the container publishing the report written by the checker through its logs.
+func GradingReporterContainer(opts *ContainerEnvOpts) corev1.Container {
+	reporter := GenericContainer(GradingReporterName, fmt.Sprintf("%s:%s", opts.ContentUploaderImg, opts.ImagesTag))
+	SetContainerResources(&reporter, 0.01, 0.1, 16, 64)
+	reporter.Command = []string{"cat", GradingResultsPath()}
+	AddContainerVolumeMount(&reporter, PersistentVolumeName, PersistentDefaultMountPath)
+	return reporter
+}
+
+// GradingServiceAccount forges the service account used by the checker in the workload cluster.
+func GradingServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: GradingServiceAccountName, Namespace: GradingServiceAccountNamespace},
+	}
+}
+
+// GradingClusterRoleBinding forges the binding granting read-only access to the checker in the workload cluster.
+func GradingClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: GradingServiceAccountName},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: GradingClusterRoleName},
+		Subjects: []rbacv1.Subject{{
+			Kind: rbacv1.ServiceAccountKind, Name: GradingServiceAccountName, Namespace: GradingServiceAccountNamespace,
+		}},
+	}
+}
+
+// GradingKubeconfig forges the kubeconfig granting the checker access to the workload cluster, with the given token.
+func GradingKubeconfig(server string, caData []byte, token string) ([]byte, error) {
+	const name = "workload-cluster"
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: caData}
+	config.AuthInfos[GradingServiceAccountName] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: GradingServiceAccountName}
+	config.CurrentContext = name
+	return clientcmd.Write(*config)
+}
+
+// ParseGradingReport parses the report written by the checker.
+func ParseGradingReport(report string) ([]clv1alpha2.GradingCheckResult, error) {
+	if report == "" {
+		return nil, errors.New("empty grading report")
+	}
+	if len(report) > GradingReportMaxBytes {
+		return nil, fmt.Errorf("the grading report exceeds the maximum size of %d bytes", GradingReportMaxBytes)
+	}
+
+	var parsed GradingReport
+	if err := json.Unmarshal([]byte(report), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse the grading report: %w", err)
+	}
+	return parsed.Checks, nil
+}
+
+// GradingStatus forges the grading status given the results of the checks.
+func GradingStatus(checks []clv1alpha2.GradingCheckResult) *clv1alpha2.InstanceGradingStatus {
+	status := &clv1alpha2.InstanceGradingStatus{Outcome: clv1alpha2.GradingPassed, Total: len(checks), Checks: checks}
+	for i := range checks {
+		if checks[i].Passed {
+			status.Passed++
+		}
+	}
+	if status.Passed < status.Total {
+		status.Outcome = clv1alpha2.GradingFailed
+	}
+	return status
+}
+
+// GradingErrorStatus forges the grading status in case the checks could not be completed.
+func GradingErrorStatus(message string) *clv1alpha2.InstanceGradingStatus {
+	return &clv1alpha2.InstanceGradingStatus{Outcome: clv1alpha2.GradingError, Message: message}
+}
@@ -0,0 +1,154 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Grading forging", func() {
+	var (
+		instance    clv1alpha2.Instance
+		environment clv1alpha2.Environment
+		opts        forge.ContainerEnvOpts
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: "tenant-tester"}}
+		environment = clv1alpha2.Environment{
+			EnvironmentType: clv1alpha2.ClassCluster,
+			Cluster: &clv1alpha2.ClusterTemplate{Grading: &clv1alpha2.ClusterGrading{
+				Image:          "internal/registry/checker:v1.0",
+				Args:           []string{"--deployment", "nginx"},
+				TimeoutSeconds: 300,
+			}},
+		}
+		opts = forge.ContainerEnvOpts{ContentUploaderImg: "uploader", ImagesTag: "v0.1"}
+	})
+
+	Describe("The forge.GradingRequired function", func() {
+		It("Should return true for cluster environments with grading enabled", func() {
+			Expect(forge.GradingRequired(&environment)).To(BeTrue())
+		})
+
+		It("Should return false if grading is not configured", func() {
+			environment.Cluster.Grading = nil
+			Expect(forge.GradingRequired(&environment)).To(BeFalse())
+		})
+
+		It("Should return false for non cluster environments", func() {
+			environment.EnvironmentType = clv1alpha2.ClassContainer
+			Expect(forge.GradingRequired(&environment)).To(BeFalse())
+		})
+	})
+
+	Describe("The forge.GradingJobSpec function", func() {
+		It("Should configure the checker with the kubeconfig and the results path", func() {
+			spec := forge.GradingJobSpec(&instance, &environment, &opts)
+			Expect(*spec.ActiveDeadlineSeconds).To(BeNumerically("==", 300))
+			Expect(spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+			Expect(spec.Template.Spec.InitContainers).To(HaveLen(1))
+
+			checker := spec.Template.Spec.InitContainers[0]
+			Expect(checker.Image).To(Equal("internal/registry/checker:v1.0"))
+			Expect(checker.Args).To(Equal([]string{"--deployment", "nginx"}))
+			Expect(checker.Env).To(ContainElements(
+				corev1.EnvVar{Name: "KUBECONFIG", Value: "/etc/crownlabs/grading/kubeconfig"},
+				corev1.EnvVar{Name: "GRADING_RESULTS_PATH", Value: "/media/data/grading.json"},
+			))
+			Expect(spec.Template.Spec.Volumes).To(ContainElement(HaveField("VolumeSource.Secret.SecretName", "kubernetes-0000-grading-kubeconfig")))
+		})
+
+		It("Should execute the inline script, if specified", func() {
+			environment.Cluster.Grading.Script = "kubectl get deployment nginx"
+			checker := forge.GradingJobSpec(&instance, &environment, &opts).Template.Spec.InitContainers[0]
+			Expect(checker.Command).To(Equal([]string{"/bin/sh", "-c", "kubectl get deployment nginx"}))
+			Expect(checker.Args).To(BeEmpty())
+		})
+
+		When("no content destination is configured", func() {
+			It("Should include only the reporter container", func() {
+				containers := forge.GradingJobSpec(&instance, &environment, &opts).Template.Spec.Containers
+				Expect(containers).To(HaveLen(1))
+				Expect(containers[0].Name).To(Equal(forge.GradingReporterName))
+				Expect(containers[0].Image).To(Equal("uploader:v0.1"))
+			})
+		})
+
+		When("a content destination is configured", func() {
+			BeforeEach(func() {
+				instance.Spec.CustomizationUrls = &clv1alpha2.InstanceCustomizationUrls{ContentDestination: "https://example.com/upload"}
+			})
+
+			It("Should upload the report to the content destination", func() {
+				containers := forge.GradingJobSpec(&instance, &environment, &opts).Template.Spec.Containers
+				Expect(containers).To(HaveLen(2))
+				Expect(containers[1]).To(Equal(forge.ContentUploaderJobContainer("https://example.com/upload", "kubernetes-0000-grading", &opts)))
+			})
+		})
+	})
+
+	Describe("The forge.GradingKubeconfig function", func() {
+		It("Should forge a kubeconfig authenticating with the given token", func() {
+			raw, err := forge.GradingKubeconfig("https://10.0.0.1:6443", []byte("ca"), "token")
+			Expect(err).ToNot(HaveOccurred())
+
+			config, err := clientcmd.RESTConfigFromKubeConfig(raw)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Host).To(Equal("https://10.0.0.1:6443"))
+			Expect(config.CAData).To(Equal([]byte("ca")))
+			Expect(config.BearerToken).To(Equal("token"))
+			Expect(config.CertData).To(BeEmpty())
+		})
+	})
+
+	Describe("The forge.ParseGradingReport and forge.GradingStatus functions", func() {
+		It("Should report the checks outcome", func() {
+			checks, err := forge.ParseGradingReport(`{"checks": [{"name": "replicas", "passed": true}, {"name": "netpol", "passed": false, "message": "traffic allowed"}]}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			status := forge.GradingStatus(checks)
+			Expect(status.Outcome).To(Equal(clv1alpha2.GradingFailed))
+			Expect(status.Passed).To(Equal(1))
+			Expect(status.Total).To(Equal(2))
+			Expect(status.Checks[1]).To(Equal(clv1alpha2.GradingCheckResult{Name: "netpol", Message: "traffic allowed"}))
+		})
+
+		It("Should report success if all checks passed", func() {
+			Expect(forge.GradingStatus([]clv1alpha2.GradingCheckResult{{Name: "replicas", Passed: true}}).Outcome).To(Equal(clv1alpha2.GradingPassed))
+		})
+
+		It("Should fail if the report is empty or malformed", func() {
+			_, err := forge.ParseGradingReport("")
+			Expect(err).To(HaveOccurred())
+			_, err = forge.ParseGradingReport("checks: []")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should fail if the report exceeds the maximum size", func() {
+			_, err := forge.ParseGradingReport(`{"checks": [], "padding": "` + strings.Repeat("x", forge.GradingReportMaxBytes) + `"}`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
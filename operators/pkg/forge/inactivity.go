@@ -0,0 +1,61 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"fmt"
+	"time"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ActivityEndpointPath -> the path of the endpoint exposing the activity of graphical instances.
+	ActivityEndpointPath = "/activity"
+)
+
+// IdleDetectionSupported returns whether the inactivity of instances running the given environment can be detected.
+func IdleDetectionSupported(environment *clv1alpha2.Environment) bool {
+	return environment.EnvironmentType == clv1alpha2.ClassContainer && environment.GuiEnabled
+}
+
+// InstanceIdlePolicy returns the idle policy of the given template applying to the given environment, if any.
+func InstanceIdlePolicy(template *clv1alpha2.TemplateSpec, environment *clv1alpha2.Environment) *clv1alpha2.IdlePolicy {
+	mode := environment.Mode
+	if mode == "" {
+		mode = clv1alpha2.ModeStandard
+	}
+
+	for i := range template.IdlePolicies {
+		if template.IdlePolicies[i].Mode == mode {
+			return &template.IdlePolicies[i]
+		}
+	}
+	return nil
+}
+
+// InstanceActivityURL returns the URL of the endpoint exposing the activity of the given instance.
+func InstanceActivityURL(instance *clv1alpha2.Instance) string {
+	service := NamespacedName(instance)
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d%s", service.Name, service.Namespace, MetricsPortNumber, ActivityEndpointPath)
+}
+
+// InstanceIdleSince returns the time since which the instance is considered idle according to the given policy.
+func InstanceIdleSince(policy *clv1alpha2.IdlePolicy, lastActivity, lastInput time.Time) time.Time {
+	if policy.InputOnly {
+		return lastInput
+	}
+	return lastActivity
+}
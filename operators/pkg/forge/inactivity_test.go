@@ -0,0 +1,99 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Inactivity forging", func() {
+	var (
+		template    clv1alpha2.TemplateSpec
+		environment clv1alpha2.Environment
+	)
+
+	BeforeEach(func() {
+		template = clv1alpha2.TemplateSpec{
+			IdlePolicies: []clv1alpha2.IdlePolicy{
+				{Mode: clv1alpha2.ModeStandard, Timeout: metav1.Duration{Duration: 2 * time.Hour}},
+				{Mode: clv1alpha2.ModeExam, Timeout: metav1.Duration{Duration: 15 * time.Minute}, InputOnly: true},
+			},
+		}
+		environment = clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassContainer, GuiEnabled: true, Mode: clv1alpha2.ModeExam}
+	})
+
+	Describe("The forge.IdleDetectionSupported function", func() {
+		It("Should return true for graphical container environments", func() {
+			Expect(forge.IdleDetectionSupported(&environment)).To(BeTrue())
+		})
+
+		It("Should return false for non graphical container environments", func() {
+			environment.GuiEnabled = false
+			Expect(forge.IdleDetectionSupported(&environment)).To(BeFalse())
+		})
+
+		It("Should return false for VM environments", func() {
+			environment.EnvironmentType = clv1alpha2.ClassVM
+			Expect(forge.IdleDetectionSupported(&environment)).To(BeFalse())
+		})
+	})
+
+	Describe("The forge.InstanceIdlePolicy function", func() {
+		It("Should return the policy matching the environment mode", func() {
+			Expect(forge.InstanceIdlePolicy(&template, &environment)).To(HaveValue(Equal(template.IdlePolicies[1])))
+		})
+
+		It("Should consider the standard mode if not specified", func() {
+			environment.Mode = ""
+			Expect(forge.InstanceIdlePolicy(&template, &environment)).To(HaveValue(Equal(template.IdlePolicies[0])))
+		})
+
+		It("Should return nil if no policy matches the environment mode", func() {
+			environment.Mode = clv1alpha2.ModeExercise
+			Expect(forge.InstanceIdlePolicy(&template, &environment)).To(BeNil())
+		})
+	})
+
+	Describe("The forge.InstanceActivityURL function", func() {
+		It("Should return the URL of the activity endpoint exposed through the instance service", func() {
+			instance := clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes.0000", Namespace: "tenant-tester"}}
+			Expect(forge.InstanceActivityURL(&instance)).To(Equal("http://kubernetes-0000.tenant-tester.svc.cluster.local:9090/activity"))
+		})
+	})
+
+	Describe("The forge.InstanceIdleSince function", func() {
+		var lastActivity, lastInput time.Time
+
+		BeforeEach(func() {
+			lastActivity = time.Now()
+			lastInput = lastActivity.Add(-time.Hour)
+		})
+
+		It("Should consider the last activity by default", func() {
+			Expect(forge.InstanceIdleSince(&template.IdlePolicies[0], lastActivity, lastInput)).To(Equal(lastActivity))
+		})
+
+		It("Should consider the last input if configured", func() {
+			Expect(forge.InstanceIdleSince(&template.IdlePolicies[1], lastActivity, lastInput)).To(Equal(lastInput))
+		})
+	})
+})
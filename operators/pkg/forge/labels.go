@@ -33,6 +33,8 @@ const (
 	labelVolumeTypeKey   = "crownlabs.polito.it/volume-type"
 	labelNodeSelectorKey = "crownlabs.polito.it/has-node-selector"
 
+	labelClusterCredentialsKey = "crownlabs.polito.it/cluster-credentials"
+
 	// InstanceTerminationSelectorLabel -> label for Instances which have to be be checked for termination.
 	InstanceTerminationSelectorLabel = "crownlabs.polito.it/watch-for-instance-termination"
 	// InstanceSubmissionSelectorLabel -> label for Instances which have to be submitted.
@@ -90,6 +92,14 @@ func InstanceObjectLabels(labels map[string]string, instance *clv1alpha2.Instanc
 	return labels
 }
 
+// ClusterCredentialsLabels receives in input a set of labels and returns the updated set depending on the specified instance,
+// flagging the secret as holding the credentials of the cluster environments (i.e., removed when the cluster is torn down or reset).
+func ClusterCredentialsLabels(labels map[string]string, instance *clv1alpha2.Instance) map[string]string {
+	labels = InstanceObjectLabels(labels, instance)
+	labels[labelClusterCredentialsKey] = strconv.FormatBool(true)
+	return labels
+}
+
 // SandboxObjectLabels receives in input a set of labels and the tenant name, returns the updated set.
 func SandboxObjectLabels(labels map[string]string, name string) map[string]string {
 	labels = deepCopyLabels(labels)
@@ -110,6 +120,22 @@ func InstanceSelectorLabels(instance *clv1alpha2.Instance) map[string]string {
 	}
 }
 
+// ClusterCredentialsSelectorLabels returns a set of selector labels matching the secrets holding the credentials of the
+// cluster environments of the specified instance.
+func ClusterCredentialsSelectorLabels(instance *clv1alpha2.Instance) map[string]string {
+	labels := InstanceSelectorLabels(instance)
+	labels[labelClusterCredentialsKey] = strconv.FormatBool(true)
+	return labels
+}
+
+// TemplateInstancesSelectorLabels returns a set of selector labels matching the instances of the specified template.
+func TemplateInstancesSelectorLabels(template *clv1alpha2.Template) map[string]string {
+	return map[string]string{
+		labelWorkspaceKey: template.Spec.WorkspaceRef.Name,
+		labelTemplateKey:  template.Name,
+	}
+}
+
 // InstanceAutomationLabelsOnTermination returns a set of labels to be set on an instance when it is terminated.
 func InstanceAutomationLabelsOnTermination(labels map[string]string, submissionRequired bool) map[string]string {
 	labels = deepCopyLabels(labels)
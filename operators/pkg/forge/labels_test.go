@@ -436,6 +436,69 @@ var _ = Describe("Labels forging", func() {
 		})
 	})
 
+	Describe("The forge.ClusterCredentialsLabels and forge.ClusterCredentialsSelectorLabels functions", func() {
+		var instance clv1alpha2.Instance
+
+		BeforeEach(func() {
+			instance = clv1alpha2.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+				Spec: clv1alpha2.InstanceSpec{
+					Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+					Tenant:   clv1alpha2.GenericRef{Name: tenantName},
+				},
+			}
+		})
+
+		It("Should flag the secrets as cluster credentials, in addition to the object labels", func() {
+			labels := forge.ClusterCredentialsLabels(map[string]string{"foo": "bar"}, &instance)
+			Expect(labels).To(HaveKeyWithValue("foo", "bar"))
+			Expect(labels).To(HaveKeyWithValue("crownlabs.polito.it/cluster-credentials", "true"))
+			for key, value := range forge.InstanceObjectLabels(nil, &instance) {
+				Expect(labels).To(HaveKeyWithValue(key, value))
+			}
+		})
+
+		It("Should select only the secrets flagged as cluster credentials", func() {
+			selector := forge.ClusterCredentialsSelectorLabels(&instance)
+			Expect(selector).To(HaveKeyWithValue("crownlabs.polito.it/cluster-credentials", "true"))
+			for key, value := range selector {
+				Expect(forge.ClusterCredentialsLabels(nil, &instance)).To(HaveKeyWithValue(key, value))
+				if key != "crownlabs.polito.it/cluster-credentials" {
+					Expect(forge.InstanceObjectLabels(nil, &instance)).To(HaveKeyWithValue(key, value))
+				}
+			}
+		})
+	})
+
+	Describe("The forge.TemplateInstancesSelectorLabels function", func() {
+		var (
+			template clv1alpha2.Template
+			instance clv1alpha2.Instance
+		)
+
+		BeforeEach(func() {
+			template = clv1alpha2.Template{
+				ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+				Spec:       clv1alpha2.TemplateSpec{WorkspaceRef: clv1alpha2.GenericRef{Name: workspaceName}},
+			}
+			instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace}}
+		})
+
+		It("Should have the correct values", func() {
+			Expect(forge.TemplateInstancesSelectorLabels(&template)).To(Equal(map[string]string{
+				"crownlabs.polito.it/workspace": workspaceName,
+				"crownlabs.polito.it/template":  templateName,
+			}))
+		})
+
+		It("Should match the labels of the instances of the template", func() {
+			instanceLabels, _ := forge.InstanceLabels(nil, &template, &instance)
+			for key, value := range forge.TemplateInstancesSelectorLabels(&template) {
+				Expect(instanceLabels).To(HaveKeyWithValue(key, value))
+			}
+		})
+	})
+
 	Describe("The forge.InstanceAutomationLabelsOnTermination function", func() {
 		type AutomationLabelsOnTerminationCase struct {
 			Input                 map[string]string
@@ -0,0 +1,435 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	jws "k8s.io/cluster-bootstrap/token/jws"
+	"k8s.io/utils/ptr"
+	virtv1 "kubevirt.io/api/core/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// NodeClusterPodCIDR is the CIDR of the pod network of VM-based clusters.
+	NodeClusterPodCIDR = "10.244.0.0/16"
+	// NodeClusterServiceCIDR is the CIDR of the service network of VM-based clusters.
+	NodeClusterServiceCIDR = "10.96.0.0/12"
+	// NodeClusterAPIServerPort is the port the API server of VM-based clusters listens on.
+	NodeClusterAPIServerPort = 6443
+
+	// NodeClusterTokenKey is the key of the bootstrap secret holding the kubeadm bootstrap token.
+	NodeClusterTokenKey = "token"
+	// NodeClusterCertificateKeyKey is the key of the bootstrap secret holding the key encrypting the control plane certificates.
+	NodeClusterCertificateKeyKey = "certificate-key"
+	// NodeClusterOperatorTokenKey is the key of the bootstrap secret holding the token used by the instance operator.
+	NodeClusterOperatorTokenKey = "operator-token"
+	// NodeClusterCACertKey is the key of the bootstrap secret holding the certificate of the cluster CA, once discovered.
+	NodeClusterCACertKey = "ca.crt"
+	// NodeClusterKubeconfigKey is the key of the kubeconfig secret holding the admin kubeconfig, consistently with the Cluster API.
+	NodeClusterKubeconfigKey = "value"
+
+	// NodeClusterAdminUser is the name identifying the instance operator in VM-based clusters.
+	NodeClusterAdminUser = "crownlabs-instance-operator"
+	// NodeClusterOperatorGroup is the group the operator token belongs to, which is granted administrative privileges.
+	NodeClusterOperatorGroup = "system:bootstrappers:crownlabs-instance-operator"
+
+	labelClusterNodeKey = "crownlabs.polito.it/cluster-node"
+
+	nodeClusterAdminKubeconfig       = "/etc/kubernetes/admin.conf"
+	nodeClusterTokenAlphabet         = "abcdefghijklmnopqrstuvwxyz0123456789"
+	nodeClusterCNIContainerName      = "cni-installer"
+	nodeClusterKubeconfigVolumeName  = "kubeconfig"
+	nodeClusterKubeconfigMountPath   = "/etc/crownlabs/kubeconfig"
+	nodeClusterCNIJobBackoffLimit    = 3
+	nodeClusterCNIJobDeadlineSeconds = 15 * 60
+)
+
+// IsNodeClusterTemplate returns whether the environments of the given template are the nodes of a VM-based cluster.
+func IsNodeClusterTemplate(template *clv1alpha2.Template) bool {
+	for i := range template.Spec.EnvironmentList {
+		if template.Spec.EnvironmentList[i].IsClusterNode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNodeClusterInitializer returns whether the given node environment initializes the cluster, i.e., it is the control plane node with ordinal 0.
+func IsNodeClusterInitializer(environment *clv1alpha2.Environment) bool {
+	return environment.ClusterRole == clv1alpha2.ClusterNodeControlPlane && environment.Ordinal == 0
+}
+
+// NodeClusterName returns the name of the VM-based cluster the given node environment belongs to.
+func NodeClusterName(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) string {
+	return fmt.Sprintf("%s-%s", instance.Name, environment.ClusterName)
+}
+
+// NodeClusterNodeName returns the name of the VM (and of the corresponding node) backing the given node environment.
+func NodeClusterNodeName(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) string {
+	return fmt.Sprintf("%s-%s-%d", NodeClusterName(instance, environment), strings.ToLower(string(environment.ClusterRole)), environment.Ordinal)
+}
+
+// NodeClusterBootstrapSecretName returns the name of the secret holding the bootstrap material of the given cluster.
+func NodeClusterBootstrapSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-kubeadm", clusterName)
+}
+
+// NodeClusterKubeconfigSecretName returns the name of the secret holding the admin kubeconfig of the given cluster.
+func NodeClusterKubeconfigSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-kubeconfig", clusterName)
+}
+
+// NodeClusterEndpointServiceName returns the name of the service exposing the API server of the given cluster.
+func NodeClusterEndpointServiceName(clusterName string) string {
+	return fmt.Sprintf("%s-api", clusterName)
+}
+
+// NodeClusterEndpoint returns the DNS name of the service exposing the API server of the given cluster.
+func NodeClusterEndpoint(instance *clv1alpha2.Instance, clusterName string) string {
+	return fmt.Sprintf("%s.%s.svc", NodeClusterEndpointServiceName(clusterName), instance.Namespace)
+}
+
+// NodeClusterNodeLabels forges the labels identifying the VM backing a given node of the cluster.
+func NodeClusterNodeLabels(instance *clv1alpha2.Instance, nodeName string) map[string]string {
+	labels := InstanceSelectorLabels(instance)
+	labels[labelClusterNodeKey] = nodeName
+	return labels
+}
+
+// NodeClusterEndpointServiceSpec forges the specification of the service exposing the API server of the
+// cluster, backed by the node initializing it, which is used as control plane endpoint by the other nodes.
+func NodeClusterEndpointServiceSpec(instance *clv1alpha2.Instance, initializer *clv1alpha2.Environment) corev1.ServiceSpec {
+	return corev1.ServiceSpec{
+		Type:     corev1.ServiceTypeClusterIP,
+		Selector: NodeClusterNodeLabels(instance, NodeClusterNodeName(instance, initializer)),
+		Ports: []corev1.ServicePort{{
+			Name:       "api-server",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       NodeClusterAPIServerPort,
+			TargetPort: intstr.FromInt32(NodeClusterAPIServerPort),
+		}},
+	}
+}
+
+// NodeClusterVirtualMachineSpec forges the specification of the VirtualMachine backing a given node of the cluster,
+// which is always configured through the cloud-init secret named after the node, independently of the environment mode.
+func NodeClusterVirtualMachineSpec(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) virtv1.VirtualMachineSpec {
+	nodeName := NodeClusterNodeName(instance, environment)
+
+	root := VolumeContainerDisk(environment.Image)
+	if environment.Persistent {
+		root = VolumePersistentDisk(nodeName)
+	}
+
+	vmi := VirtualMachineInstanceSpec(instance, environment)
+	vmi.Hostname = nodeName
+	vmi.Volumes = []virtv1.Volume{root, VolumeCloudInit(nodeName)}
+	vmi.Domain.Devices.Disks = []virtv1.Disk{VolumeDiskTarget(volumeRootName), VolumeDiskTarget(volumeCloudInitName)}
+	vmi.ReadinessProbe.TCPSocket.Port = intstr.FromInt(SSHPortNumber)
+
+	spec := virtv1.VirtualMachineSpec{
+		Template: &virtv1.VirtualMachineInstanceTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: NodeClusterNodeLabels(instance, nodeName)},
+			Spec:       vmi,
+		},
+	}
+	if environment.Persistent {
+		spec.DataVolumeTemplates = []virtv1.DataVolumeTemplateSpec{DataVolumeTemplate(nodeName, environment)}
+	}
+	return spec
+}
+
+// NodeClusterBootstrapData generates the bootstrap material of a cluster, i.e., the kubeadm bootstrap token, the key
+// encrypting the control plane certificates and the token granting the instance operator access to the cluster.
+// The cluster CA is generated by kubeadm on the node initializing the cluster, so that its private key never leaves it.
+func NodeClusterBootstrapData() (map[string][]byte, error) {
+	token, err := nodeClusterToken()
+	if err != nil {
+		return nil, err
+	}
+	operatorToken, err := nodeClusterToken()
+	if err != nil {
+		return nil, err
+	}
+
+	certificateKey := make([]byte, 32)
+	if _, err := rand.Read(certificateKey); err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		NodeClusterTokenKey:          []byte(token),
+		NodeClusterCertificateKeyKey: []byte(hex.EncodeToString(certificateKey)),
+		NodeClusterOperatorTokenKey:  []byte(operatorToken),
+	}, nil
+}
+
+// NodeClusterCACertFromClusterInfo extracts the certificate of the cluster CA from the cluster-info ConfigMap published by kubeadm,
+// which can be retrieved anonymously. Consistently with the kubeadm token discovery, the ConfigMap is trusted only if signed
+// by the given bootstrap token, proving it has been published by the cluster the token has been generated for.
+func NodeClusterCACertFromClusterInfo(clusterInfo *corev1.ConfigMap, token string) ([]byte, error) {
+	tokenID, tokenSecret, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errors.New("invalid bootstrap token")
+	}
+
+	kubeconfig, found := clusterInfo.Data[bootstrapapi.KubeConfigKey]
+	if !found {
+		return nil, errors.New("the cluster-info configmap does not contain the kubeconfig")
+	}
+	signature, found := clusterInfo.Data[bootstrapapi.JWSSignatureKeyPrefix+tokenID]
+	if !found {
+		return nil, fmt.Errorf("the cluster-info configmap has not been signed by token %q yet", tokenID)
+	}
+	if !jws.DetachedTokenIsValid(signature, kubeconfig, tokenID, tokenSecret) {
+		return nil, errors.New("the cluster-info configmap signature is not valid")
+	}
+
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the cluster-info kubeconfig: %w", err)
+	}
+	for _, cluster := range config.Clusters {
+		if _, err := parseCertificate(cluster.CertificateAuthorityData); err != nil {
+			return nil, fmt.Errorf("invalid cluster CA certificate: %w", err)
+		}
+		return cluster.CertificateAuthorityData, nil
+	}
+	return nil, errors.New("the cluster-info kubeconfig does not contain any cluster")
+}
+
+// NodeClusterCACertHash computes the hash of the public key of the cluster CA, in the format expected by kubeadm join.
+func NodeClusterCACertHash(caCertPEM []byte) (string, error) {
+	caCert, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(caCert.RawSubjectPublicKeyInfo)
+	return "sha256:" + hex.EncodeToString(hash[:]), nil
+}
+
+// NodeClusterKubeconfig forges the kubeconfig the instance operator uses to interact with the cluster, authenticated through
+// the operator token, which is granted administrative privileges by the node initializing the cluster. It requires the
+// certificate of the cluster CA to be already known.
+func NodeClusterKubeconfig(instance *clv1alpha2.Instance, clusterName string, bootstrap map[string][]byte) ([]byte, error) {
+	if _, err := parseCertificate(bootstrap[NodeClusterCACertKey]); err != nil {
+		return nil, fmt.Errorf("invalid cluster CA certificate: %w", err)
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[clusterName] = &clientcmdapi.Cluster{
+		Server:                   fmt.Sprintf("https://%s:%d", NodeClusterEndpoint(instance, clusterName), NodeClusterAPIServerPort),
+		CertificateAuthorityData: bootstrap[NodeClusterCACertKey],
+	}
+	config.AuthInfos[NodeClusterAdminUser] = &clientcmdapi.AuthInfo{Token: string(bootstrap[NodeClusterOperatorTokenKey])}
+	config.Contexts[clusterName] = &clientcmdapi.Context{Cluster: clusterName, AuthInfo: NodeClusterAdminUser}
+	config.CurrentContext = clusterName
+	return clientcmd.Write(*config)
+}
+
+// NodeClusterCloudInitUserData forges the cloud-init userdata configuration of a given node of the cluster,
+// which runs kubeadm init in case of the node initializing the cluster, and kubeadm join otherwise.
+func NodeClusterCloudInitUserData(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, bootstrap map[string][]byte,
+	publicKeys []string, mountInfos []NFSVolumeMountInfo) ([]byte, error) {
+	config := cloudInitConfig(publicKeys, mountInfos)
+
+	command, err := NodeClusterKubeadmCommand(instance, environment, bootstrap)
+	if err != nil {
+		return nil, err
+	}
+	config.RunCmd = []string{command}
+
+	if IsNodeClusterInitializer(environment) {
+		config.RunCmd = append(config.RunCmd, NodeClusterOperatorAccessCommands(bootstrap)...)
+	}
+	return marshalUserData(config)
+}
+
+// NodeClusterKubeadmCommand forges the kubeadm command bootstrapping a given node of the cluster. The node initializing
+// the cluster uploads the control plane certificates, to allow the other control plane nodes to join, while joining nodes
+// validate the control plane through the hash of the cluster CA, hence they can be configured only once it is known.
+// In both cases, the command is retried (after resetting the node) until it succeeds.
+func NodeClusterKubeadmCommand(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, bootstrap map[string][]byte) (string, error) {
+	clusterName := NodeClusterName(instance, environment)
+	endpoint := fmt.Sprintf("%s:%d", NodeClusterEndpoint(instance, clusterName), NodeClusterAPIServerPort)
+	token, certificateKey := string(bootstrap[NodeClusterTokenKey]), string(bootstrap[NodeClusterCertificateKeyKey])
+
+	if IsNodeClusterInitializer(environment) {
+		sans := []string{NodeClusterEndpoint(instance, clusterName), NodeClusterEndpointServiceName(clusterName)}
+		return nodeClusterRetryCommand(strings.Join([]string{
+			"kubeadm init",
+			"--control-plane-endpoint " + endpoint,
+			"--apiserver-cert-extra-sans " + strings.Join(sans, ","),
+			"--pod-network-cidr " + NodeClusterPodCIDR,
+			"--service-cidr " + NodeClusterServiceCIDR,
+			"--token " + token,
+			"--token-ttl 0",
+			"--upload-certs",
+			"--certificate-key " + certificateKey,
+			`--kubernetes-version "$(kubeadm version -o short)"`,
+		}, " ")), nil
+	}
+
+	hash, err := NodeClusterCACertHash(bootstrap[NodeClusterCACertKey])
+	if err != nil {
+		return "", err
+	}
+
+	join := fmt.Sprintf("kubeadm join %s --token %s --discovery-token-ca-cert-hash %s", endpoint, token, hash)
+	if environment.ClusterRole == clv1alpha2.ClusterNodeControlPlane {
+		join += " --control-plane --certificate-key " + certificateKey
+	}
+	return nodeClusterRetryCommand(join), nil
+}
+
+// NodeClusterOperatorAccessCommands forges the commands, run on the node initializing the cluster once initialized,
+// which create the operator token and grant it administrative privileges (through the corresponding group).
+// They rely on kubectl being available in the node image, along with kubeadm.
+func NodeClusterOperatorAccessCommands(bootstrap map[string][]byte) []string {
+	return []string{
+		strings.Join([]string{
+			"kubeadm token create " + string(bootstrap[NodeClusterOperatorTokenKey]),
+			"--ttl 0",
+			"--usages authentication",
+			"--groups " + NodeClusterOperatorGroup,
+			`--description "CrownLabs instance operator"`,
+		}, " "),
+		strings.Join([]string{
+			"kubectl --kubeconfig " + nodeClusterAdminKubeconfig,
+			"create clusterrolebinding " + NodeClusterAdminUser,
+			"--clusterrole cluster-admin",
+			"--group " + NodeClusterOperatorGroup,
+		}, " "),
+	}
+}
+
+// NodeClusterCNIJobName returns the name of the job installing the CNI in the given cluster.
+func NodeClusterCNIJobName(clusterName string) string {
+	return fmt.Sprintf("%s-cni", clusterName)
+}
+
+// NodeClusterCNIJobSpec forges the specification of the job installing the CNI (i.e., Cilium) in the given cluster
+// through the Cilium CLI, which interacts with the cluster through the kubeconfig used by the instance operator.
+func NodeClusterCNIJobSpec(clusterName, image string) batchv1.JobSpec {
+	installer := GenericContainer(nodeClusterCNIContainerName, image)
+	SetContainerResources(&installer, 0.1, 0.5, 64, 256)
+	installer.Command = []string{"cilium"}
+	installer.Args = append([]string{"install"}, CiliumInstallArgs([]string{NodeClusterPodCIDR})...)
+
+	AddContainerVolumeMount(&installer, nodeClusterKubeconfigVolumeName, nodeClusterKubeconfigMountPath)
+	AddEnvVariableToContainer(&installer, "KUBECONFIG", filepath.Join(nodeClusterKubeconfigMountPath, NodeClusterKubeconfigKey))
+	// The installer runs as unprivileged user, hence the cache is stored in a writable location.
+	AddEnvVariableToContainer(&installer, "HOME", os.TempDir())
+
+	return batchv1.JobSpec{
+		BackoffLimit:            ptr.To[int32](nodeClusterCNIJobBackoffLimit),
+		ActiveDeadlineSeconds:   ptr.To[int64](nodeClusterCNIJobDeadlineSeconds),
+		TTLSecondsAfterFinished: ptr.To[int32](SubmissionJobTTLSeconds),
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{installer},
+				Volumes: []corev1.Volume{{Name: nodeClusterKubeconfigVolumeName, VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: NodeClusterKubeconfigSecretName(clusterName)},
+				}}},
+				SecurityContext:              PodSecurityContext(),
+				AutomountServiceAccountToken: ptr.To(false),
+				EnableServiceLinks:           ptr.To(false),
+				RestartPolicy:                corev1.RestartPolicyNever,
+			},
+		},
+	}
+}
+
+// NodeClusterNodes forges the status entries reflecting the nodes of the cluster, sorted by role and name.
+func NodeClusterNodes(nodes []clv1alpha2.InstanceClusterNode) []clv1alpha2.InstanceClusterNode {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Role != nodes[j].Role {
+			return nodes[i].Role == clv1alpha2.ClusterNodeControlPlane
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// IsNodeReady returns whether the given node is ready.
+func IsNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeClusterToken generates a random kubeadm bootstrap token, in the <id>.<secret> format.
+func nodeClusterToken() (string, error) {
+	tokenID, err := randomString(6)
+	if err != nil {
+		return "", err
+	}
+	tokenSecret, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	return tokenID + "." + tokenSecret, nil
+}
+
+// nodeClusterRetryCommand wraps the given kubeadm command to be retried, after resetting the node, until it succeeds.
+func nodeClusterRetryCommand(command string) string {
+	return fmt.Sprintf("until %s; do kubeadm reset -f; sleep 10; done", command)
+}
+
+// randomString generates a random string of the given length, composed of the characters allowed in kubeadm bootstrap tokens.
+func randomString(length int) (string, error) {
+	var builder strings.Builder
+	for range length {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(nodeClusterTokenAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(nodeClusterTokenAlphabet[index.Int64()])
+	}
+	return builder.String(), nil
+}
+
+// parseCertificate parses the given PEM-encoded certificate.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("failed to decode the PEM-encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,280 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	jws "k8s.io/cluster-bootstrap/token/jws"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+// generateCACert generates a self-signed PEM-encoded CA certificate, mimicking the one generated by kubeadm.
+func generateCACert() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "kubernetes"},
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// signedClusterInfo forges the cluster-info configmap published by kubeadm, signed with the given token.
+func signedClusterInfo(caCert []byte, token string) *corev1.ConfigMap {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[""] = &clientcmdapi.Cluster{Server: "https://10.0.0.10:6443", CertificateAuthorityData: caCert}
+	kubeconfig, err := clientcmd.Write(*config)
+	Expect(err).ToNot(HaveOccurred())
+
+	tokenID, tokenSecret, _ := strings.Cut(token, ".")
+	signature, err := jws.ComputeDetachedSignature(string(kubeconfig), tokenID, tokenSecret)
+	Expect(err).ToNot(HaveOccurred())
+	return &corev1.ConfigMap{Data: map[string]string{"kubeconfig": string(kubeconfig), "jws-kubeconfig-" + tokenID: signature}}
+}
+
+var _ = Describe("VM-based clusters forging", func() {
+	var (
+		instance     clv1alpha2.Instance
+		controlPlane clv1alpha2.Environment
+		worker       clv1alpha2.Environment
+		bootstrap    map[string][]byte
+		caCert       []byte
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubeadm-0000", Namespace: "tenant-tester"}}
+		controlPlane = clv1alpha2.Environment{
+			Name: "control-plane", Image: "internal/registry/node:v1.30", EnvironmentType: clv1alpha2.ClassVM,
+			IsClusterNode: true, ClusterName: "lab", ClusterRole: clv1alpha2.ClusterNodeControlPlane,
+		}
+		worker = clv1alpha2.Environment{
+			Name: "worker", Image: "internal/registry/node:v1.30", EnvironmentType: clv1alpha2.ClassVM, Persistent: true,
+			IsClusterNode: true, ClusterName: "lab", ClusterRole: clv1alpha2.ClusterNodeWorker, Ordinal: 1,
+		}
+
+		var err error
+		bootstrap, err = forge.NodeClusterBootstrapData()
+		Expect(err).ToNot(HaveOccurred())
+		caCert = generateCACert()
+	})
+
+	Describe("The forge.NodeClusterNodeName function", func() {
+		It("Should name the node after the cluster, the role and the ordinal", func() {
+			Expect(forge.NodeClusterNodeName(&instance, &controlPlane)).To(Equal("kubeadm-0000-lab-controlplane-0"))
+			Expect(forge.NodeClusterNodeName(&instance, &worker)).To(Equal("kubeadm-0000-lab-worker-1"))
+		})
+	})
+
+	Describe("The forge.NodeClusterBootstrapData function", func() {
+		It("Should generate valid kubeadm bootstrap and operator tokens, and certificate key", func() {
+			Expect(string(bootstrap[forge.NodeClusterTokenKey])).To(MatchRegexp(`^[a-z0-9]{6}\.[a-z0-9]{16}$`))
+			Expect(string(bootstrap[forge.NodeClusterOperatorTokenKey])).To(MatchRegexp(`^[a-z0-9]{6}\.[a-z0-9]{16}$`))
+			Expect(bootstrap[forge.NodeClusterOperatorTokenKey]).ToNot(Equal(bootstrap[forge.NodeClusterTokenKey]))
+			Expect(string(bootstrap[forge.NodeClusterCertificateKeyKey])).To(MatchRegexp(`^[0-9a-f]{64}$`))
+		})
+
+		It("Should not generate the CA, which is left to kubeadm", func() {
+			Expect(bootstrap).ToNot(HaveKey(forge.NodeClusterCACertKey))
+		})
+	})
+
+	Describe("The forge.NodeClusterCACertFromClusterInfo function", func() {
+		var token string
+
+		BeforeEach(func() { token = string(bootstrap[forge.NodeClusterTokenKey]) })
+
+		It("Should extract the CA certificate from the cluster-info signed by the token", func() {
+			Expect(forge.NodeClusterCACertFromClusterInfo(signedClusterInfo(caCert, token), token)).To(Equal(caCert))
+		})
+
+		It("Should fail if the cluster-info is signed by a different token", func() {
+			_, err := forge.NodeClusterCACertFromClusterInfo(signedClusterInfo(caCert, string(bootstrap[forge.NodeClusterOperatorTokenKey])), token)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should fail if the cluster-info has been tampered with", func() {
+			clusterInfo := signedClusterInfo(caCert, token)
+			tampered := signedClusterInfo(generateCACert(), token)
+			clusterInfo.Data["kubeconfig"] = tampered.Data["kubeconfig"]
+
+			_, err := forge.NodeClusterCACertFromClusterInfo(clusterInfo, token)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The forge.NodeClusterCACertHash function", func() {
+		It("Should hash the public key of the CA", func() {
+			block, _ := pem.Decode(caCert)
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).ToNot(HaveOccurred())
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+			Expect(forge.NodeClusterCACertHash(caCert)).To(Equal("sha256:" + hex.EncodeToString(hash[:])))
+		})
+
+		It("Should fail with an invalid certificate", func() {
+			_, err := forge.NodeClusterCACertHash([]byte("invalid"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The forge.NodeClusterKubeconfig function", func() {
+		It("Should forge a kubeconfig authenticated through the operator token", func() {
+			bootstrap[forge.NodeClusterCACertKey] = caCert
+			raw, err := forge.NodeClusterKubeconfig(&instance, "kubeadm-0000-lab", bootstrap)
+			Expect(err).ToNot(HaveOccurred())
+
+			config, err := clientcmd.RESTConfigFromKubeConfig(raw)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Host).To(Equal("https://kubeadm-0000-lab-api.tenant-tester.svc:6443"))
+			Expect(config.CAData).To(Equal(caCert))
+			Expect(config.BearerToken).To(Equal(string(bootstrap[forge.NodeClusterOperatorTokenKey])))
+		})
+
+		It("Should fail if the CA is not yet known", func() {
+			_, err := forge.NodeClusterKubeconfig(&instance, "kubeadm-0000-lab", bootstrap)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The forge.NodeClusterKubeadmCommand function", func() {
+		BeforeEach(func() { bootstrap[forge.NodeClusterCACertKey] = caCert })
+
+		It("Should initialize the cluster on the control plane node with ordinal 0, retrying on failure", func() {
+			command, err := forge.NodeClusterKubeadmCommand(&instance, &controlPlane, bootstrap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(command).To(HavePrefix("until kubeadm init"))
+			Expect(command).To(HaveSuffix("; do kubeadm reset -f; sleep 10; done"))
+			Expect(command).To(ContainSubstring("--control-plane-endpoint kubeadm-0000-lab-api.tenant-tester.svc:6443"))
+			Expect(command).To(ContainSubstring("--token " + string(bootstrap[forge.NodeClusterTokenKey])))
+			Expect(command).To(ContainSubstring("--certificate-key " + string(bootstrap[forge.NodeClusterCertificateKeyKey])))
+			Expect(command).To(ContainSubstring("--pod-network-cidr " + forge.NodeClusterPodCIDR))
+		})
+
+		It("Should join the cluster on the worker nodes, validating the CA", func() {
+			hash, err := forge.NodeClusterCACertHash(caCert)
+			Expect(err).ToNot(HaveOccurred())
+
+			command, err := forge.NodeClusterKubeadmCommand(&instance, &worker, bootstrap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(command).To(HavePrefix("until kubeadm join kubeadm-0000-lab-api.tenant-tester.svc:6443"))
+			Expect(command).To(ContainSubstring("--discovery-token-ca-cert-hash " + hash))
+			Expect(command).ToNot(ContainSubstring("--control-plane"))
+		})
+
+		It("Should join the cluster as control plane on the other control plane nodes", func() {
+			controlPlane.Ordinal = 1
+			command, err := forge.NodeClusterKubeadmCommand(&instance, &controlPlane, bootstrap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(command).To(ContainSubstring("kubeadm join"))
+			Expect(command).To(ContainSubstring("--control-plane --certificate-key " + string(bootstrap[forge.NodeClusterCertificateKeyKey])))
+		})
+
+		It("Should fail to join the cluster if the CA is not yet known", func() {
+			delete(bootstrap, forge.NodeClusterCACertKey)
+			_, err := forge.NodeClusterKubeadmCommand(&instance, &worker, bootstrap)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("The forge.NodeClusterCloudInitUserData function", func() {
+		It("Should grant access to the operator on the node initializing the cluster, without writing the CA", func() {
+			userdata, err := forge.NodeClusterCloudInitUserData(&instance, &controlPlane, bootstrap, []string{"tenant-key"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(userdata)).To(HavePrefix("#cloud-config\n"))
+			Expect(string(userdata)).To(ContainSubstring("kubeadm init"))
+			Expect(string(userdata)).To(ContainSubstring("kubeadm token create " + string(bootstrap[forge.NodeClusterOperatorTokenKey])))
+			Expect(string(userdata)).To(ContainSubstring("--group " + forge.NodeClusterOperatorGroup))
+			Expect(string(userdata)).ToNot(ContainSubstring("write_files"))
+		})
+
+		It("Should only join the cluster on the other nodes", func() {
+			bootstrap[forge.NodeClusterCACertKey] = caCert
+			userdata, err := forge.NodeClusterCloudInitUserData(&instance, &worker, bootstrap, []string{"tenant-key"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(userdata)).To(ContainSubstring("kubeadm join"))
+			Expect(string(userdata)).ToNot(ContainSubstring("kubeadm token create"))
+		})
+	})
+
+	Describe("The forge.NodeClusterCNIJobSpec function", func() {
+		var spec batchv1.JobSpec
+
+		BeforeEach(func() { spec = forge.NodeClusterCNIJobSpec("kubeadm-0000-lab", "quay.io/cilium/cilium-cli:v0.18.3") })
+
+		It("Should install Cilium through the Cilium CLI", func() {
+			Expect(spec.Template.Spec.Containers).To(HaveLen(1))
+			container := spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("quay.io/cilium/cilium-cli:v0.18.3"))
+			Expect(container.Command).To(ConsistOf("cilium"))
+			Expect(container.Args).To(Equal(append([]string{"install"}, forge.CiliumInstallArgs([]string{forge.NodeClusterPodCIDR})...)))
+		})
+
+		It("Should interact with the cluster through the kubeconfig of the operator", func() {
+			Expect(spec.Template.Spec.Volumes).To(HaveLen(1))
+			Expect(spec.Template.Spec.Volumes[0].Secret.SecretName).To(Equal("kubeadm-0000-lab-kubeconfig"))
+			Expect(spec.Template.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "KUBECONFIG", Value: "/etc/crownlabs/kubeconfig/value"}))
+		})
+
+		It("Should not retry indefinitely", func() {
+			Expect(spec.BackoffLimit).ToNot(BeNil())
+			Expect(spec.ActiveDeadlineSeconds).ToNot(BeNil())
+			Expect(spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		})
+	})
+
+	Describe("The forge.NodeClusterVirtualMachineSpec function", func() {
+		It("Should configure the VM through the cloud-init secret named after the node", func() {
+			spec := forge.NodeClusterVirtualMachineSpec(&instance, &worker)
+			Expect(spec.Template.Spec.Hostname).To(Equal("kubeadm-0000-lab-worker-1"))
+			Expect(spec.Template.Spec.Volumes).To(HaveLen(2))
+			Expect(spec.Template.Spec.Volumes[0].DataVolume.Name).To(Equal("kubeadm-0000-lab-worker-1"))
+			Expect(spec.Template.Spec.Volumes[1].CloudInitNoCloud.UserDataSecretRef.Name).To(Equal("kubeadm-0000-lab-worker-1"))
+			Expect(spec.DataVolumeTemplates).To(HaveLen(1))
+			Expect(spec.Template.ObjectMeta.Labels).To(Equal(forge.NodeClusterNodeLabels(&instance, "kubeadm-0000-lab-worker-1")))
+		})
+	})
+
+	Describe("The forge.NodeClusterEndpointServiceSpec function", func() {
+		It("Should select the node initializing the cluster", func() {
+			spec := forge.NodeClusterEndpointServiceSpec(&instance, &controlPlane)
+			Expect(spec.Selector).To(Equal(forge.NodeClusterNodeLabels(&instance, "kubeadm-0000-lab-controlplane-0")))
+			Expect(spec.Ports).To(HaveLen(1))
+			Expect(spec.Ports[0].Port).To(BeNumerically("==", forge.NodeClusterAPIServerPort))
+		})
+	})
+})
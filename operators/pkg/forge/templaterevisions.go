@@ -0,0 +1,165 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// TemplateUpgradeAnnotation -> annotation requesting the instance to be upgraded to the current specification of the template (if set to true).
+	TemplateUpgradeAnnotation = "crownlabs.polito.it/upgrade-template"
+
+	// templateRevisionDigestLength is the number of characters of the digest included in the name of the revisions.
+	templateRevisionDigestLength = 10
+	// templateChangeValueMaxLength is the maximum length of the values reported in the changes between revisions.
+	templateChangeValueMaxLength = 64
+)
+
+// TemplateRevisionDigest returns the digest of the given template specification, identifying the corresponding revision.
+func TemplateRevisionDigest(spec *clv1alpha2.TemplateSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(hash[:])[:16], nil
+}
+
+// TemplateRevisionName returns the name of the revision of the given template, identified by the given digest.
+func TemplateRevisionName(template *clv1alpha2.Template, revision string) string {
+	digest := strings.TrimPrefix(revision, "sha256:")
+	return fmt.Sprintf("%s-%s", template.Name, digest[:min(len(digest), templateRevisionDigestLength)])
+}
+
+// TemplateRevisionObjectMeta forges the metadata of the revision of the given template, identified by the given digest.
+func TemplateRevisionObjectMeta(template *clv1alpha2.Template, revision string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      TemplateRevisionName(template, revision),
+		Namespace: template.Namespace,
+		Labels: map[string]string{
+			labelManagedByKey: labelManagedByInstanceValue,
+			labelWorkspaceKey: template.Spec.WorkspaceRef.Name,
+			labelTemplateKey:  template.Name,
+		},
+	}
+}
+
+// TemplateRevisionSpec forges the specification of the revision of the given template, identified by the given digest.
+func TemplateRevisionSpec(template *clv1alpha2.Template, revision string) clv1alpha2.TemplateRevisionSpec {
+	return clv1alpha2.TemplateRevisionSpec{
+		TemplateRef: clv1alpha2.GenericRef{Name: template.Name, Namespace: template.Namespace},
+		Revision:    revision,
+		Template:    *template.Spec.DeepCopy(),
+	}
+}
+
+// TemplateUpgradeRequested returns whether the upgrade of the instance to the current specification of the template has been requested.
+func TemplateUpgradeRequested(instance *clv1alpha2.Instance) bool {
+	return instance.GetAnnotations()[TemplateUpgradeAnnotation] == "true"
+}
+
+// TemplateSpecChanges returns the changes between two template specifications, one per modified field,
+// in the form "<path>: <previous value> -> <current value>", sorted by path.
+func TemplateSpecChanges(previous, current *clv1alpha2.TemplateSpec) ([]string, error) {
+	var before, after interface{}
+	for _, conversion := range []struct {
+		spec *clv1alpha2.TemplateSpec
+		into *interface{}
+	}{{previous, &before}, {current, &after}} {
+		data, err := json.Marshal(conversion.spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, conversion.into); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []string
+	collectTemplateChanges("", before, after, &changes)
+	return changes, nil
+}
+
+// collectTemplateChanges recursively compares the given (JSON-decoded) values, appending the differences to changes.
+func collectTemplateChanges(path string, before, after interface{}, changes *[]string) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(b)+len(a))
+			for key := range b {
+				keys = append(keys, key)
+			}
+			for key := range a {
+				if _, found := b[key]; !found {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				child := key
+				if path != "" {
+					child = path + "." + key
+				}
+				collectTemplateChanges(child, b[key], a[key], changes)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			for i := 0; i < max(len(b), len(a)); i++ {
+				var bi, ai interface{}
+				if i < len(b) {
+					bi = b[i]
+				}
+				if i < len(a) {
+					ai = a[i]
+				}
+				collectTemplateChanges(fmt.Sprintf("%s[%d]", path, i), bi, ai, changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, fmt.Sprintf("%s: %s -> %s", path, templateChangeValue(before), templateChangeValue(after)))
+	}
+}
+
+// templateChangeValue returns the (possibly truncated) representation of a value reported in the changes between revisions.
+func templateChangeValue(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > templateChangeValueMaxLength {
+		return string(data[:templateChangeValueMaxLength]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,128 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Template revisions forging", func() {
+	var template clv1alpha2.Template
+
+	BeforeEach(func() {
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "workspace-netgroup"},
+			Spec: clv1alpha2.TemplateSpec{
+				PrettyName:   "Kubernetes",
+				WorkspaceRef: clv1alpha2.GenericRef{Name: "netgroup"},
+				EnvironmentList: []clv1alpha2.Environment{{
+					Name: "cluster", Image: "registry/node:v1.30", EnvironmentType: clv1alpha2.ClassCluster,
+					Cluster: &clv1alpha2.ClusterTemplate{Name: "lab", Version: "v1.30.0"},
+				}},
+			},
+		}
+	})
+
+	Describe("The forge.TemplateRevisionDigest function", func() {
+		It("Should return the same digest for the same specification", func() {
+			first, err := forge.TemplateRevisionDigest(&template.Spec)
+			Expect(err).ToNot(HaveOccurred())
+			second, err := forge.TemplateRevisionDigest(template.Spec.DeepCopy())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("sha256:"))
+			Expect(first).To(Equal(second))
+		})
+
+		It("Should return a different digest for a different specification", func() {
+			first, err := forge.TemplateRevisionDigest(&template.Spec)
+			Expect(err).ToNot(HaveOccurred())
+			template.Spec.EnvironmentList[0].Cluster.Version = "v1.31.0"
+			second, err := forge.TemplateRevisionDigest(&template.Spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).ToNot(Equal(second))
+		})
+	})
+
+	Describe("The forge.TemplateRevisionName function", func() {
+		It("Should derive the name from the template and the digest", func() {
+			Expect(forge.TemplateRevisionName(&template, "sha256:0123456789abcdef")).To(Equal("kubernetes-0123456789"))
+		})
+	})
+
+	Describe("The forge.TemplateRevisionSpec function", func() {
+		It("Should snapshot the template specification", func() {
+			spec := forge.TemplateRevisionSpec(&template, "sha256:0123456789abcdef")
+			Expect(spec.TemplateRef).To(Equal(clv1alpha2.GenericRef{Name: "kubernetes", Namespace: "workspace-netgroup"}))
+			Expect(spec.Revision).To(Equal("sha256:0123456789abcdef"))
+			Expect(spec.Template).To(Equal(template.Spec))
+
+			template.Spec.EnvironmentList[0].Cluster.Version = "v1.31.0"
+			Expect(spec.Template.EnvironmentList[0].Cluster.Version).To(Equal("v1.30.0"))
+		})
+	})
+
+	Describe("The forge.TemplateSpecChanges function", func() {
+		var previous clv1alpha2.TemplateSpec
+
+		BeforeEach(func() { previous = *template.Spec.DeepCopy() })
+
+		It("Should report no changes for the same specification", func() {
+			Expect(forge.TemplateSpecChanges(&previous, &template.Spec)).To(BeEmpty())
+		})
+
+		It("Should report the modified fields", func() {
+			template.Spec.EnvironmentList[0].Cluster.Version = "v1.31.0"
+			template.Spec.PrettyName = "Kubernetes lab"
+			Expect(forge.TemplateSpecChanges(&previous, &template.Spec)).To(Equal([]string{
+				`environmentList[0].cluster.version: "v1.30.0" -> "v1.31.0"`,
+				`prettyName: "Kubernetes" -> "Kubernetes lab"`,
+			}))
+		})
+
+		It("Should report the added and removed fields", func() {
+			template.Spec.Description = "A Kubernetes lab"
+			template.Spec.EnvironmentList = append(template.Spec.EnvironmentList, clv1alpha2.Environment{Name: "extra"})
+			previous.EnvironmentList[0].Image = ""
+			Expect(forge.TemplateSpecChanges(&previous, &template.Spec)).To(ConsistOf(
+				`description: "" -> "A Kubernetes lab"`,
+				`environmentList[0].image: "" -> "registry/node:v1.30"`,
+				HavePrefix(`environmentList[1]: <unset> -> {`),
+			))
+		})
+
+		It("Should truncate long values", func() {
+			template.Spec.PrettyName = string(make([]byte, 100))
+			changes, err := forge.TemplateSpecChanges(&previous, &template.Spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(ConsistOf(HaveSuffix("...")))
+		})
+	})
+
+	Describe("The forge.TemplateUpgradeRequested function", func() {
+		It("Should return whether the upgrade annotation is set to true", func() {
+			instance := clv1alpha2.Instance{}
+			Expect(forge.TemplateUpgradeRequested(&instance)).To(BeFalse())
+			instance.SetAnnotations(map[string]string{forge.TemplateUpgradeAnnotation: "false"})
+			Expect(forge.TemplateUpgradeRequested(&instance)).To(BeFalse())
+			instance.SetAnnotations(map[string]string{forge.TemplateUpgradeAnnotation: "true"})
+			Expect(forge.TemplateUpgradeRequested(&instance)).To(BeTrue())
+		})
+	})
+})
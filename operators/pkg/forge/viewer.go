@@ -0,0 +1,147 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+const (
+	// ViewerShareAnnotation -> annotation requesting a view-only share link of the graphical environment, valid
+	// until the given time (in RFC 3339 format). Any new value triggers the generation of a new link.
+	ViewerShareAnnotation = "crownlabs.polito.it/share-viewer"
+	// ViewerSecretNameSuffix -> the suffix added to the name of the secret holding the key to sign the share links.
+	ViewerSecretNameSuffix = "viewer"
+	// ViewerKeyName -> the key of the secret holding the key to sign the share links.
+	ViewerKeyName = "key"
+	// ViewerKeyLength -> the length, in bytes, of the key to sign the share links.
+	ViewerKeyLength = 32
+	// ViewerVolumeName -> the name of the volume holding the key to sign the share links.
+	ViewerVolumeName = "viewer-key"
+	// ViewerKeyMountPath -> the path the volume holding the key to sign the share links is mounted at.
+	ViewerKeyMountPath = "/etc/crownlabs/viewer"
+	// ViewerTokenQueryParam -> the query parameter carrying the token granting view-only access.
+	ViewerTokenQueryParam = "viewToken"
+	// ViewerIngressNameSuffix -> the suffix added to the name of the ingress exposing the view-only sessions.
+	ViewerIngressNameSuffix = "viewer"
+	// ViewerPathPrefix -> the prefix of the path the view-only sessions are exposed at, which is independent
+	// of the one of the instance, so that share links do not disclose the path granting full control.
+	ViewerPathPrefix = "/viewer"
+
+	// viewerPathMessage is signed with the key of the instance to derive the path of the view-only sessions.
+	viewerPathMessage = "viewer-path"
+)
+
+// ViewerSharingSupported returns whether view-only share links can be generated for the given environment,
+// i.e., whether its graphical desktop is served through websockify.
+func ViewerSharingSupported(environment *clv1alpha2.Environment) bool {
+	return environment.EnvironmentType == clv1alpha2.ClassContainer && environment.GuiEnabled
+}
+
+// ViewerSecretName returns the name of the secret holding the key to sign the share links of the instance.
+func ViewerSecretName(instance *clv1alpha2.Instance) string {
+	return NamespacedNameWithSuffix(instance, ViewerSecretNameSuffix).Name
+}
+
+// ViewerVolume forges the volume holding the key to sign the share links of the instance. The volume is optional,
+// to prevent the instances from not starting in case the secret is missing (e.g., being rotated).
+func ViewerVolume(instance *clv1alpha2.Instance) corev1.Volume {
+	return corev1.Volume{
+		Name: ViewerVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: ViewerSecretName(instance),
+				Optional:   ptr.To(true),
+			},
+		},
+	}
+}
+
+// ViewerKeyPath returns the path of the file holding the key to sign the share links, inside the websockify container.
+func ViewerKeyPath() string {
+	return ViewerKeyMountPath + "/" + ViewerKeyName
+}
+
+// ViewerToken returns the token granting view-only access until the given expiration time, consisting of the
+// expiration (as Unix timestamp) and its HMAC-SHA256 signature (base64url encoded), separated by a dot.
+func ViewerToken(key []byte, expiration time.Time) string {
+	payload := strconv.FormatInt(expiration.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ViewerPath returns the path the view-only sessions of the instance are exposed at, derived from the key to sign
+// the share links (as websockify does), so that it is not guessable and it changes in case the key is rotated.
+func ViewerPath(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(viewerPathMessage))
+	return ViewerPathPrefix + "/" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ViewerShareRequired returns whether a new view-only share link has been requested for the instance.
+func ViewerShareRequired(instance *clv1alpha2.Instance) bool {
+	request := instance.GetAnnotations()[ViewerShareAnnotation]
+	if request == "" {
+		return false
+	}
+	return instance.Status.ViewerShare == nil || request != instance.Status.ViewerShare.ObservedRequest
+}
+
+// ViewerShareStatus forges the status of the view-only share link of the instance, given the key to sign it.
+// The link targets the path of the view-only sessions, on the same host of the instance.
+// The requested expiration time must be in the future, and it is capped to the given maximum validity.
+func ViewerShareStatus(instance *clv1alpha2.Instance, key []byte, maxValidity time.Duration, now time.Time) (*clv1alpha2.InstanceViewerShareStatus, error) {
+	request := instance.GetAnnotations()[ViewerShareAnnotation]
+	expiration, err := time.Parse(time.RFC3339, request)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration time %q: %w", request, err)
+	}
+	if !expiration.After(now) {
+		return nil, fmt.Errorf("expiration time %q is in the past", request)
+	}
+	if limit := now.Add(maxValidity); expiration.After(limit) {
+		expiration = limit
+	}
+
+	link, err := url.Parse(instance.Status.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance URL %q: %w", instance.Status.URL, err)
+	}
+
+	query := url.Values{}
+	query.Set(ViewerTokenQueryParam, ViewerToken(key, expiration))
+	query.Set("view_only", "true")
+	link.Path = ViewerPath(key) + "/"
+	link.RawQuery = query.Encode()
+
+	return &clv1alpha2.InstanceViewerShareStatus{
+		ObservedRequest: request,
+		URL:             link.String(),
+		ExpirationTime:  metav1.NewTime(expiration.Truncate(time.Second)),
+	}, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forge_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+var _ = Describe("Viewer share forging", func() {
+	var (
+		instance clv1alpha2.Instance
+		key      []byte
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "desktop-0000", Namespace: "tenant-tester"},
+			Status:     clv1alpha2.InstanceStatus{URL: "https://crownlabs.example.com/instance/uid/app/"},
+		}
+		key = []byte("0123456789abcdef0123456789abcdef")
+		now = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	})
+
+	Describe("The forge.ViewerToken function", func() {
+		It("Should return the expiration signed with the given key", func() {
+			token := forge.ViewerToken(key, now)
+			payload, signature, found := strings.Cut(token, ".")
+			Expect(found).To(BeTrue())
+			Expect(payload).To(Equal("1767261600"))
+
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(payload))
+			Expect(signature).To(Equal(base64.RawURLEncoding.EncodeToString(mac.Sum(nil))))
+		})
+	})
+
+	Describe("The forge.ViewerPath function", func() {
+		It("Should return a path independent of the instance, derived from the key", func() {
+			path := forge.ViewerPath(key)
+			Expect(path).To(HavePrefix(forge.ViewerPathPrefix + "/"))
+			Expect(path).To(Equal(forge.ViewerPath(key)))
+			Expect(path).ToNot(Equal(forge.ViewerPath([]byte("fedcba9876543210fedcba9876543210"))))
+		})
+	})
+
+	Describe("The forge.ViewerSharingSupported function", func() {
+		It("Should return true for graphical container environments only", func() {
+			Expect(forge.ViewerSharingSupported(&clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassContainer, GuiEnabled: true})).To(BeTrue())
+			Expect(forge.ViewerSharingSupported(&clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassContainer})).To(BeFalse())
+			Expect(forge.ViewerSharingSupported(&clv1alpha2.Environment{EnvironmentType: clv1alpha2.ClassVM, GuiEnabled: true})).To(BeFalse())
+		})
+	})
+
+	Describe("The forge.ViewerShareRequired function", func() {
+		It("Should return false if no share has been requested", func() {
+			Expect(forge.ViewerShareRequired(&instance)).To(BeFalse())
+		})
+
+		When("a share has been requested", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "2026-01-01T12:00:00Z"})
+			})
+
+			It("Should return true if not yet handled", func() {
+				Expect(forge.ViewerShareRequired(&instance)).To(BeTrue())
+			})
+
+			It("Should return false if already handled", func() {
+				instance.Status.ViewerShare = &clv1alpha2.InstanceViewerShareStatus{ObservedRequest: "2026-01-01T12:00:00Z"}
+				Expect(forge.ViewerShareRequired(&instance)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("The forge.ViewerShareStatus function", func() {
+		var (
+			status *clv1alpha2.InstanceViewerShareStatus
+			err    error
+		)
+
+		JustBeforeEach(func() {
+			status, err = forge.ViewerShareStatus(&instance, key, 4*time.Hour, now)
+		})
+
+		When("the requested expiration is valid", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "2026-01-01T12:00:00Z"})
+			})
+
+			It("Should return the share link", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.ObservedRequest).To(Equal("2026-01-01T12:00:00Z"))
+				Expect(status.ExpirationTime.Time).To(BeTemporally("==", now.Add(2*time.Hour)))
+
+				link, err := url.Parse(status.URL)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(link.Host).To(Equal("crownlabs.example.com"))
+				Expect(link.Path).To(Equal(forge.ViewerPath(key) + "/"))
+				Expect(link.Query().Get(forge.ViewerTokenQueryParam)).To(Equal(forge.ViewerToken(key, now.Add(2*time.Hour))))
+				Expect(link.Query().Get("view_only")).To(Equal("true"))
+			})
+		})
+
+		When("the requested expiration exceeds the maximum validity", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "2026-01-02T12:00:00Z"})
+			})
+
+			It("Should cap the expiration", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.ExpirationTime.Time).To(BeTemporally("==", now.Add(4*time.Hour)))
+			})
+		})
+
+		When("the requested expiration is in the past", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "2025-12-31T12:00:00Z"})
+			})
+
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+		})
+
+		When("the requested expiration is not valid", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "2h"})
+			})
+
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+		})
+	})
+})
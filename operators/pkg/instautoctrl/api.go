@@ -23,3 +23,10 @@ type StatusCheckResponse struct {
 	Deadline time.Time `json:"deadline,omitempty"`
 	ID       string    `json:"idnumber,omitempty"`
 }
+
+// ActivityResponse is the expected response from the activity endpoint of graphical instances.
+type ActivityResponse struct {
+	LastActivity   time.Time `json:"lastActivity"`
+	LastInput      time.Time `json:"lastInput"`
+	ActiveSessions int32     `json:"activeSessions"`
+}
@@ -24,11 +24,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
 )
 
-// RetrieveEnvironment retrieves the template associated to the given instance.
-func RetrieveEnvironment(ctx context.Context, c client.Client, instance *clv1alpha2.Instance) (*clv1alpha2.Environment, error) {
+// RetrieveTemplate retrieves the template associated to the given instance, with the specification
+// of the template revision the instance is pinned to (if any), rather than the current one.
+func RetrieveTemplate(ctx context.Context, c client.Client, instance *clv1alpha2.Instance) (*clv1alpha2.Template, error) {
 	log := ctrl.LoggerFrom(ctx).V(utils.LogDebugLevel)
 
 	templateName := types.NamespacedName{
@@ -38,11 +40,53 @@ func RetrieveEnvironment(ctx context.Context, c client.Client, instance *clv1alp
 
 	var template clv1alpha2.Template
 	if err := c.Get(ctx, templateName, &template); err != nil {
-		return nil, fmt.Errorf("failed retrieving the instance template")
+		return nil, fmt.Errorf("failed retrieving the instance template: %w", err)
+	}
+
+	if err := ResolveTemplateRevision(ctx, c, instance, &template); err != nil {
+		return nil, err
+	}
+
+	log.Info("retrieved the instance template", "template", templateName)
+	return &template, nil
+}
+
+// ResolveTemplateRevision replaces the specification of the given template with the one of the revision the instance
+// is pinned to. The template is left unmodified if the instance has not been pinned to any revision yet.
+func ResolveTemplateRevision(ctx context.Context, c client.Client, instance *clv1alpha2.Instance, template *clv1alpha2.Template) error {
+	pinned := instance.Status.TemplateRevision
+	if pinned == nil {
+		return nil
 	}
 
-	log.Info("retrieved the instance environment", "template", templateName)
+	// The revision is not retrieved if the instance is pinned to the current specification of the template.
+	if latest, err := forge.TemplateRevisionDigest(&template.Spec); err != nil || latest == pinned.Revision {
+		return err
+	}
+
+	var revision clv1alpha2.TemplateRevision
+	revisionName := types.NamespacedName{Namespace: template.Namespace, Name: pinned.Name}
+	if err := c.Get(ctx, revisionName, &revision); err != nil {
+		return fmt.Errorf("failed retrieving the template revision %v pinned by the instance", pinned.Name)
+	}
+
+	ctrl.LoggerFrom(ctx).V(utils.LogDebugLevel).Info("retrieved the pinned template revision", "revision", revisionName)
+	template.Spec = revision.Spec.Template
+	return nil
+}
 
+// RetrieveEnvironment retrieves the environment of the template associated to the given instance.
+func RetrieveEnvironment(ctx context.Context, c client.Client, instance *clv1alpha2.Instance) (*clv1alpha2.Environment, error) {
+	template, err := RetrieveTemplate(ctx, c, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return TemplateEnvironment(template)
+}
+
+// TemplateEnvironment returns the environment of the given template.
+func TemplateEnvironment(template *clv1alpha2.Template) (*clv1alpha2.Environment, error) {
 	if len(template.Spec.EnvironmentList) != 1 {
 		return nil, fmt.Errorf("only one environment per template is supported")
 	}
@@ -62,3 +106,26 @@ func CheckEnvironmentValidity(instance *clv1alpha2.Instance, environment *clv1al
 
 	return nil
 }
+
+// StopInstance stops the given instance, configuring the automation labels
+// to trigger the submission of its content (or its grading), if required.
+func StopInstance(ctx context.Context, c client.Client, instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) error {
+	log := ctrl.LoggerFrom(ctx)
+	submissionRequired := false
+
+	if forge.GradingRequired(environment) {
+		submissionRequired = true
+		log.Info("grading required")
+	} else if err := CheckEnvironmentValidity(instance, environment); err != nil {
+		log.Info("instance not eligible for submission", "error", err)
+	} else {
+		submissionRequired = true
+		log.Info("submission required")
+	}
+
+	instance.SetLabels(forge.InstanceAutomationLabelsOnTermination(instance.GetLabels(), submissionRequired))
+
+	instance.Spec.Running = false
+
+	return c.Update(ctx, instance)
+}
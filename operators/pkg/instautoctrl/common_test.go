@@ -0,0 +1,132 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
+)
+
+var _ = Describe("The retrieval of the template of an instance", func() {
+	const (
+		templateName      = "kubernetes"
+		templateNamespace = "workspace-netgroup"
+	)
+
+	var (
+		ctx      context.Context
+		objects  []client.Object
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+		revision clv1alpha2.TemplateRevision
+
+		retrieved *clv1alpha2.Template
+		err       error
+	)
+
+	environment := func(image string) clv1alpha2.TemplateSpec {
+		return clv1alpha2.TemplateSpec{EnvironmentList: []clv1alpha2.Environment{{Name: "app", Image: image, Persistent: true}}}
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), GinkgoLogr)
+
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec:       environment("app:v2"),
+		}
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: "tenant-tester"},
+			Spec:       clv1alpha2.InstanceSpec{Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace}},
+		}
+
+		pinned := template.DeepCopy()
+		pinned.Spec = environment("app:v1")
+		digest, digestErr := forge.TemplateRevisionDigest(&pinned.Spec)
+		Expect(digestErr).ToNot(HaveOccurred())
+		revision = clv1alpha2.TemplateRevision{
+			ObjectMeta: forge.TemplateRevisionObjectMeta(pinned, digest),
+			Spec:       forge.TemplateRevisionSpec(pinned, digest),
+		}
+
+		objects = []client.Object{&template}
+	})
+
+	JustBeforeEach(func() {
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		retrieved, err = instautoctrl.RetrieveTemplate(ctx, c, &instance)
+	})
+
+	When("the instance is not pinned to any revision yet", func() {
+		It("Should return the current specification of the template", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retrieved.Spec.EnvironmentList[0].Image).To(Equal("app:v2"))
+		})
+	})
+
+	When("the instance is pinned to the current revision", func() {
+		BeforeEach(func() {
+			digest, digestErr := forge.TemplateRevisionDigest(&template.Spec)
+			Expect(digestErr).ToNot(HaveOccurred())
+			instance.Status.TemplateRevision = &clv1alpha2.InstanceTemplateRevisionStatus{
+				Name: forge.TemplateRevisionName(&template, digest), Revision: digest,
+			}
+		})
+
+		It("Should return the current specification of the template", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retrieved.Spec.EnvironmentList[0].Image).To(Equal("app:v2"))
+		})
+	})
+
+	When("the instance is pinned to a previous revision", func() {
+		BeforeEach(func() {
+			instance.Status.TemplateRevision = &clv1alpha2.InstanceTemplateRevisionStatus{
+				Name: revision.Name, Revision: revision.Spec.Revision,
+			}
+		})
+
+		When("the revision exists", func() {
+			BeforeEach(func() { objects = append(objects, &revision) })
+
+			It("Should return the specification of the pinned revision", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(retrieved.Name).To(Equal(templateName))
+				Expect(retrieved.Spec.EnvironmentList[0].Image).To(Equal("app:v1"))
+			})
+
+			It("Should return the environment of the pinned revision", func() {
+				env, envErr := instautoctrl.TemplateEnvironment(retrieved)
+				Expect(envErr).ToNot(HaveOccurred())
+				Expect(env.Image).To(Equal("app:v1"))
+			})
+		})
+
+		When("the revision does not exist", func() {
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+		})
+	})
+})
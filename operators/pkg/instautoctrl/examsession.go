@@ -0,0 +1,396 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+const (
+	// EvExamSessionPhase -> the type of the event generated when an exam session changes phase.
+	EvExamSessionPhase = "ExamSessionPhase"
+	// EvExamSessionPhaseMsg -> the message of the event generated when an exam session changes phase.
+	EvExamSessionPhaseMsg = "Exam session phase changed to %v"
+	// EvExamSessionFailed -> the type of the event generated when an exam session cannot be carried out.
+	EvExamSessionFailed = "ExamSessionFailed"
+)
+
+// ExamSessionReconciler manages the Instances of the exam sessions, creating them shortly before
+// the start time, setting them running at the start time, and terminating them at the deadline.
+type ExamSessionReconciler struct {
+	client.Client
+	EventsRecorder     record.EventRecorder
+	Scheme             *runtime.Scheme
+	NamespaceWhitelist metav1.LabelSelector
+	// This function, if configured, is deferred at the beginning of the Reconcile.
+	// Specifically, it is meant to be set to GinkgoRecover during the tests,
+	// in order to lead to a controlled failure in case the Reconcile panics.
+	ReconcileDeferHook func()
+}
+
+// SetupWithManager registers a new controller for ExamSession resources.
+func (r *ExamSessionReconciler) SetupWithManager(mgr ctrl.Manager, concurrency int) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&clv1alpha2.ExamSession{}).
+		// Instances cannot be owned by the exam sessions, as living in different namespaces.
+		Watches(&clv1alpha2.Instance{}, handler.EnqueueRequestsFromMapFunc(r.instanceToExamSession)).
+		Named("exam-session").
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: concurrency,
+		}).
+		WithLogConstructor(utils.LogConstructor(mgr.GetLogger(), "ExamSession")).
+		Complete(r)
+}
+
+// Reconcile reconciles the Instances and the status of an ExamSession.
+func (r *ExamSessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	if r.ReconcileDeferHook != nil {
+		defer r.ReconcileDeferHook()
+	}
+
+	log := ctrl.LoggerFrom(ctx, "examsession", req.NamespacedName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	var session clv1alpha2.ExamSession
+	if err := r.Get(ctx, req.NamespacedName, &session); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "failed retrieving exam session")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Check the selector label, in order to know whether to perform or not reconciliation.
+	if proceed, err := utils.CheckSelectorLabel(ctx, r.Client, session.GetNamespace(), r.NamespaceWhitelist.MatchLabels); !proceed {
+		if err != nil {
+			err = fmt.Errorf("failed checking selector label: %w", err)
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Terminate the Instances of the exam session before it is deleted, not to leave them running.
+	if !session.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(&session, clv1alpha2.ExamSessionFinalizerName) {
+			if err := r.cleanupInstances(ctx, &session); err != nil {
+				log.Error(err, "failed cleaning up exam session instances")
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(&session, clv1alpha2.ExamSessionFinalizerName)
+			if err := r.Update(ctx, &session); err != nil {
+				log.Error(err, "failed removing finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Add the finalizer if missing, before creating any Instance.
+	if !controllerutil.ContainsFinalizer(&session, clv1alpha2.ExamSessionFinalizerName) {
+		controllerutil.AddFinalizer(&session, clv1alpha2.ExamSessionFinalizerName)
+		if err := r.Update(ctx, &session); err != nil {
+			log.Error(err, "failed adding finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Patch the status at the end of the reconciliation, if changed.
+	original := session.DeepCopy()
+	defer func() {
+		if original.Status.Phase != session.Status.Phase {
+			log.Info("exam session phase changed", "phase", session.Status.Phase)
+			r.EventsRecorder.Eventf(&session, corev1.EventTypeNormal, EvExamSessionPhase, EvExamSessionPhaseMsg, session.Status.Phase)
+		}
+		if err2 := r.Status().Patch(ctx, &session, client.MergeFrom(original)); err2 != nil {
+			log.Error(err2, "failed updating exam session status")
+			err = errors.Join(err, err2)
+		}
+	}()
+
+	phase, requeueAfter := forge.ExamSessionPhaseAt(&session, time.Now())
+	if phase == clv1alpha2.ExamSessionPhaseScheduled {
+		session.Status.Phase, session.Status.Message = phase, ""
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	templates, workspace, err := r.retrieveTemplates(ctx, &session)
+	if err != nil {
+		session.Status.Phase, session.Status.Message = clv1alpha2.ExamSessionPhaseFailed, err.Error()
+		r.EventsRecorder.Event(&session, corev1.EventTypeWarning, EvExamSessionFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	tenants, err := r.retrieveStudents(ctx, &session, workspace)
+	if err != nil {
+		log.Error(err, "failed retrieving exam session students")
+		return ctrl.Result{}, err
+	}
+
+	students := make([]clv1alpha2.ExamSessionStudentStatus, len(tenants))
+	for i := range tenants {
+		students[i] = r.enforceStudent(ctx, &session, phase, templates, workspace, tenants[i])
+	}
+
+	session.Status.Phase, session.Status.Message = phase, ""
+	session.Status.Students = students
+	session.Status.ReadyStudents = 0
+	for i := range students {
+		if forge.ExamSessionStudentReady(&students[i], len(templates)) {
+			session.Status.ReadyStudents++
+		}
+	}
+
+	// Requeue at the next phase (the updates of the Instances are watched).
+	if phase == clv1alpha2.ExamSessionPhaseCompleted {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// retrieveTemplates retrieves the Templates of the ExamSession, along with the Workspace they belong to.
+// The Templates are restricted to the namespace of the ExamSession, not to grant access to the ones of other Workspaces.
+func (r *ExamSessionReconciler) retrieveTemplates(ctx context.Context, session *clv1alpha2.ExamSession) (templates []clv1alpha2.Template, workspace string, err error) {
+	templates = make([]clv1alpha2.Template, len(session.Spec.Templates))
+	for i := range session.Spec.Templates {
+		ref := forge.ExamSessionTemplateRef(session, session.Spec.Templates[i])
+		if ref.Namespace != session.Namespace {
+			return nil, "", fmt.Errorf("template %s/%s does not belong to the namespace of the exam session", ref.Namespace, ref.Name)
+		}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &templates[i]); err != nil {
+			return nil, "", fmt.Errorf("failed retrieving template %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		if _, err := TemplateEnvironment(&templates[i]); err != nil {
+			return nil, "", fmt.Errorf("invalid template %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		if i > 0 && templates[i].Spec.WorkspaceRef.Name != workspace {
+			return nil, "", fmt.Errorf("template %s/%s belongs to a different workspace", ref.Namespace, ref.Name)
+		}
+		workspace = templates[i].Spec.WorkspaceRef.Name
+	}
+
+	if session.Spec.Workspace != "" && session.Spec.Workspace != workspace {
+		return nil, "", fmt.Errorf("workspace %s differs from the one of the templates", session.Spec.Workspace)
+	}
+	return templates, workspace, nil
+}
+
+// retrieveStudents returns the sorted names of the Tenants taking the ExamSession, i.e., the ones explicitly
+// listed and the users of the Workspace (if requested).
+func (r *ExamSessionReconciler) retrieveStudents(ctx context.Context, session *clv1alpha2.ExamSession, workspace string) ([]string, error) {
+	students := slices.Clone(session.Spec.Tenants)
+
+	if session.Spec.Workspace != "" {
+		var tenants clv1alpha2.TenantList
+		if err := r.List(ctx, &tenants, client.MatchingLabels{
+			clv1alpha2.WorkspaceLabelPrefix + workspace: string(clv1alpha2.User),
+		}); err != nil {
+			return nil, err
+		}
+		for i := range tenants.Items {
+			students = append(students, tenants.Items[i].Name)
+		}
+	}
+
+	slices.Sort(students)
+	return slices.Compact(students), nil
+}
+
+// enforceStudent enforces the Instances of a student of the ExamSession, returning the corresponding status.
+func (r *ExamSessionReconciler) enforceStudent(ctx context.Context, session *clv1alpha2.ExamSession,
+	phase clv1alpha2.ExamSessionPhase, templates []clv1alpha2.Template, workspace, tenantName string) clv1alpha2.ExamSessionStudentStatus {
+	log := ctrl.LoggerFrom(ctx, "tenant", tenantName)
+	status := clv1alpha2.ExamSessionStudentStatus{Tenant: tenantName}
+
+	var tenant clv1alpha2.Tenant
+	if err := r.Get(ctx, types.NamespacedName{Name: tenantName}, &tenant); err != nil {
+		log.Error(err, "failed retrieving tenant")
+		status.Error = fmt.Sprintf("failed retrieving tenant: %v", err)
+		return status
+	}
+	// Prevent the exam sessions from creating Instances for the Tenants outside the Workspace.
+	if _, member := tenant.GetLabels()[clv1alpha2.WorkspaceLabelPrefix+workspace]; !member {
+		status.Error = fmt.Sprintf("the tenant is not a member of workspace %s", workspace)
+		return status
+	}
+	if !tenant.Status.PersonalNamespace.Created {
+		status.Error = "the personal namespace of the tenant is not ready"
+		return status
+	}
+
+	var errs []error
+	for i := range templates {
+		instance, err := r.enforceInstance(ctrl.LoggerInto(ctx, log), session, phase, &templates[i], &tenant)
+		if err != nil {
+			log.Error(err, "failed enforcing exam session instance", "template", templates[i].Name)
+			errs = append(errs, err)
+			continue
+		}
+		if instance != nil {
+			status.Instances = append(status.Instances, forge.ExamSessionInstanceStatus(session, instance))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// enforceInstance enforces the Instance of the given Template for a student of the ExamSession, depending on its phase.
+// It returns nil if the Instance does not exist, and it is no longer to be created (i.e., after the deadline).
+func (r *ExamSessionReconciler) enforceInstance(ctx context.Context, session *clv1alpha2.ExamSession,
+	phase clv1alpha2.ExamSessionPhase, template *clv1alpha2.Template, tenant *clv1alpha2.Tenant) (*clv1alpha2.Instance, error) {
+	log := ctrl.LoggerFrom(ctx)
+	ref := clv1alpha2.GenericRef{Name: template.Name, Namespace: template.Namespace}
+
+	instance := clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{
+		Name:      forge.ExamSessionInstanceName(session, template.Name),
+		Namespace: tenant.Status.PersonalNamespace.Name,
+	}}
+
+	if err := r.Get(ctx, forge.NamespacedName(&instance), &instance); err != nil {
+		if !kerrors.IsNotFound(err) || phase == clv1alpha2.ExamSessionPhaseCompleted {
+			return nil, client.IgnoreNotFound(err)
+		}
+
+		running := phase == clv1alpha2.ExamSessionPhaseRunning
+		instance.Spec = forge.ExamSessionInstanceSpec(session, ref, tenant.Name, running)
+		instance.SetLabels(forge.ExamSessionInstanceLabels(nil, session))
+		if running {
+			instance.SetAnnotations(map[string]string{forge.ExamSessionStageAnnotation: forge.ExamSessionStageStarted})
+		}
+		if err := r.Create(ctx, &instance); err != nil {
+			return nil, fmt.Errorf("failed creating instance %s: %w", instance.Name, err)
+		}
+		log.Info("exam session instance created", "instance", instance.Name, "running", running)
+	} else if !forge.IsExamSessionInstance(&instance, session) {
+		return nil, fmt.Errorf("instance %s already exists, and it does not belong to the exam session", instance.Name)
+	}
+
+	stage := instance.GetAnnotations()[forge.ExamSessionStageAnnotation]
+	switch {
+	case phase == clv1alpha2.ExamSessionPhaseRunning && stage == "":
+		instance.SetAnnotations(labels.Merge(instance.GetAnnotations(), map[string]string{forge.ExamSessionStageAnnotation: forge.ExamSessionStageStarted}))
+		instance.Spec.Running = true
+		if err := r.Update(ctx, &instance); err != nil {
+			return nil, fmt.Errorf("failed starting instance %s: %w", instance.Name, err)
+		}
+		log.Info("exam session instance started", "instance", instance.Name)
+
+	case phase == clv1alpha2.ExamSessionPhaseCompleted && stage != forge.ExamSessionStageTerminated:
+		// The submission is configured according to the revision of the template the instance is pinned to.
+		pinned, err := RetrieveTemplate(ctx, r.Client, &instance)
+		if err != nil {
+			return nil, err
+		}
+		environment, err := TemplateEnvironment(pinned)
+		if err != nil {
+			return nil, err
+		}
+		// Trigger the submission (or grading) of the instance, as for the ones terminated by the status check.
+		instance.SetAnnotations(labels.Merge(instance.GetAnnotations(), map[string]string{forge.ExamSessionStageAnnotation: forge.ExamSessionStageTerminated}))
+		if err := StopInstance(ctx, r.Client, &instance, environment); err != nil {
+			return nil, fmt.Errorf("failed terminating instance %s: %w", instance.Name, err)
+		}
+		log.Info("exam session instance terminated", "instance", instance.Name)
+	}
+
+	return &instance, nil
+}
+
+// cleanupInstances cleans up the Instances of a deleted ExamSession: the ones not yet started are deleted, while the
+// ones started are terminated (triggering the submission of their content, or their grading), as at the deadline.
+// The terminated Instances are preserved, not to lose the work of the students.
+func (r *ExamSessionReconciler) cleanupInstances(ctx context.Context, session *clv1alpha2.ExamSession) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var instances clv1alpha2.InstanceList
+	if err := r.List(ctx, &instances, client.MatchingLabels(forge.ExamSessionInstanceLabels(nil, session))); err != nil {
+		return fmt.Errorf("failed listing instances: %w", err)
+	}
+
+	var errs []error
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		switch instance.GetAnnotations()[forge.ExamSessionStageAnnotation] {
+		case "":
+			if err := r.Delete(ctx, instance); client.IgnoreNotFound(err) != nil {
+				errs = append(errs, fmt.Errorf("failed deleting instance %s/%s: %w", instance.Namespace, instance.Name, err))
+				continue
+			}
+			log.Info("exam session instance deleted", "instance", forge.NamespacedName(instance))
+
+		case forge.ExamSessionStageStarted:
+			if err := r.terminateInstance(ctx, instance); err != nil {
+				errs = append(errs, fmt.Errorf("failed terminating instance %s/%s: %w", instance.Namespace, instance.Name, err))
+				continue
+			}
+			log.Info("exam session instance terminated", "instance", forge.NamespacedName(instance))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// terminateInstance terminates an Instance of a deleted ExamSession, triggering the submission of its content (or its grading),
+// according to the revision of the Template the Instance is pinned to. If the Template no longer exists, the Instance is just
+// stopped, as the submission cannot be configured.
+func (r *ExamSessionReconciler) terminateInstance(ctx context.Context, instance *clv1alpha2.Instance) error {
+	instance.SetAnnotations(labels.Merge(instance.GetAnnotations(), map[string]string{forge.ExamSessionStageAnnotation: forge.ExamSessionStageTerminated}))
+
+	template, err := RetrieveTemplate(ctx, r.Client, instance)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		instance.Spec.Running = false
+		return r.Update(ctx, instance)
+	}
+
+	environment, err := TemplateEnvironment(template)
+	if err != nil {
+		return err
+	}
+	return StopInstance(ctx, r.Client, instance, environment)
+}
+
+// instanceToExamSession maps an Instance to the ExamSession it belongs to, if any.
+func (r *ExamSessionReconciler) instanceToExamSession(_ context.Context, obj client.Object) []reconcile.Request {
+	instanceLabels := obj.GetLabels()
+	name, namespace := instanceLabels[forge.ExamSessionNameLabel], instanceLabels[forge.ExamSessionNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
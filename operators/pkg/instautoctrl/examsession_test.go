@@ -0,0 +1,282 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
+)
+
+var _ = Describe("The exam session controller", func() {
+	const (
+		namespace = "workspace-netgroup"
+		workspace = "netgroup"
+	)
+
+	var (
+		ctx           context.Context
+		clientBuilder *fake.ClientBuilder
+		reconciler    instautoctrl.ExamSessionReconciler
+		session       clv1alpha2.ExamSession
+		err           error
+	)
+
+	tenant := func(name string, member bool) *clv1alpha2.Tenant {
+		tenant := &clv1alpha2.Tenant{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if member {
+			tenant.SetLabels(map[string]string{clv1alpha2.WorkspaceLabelPrefix + workspace: string(clv1alpha2.User)})
+		}
+		tenant.Status.PersonalNamespace = clv1alpha2.NameCreated{Name: "tenant-" + name, Created: true}
+		return tenant
+	}
+
+	instance := func(tenantName, stage string) *clv1alpha2.Instance {
+		instance := &clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      forge.ExamSessionInstanceName(&session, "desktop"),
+				Namespace: "tenant-" + tenantName,
+				Labels:    forge.ExamSessionInstanceLabels(nil, &session),
+			},
+			Spec: forge.ExamSessionInstanceSpec(&session, session.Spec.Templates[0], tenantName, stage == forge.ExamSessionStageStarted),
+		}
+		if stage != "" {
+			instance.SetAnnotations(map[string]string{forge.ExamSessionStageAnnotation: stage})
+		}
+		return instance
+	}
+
+	// pinned pins the given instance to a revision of the template with the given environment, returning the revision as well.
+	pinned := func(instance *clv1alpha2.Instance, environment clv1alpha2.Environment) (*clv1alpha2.Instance, *clv1alpha2.TemplateRevision) {
+		template := &clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: "desktop", Namespace: namespace},
+			Spec: clv1alpha2.TemplateSpec{
+				WorkspaceRef:    clv1alpha2.GenericRef{Name: workspace},
+				EnvironmentList: []clv1alpha2.Environment{environment},
+			},
+		}
+		digest, err := forge.TemplateRevisionDigest(&template.Spec)
+		Expect(err).ToNot(HaveOccurred())
+		revision := &clv1alpha2.TemplateRevision{
+			ObjectMeta: forge.TemplateRevisionObjectMeta(template, digest),
+			Spec:       forge.TemplateRevisionSpec(template, digest),
+		}
+		instance.Status.TemplateRevision = &clv1alpha2.InstanceTemplateRevisionStatus{Name: revision.Name, Revision: digest}
+		return instance, revision
+	}
+
+	getInstance := func(tenantName string) (*clv1alpha2.Instance, error) {
+		var instance clv1alpha2.Instance
+		key := client.ObjectKey{Namespace: "tenant-" + tenantName, Name: forge.ExamSessionInstanceName(&session, "desktop")}
+		return &instance, reconciler.Get(ctx, key, &instance)
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), GinkgoLogr)
+		now := time.Now().Truncate(time.Second)
+		session = clv1alpha2.ExamSession{
+			ObjectMeta: metav1.ObjectMeta{Name: "networks-jan", Namespace: namespace},
+			Spec: clv1alpha2.ExamSessionSpec{
+				Templates:         []clv1alpha2.GenericRef{{Name: "desktop"}},
+				Tenants:           []string{"outsider"},
+				Workspace:         workspace,
+				StartTime:         metav1.NewTime(now.Add(-time.Hour)),
+				EndTime:           metav1.NewTime(now.Add(time.Hour)),
+				CustomizationUrls: &clv1alpha2.InstanceCustomizationUrls{ContentDestination: "https://exams.example.com/submissions"},
+			},
+		}
+
+		clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&clv1alpha2.ExamSession{}, &clv1alpha2.Instance{}).
+			WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+				&clv1alpha2.Template{
+					ObjectMeta: metav1.ObjectMeta{Name: "desktop", Namespace: namespace},
+					Spec: clv1alpha2.TemplateSpec{
+						WorkspaceRef:    clv1alpha2.GenericRef{Name: workspace},
+						EnvironmentList: []clv1alpha2.Environment{{Name: "desktop", Persistent: true}},
+					},
+				},
+				tenant("member", true), tenant("outsider", false),
+			)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instautoctrl.ExamSessionReconciler{
+			Client:         clientBuilder.WithObjects(&session).Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: record.NewFakeRecorder(1024),
+		}
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&session)})
+	})
+
+	When("the exam session is running", func() {
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should add the finalizer", func() {
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session)).To(Succeed())
+			Expect(session.GetFinalizers()).To(ContainElement(clv1alpha2.ExamSessionFinalizerName))
+		})
+
+		It("Should create the running instances of the members of the workspace", func() {
+			created, err := getInstance("member")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Spec.Running).To(BeTrue())
+			Expect(created.GetAnnotations()).To(HaveKeyWithValue(forge.ExamSessionStageAnnotation, forge.ExamSessionStageStarted))
+		})
+
+		It("Should not create the instances of the tenants outside the workspace", func() {
+			_, err := getInstance("outsider")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session)).To(Succeed())
+			Expect(session.Status.Students).To(ContainElement(And(
+				HaveField("Tenant", "outsider"), HaveField("Error", ContainSubstring("not a member of workspace")))))
+		})
+
+		It("Should report the deadline in the status of the session, without modifying the one of the instances", func() {
+			created, err := getInstance("member")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Status.Automation.TerminationTime.IsZero()).To(BeTrue())
+
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session)).To(Succeed())
+			Expect(session.Status.Phase).To(Equal(clv1alpha2.ExamSessionPhaseRunning))
+			Expect(session.Status.Students).To(ContainElement(And(HaveField("Tenant", "member"), HaveField("Instances", ConsistOf(
+				HaveField("Automation.TerminationTime.Time", BeTemporally("==", session.Spec.EndTime.Time)))))))
+		})
+	})
+
+	When("the exam session is completed", func() {
+		BeforeEach(func() {
+			now := time.Now().Truncate(time.Second)
+			session.Spec.StartTime = metav1.NewTime(now.Add(-2 * time.Hour))
+			session.Spec.EndTime = metav1.NewTime(now.Add(-time.Hour))
+
+			// In the revision the instance is pinned to, the environment is not persistent, hence the content cannot be submitted.
+			pinnedInstance, revision := pinned(instance("pinned", forge.ExamSessionStageStarted), clv1alpha2.Environment{Name: "desktop"})
+			clientBuilder.WithObjects(pinnedInstance, revision, tenant("pinned", true), instance("member", forge.ExamSessionStageStarted))
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should terminate the instances, triggering their submission", func() {
+			terminated, err := getInstance("member")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(terminated.Spec.Running).To(BeFalse())
+			Expect(terminated.GetAnnotations()).To(HaveKeyWithValue(forge.ExamSessionStageAnnotation, forge.ExamSessionStageTerminated))
+			Expect(terminated.GetLabels()).To(HaveKeyWithValue(forge.InstanceSubmissionSelectorLabel, "true"))
+		})
+
+		It("Should configure the submission according to the revision of the template the instances are pinned to", func() {
+			terminated, err := getInstance("pinned")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(terminated.Spec.Running).To(BeFalse())
+			Expect(terminated.GetLabels()).To(HaveKeyWithValue(forge.InstanceSubmissionSelectorLabel, "false"))
+		})
+	})
+
+	When("a template belongs to a different namespace", func() {
+		BeforeEach(func() {
+			session.Spec.Templates[0].Namespace = "workspace-other"
+		})
+
+		It("Should fail without creating any instance", func() {
+			Expect(err).To(MatchError(ContainSubstring("does not belong to the namespace of the exam session")))
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session)).To(Succeed())
+			Expect(session.Status.Phase).To(Equal(clv1alpha2.ExamSessionPhaseFailed))
+
+			_, err := getInstance("member")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	When("the workspace differs from the one of the templates", func() {
+		BeforeEach(func() {
+			session.Spec.Workspace = "other"
+		})
+
+		It("Should fail without creating any instance", func() {
+			Expect(err).To(MatchError(ContainSubstring("differs from the one of the templates")))
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session)).To(Succeed())
+			Expect(session.Status.Phase).To(Equal(clv1alpha2.ExamSessionPhaseFailed))
+		})
+	})
+
+	When("the exam session is being deleted", func() {
+		BeforeEach(func() {
+			session.SetFinalizers([]string{clv1alpha2.ExamSessionFinalizerName})
+			session.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			clientBuilder.WithObjects(
+				tenant("prepared", true), instance("prepared", ""),
+				tenant("started", true), instance("started", forge.ExamSessionStageStarted),
+				tenant("terminated", true), instance("terminated", forge.ExamSessionStageTerminated),
+			)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should delete the instances not yet started", func() {
+			_, err := getInstance("prepared")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Should terminate the running instances, triggering their submission", func() {
+			terminated, err := getInstance("started")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(terminated.Spec.Running).To(BeFalse())
+			Expect(terminated.GetAnnotations()).To(HaveKeyWithValue(forge.ExamSessionStageAnnotation, forge.ExamSessionStageTerminated))
+			Expect(terminated.GetLabels()).To(HaveKeyWithValue(forge.InstanceSubmissionSelectorLabel, "true"))
+		})
+
+		When("a running instance is pinned to a previous revision of the template", func() {
+			BeforeEach(func() {
+				// In the pinned revision, the environment is not persistent, hence the content cannot be submitted.
+				pinnedInstance, revision := pinned(instance("pinned", forge.ExamSessionStageStarted), clv1alpha2.Environment{Name: "desktop"})
+				clientBuilder.WithObjects(pinnedInstance, revision, tenant("pinned", true))
+			})
+
+			It("Should configure the submission according to the pinned revision", func() {
+				terminated, err := getInstance("pinned")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(terminated.Spec.Running).To(BeFalse())
+				Expect(terminated.GetAnnotations()).To(HaveKeyWithValue(forge.ExamSessionStageAnnotation, forge.ExamSessionStageTerminated))
+				Expect(terminated.GetLabels()).To(HaveKeyWithValue(forge.InstanceSubmissionSelectorLabel, "false"))
+			})
+		})
+
+		It("Should preserve the instances already terminated", func() {
+			_, err := getInstance("terminated")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should remove the finalizer, completing the deletion", func() {
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, client.ObjectKeyFromObject(&session), &session))).To(BeTrue())
+		})
+	})
+})
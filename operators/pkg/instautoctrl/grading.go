@@ -0,0 +1,243 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authv1 "k8s.io/api/authentication/v1"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// DefaultGradingSetupMaxRetries -> the default maximum number of attempts to set up the grading of an instance.
+const DefaultGradingSetupMaxRetries = 10
+
+// PodLogsReader returns (at most limitBytes of) the logs of the given container of a pod.
+type PodLogsReader func(ctx context.Context, namespace, pod, container string, limitBytes int64) ([]byte, error)
+
+// NewPodLogsReader returns a PodLogsReader retrieving the logs through the given clientset.
+func NewPodLogsReader(clientset kubernetes.Interface) PodLogsReader {
+	return func(ctx context.Context, namespace, pod, container string, limitBytes int64) ([]byte, error) {
+		return clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container, LimitBytes: &limitBytes}).DoRaw(ctx)
+	}
+}
+
+// EnforceInstanceGrading ensures that the grading job for the given instance is present,
+// and returns the grading status once the job has been completed (nil otherwise).
+func (r *InstanceSubmissionReconciler) EnforceInstanceGrading(ctx context.Context, instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) (*clv1alpha2.InstanceGradingStatus, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("grading")
+
+	graderName := "grader"
+	job := batch.Job{ObjectMeta: forge.ObjectMetaWithSuffix(instance, graderName)}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&job), &job); client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("failed retrieving grading job: %w", err)
+	}
+
+	// The (short-lived) credentials are generated only once, right before the creation of the job.
+	if job.CreationTimestamp.IsZero() {
+		if err := r.EnforceGradingKubeconfig(ctx, instance, environment); err != nil {
+			return r.gradingSetupFailed(ctx, instance, err)
+		}
+	}
+
+	jobSpec := forge.GradingJobSpec(instance, environment, &r.ContainerEnvOpts)
+	op, err := ctrl.CreateOrUpdate(ctx, r.Client, &job, func() error {
+		if job.CreationTimestamp.IsZero() {
+			job.Spec = jobSpec
+		}
+		job.SetLabels(forge.InstanceComponentLabels(instance, graderName))
+		return ctrl.SetControllerReference(instance, &job, r.Scheme)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed ensuring grading job (operation=%s): %w", op, err)
+	}
+
+	var status *clv1alpha2.InstanceGradingStatus
+	switch {
+	case job.Status.Succeeded > 0:
+		if status, err = r.RetrieveGradingStatus(ctx, &job); err != nil {
+			return nil, err
+		}
+	case jobFailed(&job):
+		status = forge.GradingErrorStatus(jobFailureMessage(&job))
+	default:
+		return nil, nil
+	}
+
+	status.CompletionTime = metav1.Now()
+	if job.Status.CompletionTime != nil {
+		status.CompletionTime = *job.Status.CompletionTime
+	}
+
+	log.Info("grading completed", "outcome", status.Outcome, "passed", status.Passed, "total", status.Total)
+	return status, nil
+}
+
+// gradingSetupFailed records a failed attempt to set up the grading of the instance, returning the original error
+// (i.e., to retry) until the maximum number of attempts is reached, and a grading error status afterwards.
+func (r *InstanceSubmissionReconciler) gradingSetupFailed(ctx context.Context, instance *clv1alpha2.Instance, setupErr error) (*clv1alpha2.InstanceGradingStatus, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("grading")
+
+	instance.Status.Automation.GradingSetupFailures++
+	if err := r.Status().Update(ctx, instance); err != nil {
+		log.Error(err, "failed updating the grading setup failures")
+		return nil, err
+	}
+
+	maxRetries := r.GradingSetupMaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultGradingSetupMaxRetries
+	}
+	if instance.Status.Automation.GradingSetupFailures < maxRetries {
+		return nil, setupErr
+	}
+
+	log.Info("grading setup failed too many times, giving up", "attempts", instance.Status.Automation.GradingSetupFailures, "error", setupErr)
+	status := forge.GradingErrorStatus(fmt.Sprintf("failed accessing the workload cluster: %v", setupErr))
+	status.CompletionTime = metav1.Now()
+	return status, nil
+}
+
+// EnforceGradingKubeconfig grants the checker read-only access to the workload cluster, and stores
+// the corresponding kubeconfig in a secret in the instance namespace, to be mounted by the grading job.
+func (r *InstanceSubmissionReconciler) EnforceGradingKubeconfig(ctx context.Context, instance *clv1alpha2.Instance, environment *clv1alpha2.Environment) error {
+	log := ctrl.LoggerFrom(ctx).WithName("grading")
+
+	config, err := utils.WorkloadClusterConfig(ctx, r.Client, instance.Namespace, fmt.Sprintf("%s-cluster", environment.Cluster.Name))
+	if err != nil {
+		log.Error(err, "failed retrieving the workload cluster configuration")
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed creating the workload cluster client: %w", err)
+	}
+
+	if _, err := clientset.CoreV1().ServiceAccounts(forge.GradingServiceAccountNamespace).
+		Create(ctx, forge.GradingServiceAccount(), metav1.CreateOptions{}); err != nil && !kerrors.IsAlreadyExists(err) {
+		log.Error(err, "failed creating the grading service account in the workload cluster")
+		return err
+	}
+
+	if _, err := clientset.RbacV1().ClusterRoleBindings().
+		Create(ctx, forge.GradingClusterRoleBinding(), metav1.CreateOptions{}); err != nil && !kerrors.IsAlreadyExists(err) {
+		log.Error(err, "failed creating the grading cluster role binding in the workload cluster")
+		return err
+	}
+
+	token, err := clientset.CoreV1().ServiceAccounts(forge.GradingServiceAccountNamespace).CreateToken(ctx, forge.GradingServiceAccountName,
+		&authv1.TokenRequest{Spec: authv1.TokenRequestSpec{
+			ExpirationSeconds: ptr.To(forge.GradingTokenExpirationSeconds(environment.Cluster.Grading)),
+		}}, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err, "failed requesting the grading token in the workload cluster")
+		return err
+	}
+
+	kubeconfig, err := forge.GradingKubeconfig(config.Host, config.CAData, token.Status.Token)
+	if err != nil {
+		return fmt.Errorf("failed forging the grading kubeconfig: %w", err)
+	}
+
+	secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: forge.GradingKubeconfigSecretName(instance), Namespace: instance.Namespace}}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		secret.SetLabels(forge.InstanceObjectLabels(secret.GetLabels(), instance))
+		secret.Data = map[string][]byte{forge.GradingKubeconfigKey: kubeconfig}
+		secret.Type = corev1.SecretTypeOpaque
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed enforcing the grading kubeconfig secret", "secret", klog.KObj(&secret))
+		return err
+	}
+
+	log.V(utils.FromResult(res)).Info("grading kubeconfig secret enforced", "secret", klog.KObj(&secret), "result", res)
+	return nil
+}
+
+// RetrieveGradingStatus retrieves the report published through the logs of the reporter container of the given (completed) grading job.
+func (r *InstanceSubmissionReconciler) RetrieveGradingStatus(ctx context.Context, job *batch.Job) (*clv1alpha2.InstanceGradingStatus, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("grading")
+
+	if r.PodLogs == nil {
+		return nil, errors.New("the logs of the grading pods cannot be retrieved")
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{batch.JobNameLabel: job.Name}); err != nil {
+		log.Error(err, "failed retrieving the grading pods")
+		return nil, err
+	}
+
+	for i := range pods.Items {
+		for j := range pods.Items[i].Status.ContainerStatuses {
+			status := &pods.Items[i].Status.ContainerStatuses[j]
+			if status.Name != forge.GradingReporterName || status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+				continue
+			}
+
+			// One byte more than the maximum is requested, to detect whether the report exceeds the maximum size.
+			report, err := r.PodLogs(ctx, job.Namespace, pods.Items[i].Name, forge.GradingReporterName, forge.GradingReportMaxBytes+1)
+			if err != nil {
+				log.Error(err, "failed retrieving the grading report", "pod", klog.KObj(&pods.Items[i]))
+				return nil, err
+			}
+
+			checks, err := forge.ParseGradingReport(string(report))
+			if err != nil {
+				log.Info("invalid grading report", "error", err)
+				return forge.GradingErrorStatus(err.Error()), nil
+			}
+			return forge.GradingStatus(checks), nil
+		}
+	}
+
+	return forge.GradingErrorStatus("grading report not found"), nil
+}
+
+// jobFailed returns whether the given job has failed.
+func jobFailed(job *batch.Job) bool {
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == batch.JobFailed && job.Status.Conditions[i].Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// jobFailureMessage returns a message describing the failure of the given job.
+func jobFailureMessage(job *batch.Job) string {
+	for i := range job.Status.Conditions {
+		if condition := &job.Status.Conditions[i]; condition.Type == batch.JobFailed {
+			return fmt.Sprintf("grading job failed: %s %s", condition.Reason, condition.Message)
+		}
+	}
+	return "grading job failed"
+}
@@ -0,0 +1,172 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
+)
+
+var _ = Describe("The grading of cluster environments", func() {
+	const (
+		namespace = "tenant-tester"
+		report    = `{"checks": [{"name": "replicas", "passed": true}, {"name": "netpol", "passed": false}]}`
+	)
+
+	var (
+		ctx           context.Context
+		clientBuilder *fake.ClientBuilder
+		reconciler    instautoctrl.InstanceSubmissionReconciler
+		instance      clv1alpha2.Instance
+		environment   clv1alpha2.Environment
+		logs          []byte
+		logsErr       error
+		requested     int64
+
+		status *clv1alpha2.InstanceGradingStatus
+		err    error
+	)
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), GinkgoLogr)
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: namespace}}
+		environment = clv1alpha2.Environment{
+			Name:            "kubernetes",
+			EnvironmentType: clv1alpha2.ClassCluster,
+			Cluster: &clv1alpha2.ClusterTemplate{
+				Name:    "demo",
+				Grading: &clv1alpha2.ClusterGrading{Image: "checker:v1"},
+			},
+		}
+		logs, logsErr, requested = []byte(report), nil, 0
+		clientBuilder = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&clv1alpha2.Instance{}).WithObjects(&instance)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instautoctrl.InstanceSubmissionReconciler{
+			Client:         clientBuilder.Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: record.NewFakeRecorder(1024),
+			PodLogs: func(_ context.Context, ns, pod, container string, limitBytes int64) ([]byte, error) {
+				Expect(ns).To(Equal(namespace))
+				Expect(pod).To(Equal("grader-pod"))
+				Expect(container).To(Equal(forge.GradingReporterName))
+				requested = limitBytes
+				return logs, logsErr
+			},
+			GradingSetupMaxRetries: 2,
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&instance), &instance)).To(Succeed())
+		status, err = reconciler.EnforceInstanceGrading(ctx, &instance, &environment)
+	})
+
+	When("the grading job has completed successfully", func() {
+		BeforeEach(func() {
+			completion := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+			clientBuilder.WithObjects(
+				&batch.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000-grader", Namespace: namespace, CreationTimestamp: metav1.Now()},
+					Status:     batch.JobStatus{Succeeded: 1, CompletionTime: &completion},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "grader-pod", Namespace: namespace,
+						Labels: map[string]string{batch.JobNameLabel: "kubernetes-0000-grader"}},
+					Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+						Name:  forge.GradingReporterName,
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+					}}},
+				},
+			)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should report the outcome of the checks, retrieved from the logs of the reporter", func() {
+			Expect(status).ToNot(BeNil())
+			Expect(status.Outcome).To(Equal(clv1alpha2.GradingFailed))
+			Expect(status.Passed).To(Equal(1))
+			Expect(status.Total).To(Equal(2))
+		})
+
+		It("Should bound the size of the retrieved report", func() {
+			Expect(requested).To(BeNumerically("==", forge.GradingReportMaxBytes+1))
+		})
+
+		When("the report exceeds the maximum size", func() {
+			BeforeEach(func() {
+				logs = []byte(`{"checks": [], "padding": "` + strings.Repeat("x", forge.GradingReportMaxBytes) + `"}`)
+			})
+
+			It("Should report a grading error", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Outcome).To(Equal(clv1alpha2.GradingError))
+				Expect(status.Message).To(ContainSubstring("maximum size"))
+			})
+		})
+
+		When("the logs cannot be retrieved", func() {
+			BeforeEach(func() { logsErr = errors.New("transient failure") })
+
+			It("Should return an error, to retry later", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(status).To(BeNil())
+			})
+		})
+	})
+
+	When("the workload cluster cannot be accessed to set up the grading", func() {
+		It("Should return an error, to retry later", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(status).To(BeNil())
+		})
+
+		It("Should record the failed attempt", func() {
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&instance), &instance)).To(Succeed())
+			Expect(instance.Status.Automation.GradingSetupFailures).To(BeNumerically("==", 1))
+		})
+
+		It("Should report a grading error once the maximum number of attempts is reached", func() {
+			status, err = reconciler.EnforceInstanceGrading(ctx, &instance, &environment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).ToNot(BeNil())
+			Expect(status.Outcome).To(Equal(clv1alpha2.GradingError))
+			Expect(status.CompletionTime.IsZero()).To(BeFalse())
+		})
+
+		It("Should not create the grading job", func() {
+			var jobs batch.JobList
+			Expect(reconciler.List(ctx, &jobs)).To(Succeed())
+			Expect(jobs.Items).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,208 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+const (
+	// EvInstanceIdle -> the type of the event generated when an instance is stopped due to inactivity.
+	EvInstanceIdle = "InstanceIdle"
+	// EvInstanceIdleMsg -> the message of the event generated when an instance is stopped due to inactivity.
+	EvInstanceIdleMsg = "Instance stopped after being idle since %v (timeout: %v)"
+)
+
+// InstanceInactivityReconciler watches for instances to be stopped due to inactivity.
+type InstanceInactivityReconciler struct {
+	client.Client
+	EventsRecorder                record.EventRecorder
+	Scheme                        *runtime.Scheme
+	NamespaceWhitelist            metav1.LabelSelector
+	ActivityCheckRequestTimeout   time.Duration
+	InstanceActivityCheckInterval time.Duration
+	// The function returning the URL of the endpoint exposing the activity of an instance (forge.InstanceActivityURL if not set).
+	ActivityURL func(instance *clv1alpha2.Instance) string
+	// This function, if configured, is deferred at the beginning of the Reconcile.
+	// Specifically, it is meant to be set to GinkgoRecover during the tests,
+	// in order to lead to a controlled failure in case the Reconcile panics.
+	ReconcileDeferHook func()
+}
+
+// SetupWithManager registers a new controller for InstanceInactivityReconciler resources.
+// Changes to the status and to the metadata of the instances are ignored, as the activity
+// is periodically checked while the instances are running, through the requeue interval.
+func (r *InstanceInactivityReconciler) SetupWithManager(mgr ctrl.Manager, concurrency int) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&clv1alpha2.Instance{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("instance-inactivity").
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: concurrency,
+		}).
+		WithLogConstructor(utils.LogConstructor(mgr.GetLogger(), "InstanceInactivity")).
+		Complete(r)
+}
+
+// Reconcile checks whether the instance has been idle for longer than allowed by the template policy, and stops it if so.
+func (r *InstanceInactivityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.ReconcileDeferHook != nil {
+		defer r.ReconcileDeferHook()
+	}
+
+	log := ctrl.LoggerFrom(ctx, "instance", req.NamespacedName)
+	dbgLog := log.V(utils.LogDebugLevel)
+	tracer := trace.New("reconcile", trace.Field{Key: "instance", Value: req.NamespacedName})
+	ctx = ctrl.LoggerInto(trace.ContextWithTrace(ctx, tracer), log)
+
+	if dbgLog.Enabled() {
+		defer tracer.Log()
+	} else {
+		defer tracer.LogIfLong(r.ActivityCheckRequestTimeout / 2)
+	}
+
+	// Get the instance object.
+	var instance clv1alpha2.Instance
+	if err := r.Get(ctx, req.NamespacedName, &instance); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "failed retrieving instance")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	tracer.Step("instance retrieved")
+
+	// Skip if the instance is not running, as there is nothing to stop.
+	if !instance.Spec.Running {
+		dbgLog.Info("skipping instance", "reason", "not running")
+		return ctrl.Result{}, nil
+	}
+
+	// Check the selector label, in order to know whether to perform or not reconciliation.
+	if proceed, err := utils.CheckSelectorLabel(ctx, r.Client, instance.GetNamespace(), r.NamespaceWhitelist.MatchLabels); !proceed {
+		if err != nil {
+			err = fmt.Errorf("failed checking selector label: %w", err)
+		}
+		return ctrl.Result{}, err
+	}
+
+	tracer.Step("labels checked")
+
+	template, err := RetrieveTemplate(ctx, r.Client, &instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	environment, err := TemplateEnvironment(template)
+	if err != nil {
+		dbgLog.Info("skipping instance", "reason", err)
+		return ctrl.Result{}, nil
+	}
+
+	// Skip if the instance is not subject to any idle policy.
+	policy := forge.InstanceIdlePolicy(&template.Spec, environment)
+	if policy == nil || !forge.IdleDetectionSupported(environment) {
+		dbgLog.Info("skipping instance", "reason", "no applicable idle policy")
+		return ctrl.Result{}, nil
+	}
+
+	tracer.Step("policy retrieved")
+
+	// The activity endpoint is not available until the instance is ready.
+	if instance.Status.Phase != clv1alpha2.EnvironmentPhaseReady {
+		dbgLog.Info("requeueing instance", "reason", "not ready")
+		return ctrl.Result{RequeueAfter: r.InstanceActivityCheckInterval}, nil
+	}
+
+	idleSince, err := r.CheckInstanceActivity(ctx, &instance, policy)
+	if err != nil {
+		// Errors are not propagated, to avoid stopping instances in case of transient failures.
+		log.Error(err, "failed checking instance activity")
+		return ctrl.Result{RequeueAfter: r.InstanceActivityCheckInterval}, nil
+	}
+
+	tracer.Step("activity checked")
+
+	if idle := time.Since(idleSince); idle < policy.Timeout.Duration {
+		dbgLog.Info("requeueing instance", "idle", idle.Truncate(time.Second))
+		return ctrl.Result{RequeueAfter: min(r.InstanceActivityCheckInterval, policy.Timeout.Duration-idle)}, nil
+	}
+
+	log.Info("stopping idle instance", "idle-since", idleSince, "timeout", policy.Timeout.Duration)
+	if err := StopInstance(ctrl.LoggerInto(ctx, dbgLog), r.Client, &instance, environment); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed stopping idle instance: %w", err)
+	}
+
+	r.EventsRecorder.Eventf(&instance, corev1.EventTypeNormal, EvInstanceIdle, EvInstanceIdleMsg, idleSince.Format(time.RFC3339), policy.Timeout.Duration)
+	tracer.Step("instance stopped")
+	log.Info("idle instance stopped")
+
+	return ctrl.Result{}, nil
+}
+
+// CheckInstanceActivity retrieves the activity of the instance, records it in the status, and returns the time since which it is idle.
+// The status is updated only if the recorded activity differs by at least the check interval, i.e., when the instance becomes idle,
+// or at most once per interval while it is in use, to avoid continuously triggering the other controllers watching the instances.
+func (r *InstanceInactivityReconciler) CheckInstanceActivity(ctx context.Context, instance *clv1alpha2.Instance, policy *clv1alpha2.IdlePolicy) (time.Time, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("activity-check")
+	log.V(utils.LogDebugLevel).Info("performing instance activity check")
+
+	activityURL := forge.InstanceActivityURL
+	if r.ActivityURL != nil {
+		activityURL = r.ActivityURL
+	}
+
+	activity := ActivityResponse{}
+	statusCode, err := utils.HTTPGetJSONIntoStruct(ctx, activityURL(instance), &activity, r.ActivityCheckRequestTimeout)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if statusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("unexpected status code %d", statusCode)
+	}
+
+	idleSince := forge.InstanceIdleSince(policy, activity.LastActivity, activity.LastInput)
+
+	recorded := instance.Status.Automation.LastActivityTime
+	if !recorded.IsZero() && idleSince.Sub(recorded.Time).Abs() < r.InstanceActivityCheckInterval {
+		return idleSince, nil
+	}
+
+	original := instance.DeepCopy()
+	instance.Status.Automation.LastActivityTime = metav1.NewTime(idleSince)
+	if err := r.Status().Patch(ctx, instance, client.MergeFrom(original)); err != nil {
+		log.Error(err, "failed updating instance status")
+		return time.Time{}, err
+	}
+
+	return idleSince, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instautoctrl_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
+)
+
+var _ = Describe("The check of the activity of an instance", func() {
+	const checkInterval = 5 * time.Minute
+
+	var (
+		ctx        context.Context
+		reconciler instautoctrl.InstanceInactivityReconciler
+		instance   clv1alpha2.Instance
+		policy     clv1alpha2.IdlePolicy
+		activity   instautoctrl.ActivityResponse
+		statusCode int
+		server     *httptest.Server
+
+		idleSince time.Time
+		err       error
+	)
+
+	now := time.Now().Truncate(time.Second)
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), GinkgoLogr)
+		instance = clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0000", Namespace: "tenant-tester"}}
+		policy = clv1alpha2.IdlePolicy{Timeout: metav1.Duration{Duration: time.Hour}}
+		activity = instautoctrl.ActivityResponse{LastActivity: now, LastInput: now.Add(-30 * time.Minute), ActiveSessions: 1}
+		statusCode = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(statusCode)
+			_ = json.NewEncoder(w).Encode(activity)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instautoctrl.InstanceInactivityReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithStatusSubresource(&clv1alpha2.Instance{}).WithObjects(&instance).Build(),
+			Scheme:                        scheme.Scheme,
+			EventsRecorder:                record.NewFakeRecorder(1024),
+			ActivityCheckRequestTimeout:   time.Second,
+			InstanceActivityCheckInterval: checkInterval,
+			ActivityURL:                   func(*clv1alpha2.Instance) string { return server.URL },
+		}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&instance), &instance)).To(Succeed())
+		idleSince, err = reconciler.CheckInstanceActivity(ctx, &instance, &policy)
+	})
+
+	persisted := func() time.Time {
+		GinkgoHelper()
+		var current clv1alpha2.Instance
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(&instance), &current)).To(Succeed())
+		return current.Status.Automation.LastActivityTime.Time
+	}
+
+	It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+	It("Should return the time of the last activity", func() { Expect(idleSince).To(BeTemporally("==", now)) })
+	It("Should record the activity in the status", func() { Expect(persisted()).To(BeTemporally("==", now)) })
+
+	When("only the input is considered", func() {
+		BeforeEach(func() { policy.InputOnly = true })
+
+		It("Should return the time of the last input", func() {
+			Expect(idleSince).To(BeTemporally("==", now.Add(-30*time.Minute)))
+		})
+	})
+
+	When("the recorded activity is more recent than the check interval", func() {
+		BeforeEach(func() {
+			instance.Status.Automation.LastActivityTime = metav1.NewTime(now.Add(-time.Minute))
+		})
+
+		It("Should return the time of the last activity", func() { Expect(idleSince).To(BeTemporally("==", now)) })
+
+		It("Should not update the status", func() {
+			Expect(persisted()).To(BeTemporally("==", now.Add(-time.Minute)))
+		})
+	})
+
+	When("the recorded activity is older than the check interval", func() {
+		BeforeEach(func() {
+			instance.Status.Automation.LastActivityTime = metav1.NewTime(now.Add(-2 * checkInterval))
+		})
+
+		It("Should update the status", func() { Expect(persisted()).To(BeTemporally("==", now)) })
+	})
+
+	When("the instance became idle since the recorded activity", func() {
+		BeforeEach(func() {
+			instance.Status.Automation.LastActivityTime = metav1.NewTime(now)
+			activity.LastActivity = now.Add(-time.Hour)
+		})
+
+		It("Should update the status", func() { Expect(persisted()).To(BeTemporally("==", now.Add(-time.Hour))) })
+	})
+
+	When("the activity endpoint returns an error", func() {
+		BeforeEach(func() { statusCode = http.StatusServiceUnavailable })
+
+		It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+		It("Should not update the status", func() { Expect(persisted()).To(BeZero()) })
+	})
+})
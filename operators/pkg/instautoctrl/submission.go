@@ -42,6 +42,10 @@ type InstanceSubmissionReconciler struct {
 	Scheme             *runtime.Scheme
 	NamespaceWhitelist metav1.LabelSelector
 	ContainerEnvOpts   forge.ContainerEnvOpts
+	// The function retrieving the logs of the grading jobs, which publish the grading report.
+	PodLogs PodLogsReader
+	// The maximum number of attempts to set up the grading (i.e., to access the workload cluster), before reporting a grading error.
+	GradingSetupMaxRetries int32
 	// This function, if configured, is deferred at the beginning of the Reconcile.
 	// Specifically, it is meant to be set to GinkgoRecover during the tests,
 	// in order to lead to a controlled failure in case the Reconcile panics.
@@ -100,7 +104,27 @@ func (r *InstanceSubmissionReconciler) Reconcile(ctx context.Context, req ctrl.R
 	}
 	tracer.Step("retrieved the instance environment")
 
-	if err := CheckEnvironmentValidity(&instance, environment); err != nil {
+	if forge.GradingRequired(environment) {
+		grading, err := r.EnforceInstanceGrading(ctx, &instance, environment)
+		switch {
+		case err != nil:
+			return ctrl.Result{}, err
+		case grading == nil: // the grading job hasn't been completed yet
+			tracer.Step("grading job enforced")
+			dbgLog.Info("waiting for grading completion")
+			return ctrl.Result{}, nil
+		}
+
+		instance.Status.Grading = grading
+		instance.Status.Automation.SubmissionTime = grading.CompletionTime
+		if err := r.Status().Update(ctx, &instance); err != nil {
+			log.Error(err, "failed updating instance status")
+			return ctrl.Result{}, err
+		}
+		tracer.Step("instance status updated")
+		log.Info("instance grading completed", "outcome", grading.Outcome)
+		instance.SetLabels(forge.InstanceAutomationLabelsOnSubmission(instance.GetLabels(), grading.Outcome != clv1alpha2.GradingError))
+	} else if err := CheckEnvironmentValidity(&instance, environment); err != nil {
 		instance.SetLabels(forge.InstanceAutomationLabelsOnSubmission(instance.GetLabels(), false))
 		dbgLog.Info("instance submission aborted")
 	} else {
@@ -181,24 +181,11 @@ func (r *InstanceTerminationReconciler) TerminateInstance(ctx context.Context, i
 	log := ctrl.LoggerFrom(ctx).WithName("termination")
 	log.Info("terminating instance")
 
-	submissionRequired := false
-
 	environment, err := RetrieveEnvironment(ctx, r.Client, instance)
 	if err != nil {
 		log.Info("failed retrieving environment", "error", err)
 		return err
 	}
 
-	if err := CheckEnvironmentValidity(instance, environment); err != nil {
-		log.Info("instance not eligible for submission", "error", err)
-	} else {
-		submissionRequired = true
-		log.Info("submission required")
-	}
-
-	instance.SetLabels(forge.InstanceAutomationLabelsOnTermination(instance.GetLabels(), submissionRequired))
-
-	instance.Spec.Running = false
-
-	return r.Update(ctx, instance)
+	return StopInstance(ctrl.LoggerInto(ctx, log), r.Client, instance, environment)
 }
@@ -44,10 +44,7 @@ const (
 // EnforceCloudInitSecret enforces the creation/update of a secret containing the cloud-init configuration,
 // based on the information retrieved for the tenant object and its associated WebDav credentials.
 func (r *InstanceReconciler) EnforceCloudInitSecret(ctx context.Context) error {
-	var nfsServerName, nfsPath string
-
 	log := ctrl.LoggerFrom(ctx)
-	env := clctx.EnvironmentFrom(ctx)
 
 	// Retrieve the public keys.
 	publicKeys, err := r.GetPublicKeys(ctx)
@@ -57,29 +54,9 @@ func (r *InstanceReconciler) EnforceCloudInitSecret(ctx context.Context) error {
 	}
 	log.V(utils.LogDebugLevel).Info("public keys correctly retrieved")
 
-	if env.MountMyDriveVolume {
-		nfsServerName, nfsPath, err = r.GetNFSSpecs(ctx)
-
-		if err != nil {
-			log.Error(err, "unable to retrieve NFS volume dns name and path")
-			return err
-		}
-	}
-
-	mountInfos := []forge.NFSVolumeMountInfo{}
-
-	if nfsServerName != "" && nfsPath != "" {
-		mountInfos = append(mountInfos, forge.MyDriveNFSVolumeMountInfo(nfsServerName, nfsPath))
-	}
-
-	for i, mount := range env.SharedVolumeMounts {
-		var shvol clv1alpha2.SharedVolume
-		if err := r.Get(ctx, forge.NamespacedNameFromMount(mount), &shvol); err != nil {
-			log.Error(err, "unable to retrieve shvol to mount")
-			return err
-		}
-
-		mountInfos = append(mountInfos, forge.ShVolNFSVolumeMountInfo(i, &shvol, mount))
+	mountInfos, err := r.cloudInitMountInfos(ctx)
+	if err != nil {
+		return err
 	}
 
 	userdata, err := forge.CloudInitUserData(publicKeys, mountInfos)
@@ -113,6 +90,37 @@ func (r *InstanceReconciler) EnforceCloudInitSecret(ctx context.Context) error {
 	return nil
 }
 
+// cloudInitMountInfos returns the information about the NFS volumes (i.e., the MyDrive and the SharedVolumes) to be mounted by the VM.
+func (r *InstanceReconciler) cloudInitMountInfos(ctx context.Context) ([]forge.NFSVolumeMountInfo, error) {
+	log := ctrl.LoggerFrom(ctx)
+	env := clctx.EnvironmentFrom(ctx)
+	mountInfos := []forge.NFSVolumeMountInfo{}
+
+	if env.MountMyDriveVolume {
+		nfsServerName, nfsPath, err := r.GetNFSSpecs(ctx)
+		if err != nil {
+			log.Error(err, "unable to retrieve NFS volume dns name and path")
+			return nil, err
+		}
+
+		if nfsServerName != "" && nfsPath != "" {
+			mountInfos = append(mountInfos, forge.MyDriveNFSVolumeMountInfo(nfsServerName, nfsPath))
+		}
+	}
+
+	for i, mount := range env.SharedVolumeMounts {
+		var shvol clv1alpha2.SharedVolume
+		if err := r.Get(ctx, forge.NamespacedNameFromMount(mount), &shvol); err != nil {
+			log.Error(err, "unable to retrieve shvol to mount")
+			return nil, err
+		}
+
+		mountInfos = append(mountInfos, forge.ShVolNFSVolumeMountInfo(i, &shvol, mount))
+	}
+
+	return mountInfos, nil
+}
+
 // GetNFSSpecs extracts the NFS server name and path for the user's personal NFS volume,
 // required to mount the MyDrive disk of a given tenant from the associated secret.
 func (r *InstanceReconciler) GetNFSSpecs(ctx context.Context) (nfsServerName, nfsPath string, err error) {
@@ -10,12 +10,10 @@ import (
 	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
-	infrav1 "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
@@ -27,36 +25,95 @@ import (
 // Kubernetes resources required to start a CrownLabs environment.
 func (r *InstanceReconciler) EnforceClusterEnvironment(ctx context.Context) error {
 	log := ctrl.LoggerFrom(ctx)
-	environment := clctx.EnvironmentFrom(ctx)
+	// allocate the cluster networks first, as they are part of the forged objects
+	environment, err := r.enforceClusterNetworkAllocation(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, _ = clctx.EnvironmentInto(ctx, environment)
 	Provider := environment.Cluster.ControlPlane.Provider
 	instance := clctx.InstanceFrom(ctx)
 	host := forge.HostName(r.ServiceUrls.WebsiteBaseURL, environment.Mode)
-	if environment.Visulizer.Isvisualizer {
+	infrastructure := forge.ClusterInfrastructureFor(environment)
+	if environment.Visulizer != nil && environment.Visulizer.Isvisualizer && !r.SkipClusterAddons {
 		forge.ClusterVisulizer(ctx)
 	}
-	r.enforceCluster(ctx)
-	// choose the a proper controlplabe provider
+	// isolate the pods of the cluster before they are created
+	if err := r.enforceClusterNetworkPolicies(ctx); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageInfrastructure, err)
+	}
+	if err := r.enforceCluster(ctx); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageInfrastructure, err)
+	}
+	// enforce the infrastructure-specific cluster object
+	if err := r.enforceInfraCluster(ctx, infrastructure); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageInfrastructure, err)
+	}
+	// retrieve the public keys to be authorized on the cluster nodes
+	publicKeys, err := r.GetPublicKeys(ctx)
+	if err != nil {
+		log.Error(err, "unable to get public keys")
+		return err
+	}
+	// resolve the files to be written on the cluster nodes
+	files, err := r.enforceNodeBootstrapFiles(ctx)
+	if err != nil {
+		return err
+	}
+	// choose the proper control plane provider
 	if Provider == clv1alpha2.ProviderKubeadm {
-		r.enforceKubeadmInfra(ctx)
-		r.enforceKubeadmControlPlane(ctx)
+		if err := r.enforceKubeadmControlPlane(ctx, publicKeys, files); err != nil {
+			return observeClusterStageFailure(environment, clv1alpha2.ClusterStageControlPlane, err)
+		}
 	} else {
-		r.enforceKamajiInfra(ctx)
-		r.enforceKamajiControlPlane(ctx)
+		if err := r.enforceKamajiControlPlane(ctx); err != nil {
+			return observeClusterStageFailure(environment, clv1alpha2.ClusterStageControlPlane, err)
+		}
 	}
 	// enforce a machinedeployment for VM management
-	r.enforceMachineDeployment(ctx)
-	// enforce a worker virtual machine template
-	r.enforceKubevirtMachine(ctx)
+	if err := r.enforceMachineDeployment(ctx); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageMachines, err)
+	}
+	// enforce the worker (and control plane) machine templates
+	if err := r.enforceMachineTemplates(ctx, infrastructure); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageMachines, err)
+	}
 	// enforce a boostrap for woker virtual machines
-	r.enforceBootstrap(ctx)
+	if err := r.enforceBootstrap(ctx, publicKeys, files); err != nil {
+		return observeClusterStageFailure(environment, clv1alpha2.ClusterStageNodeJoin, err)
+	}
 	// Enforce the service and the ingress to expose the environment.
-	err := r.EnforceInstanceExposition(ctx)
+	err = r.EnforceInstanceExposition(ctx)
 	if err != nil {
 		log.Error(err, "failed to enforce the instance exposition objects")
 		return err
 	}
-	// install cni and export kubeconfig
-	forge.Insinstallcni(instance, environment, host)
+	// publish the nodes of the cluster in the instance status
+	if err := r.enforceClusterNodesStatus(ctx); err != nil {
+		return err
+	}
+	// install cni and export kubeconfig until the cni is rolled out, unless the nodes are simulated
+	// (the successful installation is accounted once the corresponding provisioning stage is completed)
+	if infrastructure.RunsWorkloads() && !r.SkipClusterAddons &&
+		!forge.ClusterStageCompleted(instance.Status.ClusterStages, clv1alpha2.ClusterStageCNI) {
+		if err := forge.Insinstallcni(instance, environment, host); err != nil {
+			log.Error(err, "failed to roll out the CNI")
+			observeClusterAddonInstall(instance, environment, metricClusterAddonCNI, err)
+		}
+	}
+	// record the completed provisioning stages of the cluster
+	if err := r.enforceClusterStagesStatus(ctx, infrastructure.RunsWorkloads()); err != nil {
+		return err
+	}
+	// expose the NFS volumes in the cluster, once ready
+	if err := r.enforceClusterVolumes(ctx); err != nil {
+		return err
+	}
+	// apply the lab content, once the cluster is ready
+	if err := r.enforceClusterContent(ctx); err != nil {
+		observeClusterAddonInstall(instance, environment, metricClusterAddonContent, err)
+		return err
+	}
 	// echo to template status
 	r.updatetemplatestatus(ctx)
 	return nil
@@ -90,24 +147,17 @@ func (r *InstanceReconciler) enforceCluster(ctx context.Context) error {
 	return nil
 }
 
-// enforceInfra creates or updates the KubevirtCluster resource and labels it for CAPI
-func (r *InstanceReconciler) enforceKubeadmInfra(ctx context.Context) error {
+// enforceInfraCluster creates or updates the infrastructure cluster resource and labels it for CAPI
+func (r *InstanceReconciler) enforceInfraCluster(ctx context.Context, infrastructure forge.ClusterInfrastructure) error {
 	log := ctrl.LoggerFrom(ctx)
 	instance := clctx.InstanceFrom(ctx)
 	environment := clctx.EnvironmentFrom(ctx)
-	cluster := environment.Cluster
-	infra := &infrav1.KubevirtCluster{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-infra", cluster.Name),
-			Namespace: instance.Namespace,
-		},
-	}
+	infra := infrastructure.InfraCluster(instance, environment)
 	res, err := ctrl.CreateOrUpdate(ctx, r.Client, infra, func() error {
-		if infra.CreationTimestamp.IsZero() {
-			infra.Spec.ControlPlaneServiceTemplate.Spec.Type = corev1.ServiceType(cluster.ServiceType)
-		}
-		if infra.Labels == nil {
-			infra.Labels = map[string]string{}
+		if infra.GetCreationTimestamp().Time.IsZero() {
+			if err := infrastructure.MutateInfraCluster(infra, environment); err != nil {
+				return err
+			}
 		}
 		infra.SetLabels(forge.InstanceObjectLabels(infra.GetLabels(), instance))
 		// unnecessary to set contoller ref, all will be managed by cluster
@@ -121,36 +171,8 @@ func (r *InstanceReconciler) enforceKubeadmInfra(ctx context.Context) error {
 	return nil
 }
 
-// kamaji infra
-func (r *InstanceReconciler) enforceKamajiInfra(ctx context.Context) error {
-	log := ctrl.LoggerFrom(ctx)
-	instance := clctx.InstanceFrom(ctx)
-	environment := clctx.EnvironmentFrom(ctx)
-	cluster := environment.Cluster
-	infra := &infrav1.KubevirtCluster{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        fmt.Sprintf("%s-infra", cluster.Name),
-			Namespace:   instance.Namespace,
-			Annotations: map[string]string{"cluster.x-k8s.io/managed-by": "kamaji"},
-		},
-	}
-	res, err := ctrl.CreateOrUpdate(ctx, r.Client, infra, func() error {
-		if infra.Labels == nil {
-			infra.Labels = map[string]string{}
-		}
-		infra.SetLabels(forge.InstanceObjectLabels(infra.GetLabels(), instance))
-		return nil
-	})
-	if err != nil {
-		log.Error(err, "failed to enforce infrastructure", "infra", klog.KObj(infra))
-		return err
-	}
-	log.V(utils.FromResult(res)).Info("Infrastructure enforced", "infra", klog.KObj(infra), "result", res)
-	return nil
-}
-
 // enforceControlPlane creates or updates the KubeadmControlPlane resource and labels it
-func (r *InstanceReconciler) enforceKubeadmControlPlane(ctx context.Context) error {
+func (r *InstanceReconciler) enforceKubeadmControlPlane(ctx context.Context, publicKeys []string, files []bootstrapv1.File) error {
 	log := ctrl.LoggerFrom(ctx)
 	instance := clctx.InstanceFrom(ctx)
 	environment := clctx.EnvironmentFrom(ctx)
@@ -168,8 +190,13 @@ func (r *InstanceReconciler) enforceKubeadmControlPlane(ctx context.Context) err
 			cp.Spec.Version = cluster.Version
 			host := forge.HostName(r.ServiceUrls.WebsiteBaseURL, environment.Mode)
 			cp.Spec = forge.ClusterControlPlaneSepc(instance, environment, host)
+			// configured only at creation time, since changes would trigger the rollout of the control plane
+			cp.Spec.KubeadmConfigSpec.Users = forge.ClusterNodeUsers(publicKeys)
+			forge.ApplyNodeBootstrap(&cp.Spec.KubeadmConfigSpec, cluster.NodeBootstrap, files)
 		}
 		cp.Spec.Replicas = ptr.To(int32(controlplane.Replicas))
+		// propagated to the machines, to map them back to the instance
+		cp.Spec.MachineTemplate.ObjectMeta.Labels = forge.InstanceObjectLabels(cp.Spec.MachineTemplate.ObjectMeta.Labels, instance)
 		if cp.Labels == nil {
 			cp.Labels = map[string]string{}
 		}
@@ -233,6 +260,8 @@ func (r *InstanceReconciler) enforceMachineDeployment(ctx context.Context) error
 			md.Spec.Template.Spec = forge.MachineDeploymentSepc(instance, environment)
 		}
 		md.Spec.Replicas = ptr.To(int32(machinedeployment.Replicas))
+		// propagated to the machines, to map them back to the instance
+		md.Spec.Template.Labels = forge.InstanceObjectLabels(md.Spec.Template.Labels, instance)
 		if md.Labels == nil {
 			md.Labels = map[string]string{}
 		}
@@ -247,77 +276,50 @@ func (r *InstanceReconciler) enforceMachineDeployment(ctx context.Context) error
 	return nil
 }
 
-// enforceKubevirtMachine creates or updates KubevirtMachineTemplates with RunStrategy and DV mapping
-func (r *InstanceReconciler) enforceKubevirtMachine(ctx context.Context) error {
+// enforceMachineTemplates creates or updates the infrastructure machine templates for workers and (if kubeadm) control plane
+func (r *InstanceReconciler) enforceMachineTemplates(ctx context.Context, infrastructure forge.ClusterInfrastructure) error {
 	log := ctrl.LoggerFrom(ctx)
 	instance := clctx.InstanceFrom(ctx)
 	environment := clctx.EnvironmentFrom(ctx)
 	cluster := environment.Cluster
-	controlplane := cluster.ControlPlane
-
-	// worker template
-	wmworker := infrav1.KubevirtMachineTemplate{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-md-worker", cluster.Name), Namespace: instance.Namespace}}
-	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &wmworker, func() error {
-		if wmworker.CreationTimestamp.IsZero() {
-			wmworker.Spec.Template.Spec.BootstrapCheckSpec.CheckStrategy = "ssh"
 
-			vmSpec := forge.ClusterVMSpec(environment)
-			wmworker.Spec.Template.Spec.VirtualMachineTemplate.Spec = vmSpec
-		}
-		if wmworker.Labels == nil {
-			wmworker.Labels = map[string]string{}
-		}
-		wmworker.Labels[capiv1.ClusterNameLabel] = fmt.Sprintf("%s-cluster", cluster.Name)
-		return nil
-	})
-	if err != nil {
-		log.Error(err, "failed to enforce virtualmachine-worker")
-		return err
+	names := []string{fmt.Sprintf("%s-md-worker", cluster.Name)}
+	if cluster.ControlPlane.Provider == v1alpha2.ProviderKubeadm {
+		names = append(names, fmt.Sprintf("%s-control-plane-machine", cluster.Name))
 	}
-	log.V(utils.FromResult(res)).Info("virtualmachine-worker enforced")
-
-	// control-plane template
-	if controlplane.Provider == v1alpha2.ProviderKubeadm {
-		wmcp := infrav1.KubevirtMachineTemplate{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-control-plane-machine", cluster.Name), Namespace: instance.Namespace}}
-		res, err := ctrl.CreateOrUpdate(ctx, r.Client, &wmcp, func() error {
-
-			if wmcp.CreationTimestamp.IsZero() {
-				wmcp.Spec.Template.Spec.BootstrapCheckSpec.CheckStrategy = "ssh"
 
-				vmSpec := forge.ClusterVMSpec(environment)
-				wmcp.Spec.Template.Spec.VirtualMachineTemplate.Spec = vmSpec
+	for _, name := range names {
+		tmpl := infrastructure.MachineTemplate(instance, name)
+		res, err := ctrl.CreateOrUpdate(ctx, r.Client, tmpl, func() error {
+			if tmpl.GetCreationTimestamp().Time.IsZero() {
+				if err := infrastructure.MutateMachineTemplate(tmpl, environment); err != nil {
+					return err
+				}
 			}
-
-			if wmcp.Labels == nil {
-				wmcp.Labels = map[string]string{}
-			}
-			wmcp.Labels[capiv1.ClusterNameLabel] = fmt.Sprintf("%s-cluster", cluster.Name)
+			labels := forge.InstanceObjectLabels(tmpl.GetLabels(), instance)
+			labels[capiv1.ClusterNameLabel] = fmt.Sprintf("%s-cluster", cluster.Name)
+			tmpl.SetLabels(labels)
 			return nil
 		})
 		if err != nil {
-			log.Error(err, "failed to enforce virtualmachine-control-plane")
+			log.Error(err, "failed to enforce machine template", "template", klog.KObj(tmpl))
 			return err
 		}
-		log.V(utils.FromResult(res)).Info("virtualmachine-control-plane enforced")
+		log.V(utils.FromResult(res)).Info("machine template enforced", "template", klog.KObj(tmpl), "result", res)
 	}
 	return nil
 }
 
 // enforceBootstrap creates or updates the KubeadmConfigTemplate and labels it
-func (r *InstanceReconciler) enforceBootstrap(ctx context.Context) error {
+func (r *InstanceReconciler) enforceBootstrap(ctx context.Context, publicKeys []string, files []bootstrapv1.File) error {
 	log := ctrl.LoggerFrom(ctx)
 	instance := clctx.InstanceFrom(ctx)
 	environment := clctx.EnvironmentFrom(ctx)
 	cluster := environment.Cluster
 	bt := bootstrapv1.KubeadmConfigTemplate{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-md-bootstrap", cluster.Name), Namespace: instance.Namespace}}
 	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &bt, func() error {
-		if bt.CreationTimestamp.IsZero() {
-			bt.Spec.Template.Spec.JoinConfiguration = &bootstrapv1.JoinConfiguration{
-				NodeRegistration: bootstrapv1.NodeRegistrationOptions{
-					KubeletExtraArgs: map[string]string{},
-				},
-			}
-		}
+		// updated at every reconciliation, as only newly created machines are affected
+		bt.Spec.Template.Spec = forge.WorkerKubeadmConfigSpec(environment, publicKeys, files)
 		if bt.Labels == nil {
 			bt.Labels = map[string]string{}
 		}
@@ -332,6 +334,24 @@ func (r *InstanceReconciler) enforceBootstrap(ctx context.Context) error {
 	return nil
 }
 
+// enforceClusterNodesStatus publishes the nodes of the cluster, along with their IPs, in the instance status
+func (r *InstanceReconciler) enforceClusterNodesStatus(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	var machines capiv1.MachineList
+	if err := r.List(ctx, &machines, client.InNamespace(instance.Namespace),
+		client.MatchingLabels{capiv1.ClusterNameLabel: fmt.Sprintf("%s-cluster", environment.Cluster.Name)}); err != nil {
+		log.Error(err, "failed to retrieve the cluster machines")
+		return err
+	}
+
+	instance.Status.ClusterNodes = forge.ClusterNodes(machines.Items)
+	log.V(utils.LogDebugLevel).Info("cluster nodes status enforced", "nodes", len(instance.Status.ClusterNodes))
+	return nil
+}
+
 // insertKubeConfig export the KUBECONFIG into kubeconfig folders
 
 // update the template status with the address of  relative kubeconfig file
@@ -0,0 +1,835 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"time"
+
+	kamajiv1alpha1 "github.com/clastix/cluster-api-control-plane-provider-kamaji/api/v1alpha1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	infrav1 "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+)
+
+var _ = Describe("Generation of the cluster environments", func() {
+	var (
+		ctx           context.Context
+		clientBuilder fake.ClientBuilder
+		reconciler    instctrl.InstanceReconciler
+
+		instance    clv1alpha2.Instance
+		template    clv1alpha2.Template
+		environment clv1alpha2.Environment
+		tenant      clv1alpha2.Tenant
+
+		applied           []string
+		appliedNamespaces map[string]string
+
+		err error
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "kubernetes"
+		templateNamespace = "workspace-netgroup"
+		environmentName   = "cluster"
+		tenantName        = "tester"
+		clusterName       = "demo"
+
+		image     = "internal/registry/image:v1.0"
+		publicKey = "ssh-ed25519 AAAA tester@crownlabs"
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: instanceNamespace, Name: name}
+	}
+
+	inMemoryObject := func(kind string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(forge.InfrastructureAPIVersion, kind))
+		return obj
+	}
+
+	workloadClusterClient := func(context.Context, string, string) (client.Client, error) {
+		return fake.NewClientBuilder().WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme)).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch, _ ...client.PatchOption) error {
+				Expect(patch.Type()).To(Equal(types.ApplyPatchType))
+				applied = append(applied, obj.GetObjectKind().GroupVersionKind().Kind+"/"+obj.GetName())
+				appliedNamespaces[obj.GetObjectKind().GroupVersionKind().Kind+"/"+obj.GetName()] = obj.GetNamespace()
+				return nil
+			},
+		}).Build(), nil
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+		applied = nil
+		appliedNamespaces = map[string]string{}
+		clientBuilder = *fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&clv1alpha2.Template{ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace}},
+			&clv1alpha2.Tenant{ObjectMeta: metav1.ObjectMeta{Name: tenantName}},
+		)
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+			Spec: clv1alpha2.InstanceSpec{
+				Running:  true,
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: tenantName},
+			},
+		}
+		environment = clv1alpha2.Environment{
+			Name:            environmentName,
+			EnvironmentType: clv1alpha2.ClassCluster,
+			Image:           image,
+			Mode:            clv1alpha2.ModeStandard,
+			Resources: clv1alpha2.EnvironmentResources{
+				CPU:                   2,
+				ReservedCPUPercentage: 50,
+				Memory:                resource.MustParse("4G"),
+			},
+			Cluster: &clv1alpha2.ClusterTemplate{
+				Name:    clusterName,
+				Version: "v1.30.2",
+				ClusterNet: clv1alpha2.ClusterNetwork{
+					Pods:     "10.80.0.0/16",
+					Services: "10.95.0.0/16",
+					Cni:      clv1alpha2.CniCilium,
+				},
+				ControlPlane:   clv1alpha2.ControlPlaneRef{Provider: clv1alpha2.ProviderKubeadm, Replicas: 1},
+				MachineDeploy:  clv1alpha2.MachineDeployment{Replicas: 2},
+				Infrastructure: clv1alpha2.InfraInMemory,
+			},
+		}
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec: clv1alpha2.TemplateSpec{
+				EnvironmentList: []clv1alpha2.Environment{environment},
+			},
+		}
+		tenant = clv1alpha2.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: tenantName},
+			Spec:       clv1alpha2.TenantSpec{PublicKeys: []string{publicKey}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instctrl.InstanceReconciler{
+			Client:         clientBuilder.Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: record.NewFakeRecorder(1024),
+			ServiceUrls:    instctrl.ServiceUrls{WebsiteBaseURL: "fakesite.com"},
+			ClusterNetworkPools: instctrl.ClusterNetworkPools{
+				Pods:         "10.64.0.0/12",
+				Services:     "10.112.0.0/12",
+				PodsIPv6:     "fd10:64::/48",
+				ServicesIPv6: "fd10:112::/96",
+			},
+			WorkloadClusterClient: workloadClusterClient,
+		}
+
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		ctx, _ = clctx.TemplateInto(ctx, &template)
+		ctx, _ = clctx.EnvironmentInto(ctx, &environment)
+		ctx, _ = clctx.TenantInto(ctx, &tenant)
+		err = reconciler.EnforceClusterEnvironment(ctx)
+	})
+
+	It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+	It("Should report the configured cluster network in the status", func() {
+		Expect(instance.Status.ClusterNetwork).To(Equal(&clv1alpha2.InstanceClusterNetworkStatus{
+			Pods: "10.80.0.0/16", PodsCIDRs: []string{"10.80.0.0/16"},
+			Services: "10.95.0.0/16", ServicesCIDRs: []string{"10.95.0.0/16"},
+		}))
+	})
+
+	It("Should enforce the cluster, referencing the in-memory infrastructure", func() {
+		var cluster capiv1.Cluster
+		Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+		Expect(cluster.Spec).To(Equal(forge.ClusterSpec(&instance, &environment)))
+		Expect(cluster.Spec.InfrastructureRef.Kind).To(Equal("InMemoryCluster"))
+		Expect(cluster.GetLabels()).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+	})
+
+	It("Should enforce the network policy isolating the cluster nodes", func() {
+		var policy netv1.NetworkPolicy
+		Expect(reconciler.Get(ctx, key("kubernetes-0000-cluster-nodes"), &policy)).To(Succeed())
+		Expect(policy.Spec).To(Equal(forge.ClusterNodesNetworkPolicySpec(&environment, nil)))
+		Expect(policy.GetLabels()).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+		Expect(policy.GetOwnerReferences()).To(HaveLen(1))
+	})
+
+	It("Should not enforce the network policy of the hosted control plane", func() {
+		var policy netv1.NetworkPolicy
+		Expect(reconciler.Get(ctx, key("kubernetes-0000-cluster-control-plane"), &policy)).ToNot(Succeed())
+	})
+
+	It("Should enforce the in-memory infrastructure cluster", func() {
+		infra := inMemoryObject("InMemoryCluster")
+		Expect(reconciler.Get(ctx, key("demo-infra"), infra)).To(Succeed())
+		Expect(infra.GetLabels()).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+	})
+
+	It("Should enforce the in-memory machine templates", func() {
+		for _, name := range []string{"demo-md-worker", "demo-control-plane-machine"} {
+			tmpl := inMemoryObject("InMemoryMachineTemplate")
+			Expect(reconciler.Get(ctx, key(name), tmpl)).To(Succeed())
+			Expect(tmpl.GetLabels()).To(HaveKeyWithValue(capiv1.ClusterNameLabel, "demo-cluster"))
+			Expect(tmpl.GetLabels()).To(HaveKeyWithValue("crownlabs.polito.it/instance", instanceName))
+
+			behaviour, _, err := unstructured.NestedMap(tmpl.Object, "spec", "template", "spec", "behaviour")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(behaviour).To(Equal(forge.InMemoryMachineBehaviour()))
+		}
+	})
+
+	It("Should not enforce any KubeVirt object", func() {
+		var infra infrav1.KubevirtCluster
+		Expect(reconciler.Get(ctx, key("demo-infra"), &infra)).ToNot(Succeed())
+		var tmpl infrav1.KubevirtMachineTemplate
+		Expect(reconciler.Get(ctx, key("demo-md-worker"), &tmpl)).ToNot(Succeed())
+	})
+
+	It("Should enforce the kubeadm control plane", func() {
+		var cp controlplanev1.KubeadmControlPlane
+		Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+		Expect(*cp.Spec.Replicas).To(BeNumerically("==", 1))
+		Expect(cp.Spec.MachineTemplate.InfrastructureRef.Kind).To(Equal("InMemoryMachineTemplate"))
+	})
+
+	It("Should enforce the machine deployment", func() {
+		var md capiv1.MachineDeployment
+		Expect(reconciler.Get(ctx, key("demo-md"), &md)).To(Succeed())
+		Expect(*md.Spec.Replicas).To(BeNumerically("==", 2))
+		Expect(md.Spec.Template.Spec.InfrastructureRef.Kind).To(Equal("InMemoryMachineTemplate"))
+	})
+
+	It("Should enforce the worker bootstrap template", func() {
+		var bt bootstrapv1.KubeadmConfigTemplate
+		Expect(reconciler.Get(ctx, key("demo-md-bootstrap"), &bt)).To(Succeed())
+	})
+
+	It("Should authorize the tenant keys on the worker nodes", func() {
+		var bt bootstrapv1.KubeadmConfigTemplate
+		Expect(reconciler.Get(ctx, key("demo-md-bootstrap"), &bt)).To(Succeed())
+		Expect(bt.Spec.Template.Spec.Users).To(Equal(forge.ClusterNodeUsers([]string{publicKey})))
+	})
+
+	It("Should authorize the tenant keys on the control plane nodes", func() {
+		var cp controlplanev1.KubeadmControlPlane
+		Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+		Expect(cp.Spec.KubeadmConfigSpec.Users).To(Equal(forge.ClusterNodeUsers([]string{publicKey})))
+	})
+
+	It("Should propagate the instance labels to the machines", func() {
+		var md capiv1.MachineDeployment
+		Expect(reconciler.Get(ctx, key("demo-md"), &md)).To(Succeed())
+		Expect(md.Spec.Template.Labels).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+		var cp controlplanev1.KubeadmControlPlane
+		Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+		Expect(cp.Spec.MachineTemplate.ObjectMeta.Labels).To(Equal(forge.InstanceObjectLabels(nil, &instance)))
+	})
+
+	It("Should not publish any node, if no machine exists", func() {
+		Expect(instance.Status.ClusterNodes).To(BeEmpty())
+	})
+
+	When("the machines of the cluster exist", func() {
+		BeforeEach(func() {
+			clientBuilder.WithObjects(
+				&capiv1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "demo-md-abcde", Namespace: instanceNamespace,
+						Labels: map[string]string{capiv1.ClusterNameLabel: "demo-cluster"}},
+					Status: capiv1.MachineStatus{Addresses: capiv1.MachineAddresses{{Type: capiv1.MachineInternalIP, Address: "10.0.0.2"}}},
+				},
+				&capiv1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-md-abcde", Namespace: instanceNamespace,
+						Labels: map[string]string{capiv1.ClusterNameLabel: "other-cluster"}},
+				},
+			)
+		})
+
+		It("Should publish the nodes of the cluster in the instance status", func() {
+			Expect(instance.Status.ClusterNodes).To(ConsistOf(clv1alpha2.InstanceClusterNode{
+				Name: "demo-md-abcde", Role: clv1alpha2.ClusterNodeWorker, IP: "10.0.0.2",
+			}))
+		})
+	})
+
+	When("the cluster reports its provisioning progress", func() {
+		BeforeEach(func() {
+			created := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+			clientBuilder.WithObjects(&capiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "demo-cluster", Namespace: instanceNamespace, CreationTimestamp: created},
+				Status: capiv1.ClusterStatus{Conditions: capiv1.Conditions{{
+					Type: capiv1.InfrastructureReadyCondition, Status: corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(created.Add(30 * time.Second)),
+				}}},
+			})
+		})
+
+		It("Should record the completed stages in the instance status", func() {
+			Expect(instance.Status.ClusterStages).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Name":     Equal(clv1alpha2.ClusterStageInfrastructure),
+				"Duration": Equal("30s"),
+			})))
+		})
+	})
+
+	When("the node bootstrap is customized", func() {
+		BeforeEach(func() {
+			environment.Cluster.NodeBootstrap = &clv1alpha2.NodeBootstrap{
+				Files: []clv1alpha2.NodeFile{
+					{Path: "/etc/motd", Content: "Welcome"},
+					{Path: "/etc/crownlabs/config", ContentFrom: &clv1alpha2.NodeFileSource{
+						ConfigMap: &clv1alpha2.NodeFileKeyRef{Name: "node-config", Key: "config"},
+					}},
+					{Path: "/etc/crownlabs/token", Permissions: "0600", ContentFrom: &clv1alpha2.NodeFileSource{
+						Secret: &clv1alpha2.NodeFileKeyRef{Name: "node-token", Key: "token"},
+					}},
+				},
+				Packages:            []string{"jq"},
+				PostKubeadmCommands: []string{"echo ready"},
+				KubeletExtraArgs:    map[string]string{"max-pods": "50"},
+			}
+			clientBuilder.WithObjects(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "node-config", Namespace: templateNamespace},
+					Data: map[string]string{"config": "key: value"}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "node-token", Namespace: templateNamespace,
+					Labels: map[string]string{forge.NodeBootstrapSecretLabel: "true"}},
+					Data: map[string][]byte{"token": []byte("s3cr3t")}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unflagged", Namespace: templateNamespace},
+					Data: map[string][]byte{"token": []byte("s3cr3t")}},
+			)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should copy the secret content to the instance namespace", func() {
+			var secret corev1.Secret
+			Expect(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &secret)).To(Succeed())
+			Expect(secret.Data).To(Equal(map[string][]byte{"file-2": []byte("s3cr3t")}))
+			Expect(secret.GetLabels()).To(Equal(forge.ClusterCredentialsLabels(nil, &instance)))
+		})
+
+		It("Should configure the worker bootstrap template", func() {
+			var bt bootstrapv1.KubeadmConfigTemplate
+			Expect(reconciler.Get(ctx, key("demo-md-bootstrap"), &bt)).To(Succeed())
+
+			spec := bt.Spec.Template.Spec
+			Expect(spec.Files).To(HaveLen(3))
+			Expect(spec.Files[0].Content).To(Equal("Welcome"))
+			Expect(spec.Files[1].Content).To(Equal("key: value"))
+			Expect(spec.Files[2].ContentFrom.Secret).To(Equal(bootstrapv1.SecretFileSource{Name: "kubernetes-0000-node-files", Key: "file-2"}))
+			Expect(spec.PreKubeadmCommands).To(ConsistOf(forge.NodePackagesCommand([]string{"jq"})))
+			Expect(spec.PostKubeadmCommands).To(ConsistOf("echo ready"))
+			Expect(spec.JoinConfiguration.NodeRegistration.KubeletExtraArgs).To(HaveKeyWithValue("max-pods", "50"))
+		})
+
+		It("Should configure the kubeadm control plane", func() {
+			var cp controlplanev1.KubeadmControlPlane
+			Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+			Expect(cp.Spec.KubeadmConfigSpec.Files).To(HaveLen(3))
+			Expect(cp.Spec.KubeadmConfigSpec.PostKubeadmCommands).To(ContainElement("echo ready"))
+			Expect(cp.Spec.KubeadmConfigSpec.InitConfiguration.NodeRegistration.KubeletExtraArgs).To(HaveKeyWithValue("max-pods", "50"))
+		})
+
+		When("a referenced configmap does not exist", func() {
+			BeforeEach(func() { environment.Cluster.NodeBootstrap.Files[1].ContentFrom.ConfigMap.Name = "missing" })
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+		})
+
+		When("a referenced secret is not flagged for node bootstrap", func() {
+			BeforeEach(func() { environment.Cluster.NodeBootstrap.Files[2].ContentFrom.Secret.Name = "unflagged" })
+
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+
+			It("Should not copy the secret content to the instance namespace", func() {
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &corev1.Secret{}))).To(BeTrue())
+			})
+		})
+	})
+
+	When("the cluster content is configured", func() {
+		const manifests = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: nginx\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: lab\n"
+
+		var clusterReady bool
+
+		BeforeEach(func() {
+			clusterReady = true
+			environment.Cluster.Content = &clv1alpha2.ClusterContent{ConfigMap: "lab-content"}
+			clientBuilder.WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "lab-content", Namespace: templateNamespace},
+				Data:       map[string]string{"lab.yaml": manifests},
+			})
+		})
+
+		JustBeforeEach(func() {
+			// The status of the cluster is updated after its creation, as it would be done by the Cluster API.
+			if clusterReady {
+				var cluster capiv1.Cluster
+				Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+				cluster.Status.Conditions = capiv1.Conditions{{Type: capiv1.ReadyCondition, Status: corev1.ConditionTrue}}
+				Expect(reconciler.Update(ctx, &cluster)).To(Succeed())
+				err = reconciler.EnforceClusterEnvironment(ctx)
+			}
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should apply the content to the workload cluster, namespaces first", func() {
+			Expect(applied).To(Equal([]string{"Namespace/lab", "Deployment/nginx"}))
+		})
+
+		It("Should default the namespace of the namespaced objects only", func() {
+			Expect(appliedNamespaces).To(HaveKeyWithValue("Deployment/nginx", forge.ClusterContentDefaultNamespace))
+			Expect(appliedNamespaces).To(HaveKeyWithValue("Namespace/lab", ""))
+		})
+
+		It("Should record the applied revision in the instance status", func() {
+			Expect(instance.Status.ClusterContent).ToNot(BeNil())
+			Expect(instance.Status.ClusterContent.Source).To(Equal("configmap/lab-content"))
+			Expect(instance.Status.ClusterContent.Revision).To(HavePrefix("sha256:"))
+			Expect(instance.Status.ClusterContent.Objects).To(Equal(2))
+		})
+
+		When("the cluster is not ready", func() {
+			BeforeEach(func() { clusterReady = false })
+
+			It("Should not apply the content", func() {
+				Expect(applied).To(BeEmpty())
+				Expect(instance.Status.ClusterContent).To(BeNil())
+			})
+		})
+
+		When("the content has already been applied", func() {
+			BeforeEach(func() {
+				instance.Status.ClusterContent = &clv1alpha2.InstanceClusterContentStatus{Revision: "sha256:0123", ObservedReset: "1"}
+				instance.SetAnnotations(map[string]string{forge.ClusterContentResetAnnotation: "1"})
+			})
+
+			It("Should not apply the content again", func() {
+				Expect(applied).To(BeEmpty())
+				Expect(instance.Status.ClusterContent.Revision).To(Equal("sha256:0123"))
+			})
+
+			When("a lab reset is requested", func() {
+				BeforeEach(func() { instance.SetAnnotations(map[string]string{forge.ClusterContentResetAnnotation: "2"}) })
+
+				It("Should apply the content again", func() {
+					Expect(applied).To(Equal([]string{"Namespace/lab", "Deployment/nginx"}))
+					Expect(instance.Status.ClusterContent.ObservedReset).To(Equal("2"))
+				})
+			})
+		})
+
+		When("the content cannot be retrieved", func() {
+			addonFailures := func() float64 {
+				families, err := ctrlmetrics.Registry.Gather()
+				Expect(err).ToNot(HaveOccurred())
+				for _, family := range families {
+					if family.GetName() != "instance_cluster_addon_installs_total" {
+						continue
+					}
+					for _, metric := range family.GetMetric() {
+						labels := map[string]string{}
+						for _, label := range metric.GetLabel() {
+							labels[label.GetName()] = label.GetValue()
+						}
+						if labels["addon"] == "content" && labels["outcome"] == "failure" {
+							return metric.GetCounter().GetValue()
+						}
+					}
+				}
+				return 0
+			}
+
+			var failures float64
+
+			BeforeEach(func() {
+				environment.Cluster.Content.ConfigMap = "missing"
+				failures = addonFailures()
+			})
+
+			It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+
+			It("Should flag the add-on failure in the instance conditions", func() {
+				condition := meta.FindStatusCondition(instance.Status.Conditions, clv1alpha2.InstanceConditionClusterAddonFailed)
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal("ContentInstallFailed"))
+			})
+
+			It("Should account for the failure only once", func() {
+				Expect(addonFailures()).To(Equal(failures + 1))
+				Expect(reconciler.EnforceClusterEnvironment(ctx)).ToNot(Succeed())
+				Expect(addonFailures()).To(Equal(failures + 1))
+			})
+		})
+
+		When("the content is retrieved from an HTTP URL", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(manifests))
+				}))
+				DeferCleanup(server.Close)
+				instance.Spec.CustomizationUrls = &clv1alpha2.InstanceCustomizationUrls{ContentOrigin: server.URL + "/lab.yaml"}
+			})
+
+			It("Should apply the downloaded content", func() {
+				Expect(applied).To(Equal([]string{"Namespace/lab", "Deployment/nginx"}))
+				Expect(instance.Status.ClusterContent.Source).To(Equal(server.URL + "/lab.yaml"))
+			})
+		})
+
+		When("the content is a tarball including a kustomization", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				var tarball bytes.Buffer
+				writer := tar.NewWriter(&tarball)
+				for name, content := range map[string]string{
+					"kustomization.yaml": "resources:\n- lab.yaml\nnamePrefix: demo-\n",
+					"lab.yaml":           manifests,
+				} {
+					Expect(writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))})).To(Succeed())
+					_, err := writer.Write([]byte(content))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(writer.Close()).To(Succeed())
+
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write(tarball.Bytes())
+				}))
+				DeferCleanup(server.Close)
+				instance.Spec.CustomizationUrls = &clv1alpha2.InstanceCustomizationUrls{ContentOrigin: server.URL + "/lab.tar"}
+			})
+
+			It("Should build the kustomization and apply the resulting content", func() {
+				Expect(applied).To(Equal([]string{"Namespace/lab", "Deployment/demo-nginx"}))
+				Expect(appliedNamespaces).To(HaveKeyWithValue("Deployment/demo-nginx", forge.ClusterContentDefaultNamespace))
+			})
+		})
+	})
+
+	When("the MyDrive and a SharedVolume are mounted", func() {
+		BeforeEach(func() {
+			environment.MountMyDriveVolume = true
+			environment.SharedVolumeMounts = []clv1alpha2.SharedVolumeMountInfo{{
+				SharedVolumeRef: clv1alpha2.GenericRef{Name: "datasets", Namespace: templateNamespace},
+				MountPath:       "/mnt/datasets",
+				ReadOnly:        true,
+			}}
+			clientBuilder.WithObjects(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "mydrive-info", Namespace: instanceNamespace},
+					Data:       map[string][]byte{"server-name": []byte("nfs.example.com"), "path": []byte("/tester")},
+				},
+				&clv1alpha2.SharedVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "datasets", Namespace: templateNamespace},
+					Status:     clv1alpha2.SharedVolumeStatus{ServerAddress: "nfs.example.com", ExportPath: "/datasets"},
+				},
+			)
+		})
+
+		JustBeforeEach(func() {
+			// The status of the cluster is updated after its creation, as it would be done by the Cluster API.
+			var cluster capiv1.Cluster
+			Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+			cluster.Status.Conditions = capiv1.Conditions{{Type: capiv1.ReadyCondition, Status: corev1.ConditionTrue}}
+			Expect(reconciler.Update(ctx, &cluster)).To(Succeed())
+			err = reconciler.EnforceClusterEnvironment(ctx)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should apply the volumes and the pre-bound claims to the workload cluster", func() {
+			Expect(applied).To(Equal([]string{
+				"PersistentVolume/crownlabs-mydrive-d8d0648e", "PersistentVolumeClaim/mydrive",
+				"PersistentVolume/crownlabs-workspace-netgroup-datasets-a8c456bd", "PersistentVolumeClaim/workspace-netgroup-datasets",
+			}))
+		})
+
+		It("Should record the exposed volumes in the instance status", func() {
+			Expect(instance.Status.ClusterVolumes).To(Equal([]clv1alpha2.InstanceClusterVolume{
+				{ClaimName: "mydrive", Server: "nfs.example.com", Path: "/tester"},
+				{ClaimName: "workspace-netgroup-datasets", Server: "nfs.example.com", Path: "/datasets", ReadOnly: true},
+			}))
+		})
+
+		When("the volumes have already been applied", func() {
+			JustBeforeEach(func() {
+				applied = nil
+				err = reconciler.EnforceClusterEnvironment(ctx)
+			})
+
+			It("Should apply them again, restoring the ones possibly deleted in the workload cluster", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(applied).To(HaveLen(4))
+			})
+		})
+	})
+
+	When("the control plane provider is kamaji", func() {
+		BeforeEach(func() { environment.Cluster.ControlPlane.Provider = clv1alpha2.ProviderKamaji })
+
+		It("Should enforce the kamaji control plane", func() {
+			var cp kamajiv1alpha1.KamajiControlPlane
+			Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+		})
+
+		It("Should enforce the network policy isolating the hosted control plane", func() {
+			var policy netv1.NetworkPolicy
+			Expect(reconciler.Get(ctx, key("kubernetes-0000-cluster-control-plane"), &policy)).To(Succeed())
+			Expect(policy.Spec).To(Equal(forge.ClusterControlPlaneNetworkPolicySpec(&environment)))
+		})
+
+		It("Should enforce only the worker machine template", func() {
+			Expect(reconciler.Get(ctx, key("demo-md-worker"), inMemoryObject("InMemoryMachineTemplate"))).To(Succeed())
+			Expect(reconciler.Get(ctx, key("demo-control-plane-machine"), inMemoryObject("InMemoryMachineTemplate"))).ToNot(Succeed())
+		})
+	})
+
+	When("the infrastructure provider is KubeVirt", func() {
+		BeforeEach(func() { environment.Cluster.Infrastructure = clv1alpha2.InfraKubevirt })
+
+		It("Should enforce the KubeVirt infrastructure cluster", func() {
+			var infra infrav1.KubevirtCluster
+			Expect(reconciler.Get(ctx, key("demo-infra"), &infra)).To(Succeed())
+		})
+
+		It("Should enforce the KubeVirt machine templates", func() {
+			var tmpl infrav1.KubevirtMachineTemplate
+			Expect(reconciler.Get(ctx, key("demo-md-worker"), &tmpl)).To(Succeed())
+			Expect(tmpl.Spec.Template.Spec.BootstrapCheckSpec.CheckStrategy).To(Equal("ssh"))
+			Expect(tmpl.Spec.Template.Spec.VirtualMachineTemplate.Spec.Template.Spec.Volumes[0].ContainerDisk.Image).To(Equal(image))
+			Expect(reconciler.Get(ctx, key("demo-control-plane-machine"), &tmpl)).To(Succeed())
+		})
+	})
+
+	When("the automatic allocation of the cluster network is requested", func() {
+		allocations := func() []clv1alpha2.ClusterNetworkAllocation {
+			var list clv1alpha2.ClusterNetworkAllocationList
+			Expect(reconciler.List(ctx, &list)).To(Succeed())
+			return list.Items
+		}
+
+		BeforeEach(func() {
+			environment.Cluster.ClusterNet.Allocation = &clv1alpha2.NetworkAllocationPolicy{
+				IPFamilies:              []clv1alpha2.IPFamily{clv1alpha2.IPv4Family},
+				PodPrefixLength:         16,
+				PodIPv6PrefixLength:     64,
+				ServicePrefixLength:     20,
+				ServiceIPv6PrefixLength: 112,
+			}
+			// A CIDR already allocated to a different instance
+			other := clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: instanceNamespace}}
+			clientBuilder.WithObjects(&clv1alpha2.ClusterNetworkAllocation{
+				ObjectMeta: metav1.ObjectMeta{Name: forge.NetworkAllocationName("10.64.0.0/16")},
+				Spec:       forge.ClusterNetworkAllocationSpec(&other, "10.64.0.0/16", "10.64.0.0/12", clv1alpha2.NetworkAllocationPods, clv1alpha2.IPv4Family),
+			})
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should allocate non-overlapping CIDRs from the default pools", func() {
+			Expect(instance.Status.ClusterNetwork).To(Equal(&clv1alpha2.InstanceClusterNetworkStatus{
+				Pods: "10.65.0.0/16", PodsCIDRs: []string{"10.65.0.0/16"},
+				Services: "10.112.0.0/20", ServicesCIDRs: []string{"10.112.0.0/20"},
+			}))
+			Expect(allocations()).To(HaveLen(3))
+		})
+
+		It("Should configure the cluster with the allocated CIDRs", func() {
+			var cluster capiv1.Cluster
+			Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+			Expect(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks).To(ConsistOf("10.65.0.0/16"))
+			Expect(cluster.Spec.ClusterNetwork.Services.CIDRBlocks).To(ConsistOf("10.112.0.0/20"))
+		})
+
+		It("Should reuse the same CIDRs on subsequent reconciliations", func() {
+			Expect(reconciler.EnforceClusterEnvironment(ctx)).To(Succeed())
+			Expect(instance.Status.ClusterNetwork.PodsCIDRs).To(ConsistOf("10.65.0.0/16"))
+			Expect(allocations()).To(HaveLen(3))
+		})
+
+		When("the allocations of the instance are not yet propagated to the cache", func() {
+			var backing client.Client
+
+			// The allocations are retrieved bypassing the stale client.
+			allocations := func() []clv1alpha2.ClusterNetworkAllocation {
+				var list clv1alpha2.ClusterNetworkAllocationList
+				Expect(backing.List(ctx, &list)).To(Succeed())
+				return list.Items
+			}
+
+			JustBeforeEach(func() {
+				backing = reconciler.Client
+				reconciler.APIReader = backing
+				reconciler.Client = &staleAllocationsClient{Client: backing, hidden: forge.NetworkAllocationName("10.65.0.0/16")}
+				err = reconciler.EnforceClusterEnvironment(ctx)
+			})
+
+			It("Should reuse the allocation retrieved from the API server", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instance.Status.ClusterNetwork.PodsCIDRs).To(ConsistOf("10.65.0.0/16"))
+				Expect(allocations()).To(HaveLen(3))
+			})
+
+			When("they are not yet listed by the API server either", func() {
+				JustBeforeEach(func() {
+					reconciler.APIReader = reconciler.Client
+					err = reconciler.EnforceClusterEnvironment(ctx)
+				})
+
+				It("Should reuse the allocation conflicting with the one being created", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(instance.Status.ClusterNetwork.PodsCIDRs).To(ConsistOf("10.65.0.0/16"))
+					Expect(allocations()).To(HaveLen(3))
+				})
+			})
+		})
+
+		When("a pool is specified in the template", func() {
+			BeforeEach(func() { environment.Cluster.ClusterNet.Allocation.ServicePools = []string{"172.30.0.0/16"} })
+
+			It("Should allocate the CIDR from that pool", func() {
+				Expect(instance.Status.ClusterNetwork.ServicesCIDRs).To(ConsistOf("172.30.0.0/20"))
+			})
+		})
+
+		When("an overlapping CIDR is concurrently allocated to a different instance", func() {
+			JustBeforeEach(func() {
+				other := clv1alpha2.Instance{ObjectMeta: metav1.ObjectMeta{Name: "concurrent", Namespace: instanceNamespace}}
+				reconciler.APIReader = &concurrentAllocationReader{Reader: reconciler.Client, concurrent: clv1alpha2.ClusterNetworkAllocation{
+					ObjectMeta: metav1.ObjectMeta{Name: forge.NetworkAllocationName("10.64.0.0/15")},
+					Spec:       forge.ClusterNetworkAllocationSpec(&other, "10.64.0.0/15", "10.64.0.0/12", clv1alpha2.NetworkAllocationPods, clv1alpha2.IPv4Family),
+				}}
+				Expect(reconciler.Delete(ctx, &clv1alpha2.ClusterNetworkAllocation{
+					ObjectMeta: metav1.ObjectMeta{Name: forge.NetworkAllocationName("10.65.0.0/16")}})).To(Succeed())
+				err = reconciler.EnforceClusterEnvironment(ctx)
+			})
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+			It("Should allocate a CIDR not overlapping with the concurrent one", func() {
+				Expect(instance.Status.ClusterNetwork.PodsCIDRs).To(ConsistOf("10.66.0.0/16"))
+				Expect(allocations()).To(ContainElement(HaveField("Spec.CIDR", "10.66.0.0/16")))
+				Expect(allocations()).ToNot(ContainElement(HaveField("Spec.CIDR", "10.65.0.0/16")))
+			})
+		})
+
+		When("a dual-stack cluster is requested", func() {
+			BeforeEach(func() {
+				environment.Cluster.ClusterNet.Allocation.IPFamilies = []clv1alpha2.IPFamily{clv1alpha2.IPv6Family, clv1alpha2.IPv4Family}
+			})
+
+			It("Should allocate a CIDR per IP family, in the requested order", func() {
+				Expect(instance.Status.ClusterNetwork).To(Equal(&clv1alpha2.InstanceClusterNetworkStatus{
+					Pods:          "fd10:64::/64",
+					PodsCIDRs:     []string{"fd10:64::/64", "10.65.0.0/16"},
+					Services:      "fd10:112::/112",
+					ServicesCIDRs: []string{"fd10:112::/112", "10.112.0.0/20"},
+				}))
+				Expect(allocations()).To(HaveLen(5))
+			})
+
+			It("Should configure the kubeadm control plane in dual-stack mode", func() {
+				var cp controlplanev1.KubeadmControlPlane
+				Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+				config := cp.Spec.KubeadmConfigSpec.ClusterConfiguration
+				Expect(config.Networking.PodSubnet).To(Equal("fd10:64::/64,10.65.0.0/16"))
+				Expect(config.APIServer.ExtraArgs).To(HaveKeyWithValue("service-cluster-ip-range", "fd10:112::/112,10.112.0.0/20"))
+			})
+		})
+	})
+
+	When("the cluster network is not valid", func() {
+		BeforeEach(func() { environment.Cluster.ClusterNet.ServicesCIDRs = []string{"fd10:112::/112"} })
+
+		It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+	})
+})
+
+// concurrentAllocationReader simulates a ClusterNetworkAllocation concurrently created, and not yet propagated to the cache.
+type concurrentAllocationReader struct {
+	client.Reader
+	concurrent clv1alpha2.ClusterNetworkAllocation
+}
+
+func (r *concurrentAllocationReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	if allocations, ok := list.(*clv1alpha2.ClusterNetworkAllocationList); ok {
+		allocations.Items = append(allocations.Items, r.concurrent)
+	}
+	return nil
+}
+
+// staleAllocationsClient simulates a ClusterNetworkAllocation already created, and not yet listed.
+type staleAllocationsClient struct {
+	client.Client
+	hidden string
+}
+
+func (c *staleAllocationsClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	if allocations, ok := list.(*clv1alpha2.ClusterNetworkAllocationList); ok {
+		allocations.Items = slices.DeleteFunc(allocations.Items, func(allocation clv1alpha2.ClusterNetworkAllocation) bool {
+			return allocation.Name == c.hidden
+		})
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// enforceNodeBootstrapFiles resolves the files to be written on the cluster nodes, as defined by the template.
+// The content of files retrieved from Secrets (only if flagged for this purpose) is copied to a secret in the instance
+// namespace, where it can be referenced by the kubeadm configurations, while the one retrieved from ConfigMaps is inlined.
+func (r *InstanceReconciler) enforceNodeBootstrapFiles(ctx context.Context) ([]bootstrapv1.File, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	template := clctx.TemplateFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	bootstrap := environment.Cluster.NodeBootstrap
+	if bootstrap == nil || len(bootstrap.Files) == 0 {
+		return nil, nil
+	}
+
+	files := make([]bootstrapv1.File, 0, len(bootstrap.Files))
+	secretData := map[string][]byte{}
+	for i := range bootstrap.Files {
+		file := &bootstrap.Files[i]
+		content := file.Content
+
+		switch {
+		case file.ContentFrom == nil:
+		case file.ContentFrom.ConfigMap != nil:
+			ref := file.ContentFrom.ConfigMap
+			var configMap corev1.ConfigMap
+			if err := r.Get(ctx, types.NamespacedName{Namespace: template.Namespace, Name: ref.Name}, &configMap); err != nil {
+				log.Error(err, "failed to retrieve the configmap of a node file", "configmap", ref.Name, "path", file.Path)
+				return nil, err
+			}
+			value, found := configMap.Data[ref.Key]
+			if !found {
+				err := fmt.Errorf("cannot find %v key in configmap %v", ref.Key, ref.Name)
+				log.Error(err, "failed to retrieve the content of a node file", "path", file.Path)
+				return nil, err
+			}
+			content = value
+		case file.ContentFrom.Secret != nil:
+			ref := file.ContentFrom.Secret
+			var secret corev1.Secret
+			if err := r.Get(ctx, types.NamespacedName{Namespace: template.Namespace, Name: ref.Name}, &secret); err != nil {
+				log.Error(err, "failed to retrieve the secret of a node file", "secret", ref.Name, "path", file.Path)
+				return nil, err
+			}
+			if !forge.NodeBootstrapSecretAllowed(&secret) {
+				err := fmt.Errorf("secret %v is not flagged with the %v=true label", ref.Name, forge.NodeBootstrapSecretLabel)
+				log.Error(err, "refusing to copy the content of a node file", "path", file.Path)
+				return nil, err
+			}
+			value, found := secret.Data[ref.Key]
+			if !found {
+				err := fmt.Errorf("cannot find %v key in secret %v", ref.Key, ref.Name)
+				log.Error(err, "failed to retrieve the content of a node file", "path", file.Path)
+				return nil, err
+			}
+			secretData[forge.NodeBootstrapSecretKey(i)] = value
+		}
+
+		files = append(files, forge.NodeBootstrapFile(instance, file, i, content))
+	}
+
+	if len(secretData) == 0 {
+		return files, nil
+	}
+
+	secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: forge.NodeBootstrapSecretName(instance), Namespace: instance.Namespace}}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		secret.SetLabels(forge.ClusterCredentialsLabels(secret.GetLabels(), instance))
+		secret.Data = secretData
+		secret.Type = corev1.SecretTypeOpaque
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce the node files secret", "secret", klog.KObj(&secret))
+		return nil, err
+	}
+
+	log.V(utils.FromResult(res)).Info("node files secret enforced", "secret", klog.KObj(&secret), "result", res)
+	return files, nil
+}
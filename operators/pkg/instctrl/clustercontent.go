@@ -0,0 +1,262 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// clusterContentRetrievalTimeout bounds the time to download the content of cluster environments.
+const clusterContentRetrievalTimeout = time.Minute
+
+// kustomizationFiles are the names identifying the root of a kustomization.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// WorkloadClusterClientFactory returns a client to interact with the given workload cluster.
+type WorkloadClusterClientFactory func(ctx context.Context, namespace, clusterName string) (client.Client, error)
+
+// enforceClusterContent applies the content configured for the cluster environment (through server-side apply), once the cluster is ready.
+// The content is applied only once, unless a reset is explicitly requested through the corresponding annotation.
+func (r *InstanceReconciler) enforceClusterContent(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	source := forge.ClusterContentSource(instance, environment)
+	if source == "" || !forge.ClusterContentApplyRequired(instance) {
+		return nil
+	}
+
+	workloadClient, err := r.readyWorkloadClusterClient(ctx)
+	if err != nil || workloadClient == nil {
+		return err
+	}
+
+	objects, err := r.retrieveClusterContent(ctx)
+	if err != nil {
+		log.Error(err, "failed to retrieve the cluster content", "source", source)
+		return err
+	}
+	forge.SortClusterContent(objects)
+
+	revision, err := forge.ClusterContentRevision(objects)
+	if err != nil {
+		return err
+	}
+
+	for i := range objects {
+		obj := &objects[i]
+		if err := defaultClusterContentNamespace(workloadClient, obj); err != nil {
+			log.Error(err, "failed to determine the scope of the cluster content", "kind", obj.GetKind(), "object", klog.KObj(obj))
+			return err
+		}
+		if err := workloadClient.Patch(ctx, obj, client.Apply, client.FieldOwner(forge.ClusterContentFieldManager), client.ForceOwnership); err != nil {
+			log.Error(err, "failed to apply the cluster content", "kind", obj.GetKind(), "object", klog.KObj(obj))
+			return err
+		}
+	}
+
+	instance.Status.ClusterContent = forge.ClusterContentStatus(instance, source, revision, len(objects))
+	observeClusterAddonInstall(instance, environment, metricClusterAddonContent, nil)
+	r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvClusterContentApplied, EvClusterContentAppliedMsg, revision, source)
+	log.Info("cluster content applied", "source", source, "revision", revision, "objects", len(objects))
+	return nil
+}
+
+// retrieveClusterContent retrieves and decodes the objects configured as content of the cluster environment.
+func (r *InstanceReconciler) retrieveClusterContent(ctx context.Context) ([]unstructured.Unstructured, error) {
+	instance := clctx.InstanceFrom(ctx)
+	template := clctx.TemplateFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	contentURL := forge.ClusterContentURL(instance, environment)
+	if contentURL == "" {
+		var configMap corev1.ConfigMap
+		name := types.NamespacedName{Namespace: template.Namespace, Name: environment.Cluster.Content.ConfigMap}
+		if err := r.Get(ctx, name, &configMap); err != nil {
+			return nil, err
+		}
+		return decodeConfigMapManifests(&configMap)
+	}
+
+	format := forge.ClusterContentFormatFor(contentURL)
+	if format == forge.ClusterContentKustomization {
+		return kustomize(contentURL)
+	}
+
+	statusCode, data, err := utils.HTTPGet(ctx, contentURL, clusterContentRetrievalTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the cluster content: unexpected status code %d", statusCode)
+	}
+
+	if format == forge.ClusterContentManifest {
+		return forge.DecodeManifests(data)
+	}
+
+	dir, err := os.MkdirTemp("", "cluster-content-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := utils.ExtractTarball(data, dir); err != nil {
+		return nil, err
+	}
+	return decodeDirectoryManifests(dir)
+}
+
+// decodeConfigMapManifests decodes the manifests contained in the values of the given configmap, sorted by key.
+func decodeConfigMapManifests(configMap *corev1.ConfigMap) ([]unstructured.Unstructured, error) {
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var objects []unstructured.Unstructured
+	for _, key := range keys {
+		decoded, err := forge.DecodeManifests([]byte(configMap.Data[key]))
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", key, err)
+		}
+		objects = append(objects, decoded...)
+	}
+	return objects, nil
+}
+
+// decodeDirectoryManifests builds the kustomization at the root of the given directory, if any,
+// and decodes the manifests contained in the directory (sorted by path) otherwise.
+func decodeDirectoryManifests(dir string) ([]unstructured.Unstructured, error) {
+	for _, name := range kustomizationFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return kustomize(dir)
+		}
+	}
+
+	var objects []unstructured.Unstructured
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || forge.ClusterContentFormatFor(path) != forge.ClusterContentManifest {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		decoded, err := forge.DecodeManifests(data)
+		if err != nil {
+			return fmt.Errorf("file %v: %w", filepath.Base(path), err)
+		}
+		objects = append(objects, decoded...)
+		return nil
+	})
+	return objects, err
+}
+
+// kustomize builds the given (local or remote) kustomization.
+func kustomize(target string) ([]unstructured.Unstructured, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := kustomizer.Run(filesys.MakeFsOnDisk(), target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the kustomization: %w", err)
+	}
+
+	output, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the kustomization: %w", err)
+	}
+	return forge.DecodeManifests(output)
+}
+
+// defaultClusterContentNamespace configures the default namespace for the namespaced objects not specifying one,
+// as server-side apply does not default it (differently from kubectl apply).
+func defaultClusterContentNamespace(workloadClient client.Client, obj *unstructured.Unstructured) error {
+	if obj.GetNamespace() != "" {
+		return nil
+	}
+
+	namespaced, err := workloadClient.IsObjectNamespaced(obj)
+	if err != nil {
+		return err
+	}
+	if namespaced {
+		obj.SetNamespace(forge.ClusterContentDefaultNamespace)
+	}
+	return nil
+}
+
+// readyWorkloadClusterClient returns a client to interact with the workload cluster of the environment,
+// or nil in case the cluster is not yet ready.
+func (r *InstanceReconciler) readyWorkloadClusterClient(ctx context.Context) (client.Client, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	clusterName := fmt.Sprintf("%s-cluster", environment.Cluster.Name)
+	var cluster capiv1.Cluster
+	if err := r.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: clusterName}, &cluster); err != nil {
+		log.Error(err, "failed to retrieve the cluster", "cluster", clusterName)
+		return nil, err
+	}
+	if !conditions.IsTrue(&cluster, capiv1.ReadyCondition) {
+		log.V(utils.LogDebugLevel).Info("cluster not yet ready", "cluster", klog.KObj(&cluster))
+		return nil, nil
+	}
+
+	newClient := r.WorkloadClusterClient
+	if newClient == nil {
+		newClient = r.workloadClusterClient
+	}
+	workloadClient, err := newClient(ctx, instance.Namespace, clusterName)
+	if err != nil {
+		log.Error(err, "failed to create the workload cluster client", "cluster", clusterName)
+		return nil, err
+	}
+	return workloadClient, nil
+}
+
+// workloadClusterClient returns a client to interact with the given workload cluster, based on the kubeconfig generated by the Cluster API.
+func (r *InstanceReconciler) workloadClusterClient(ctx context.Context, namespace, clusterName string) (client.Client, error) {
+	config, err := utils.WorkloadClusterConfig(ctx, r.Client, namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{})
+}
@@ -0,0 +1,73 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// enforceClusterNetworkPolicies ensures the presence of the NetworkPolicies isolating the pods of the cluster environment
+// (i.e. the nodes and the control plane hosted by Kamaji) from the other environments and services in the hosting cluster.
+func (r *InstanceReconciler) enforceClusterNetworkPolicies(ctx context.Context) error {
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	// The NFS servers the nodes are allowed to reach are those of the volumes exposed in the cluster. In case they cannot be
+	// retrieved, the ones already exposed are considered, as the error is anyway surfaced while enforcing the volumes.
+	volumes := instance.Status.ClusterVolumes
+	if forge.ClusterVolumesRequired(environment) {
+		if current, err := r.clusterVolumes(ctx); err == nil {
+			volumes = current
+		}
+	}
+
+	nodes := netv1.NetworkPolicy{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.ClusterNodesNetworkPolicySuffix)}
+	if err := r.enforceNetworkPolicy(ctx, &nodes, forge.ClusterNodesNetworkPolicySpec(environment, volumes)); err != nil {
+		return err
+	}
+
+	controlPlane := netv1.NetworkPolicy{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.ClusterControlPlaneNetworkPolicySuffix)}
+	if !forge.ClusterHostedControlPlane(environment) {
+		return utils.EnforceObjectAbsence(ctx, r.Client, &controlPlane, "networkpolicy")
+	}
+	return r.enforceNetworkPolicy(ctx, &controlPlane, forge.ClusterControlPlaneNetworkPolicySpec(environment))
+}
+
+// enforceNetworkPolicy creates or updates the given NetworkPolicy, with the given specification.
+func (r *InstanceReconciler) enforceNetworkPolicy(ctx context.Context, policy *netv1.NetworkPolicy, spec netv1.NetworkPolicySpec) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		// Differently from services, policies are always enforced, as they do not depend on the configuration of the backends.
+		policy.Spec = spec
+		policy.SetLabels(forge.InstanceObjectLabels(policy.GetLabels(), instance))
+		return ctrl.SetControllerReference(instance, policy, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce object", "networkpolicy", klog.KObj(policy))
+		return err
+	}
+	log.V(utils.FromResult(res)).Info("object enforced", "networkpolicy", klog.KObj(policy), "result", res)
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// maxNetworkAllocationAttempts bounds the number of attempts to allocate a CIDR, in case of conflicts.
+const maxNetworkAllocationAttempts = 10
+
+// enforceClusterNetworkAllocation ensures a unique pod and service CIDR (per IP family) is allocated to the instance, in case the
+// automatic allocation is requested by the template, and returns the environment configured with the actual CIDRs.
+func (r *InstanceReconciler) enforceClusterNetworkAllocation(ctx context.Context) (*clv1alpha2.Environment, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	if policy := environment.Cluster.ClusterNet.Allocation; policy != nil {
+		allocated := environment.DeepCopy()
+		var podCIDRs, serviceCIDRs []string
+
+		families := policy.IPFamilies
+		if len(families) == 0 {
+			families = []clv1alpha2.IPFamily{clv1alpha2.IPv4Family}
+		}
+
+		for _, family := range families {
+			podPool, servicePool := r.ClusterNetworkPools.PodsFor(family), r.ClusterNetworkPools.ServicesFor(family)
+			podPrefixLength, servicePrefixLength := policy.PodPrefixLength, policy.ServicePrefixLength
+			if family == clv1alpha2.IPv6Family {
+				podPrefixLength, servicePrefixLength = policy.PodIPv6PrefixLength, policy.ServiceIPv6PrefixLength
+			}
+
+			pods, err := r.allocateClusterCIDR(ctx, clv1alpha2.NetworkAllocationPods, family,
+				poolFor(policy.PodPools, family, podPool), int(podPrefixLength))
+			if err != nil {
+				return nil, err
+			}
+
+			services, err := r.allocateClusterCIDR(ctx, clv1alpha2.NetworkAllocationServices, family,
+				poolFor(policy.ServicePools, family, servicePool), int(servicePrefixLength))
+			if err != nil {
+				return nil, err
+			}
+
+			podCIDRs, serviceCIDRs = append(podCIDRs, pods), append(serviceCIDRs, services)
+		}
+		forge.SetClusterCIDRs(&allocated.Cluster.ClusterNet, podCIDRs, serviceCIDRs)
+		environment = allocated
+	}
+
+	if err := forge.ValidateClusterNetwork(&environment.Cluster.ClusterNet); err != nil {
+		log.Error(err, "invalid cluster network configuration")
+		return nil, err
+	}
+
+	instance.Status.ClusterNetwork = forge.ClusterNetworkStatus(environment)
+	return environment, nil
+}
+
+// allocateClusterCIDR returns the CIDR of the given type and family allocated to the instance, allocating a new one if necessary.
+func (r *InstanceReconciler) allocateClusterCIDR(ctx context.Context, allocationType clv1alpha2.NetworkAllocationType,
+	family clv1alpha2.IPFamily, pool string, prefixLength int) (string, error) {
+	log := ctrl.LoggerFrom(ctx, "type", allocationType, "family", family, "pool", pool)
+	instance := clctx.InstanceFrom(ctx)
+
+	// The allocations of the instance are retrieved from the API server directly, as the ones created
+	// by a previous reconciliation may not be propagated to the cache yet, and would be allocated twice.
+	var owned clv1alpha2.ClusterNetworkAllocationList
+	if err := r.allocationsReader().List(ctx, &owned, client.MatchingLabels(forge.InstanceSelectorLabels(instance))); err != nil {
+		log.Error(err, "failed to retrieve the cluster network allocations of the instance")
+		return "", err
+	}
+	for i := range owned.Items {
+		if isClusterCIDRAllocationOf(&owned.Items[i], instance, allocationType, family) {
+			return owned.Items[i].Spec.CIDR, nil
+		}
+	}
+
+	var allocations clv1alpha2.ClusterNetworkAllocationList
+	if err := r.List(ctx, &allocations); err != nil {
+		log.Error(err, "failed to retrieve the cluster network allocations")
+		return "", err
+	}
+
+	taken := make([]string, 0, len(allocations.Items))
+	for i := range allocations.Items {
+		taken = append(taken, allocations.Items[i].Spec.CIDR)
+	}
+
+	// Allocations are named after the corresponding CIDR, hence concurrent attempts
+	// to allocate the same CIDR are detected by the creation failing with a conflict.
+	// Overlapping CIDRs (e.g., with different prefix lengths) are instead detected once
+	// created, and only the allocation which takes precedence is retained.
+	for attempt := 0; attempt < maxNetworkAllocationAttempts; attempt++ {
+		cidr, err := utils.NextFreeSubnet(pool, prefixLength, taken)
+		if err != nil {
+			log.Error(err, "failed to find a free CIDR")
+			return "", err
+		}
+
+		allocation := clv1alpha2.ClusterNetworkAllocation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   forge.NetworkAllocationName(cidr),
+				Labels: forge.InstanceObjectLabels(nil, instance),
+			},
+			Spec: forge.ClusterNetworkAllocationSpec(instance, cidr, pool, allocationType, family),
+		}
+		if err := r.Create(ctx, &allocation); kerrors.IsAlreadyExists(err) {
+			// The CIDR may have been allocated to this instance, but not yet reported by the API server when listed.
+			var existing clv1alpha2.ClusterNetworkAllocation
+			if err := r.allocationsReader().Get(ctx, client.ObjectKeyFromObject(&allocation), &existing); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "failed to retrieve the cluster network allocation", "allocation", klog.KObj(&allocation))
+				return "", err
+			} else if err == nil && isClusterCIDRAllocationOf(&existing, instance, allocationType, family) {
+				return cidr, nil
+			}
+			log.V(utils.LogDebugLevel).Info("CIDR concurrently allocated, retrying", "cidr", cidr)
+			taken = append(taken, cidr)
+			continue
+		} else if err != nil {
+			log.Error(err, "failed to create the cluster network allocation", "allocation", klog.KObj(&allocation))
+			return "", err
+		}
+
+		overlapping, err := r.overlappingAllocation(ctx, &allocation)
+		if err != nil {
+			return "", err
+		}
+		if overlapping != "" {
+			log.V(utils.LogDebugLevel).Info("CIDR overlapping with a concurrent allocation, retrying", "cidr", cidr, "overlapping", overlapping)
+			if err := utils.EnforceObjectAbsence(ctx, r.Client, &allocation, "cluster network allocation"); err != nil {
+				return "", err
+			}
+			taken = append(taken, cidr, overlapping)
+			continue
+		}
+
+		log.Info("cluster network CIDR allocated", "cidr", cidr)
+		return cidr, nil
+	}
+
+	return "", fmt.Errorf("failed to allocate a %v %v CIDR from pool %v after %d attempts", family, allocationType, pool, maxNetworkAllocationAttempts)
+}
+
+// overlappingAllocation returns the CIDR of an existing allocation overlapping with the given one, and which takes precedence
+// over it (i.e., it was created earlier, or at the same time with a lower name), if any. The check is performed against
+// the API server directly, to prevent missing the allocations not yet propagated to the cache.
+func (r *InstanceReconciler) overlappingAllocation(ctx context.Context, allocation *clv1alpha2.ClusterNetworkAllocation) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var allocations clv1alpha2.ClusterNetworkAllocationList
+	if err := r.allocationsReader().List(ctx, &allocations); err != nil {
+		log.Error(err, "failed to retrieve the cluster network allocations")
+		return "", err
+	}
+
+	for i := range allocations.Items {
+		other := &allocations.Items[i]
+		if other.Name == allocation.Name {
+			continue
+		}
+		overlaps, err := utils.SubnetsOverlap(allocation.Spec.CIDR, other.Spec.CIDR)
+		if err != nil || !overlaps {
+			continue
+		}
+		if other.CreationTimestamp.Before(&allocation.CreationTimestamp) ||
+			(other.CreationTimestamp.Equal(&allocation.CreationTimestamp) && other.Name < allocation.Name) {
+			return other.Spec.CIDR, nil
+		}
+	}
+	return "", nil
+}
+
+// allocationsReader returns the reader retrieving the cluster network allocations from the API server directly, if configured.
+func (r *InstanceReconciler) allocationsReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
+
+// isClusterCIDRAllocationOf returns whether the given allocation is the one of the given type and family, belonging to the instance.
+func isClusterCIDRAllocationOf(allocation *clv1alpha2.ClusterNetworkAllocation, instance *clv1alpha2.Instance,
+	allocationType clv1alpha2.NetworkAllocationType, family clv1alpha2.IPFamily) bool {
+	return forge.IsNetworkAllocationOf(allocation, instance) && allocation.Spec.Type == allocationType && allocation.Spec.Family == family
+}
+
+// releaseClusterNetworkAllocations deletes the ClusterNetworkAllocations associated with the instance, if any.
+func (r *InstanceReconciler) releaseClusterNetworkAllocations(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	var allocations clv1alpha2.ClusterNetworkAllocationList
+	if err := r.List(ctx, &allocations, client.MatchingLabels(forge.InstanceSelectorLabels(instance))); err != nil {
+		log.Error(err, "failed to retrieve the cluster network allocations")
+		return err
+	}
+
+	for i := range allocations.Items {
+		allocation := &allocations.Items[i]
+		if !forge.IsNetworkAllocationOf(allocation, instance) {
+			continue
+		}
+		if err := utils.EnforceObjectAbsence(ctx, r.Client, allocation, "cluster network allocation"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poolFor returns the pool of the given IP family configured in the template, falling back to the default one.
+func poolFor(pools []string, family clv1alpha2.IPFamily, fallback string) string {
+	for _, pool := range pools {
+		if poolFamily, err := forge.CIDRFamily(pool); err == nil && poolFamily == family {
+			return pool
+		}
+	}
+	return fallback
+}
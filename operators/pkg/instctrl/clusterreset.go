@@ -0,0 +1,130 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	virtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// EnforceClusterReset tears down the cluster of the Instance in case a reset is requested through the corresponding
+// annotation, returning whether the environments can be enforced (i.e., no reset is in progress). Once the teardown
+// is completed, the status of the cluster is cleared, so that it is provisioned again from scratch (including the
+// credentials, the add-ons and the content) while preserving the identity of the Instance (i.e., its name and URLs).
+func (r *InstanceReconciler) EnforceClusterReset(ctx context.Context) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	template := clctx.TemplateFrom(ctx)
+
+	if instance.Status.ClusterReset == nil && forge.IsClusterTemplate(template) {
+		instance.Status.ClusterReset = forge.ClusterResetInitialStatus(instance)
+	}
+	if !forge.ClusterResetRequired(instance, template) {
+		return true, nil
+	}
+
+	reset := instance.GetAnnotations()[forge.ClusterResetAnnotation]
+	if instance.Status.Phase != clv1alpha2.EnvironmentPhaseStopping {
+		log.Info("cluster reset requested", "reset", reset)
+		r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvClusterResetStarted, EvClusterResetStartedMsg, reset)
+		instance.Status.Phase = clv1alpha2.EnvironmentPhaseStopping
+	}
+
+	var completed bool
+	var err error
+	if forge.IsNodeClusterTemplate(template) {
+		completed, err = r.enforceNodeClusterTeardown(ctx, forge.NodeClusterName(instance, &template.Spec.EnvironmentList[0]))
+	} else {
+		completed, err = r.EnforceClusterTeardown(ctx)
+	}
+	if err != nil || !completed {
+		return false, err
+	}
+
+	instance.Status.ClusterNodes = nil
+	instance.Status.ClusterContent = nil
+	instance.Status.ClusterVolumes = nil
+	instance.Status.ClusterStages = nil
+	instance.Status.ClusterReset = forge.ClusterResetStatus(instance)
+	instance.Status.Phase = clv1alpha2.EnvironmentPhaseStarting
+
+	log.Info("cluster reset completed", "reset", reset, "count", instance.Status.ClusterReset.Count)
+	r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvClusterResetCompleted, EvClusterResetCompletedMsg, reset)
+	return true, nil
+}
+
+// enforceNodeClusterTeardown tears down the given VM-based cluster of the Instance, returning whether the teardown has been completed.
+// The VMs backing the nodes are deleted first, and the API service, the CNI installation job and the credentials are removed only once they are gone.
+func (r *InstanceReconciler) enforceNodeClusterTeardown(ctx context.Context, clusterName string) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	selector := []client.ListOption{client.InNamespace(instance.Namespace), client.MatchingLabels(forge.InstanceSelectorLabels(instance))}
+
+	var vms virtv1.VirtualMachineList
+	if err := r.List(ctx, &vms, selector...); err != nil {
+		log.Error(err, "failed to retrieve the cluster virtualmachines")
+		return false, err
+	}
+	var vmis virtv1.VirtualMachineInstanceList
+	if err := r.List(ctx, &vmis, selector...); err != nil {
+		log.Error(err, "failed to retrieve the cluster virtualmachineinstances")
+		return false, err
+	}
+
+	if len(vms.Items)+len(vmis.Items) > 0 {
+		for i := range vms.Items {
+			if vms.Items[i].DeletionTimestamp.IsZero() {
+				if err := utils.EnforceObjectAbsence(ctx, r.Client, &vms.Items[i], "virtualmachine"); err != nil {
+					return false, err
+				}
+			}
+		}
+		for i := range vmis.Items {
+			if vmis.Items[i].DeletionTimestamp.IsZero() {
+				if err := utils.EnforceObjectAbsence(ctx, r.Client, &vmis.Items[i], "virtualmachineinstance"); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		log.Info("waiting for the cluster nodes to be torn down", "nodes", len(vms.Items)+len(vmis.Items))
+		return false, nil
+	}
+
+	// Only the objects of the cluster are removed, while the other ones (e.g., the services exposing the instance) are preserved.
+	service := corev1.Service{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterEndpointServiceName(clusterName)})}
+	if err := utils.EnforceObjectAbsence(ctx, r.Client, &service, "service"); err != nil {
+		return false, err
+	}
+	job := batchv1.Job{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterCNIJobName(clusterName)})}
+	if err := utils.EnforceObjectAbsence(ctx, r.Client, &job, "job", client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return false, err
+	}
+	return true, r.enforceClusterCredentialsAbsence(ctx)
+}
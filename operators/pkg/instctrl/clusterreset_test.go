@@ -0,0 +1,305 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	virtv1 "kubevirt.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+)
+
+var _ = Describe("Reset of the cluster environments", func() {
+	var (
+		ctx        context.Context
+		objects    []client.Object
+		reconciler instctrl.InstanceReconciler
+		recorder   *record.FakeRecorder
+
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+
+		proceed bool
+		err     error
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "kubernetes"
+		templateNamespace = "workspace-netgroup"
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: instanceNamespace, Name: name}
+	}
+
+	labelled := func(obj client.Object, name string) client.Object {
+		obj.SetName(name)
+		obj.SetNamespace(instanceNamespace)
+		obj.SetLabels(forge.InstanceObjectLabels(nil, &instance))
+		return obj
+	}
+
+	credentials := func(name string) client.Object {
+		secret := labelled(&corev1.Secret{}, name)
+		secret.SetLabels(forge.ClusterCredentialsLabels(nil, &instance))
+		return secret
+	}
+
+	events := func() []string {
+		var received []string
+		for len(recorder.Events) > 0 {
+			received = append(received, <-recorder.Events)
+		}
+		return received
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        instanceName,
+				Namespace:   instanceNamespace,
+				Annotations: map[string]string{forge.ClusterResetAnnotation: "2"},
+			},
+			Spec: clv1alpha2.InstanceSpec{
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: "tester"},
+				Running:  true,
+			},
+			Status: clv1alpha2.InstanceStatus{
+				Phase:          clv1alpha2.EnvironmentPhaseReady,
+				ClusterNodes:   []clv1alpha2.InstanceClusterNode{{Name: "demo-md-0", Role: clv1alpha2.ClusterNodeWorker}},
+				ClusterContent: &clv1alpha2.InstanceClusterContentStatus{Revision: "sha256:0123"},
+				ClusterStages:  []clv1alpha2.InstanceClusterStage{{Name: clv1alpha2.ClusterStageInfrastructure}},
+				ClusterNetwork: &clv1alpha2.InstanceClusterNetworkStatus{Pods: "10.64.0.0/16", PodsCIDRs: []string{"10.64.0.0/16"}},
+				ClusterReset:   &clv1alpha2.InstanceClusterResetStatus{ObservedReset: "1", Count: 1},
+			},
+		}
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec: clv1alpha2.TemplateSpec{EnvironmentList: []clv1alpha2.Environment{{
+				Name:            "cluster",
+				EnvironmentType: clv1alpha2.ClassCluster,
+				Cluster:         &clv1alpha2.ClusterTemplate{Name: "demo"},
+			}}},
+		}
+
+		objects = []client.Object{
+			labelled(&controlplanev1.KubeadmControlPlane{}, "demo-control-plane"),
+			credentials("kubernetes-0000-node-files"),
+			labelled(&corev1.Secret{}, "kubernetes-0000-viewer"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		recorder = record.NewFakeRecorder(1024)
+		reconciler = instctrl.InstanceReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(append(objects, &instance, &template)...).
+				WithStatusSubresource(&instance, &template).Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: recorder,
+		}
+
+		Expect(reconciler.Get(ctx, key(instanceName), &instance)).To(Succeed())
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		ctx, _ = clctx.TemplateInto(ctx, &template)
+		proceed, err = reconciler.EnforceClusterReset(ctx)
+	})
+
+	When("no new reset is requested", func() {
+		BeforeEach(func() { instance.Annotations[forge.ClusterResetAnnotation] = "1" })
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should proceed with the enforcement", func() { Expect(proceed).To(BeTrue()) })
+		It("Should not alter the status", func() { Expect(instance.Status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseReady)) })
+		It("Should not emit any event", func() { Expect(events()).To(BeEmpty()) })
+	})
+
+	When("the instance has just been created with the annotation", func() {
+		BeforeEach(func() {
+			instance.Status = clv1alpha2.InstanceStatus{}
+			objects = append(objects, labelled(&capiv1.Cluster{}, "demo-cluster"))
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should proceed with the enforcement", func() { Expect(proceed).To(BeTrue()) })
+		It("Should not emit any event", func() { Expect(events()).To(BeEmpty()) })
+
+		It("Should record the annotation as observed, without resetting the cluster", func() {
+			Expect(instance.Status.ClusterReset).To(Equal(&clv1alpha2.InstanceClusterResetStatus{ObservedReset: "2"}))
+			var cluster capiv1.Cluster
+			Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+			Expect(cluster.DeletionTimestamp.IsZero()).To(BeTrue())
+		})
+	})
+
+	When("the template is not backed by a cluster", func() {
+		BeforeEach(func() { template.Spec.EnvironmentList[0].EnvironmentType = clv1alpha2.ClassContainer })
+
+		It("Should proceed with the enforcement", func() { Expect(proceed).To(BeTrue()) })
+		It("Should not emit any event", func() { Expect(events()).To(BeEmpty()) })
+	})
+
+	When("the cluster still exists", func() {
+		BeforeEach(func() {
+			cluster := labelled(&capiv1.Cluster{}, "demo-cluster")
+			cluster.SetFinalizers([]string{capiv1.ClusterFinalizer})
+			objects = append(objects, cluster)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should not proceed with the enforcement", func() { Expect(proceed).To(BeFalse()) })
+		It("Should set the stopping phase", func() { Expect(instance.Status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseStopping)) })
+
+		It("Should delete the cluster", func() {
+			var cluster capiv1.Cluster
+			Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+			Expect(cluster.DeletionTimestamp.IsZero()).To(BeFalse())
+		})
+
+		It("Should not yet record the reset", func() {
+			Expect(instance.Status.ClusterReset.ObservedReset).To(Equal("1"))
+			Expect(instance.Status.ClusterReset.Count).To(Equal(1))
+		})
+
+		It("Should emit the start event", func() {
+			Expect(events()).To(ConsistOf(ContainSubstring(instctrl.EvClusterResetStarted), ContainSubstring(instctrl.EvClusterTeardownStarted)))
+		})
+
+		When("the reset is already in progress", func() {
+			BeforeEach(func() { instance.Status.Phase = clv1alpha2.EnvironmentPhaseStopping })
+
+			It("Should not emit the start event again", func() {
+				Expect(events()).ToNot(ContainElement(ContainSubstring(instctrl.EvClusterResetStarted)))
+			})
+		})
+	})
+
+	When("the cluster has been torn down", func() {
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should proceed with the enforcement", func() { Expect(proceed).To(BeTrue()) })
+
+		It("Should remove the remaining cluster objects and credentials", func() {
+			var cp controlplanev1.KubeadmControlPlane
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("demo-control-plane"), &cp))).To(BeTrue())
+			var secret corev1.Secret
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &secret))).To(BeTrue())
+		})
+
+		It("Should preserve the other secrets of the instance", func() {
+			var secret corev1.Secret
+			Expect(reconciler.Get(ctx, key("kubernetes-0000-viewer"), &secret)).To(Succeed())
+		})
+
+		It("Should clear the status of the cluster", func() {
+			Expect(instance.Status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseStarting))
+			Expect(instance.Status.ClusterNodes).To(BeEmpty())
+			Expect(instance.Status.ClusterContent).To(BeNil())
+			Expect(instance.Status.ClusterStages).To(BeEmpty())
+		})
+
+		It("Should preserve the identity of the instance", func() {
+			Expect(instance.Status.ClusterNetwork).ToNot(BeNil())
+			Expect(instance.Status.ClusterNetwork.PodsCIDRs).To(ConsistOf("10.64.0.0/16"))
+		})
+
+		It("Should record the reset", func() {
+			Expect(instance.Status.ClusterReset.ObservedReset).To(Equal("2"))
+			Expect(instance.Status.ClusterReset.Count).To(Equal(2))
+			Expect(instance.Status.ClusterReset.LastResetTime.IsZero()).To(BeFalse())
+		})
+
+		It("Should emit the completion event", func() {
+			Expect(events()).To(ContainElement(ContainSubstring(instctrl.EvClusterResetCompleted)))
+		})
+	})
+
+	Context("The template describes a VM-based cluster", func() {
+		BeforeEach(func() {
+			template.Spec.EnvironmentList = []clv1alpha2.Environment{{
+				Name: "control-plane", EnvironmentType: clv1alpha2.ClassVM, Persistent: true,
+				IsClusterNode: true, ClusterName: "lab", ClusterRole: clv1alpha2.ClusterNodeControlPlane,
+			}}
+			objects = []client.Object{
+				credentials("kubernetes-0000-lab-kubeadm"),
+				labelled(&corev1.Service{}, "kubernetes-0000-lab-api"),
+				labelled(&corev1.Service{}, "kubernetes-0000-ssh"),
+				labelled(&batchv1.Job{}, "kubernetes-0000-lab-cni"),
+			}
+		})
+
+		When("the nodes still exist", func() {
+			BeforeEach(func() {
+				objects = append(objects, labelled(&virtv1.VirtualMachine{}, "kubernetes-0000-lab-controlplane-0"))
+			})
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("Should not proceed with the enforcement", func() { Expect(proceed).To(BeFalse()) })
+
+			It("Should delete the virtual machines", func() {
+				var vm virtv1.VirtualMachine
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-lab-controlplane-0"), &vm))).To(BeTrue())
+			})
+
+			It("Should retain the credentials until the nodes are torn down", func() {
+				var secret corev1.Secret
+				Expect(reconciler.Get(ctx, key("kubernetes-0000-lab-kubeadm"), &secret)).To(Succeed())
+			})
+		})
+
+		When("the nodes have been torn down", func() {
+			It("Should proceed with the enforcement", func() { Expect(proceed).To(BeTrue()) })
+
+			It("Should remove the credentials, the API service and the CNI installation job", func() {
+				var secret corev1.Secret
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-lab-kubeadm"), &secret))).To(BeTrue())
+				var service corev1.Service
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-lab-api"), &service))).To(BeTrue())
+				var job batchv1.Job
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-lab-cni"), &job))).To(BeTrue())
+			})
+
+			It("Should preserve the other services of the instance", func() {
+				var service corev1.Service
+				Expect(reconciler.Get(ctx, key("kubernetes-0000-ssh"), &service)).To(Succeed())
+			})
+
+			It("Should record the reset", func() { Expect(instance.Status.ClusterReset.Count).To(Equal(2)) })
+		})
+	})
+})
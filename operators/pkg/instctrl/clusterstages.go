@@ -0,0 +1,84 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+// enforceClusterStagesStatus records the provisioning stages completed by the cluster environment in the instance status,
+// and emits the corresponding prometheus metrics for the newly completed ones.
+func (r *InstanceReconciler) enforceClusterStagesStatus(ctx context.Context, runsWorkloads bool) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+	clusterName := fmt.Sprintf("%s-cluster", environment.Cluster.Name)
+
+	var cluster capiv1.Cluster
+	if err := r.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: clusterName}, &cluster); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		log.Error(err, "failed to retrieve the cluster", "cluster", clusterName)
+		return err
+	}
+
+	var machines capiv1.MachineList
+	if err := r.List(ctx, &machines, client.InNamespace(instance.Namespace),
+		client.MatchingLabels{capiv1.ClusterNameLabel: clusterName}); err != nil {
+		log.Error(err, "failed to retrieve the cluster machines")
+		return err
+	}
+
+	completions := forge.ClusterStageCompletions(&cluster, machines.Items, forge.ExpectedClusterMachines(environment))
+	updated, added := forge.ClusterStagesStatus(instance.Status.ClusterStages, &cluster, completions)
+	instance.Status.ClusterStages = updated
+
+	for i := range added {
+		stage := &added[i]
+		log.Info("cluster provisioning stage completed", "stage", stage.Name, "duration", stage.Duration)
+
+		// The CNI is the only add-on installed by the operator which is reported as a provisioning stage.
+		if stage.Name == clv1alpha2.ClusterStageCNI && runsWorkloads {
+			observeClusterAddonInstall(instance, environment, metricClusterAddonCNI, nil)
+		}
+
+		// Filter out possible outliers from the prometheus metrics (e.g. stages recorded after an operator downtime).
+		if stage.CompletionTime.Sub(cluster.CreationTimestamp.Time) > time.Hour {
+			continue
+		}
+		duration, err := time.ParseDuration(stage.Duration)
+		if err != nil {
+			continue
+		}
+
+		labels := clusterMetricLabels(environment)
+		labels[metricClusterLabelStage] = string(stage.Name)
+		metricClusterStageDurations.With(labels).Observe(duration.Seconds())
+	}
+	return nil
+}
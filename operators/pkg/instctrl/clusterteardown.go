@@ -0,0 +1,247 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+const (
+	// DefaultClusterTeardownTimeout -> the default time granted to the Cluster API to tear down the cluster of an Instance being deleted.
+	DefaultClusterTeardownTimeout = 15 * time.Minute
+	// clusterTeardownPollInterval -> the interval the teardown of a cluster is checked at, in addition to the Cluster events.
+	clusterTeardownPollInterval = 10 * time.Second
+)
+
+// EnforceClusterTeardown tears down the cluster environments of an Instance being deleted, returning whether the
+// teardown has been completed. The Clusters are deleted first, and the remaining objects (control planes, machine
+// templates, exposition and credentials) are removed only once the Cluster API has torn down the machines.
+func (r *InstanceReconciler) EnforceClusterTeardown(ctx context.Context) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	var clusters capiv1.ClusterList
+	if err := r.List(ctx, &clusters, client.InNamespace(instance.Namespace),
+		client.MatchingLabels(forge.InstanceSelectorLabels(instance))); err != nil && !meta.IsNoMatchError(err) {
+		log.Error(err, "failed to retrieve the clusters")
+		return false, err
+	}
+
+	if len(clusters.Items) > 0 {
+		names := make([]string, 0, len(clusters.Items))
+		for i := range clusters.Items {
+			cluster := &clusters.Items[i]
+			names = append(names, cluster.Name)
+			if !cluster.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if err := utils.EnforceObjectAbsence(ctx, r.Client, cluster, "cluster"); err != nil {
+				return false, err
+			}
+			r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvClusterTeardownStarted, EvClusterTeardownStartedMsg, cluster.Name)
+		}
+
+		log.Info("waiting for the clusters to be torn down", "clusters", names)
+		return false, r.checkClusterTeardownTimeout(ctx, names)
+	}
+
+	removed, err := r.enforceClusterComponentsAbsence(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if removed > 0 {
+		if err := r.enforceInstanceExpositionAbsence(ctx); err != nil {
+			return false, err
+		}
+		if err := r.enforceClusterCredentialsAbsence(ctx); err != nil {
+			return false, err
+		}
+		r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvClusterTeardownCompleted, EvClusterTeardownCompletedMsg, removed)
+		log.Info("cluster teardown completed", "objects", removed)
+	}
+	return true, nil
+}
+
+// checkClusterTeardownTimeout flags the Instance with the DeletionStuck condition, in case the given clusters
+// have not been torn down within the configured timeout since the deletion of the Instance was requested.
+func (r *InstanceReconciler) checkClusterTeardownTimeout(ctx context.Context, clusters []string) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	timeout := r.ClusterTeardownTimeout
+	if timeout == 0 {
+		timeout = DefaultClusterTeardownTimeout
+	}
+	if instance.DeletionTimestamp.IsZero() || time.Since(instance.DeletionTimestamp.Time) < timeout {
+		return nil
+	}
+
+	original := instance.DeepCopy()
+	changed := meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               clv1alpha2.InstanceConditionDeletionStuck,
+		Status:             metav1.ConditionTrue,
+		Reason:             clv1alpha2.InstanceReasonClusterTeardownTimeout,
+		Message:            fmt.Sprintf("Cluster(s) %v not torn down within %v", strings.Join(clusters, ", "), timeout),
+		ObservedGeneration: instance.Generation,
+	})
+	if !changed {
+		return nil
+	}
+
+	if err := r.Status().Patch(ctx, instance, client.MergeFrom(original)); err != nil {
+		log.Error(err, "failed to flag the instance deletion as stuck")
+		return err
+	}
+	log.Info("cluster teardown timed out", "clusters", clusters, "timeout", timeout)
+	r.EventsRecorder.Eventf(instance, corev1.EventTypeWarning, EvClusterTeardownStuck, EvClusterTeardownStuckMsg, strings.Join(clusters, ", "), timeout)
+	return nil
+}
+
+// enforceClusterComponentsAbsence removes the objects composing the cluster environments of the Instance,
+// other than the Clusters, returning the number of objects which have been deleted.
+func (r *InstanceReconciler) enforceClusterComponentsAbsence(ctx context.Context) (int, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	removed := 0
+	for _, gvk := range forge.ClusterComponentKinds() {
+		var objects unstructured.UnstructuredList
+		objects.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := r.List(ctx, &objects, client.InNamespace(instance.Namespace),
+			client.MatchingLabels(forge.InstanceSelectorLabels(instance)))
+		switch {
+		case meta.IsNoMatchError(err) || kerrors.IsNotFound(err):
+			// The corresponding provider is not installed, hence there is nothing to remove.
+			log.V(utils.LogDebugLevel).Info("cluster component kind not available", "kind", gvk.Kind)
+			continue
+		case err != nil:
+			log.Error(err, "failed to retrieve the cluster components", "kind", gvk.Kind)
+			return removed, err
+		}
+
+		for i := range objects.Items {
+			if err := utils.EnforceObjectAbsence(ctx, r.Client, &objects.Items[i], strings.ToLower(gvk.Kind)); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// enforceClusterCredentialsAbsence removes the credentials associated with the cluster environments of the Instance,
+// i.e., the secrets flagged as cluster credentials and the kubeconfigs published in the status of the Template.
+// The other secrets of the Instance (e.g., the key of the view-only links) are preserved, as the Instance may be reset.
+// The kubeconfigs are shared among the Instances of the same Template, hence they are removed only once no other Instance references it.
+func (r *InstanceReconciler) enforceClusterCredentialsAbsence(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, client.InNamespace(instance.Namespace),
+		client.MatchingLabels(forge.ClusterCredentialsSelectorLabels(instance))); err != nil {
+		log.Error(err, "failed to retrieve the cluster secrets")
+		return err
+	}
+	for i := range secrets.Items {
+		if err := utils.EnforceObjectAbsence(ctx, r.Client, &secrets.Items[i], "secret"); err != nil {
+			return err
+		}
+	}
+
+	var template clv1alpha2.Template
+	if err := r.Get(ctx, client.ObjectKey{
+		Name:      instance.Spec.Template.Name,
+		Namespace: instance.Spec.Template.Namespace,
+	}, &template); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		log.Error(err, "failed to retrieve the template", "template", klog.KObj(&template))
+		return err
+	}
+
+	referenced, err := r.templateReferencedByOtherInstances(ctx, &template, instance)
+	if err != nil {
+		log.Error(err, "failed to retrieve the instances of the template", "template", klog.KObj(&template))
+		return err
+	}
+	if referenced {
+		log.V(utils.LogDebugLevel).Info("template referenced by other instances, preserving the published kubeconfigs", "template", klog.KObj(&template))
+		return nil
+	}
+
+	published := make(map[string]bool)
+	for i := range template.Spec.EnvironmentList {
+		if cluster := template.Spec.EnvironmentList[i].Cluster; cluster != nil {
+			published[fmt.Sprintf("%s-cluster", cluster.Name)] = true
+		}
+	}
+
+	kubeconfigs := make([]clv1alpha2.KubeconfigTemplate, 0, len(template.Status.KubeConfigs))
+	for _, kubeconfig := range template.Status.KubeConfigs {
+		if !published[kubeconfig.Name] {
+			kubeconfigs = append(kubeconfigs, kubeconfig)
+		}
+	}
+	if len(kubeconfigs) == len(template.Status.KubeConfigs) {
+		return nil
+	}
+
+	template.Status.KubeConfigs = kubeconfigs
+	if err := r.Status().Update(ctx, &template); err != nil {
+		log.Error(err, "failed to remove the published kubeconfigs", "template", klog.KObj(&template))
+		return err
+	}
+	return nil
+}
+
+// templateReferencedByOtherInstances returns whether the given Template is referenced by Instances not being deleted, other than the given one.
+func (r *InstanceReconciler) templateReferencedByOtherInstances(ctx context.Context, template *clv1alpha2.Template, instance *clv1alpha2.Instance) (bool, error) {
+	var instances clv1alpha2.InstanceList
+	if err := r.List(ctx, &instances, client.MatchingLabels(forge.TemplateInstancesSelectorLabels(template))); err != nil {
+		return false, err
+	}
+
+	for i := range instances.Items {
+		other := &instances.Items[i]
+		if (other.Namespace == instance.Namespace && other.Name == instance.Name) || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if other.Spec.Template.Name == instance.Spec.Template.Name && other.Spec.Template.Namespace == instance.Spec.Template.Namespace {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+)
+
+var _ = Describe("Teardown of the cluster environments", func() {
+	var (
+		ctx        context.Context
+		objects    []client.Object
+		reconciler instctrl.InstanceReconciler
+		recorder   *record.FakeRecorder
+
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+
+		completed bool
+		err       error
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "kubernetes"
+		templateNamespace = "workspace-netgroup"
+		tenantName        = "tester"
+		clusterName       = "demo"
+		finalizer         = "crownlabs.polito.it/instance-cleanup"
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: instanceNamespace, Name: name}
+	}
+
+	labelled := func(obj client.Object, name string) client.Object {
+		obj.SetName(name)
+		obj.SetNamespace(instanceNamespace)
+		obj.SetLabels(forge.InstanceObjectLabels(nil, &instance))
+		return obj
+	}
+
+	credentials := func(name string) client.Object {
+		secret := labelled(&corev1.Secret{}, name)
+		secret.SetLabels(forge.ClusterCredentialsLabels(nil, &instance))
+		return secret
+	}
+
+	inMemoryObject := func(kind string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(forge.InfrastructureAPIVersion, kind))
+		return obj
+	}
+
+	events := func() []string {
+		var received []string
+		for len(recorder.Events) > 0 {
+			received = append(received, <-recorder.Events)
+		}
+		return received
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              instanceName,
+				Namespace:         instanceNamespace,
+				Finalizers:        []string{finalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+			Spec: clv1alpha2.InstanceSpec{
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: tenantName},
+			},
+		}
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec: clv1alpha2.TemplateSpec{
+				WorkspaceRef: clv1alpha2.GenericRef{Name: "netgroup"},
+				EnvironmentList: []clv1alpha2.Environment{{
+					Name:            "cluster",
+					EnvironmentType: clv1alpha2.ClassCluster,
+					Cluster:         &clv1alpha2.ClusterTemplate{Name: clusterName},
+				}},
+			},
+			Status: clv1alpha2.TemplateStatus{KubeConfigs: []clv1alpha2.KubeconfigTemplate{{
+				Name: "demo-cluster", FileAddress: "./kubeconfigs/demo-cluster.kubeconfig",
+			}}},
+		}
+
+		objects = []client.Object{
+			labelled(&controlplanev1.KubeadmControlPlane{}, "demo-control-plane"),
+			labelled(&capiv1.MachineDeployment{}, "demo-md"),
+			labelled(inMemoryObject("InMemoryMachineTemplate"), "demo-md-worker"),
+			labelled(inMemoryObject("InMemoryCluster"), "demo-infra"),
+			credentials("kubernetes-0000-node-files"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		recorder = record.NewFakeRecorder(1024)
+		reconciler = instctrl.InstanceReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(append(objects, &instance, &template)...).
+				WithStatusSubresource(&instance, &template).Build(),
+			Scheme:                 scheme.Scheme,
+			EventsRecorder:         recorder,
+			ClusterTeardownTimeout: 10 * time.Minute,
+		}
+
+		Expect(reconciler.Get(ctx, key(instanceName), &instance)).To(Succeed())
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		completed, err = reconciler.EnforceClusterTeardown(ctx)
+	})
+
+	When("the cluster still exists", func() {
+		BeforeEach(func() {
+			cluster := labelled(&capiv1.Cluster{}, "demo-cluster")
+			cluster.SetFinalizers([]string{capiv1.ClusterFinalizer})
+			objects = append(objects, cluster)
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should not complete the teardown", func() { Expect(completed).To(BeFalse()) })
+
+		It("Should delete the cluster", func() {
+			var cluster capiv1.Cluster
+			Expect(reconciler.Get(ctx, key("demo-cluster"), &cluster)).To(Succeed())
+			Expect(cluster.DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(events()).To(ConsistOf(ContainSubstring(instctrl.EvClusterTeardownStarted)))
+		})
+
+		It("Should retain the remaining objects until the cluster is torn down", func() {
+			var cp controlplanev1.KubeadmControlPlane
+			Expect(reconciler.Get(ctx, key("demo-control-plane"), &cp)).To(Succeed())
+			Expect(reconciler.Get(ctx, key("demo-infra"), inMemoryObject("InMemoryCluster"))).To(Succeed())
+			var secret corev1.Secret
+			Expect(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &secret)).To(Succeed())
+		})
+
+		It("Should not flag the deletion as stuck", func() {
+			Expect(meta.FindStatusCondition(instance.Status.Conditions, clv1alpha2.InstanceConditionDeletionStuck)).To(BeNil())
+		})
+
+		When("the teardown exceeds the timeout", func() {
+			BeforeEach(func() {
+				instance.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			})
+
+			It("Should not complete the teardown", func() { Expect(completed).To(BeFalse()) })
+
+			It("Should flag the deletion as stuck", func() {
+				var updated clv1alpha2.Instance
+				Expect(reconciler.Get(ctx, key(instanceName), &updated)).To(Succeed())
+				condition := meta.FindStatusCondition(updated.Status.Conditions, clv1alpha2.InstanceConditionDeletionStuck)
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal(clv1alpha2.InstanceReasonClusterTeardownTimeout))
+				Expect(condition.Message).To(ContainSubstring("demo-cluster"))
+			})
+
+			It("Should emit a warning event", func() {
+				Expect(events()).To(ContainElement(And(HavePrefix(corev1.EventTypeWarning), ContainSubstring(instctrl.EvClusterTeardownStuck))))
+			})
+		})
+	})
+
+	When("the cluster has been torn down", func() {
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should complete the teardown", func() { Expect(completed).To(BeTrue()) })
+
+		It("Should remove the remaining cluster objects", func() {
+			var cp controlplanev1.KubeadmControlPlane
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("demo-control-plane"), &cp))).To(BeTrue())
+			var md capiv1.MachineDeployment
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("demo-md"), &md))).To(BeTrue())
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("demo-md-worker"), inMemoryObject("InMemoryMachineTemplate")))).To(BeTrue())
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("demo-infra"), inMemoryObject("InMemoryCluster")))).To(BeTrue())
+		})
+
+		It("Should remove the cluster credentials", func() {
+			var secret corev1.Secret
+			Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &secret))).To(BeTrue())
+
+			var updated clv1alpha2.Template
+			Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: templateNamespace, Name: templateName}, &updated)).To(Succeed())
+			Expect(updated.Status.KubeConfigs).To(BeEmpty())
+		})
+
+		It("Should emit the completion event", func() {
+			Expect(events()).To(ConsistOf(ContainSubstring(instctrl.EvClusterTeardownCompleted)))
+		})
+
+		When("another instance references the same template", func() {
+			BeforeEach(func() {
+				objects = append(objects, &clv1alpha2.Instance{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-0001", Namespace: "tenant-another",
+						Labels: forge.TemplateInstancesSelectorLabels(&template)},
+					Spec: clv1alpha2.InstanceSpec{
+						Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+						Tenant:   clv1alpha2.GenericRef{Name: "another"},
+					},
+				})
+			})
+
+			It("Should remove the cluster secrets", func() {
+				var secret corev1.Secret
+				Expect(kerrors.IsNotFound(reconciler.Get(ctx, key("kubernetes-0000-node-files"), &secret))).To(BeTrue())
+			})
+
+			It("Should preserve the kubeconfigs published in the template status", func() {
+				var updated clv1alpha2.Template
+				Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: templateNamespace, Name: templateName}, &updated)).To(Succeed())
+				Expect(updated.Status.KubeConfigs).To(HaveLen(1))
+			})
+		})
+
+		When("the instance is not associated with any cluster", func() {
+			BeforeEach(func() { objects = nil })
+
+			It("Should complete the teardown", func() { Expect(completed).To(BeTrue()) })
+			It("Should not emit any event", func() { Expect(events()).To(BeEmpty()) })
+		})
+	})
+})
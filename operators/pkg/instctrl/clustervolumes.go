@@ -0,0 +1,104 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// enforceClusterVolumes exposes the MyDrive and the SharedVolumes of the environment in the workload cluster, once ready,
+// as NFS-backed PersistentVolumes pre-bound to the corresponding PersistentVolumeClaims. The volumes are applied (through
+// server-side apply) at every reconciliation, hence restoring the ones possibly deleted from within the workload cluster.
+func (r *InstanceReconciler) enforceClusterVolumes(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	if !forge.ClusterVolumesRequired(environment) {
+		instance.Status.ClusterVolumes = nil
+		return nil
+	}
+
+	volumes, err := r.clusterVolumes(ctx)
+	if err != nil {
+		return err
+	}
+
+	workloadClient, err := r.readyWorkloadClusterClient(ctx)
+	if err != nil || workloadClient == nil {
+		return err
+	}
+
+	for i := range volumes {
+		volume := &volumes[i]
+		objects := []client.Object{forge.ClusterPersistentVolume(instance, volume), forge.ClusterPersistentVolumeClaim(instance, volume)}
+		for _, obj := range objects {
+			if err := workloadClient.Patch(ctx, obj, client.Apply, client.FieldOwner(forge.ClusterVolumesFieldManager), client.ForceOwnership); err != nil {
+				log.Error(err, "failed to apply the cluster volume", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "object", klog.KObj(obj))
+				return err
+			}
+		}
+	}
+
+	if reflect.DeepEqual(volumes, instance.Status.ClusterVolumes) {
+		log.V(utils.LogDebugLevel).Info("cluster volumes enforced", "volumes", len(volumes))
+		return nil
+	}
+
+	instance.Status.ClusterVolumes = volumes
+	log.Info("cluster volumes applied", "volumes", len(volumes))
+	return nil
+}
+
+// clusterVolumes returns the NFS volumes to be exposed in the workload cluster, i.e. the MyDrive of the tenant
+// (retrieved from the corresponding secret) and the SharedVolumes mounted by the environment.
+func (r *InstanceReconciler) clusterVolumes(ctx context.Context) ([]clv1alpha2.InstanceClusterVolume, error) {
+	log := ctrl.LoggerFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	var volumes []clv1alpha2.InstanceClusterVolume
+	if environment.MountMyDriveVolume {
+		nfsServerName, nfsPath, err := r.GetNFSSpecs(ctx)
+		if err != nil {
+			log.Error(err, "unable to retrieve NFS volume dns name and path")
+			return nil, err
+		}
+		volumes = append(volumes, forge.MyDriveClusterVolume(nfsServerName, nfsPath))
+	}
+
+	for _, mount := range environment.SharedVolumeMounts {
+		var shvol clv1alpha2.SharedVolume
+		if err := r.Get(ctx, forge.NamespacedNameFromMount(mount), &shvol); err != nil {
+			log.Error(err, "unable to retrieve shvol to mount")
+			return nil, err
+		}
+		if shvol.Status.ServerAddress == "" || shvol.Status.ExportPath == "" {
+			log.Info("shvol not yet provisioned, skipping", "shvol", klog.KObj(&shvol))
+			continue
+		}
+		volumes = append(volumes, forge.SharedClusterVolume(&shvol, mount))
+	}
+	return volumes, nil
+}
@@ -29,4 +29,58 @@ const (
 	EvEnvironmentErr = "EnvironmentEnforcementFailed"
 	// EvEnvironmentErrMsg -> the event message corresponding to a failed environment enforcement.
 	EvEnvironmentErrMsg = "Failed to enforce environment %v"
+
+	// EvClusterContentApplied -> the event key corresponding to the content applied to a cluster environment.
+	EvClusterContentApplied = "ClusterContentApplied"
+	// EvClusterContentAppliedMsg -> the event message corresponding to the content applied to a cluster environment.
+	EvClusterContentAppliedMsg = "Applied cluster content revision %v from %v"
+
+	// EvClusterTeardownStarted -> the event key corresponding to the start of the teardown of a cluster environment.
+	EvClusterTeardownStarted = "ClusterTeardownStarted"
+	// EvClusterTeardownStartedMsg -> the event message corresponding to the start of the teardown of a cluster environment.
+	EvClusterTeardownStartedMsg = "Deleting cluster %v, waiting for the machines and the control plane to be torn down"
+
+	// EvClusterTeardownCompleted -> the event key corresponding to the completion of the teardown of a cluster environment.
+	EvClusterTeardownCompleted = "ClusterTeardownCompleted"
+	// EvClusterTeardownCompletedMsg -> the event message corresponding to the completion of the teardown of a cluster environment.
+	EvClusterTeardownCompletedMsg = "Cluster torn down, %d remaining objects removed"
+
+	// EvTemplateUpgraded -> the event key corresponding to the upgrade of an instance to the current revision of its template.
+	EvTemplateUpgraded = "TemplateUpgraded"
+	// EvTemplateUpgradedMsg -> the event message corresponding to the upgrade of an instance to the current revision of its template.
+	EvTemplateUpgradedMsg = "Upgraded from template revision %v to %v"
+
+	// EvTemplateRevisionNotFound -> the event key corresponding to the revision pinned by an instance not being found.
+	EvTemplateRevisionNotFound = "TemplateRevisionNotFound"
+	// EvTemplateRevisionNotFoundMsg -> the event message corresponding to the revision pinned by an instance not being found.
+	EvTemplateRevisionNotFoundMsg = "Template revision %v not found"
+
+	// EvClusterResetStarted -> the event key corresponding to the start of the reset of a cluster environment.
+	EvClusterResetStarted = "ClusterResetStarted"
+	// EvClusterResetStartedMsg -> the event message corresponding to the start of the reset of a cluster environment.
+	EvClusterResetStartedMsg = "Reset %v requested, tearing down the cluster"
+
+	// EvClusterResetCompleted -> the event key corresponding to the completion of the reset of a cluster environment.
+	EvClusterResetCompleted = "ClusterResetCompleted"
+	// EvClusterResetCompletedMsg -> the event message corresponding to the completion of the reset of a cluster environment.
+	EvClusterResetCompletedMsg = "Reset %v completed, provisioning the cluster again"
+
+	// EvNodeClusterCNIInstalled -> the event key corresponding to the CNI installed in a VM-based cluster.
+	EvNodeClusterCNIInstalled = "NodeClusterCNIInstalled"
+	// EvNodeClusterCNIInstalledMsg -> the event message corresponding to the CNI installed in a VM-based cluster.
+	EvNodeClusterCNIInstalledMsg = "Installed the CNI in cluster %v"
+
+	// EvClusterTeardownStuck -> the event key corresponding to the teardown of a cluster environment not completing in time.
+	EvClusterTeardownStuck = "ClusterTeardownStuck"
+	// EvClusterTeardownStuckMsg -> the event message corresponding to the teardown of a cluster environment not completing in time.
+	EvClusterTeardownStuckMsg = "Cluster(s) %v not torn down within %v, the deletion is stuck"
+
+	// EvViewerShared -> the event key corresponding to the generation of a view-only share link.
+	EvViewerShared = "ViewerShared"
+	// EvViewerSharedMsg -> the event message corresponding to the generation of a view-only share link.
+	EvViewerSharedMsg = "Generated a view-only share link, valid until %v"
+	// EvViewerShareInvalid -> the event key corresponding to an invalid request of a view-only share link.
+	EvViewerShareInvalid = "ViewerShareInvalid"
+	// EvViewerShareInvalidMsg -> the event message corresponding to an invalid request of a view-only share link.
+	EvViewerShareInvalidMsg = "Invalid view-only share request %q: %v"
 )
@@ -47,6 +47,13 @@ func (r *InstanceReconciler) EnforceContainerEnvironment(ctx context.Context) er
 		}
 	}
 
+	// Enforce the key to sign the view-only share links, before the deployment mounting it.
+	if forge.ViewerSharingSupported(environment) {
+		if err := r.enforceViewerSharing(ctx); err != nil {
+			return err
+		}
+	}
+
 	return r.enforceContainer(ctx)
 }
 
@@ -26,18 +26,24 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/trace"
 	virtv1 "kubevirt.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
@@ -54,6 +60,23 @@ type InstanceReconciler struct {
 	NamespaceWhitelist metav1.LabelSelector
 	ServiceUrls        ServiceUrls
 	ContainerEnvOpts   forge.ContainerEnvOpts
+	// The pools the cluster networks are allocated from, unless specified by the template.
+	ClusterNetworkPools ClusterNetworkPools
+	// The uncached reader used to detect overlapping cluster network allocations (defaults to the cached client).
+	APIReader client.Reader
+	// The function returning the clients to interact with the workload clusters (defaults to the kubeconfig generated by the Cluster API).
+	WorkloadClusterClient WorkloadClusterClientFactory
+	// The function retrieving the cluster-info of the VM-based clusters, to discover their CA (defaults to an anonymous request to the API server).
+	ClusterInfoRetriever ClusterInfoRetriever
+	// The image of the Cilium CLI, used to install the CNI in the VM-based clusters.
+	NodeClusterCNIImage string
+	// The maximum time the Cluster API is granted to tear down the cluster of an Instance being deleted, before the deletion is flagged as stuck.
+	ClusterTeardownTimeout time.Duration
+	// The maximum validity of the view-only share links of graphical environments.
+	ViewerShareMaxValidity time.Duration
+	// Whether to skip the installation of the cluster add-ons relying on external tools (i.e., the CNI and the visualizer),
+	// e.g. when rendering the forged objects offline.
+	SkipClusterAddons bool
 
 	// This function, if configured, is deferred at the beginning of the Reconcile.
 	// Specifically, it is meant to be set to GinkgoRecover during the tests,
@@ -61,6 +84,30 @@ type InstanceReconciler struct {
 	ReconcileDeferHook func()
 }
 
+// ClusterNetworkPools holds the default pools the pod and service CIDRs of cluster environments are allocated from.
+type ClusterNetworkPools struct {
+	Pods         string
+	Services     string
+	PodsIPv6     string
+	ServicesIPv6 string
+}
+
+// PodsFor returns the default pod pool for the given IP family.
+func (p ClusterNetworkPools) PodsFor(family clv1alpha2.IPFamily) string {
+	if family == clv1alpha2.IPv6Family {
+		return p.PodsIPv6
+	}
+	return p.Pods
+}
+
+// ServicesFor returns the default service pool for the given IP family.
+func (p ClusterNetworkPools) ServicesFor(family clv1alpha2.IPFamily) string {
+	if family == clv1alpha2.IPv6Family {
+		return p.ServicesIPv6
+	}
+	return p.Services
+}
+
 // ServiceUrls holds URL parameters for the instance reconciler.
 type ServiceUrls struct {
 	WebsiteBaseURL   string
@@ -108,10 +155,15 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
 		log.Info("Instance is marked for deletion, handling finalizer")
 		if controllerutil.ContainsFinalizer(&instance, instanceCleanupFinalizer) {
-			if err := r.cleanupResource(ctx); err != nil {
+			completed, cleanupErr := r.cleanupResource(ctx)
+			if err = cleanupErr; err != nil {
 				log.Error(err, "failed to clean up resources")
 				return ctrl.Result{}, err
 			}
+			if !completed {
+				log.Info("Resources still being cleaned up, finalizer retained")
+				return ctrl.Result{RequeueAfter: clusterTeardownPollInterval}, nil
+			}
 			controllerutil.RemoveFinalizer(&instance, instanceCleanupFinalizer)
 			if err := r.Update(ctx, &instance); err != nil {
 				log.Error(err, "failed to remove finalizer")
@@ -122,6 +174,17 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return ctrl.Result{}, nil
 	}
 
+	// Add the finalizer if missing, before creating any resource (e.g., cluster network
+	// allocations) which needs to be released when the instance is deleted.
+	if !controllerutil.ContainsFinalizer(&instance, instanceCleanupFinalizer) {
+		controllerutil.AddFinalizer(&instance, instanceCleanupFinalizer)
+		if err := r.Update(ctx, &instance); err != nil {
+			log.Error(err, "failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+		log.Info("Finalizer added")
+	}
+
 	// Defer the function to update the instance status depending on the modifications
 	// performed while enforcing the desired environments. This is deferred early to
 	// allow setting the CreationLoopBackOff phase in case of errors.
@@ -187,8 +250,28 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		log.Info("instance labels correctly configured")
 	}
 
+	// Pin the instance to a revision of the template, and enforce the environments from the pinned specification.
+	if err = r.EnforceTemplateRevision(ctx); err != nil {
+		log.Error(err, "failed to enforce the template revision")
+		return ctrl.Result{}, err
+	}
+	tracer.Step("template revision enforced")
+
+	// Tear down the cluster of the instance first, in case a reset has been requested.
+	proceed, resetErr := r.EnforceClusterReset(ctx)
+	if err = resetErr; err != nil {
+		log.Error(err, "failed to reset the instance cluster")
+		return ctrl.Result{}, err
+	}
+	if !proceed {
+		log.Info("waiting for the instance cluster to be torn down for the reset")
+		return ctrl.Result{RequeueAfter: clusterTeardownPollInterval}, nil
+	}
+	tracer.Step("instance cluster reset enforced")
+
 	// Iterate over and enforce the instance environments.
-	if err := r.enforceEnvironments(ctx); err != nil {
+	result, envErr := r.enforceEnvironments(ctx)
+	if err = envErr; err != nil {
 		log.Error(err, "failed to enforce instance environments")
 		return ctrl.Result{}, err
 	}
@@ -200,23 +283,29 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 	tracer.Step("instance environments enforced")
 	log.Info("instance environments correctly enforced")
 
-	// Add Finalizer if missing
-	if !controllerutil.ContainsFinalizer(&instance, instanceCleanupFinalizer) {
-		controllerutil.AddFinalizer(&instance, instanceCleanupFinalizer)
-		if err := r.Update(ctx, &instance); err != nil {
-			log.Error(err, "failed to add finalizer")
-			return ctrl.Result{}, err
-		}
-		log.Info("Finalizer added")
-		return ctrl.Result{}, nil
-	}
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
-func (r *InstanceReconciler) enforceEnvironments(ctx context.Context) error {
+func (r *InstanceReconciler) enforceEnvironments(ctx context.Context) (ctrl.Result, error) {
 	instance := clctx.InstanceFrom(ctx)
 	template := clctx.TemplateFrom(ctx)
 
+	// The environments of the template are the nodes of a single VM-based cluster, enforced altogether.
+	// The cluster is periodically inspected until ready, as the nodes join it without further events.
+	if forge.IsNodeClusterTemplate(template) {
+		ctx, _ := clctx.EnvironmentInto(ctx, &template.Spec.EnvironmentList[0])
+		ready, err := r.EnforceNodeClusterEnvironments(ctx)
+		if err != nil {
+			r.EventsRecorder.Eventf(instance, v1.EventTypeWarning, EvEnvironmentErr, EvEnvironmentErrMsg, template.Spec.EnvironmentList[0].ClusterName)
+			return ctrl.Result{}, err
+		}
+		r.setInitialReadyTimeIfNecessary(ctx)
+		if !ready && instance.Spec.Running {
+			return ctrl.Result{RequeueAfter: nodeClusterPollInterval}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
 	for i := range template.Spec.EnvironmentList {
 		environment := &template.Spec.EnvironmentList[i]
 		ctx, log := clctx.EnvironmentInto(ctx, environment)
@@ -226,28 +315,28 @@ func (r *InstanceReconciler) enforceEnvironments(ctx context.Context) error {
 		if i >= 1 {
 			err := fmt.Errorf("instances composed of multiple environments are currently not supported")
 			log.Error(err, "failed to process environment")
-			return nil
+			return ctrl.Result{}, nil
 		}
 		switch template.Spec.EnvironmentList[i].EnvironmentType {
 		case clv1alpha2.ClassVM, clv1alpha2.ClassCloudVM:
 			if err := r.EnforceVMEnvironment(ctx); err != nil {
 				r.EventsRecorder.Eventf(instance, v1.EventTypeWarning, EvEnvironmentErr, EvEnvironmentErrMsg, environment.Name)
-				return err
+				return ctrl.Result{}, err
 			}
 		case clv1alpha2.ClassContainer, clv1alpha2.ClassStandalone:
 			if err := r.EnforceContainerEnvironment(ctx); err != nil {
 				r.EventsRecorder.Eventf(instance, v1.EventTypeWarning, EvEnvironmentErr, EvEnvironmentErrMsg, environment.Name)
-				return err
+				return ctrl.Result{}, err
 			}
 		case clv1alpha2.ClassCluster:
 			if err := r.EnforceClusterEnvironment(ctx); err != nil {
 				r.EventsRecorder.Eventf(instance, v1.EventTypeWarning, EvEnvironmentErr, EvEnvironmentErrMsg, environment.Name)
-				return err
+				return ctrl.Result{}, err
 			}
 		}
 		r.setInitialReadyTimeIfNecessary(ctx)
 	}
-	return nil
+	return ctrl.Result{}, nil
 }
 
 // setInitialReadyTimeIfNecessary configures the instance InitialReadyTime status value and emits the corresponding
@@ -281,13 +370,34 @@ func (r *InstanceReconciler) setInitialReadyTimeIfNecessary(ctx context.Context)
 // SetupWithManager registers a new controller for Instance resources.
 func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager, concurrency int) error {
 	mgr.GetLogger().Info("setup manager")
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := registerClusterInstancesCollector(mgr.GetClient()); err != nil {
+		return err
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
 		For(&clv1alpha2.Instance{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&virtv1.VirtualMachine{}).
+		Owns(&netv1.NetworkPolicy{}).
 		// Here, we use Watches instead of Owns since we need to react also in case a VMI generated from a VM is updated,
 		// to correctly update the instance phase in case of persistent VMs with resource quota exceeded.
 		Watches(&virtv1.VirtualMachineInstance{}, handler.EnqueueRequestsFromMapFunc(r.vmiToInstance)).
+		// Templates are watched to keep the changes pending for the instances pinned to a previous revision up to date.
+		Watches(&clv1alpha2.Template{}, handler.EnqueueRequestsFromMapFunc(r.templateToInstances),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+
+	// The Cluster API kinds are watched only if the corresponding CRDs are installed, as cluster environments are optional.
+	if clusterAPIAvailable(mgr.GetRESTMapper()) {
+		controllerBuilder = controllerBuilder.
+			Owns(&capiv1.Cluster{}).
+			// Machines are not owned by the instance, but carry its labels, propagated from the corresponding templates.
+			// They are watched to publish the nodes of cluster environments (and their IPs) in the instance status.
+			Watches(&capiv1.Machine{}, handler.EnqueueRequestsFromMapFunc(r.machineToInstance))
+	} else {
+		mgr.GetLogger().Info("cluster API CRDs not found, cluster environments will not be watched")
+	}
+
+	return controllerBuilder.
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: concurrency,
 		}).
@@ -295,6 +405,16 @@ func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager, concurrency int)
 		Complete(r)
 }
 
+// clusterAPIAvailable returns whether the Cluster API kinds watched by the controller are known to the given mapper.
+func clusterAPIAvailable(mapper meta.RESTMapper) bool {
+	for _, kind := range []string{"Cluster", "Machine"} {
+		if _, err := mapper.RESTMapping(capiv1.GroupVersion.WithKind(kind).GroupKind(), capiv1.GroupVersion.Version); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
 // vmiToInstance returns a reconcile request for the instance associated with the given VMI object.
 func (r *InstanceReconciler) vmiToInstance(_ context.Context, o client.Object) []reconcile.Request {
 	if instance, found := forge.InstanceNameFromLabels(o.GetLabels()); found {
@@ -304,16 +424,60 @@ func (r *InstanceReconciler) vmiToInstance(_ context.Context, o client.Object) [
 	return nil
 }
 
-// cleanupResource clean file and release visualizer afer deleting instance
-func (r *InstanceReconciler) cleanupResource(ctx context.Context) error {
+// templateToInstances returns a reconcile request for each instance created from the given template.
+func (r *InstanceReconciler) templateToInstances(ctx context.Context, o client.Object) []reconcile.Request {
+	template, ok := o.(*clv1alpha2.Template)
+	if !ok {
+		return nil
+	}
+
+	var instances clv1alpha2.InstanceList
+	if err := r.List(ctx, &instances, client.MatchingLabels(forge.TemplateInstancesSelectorLabels(template))); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list the instances of the template", "template", klog.KObj(template))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range instances.Items {
+		// Templates with the same name may exist in different workspaces.
+		if instances.Items[i].Spec.Template.Namespace == template.Namespace {
+			requests = append(requests, reconcile.Request{NamespacedName: forge.NamespacedName(&instances.Items[i])})
+		}
+	}
+	return requests
+}
+
+// machineToInstance returns a reconcile request for the instance associated with the given Cluster API Machine object.
+func (r *InstanceReconciler) machineToInstance(_ context.Context, o client.Object) []reconcile.Request {
+	machine, ok := o.(*capiv1.Machine)
+	if !ok {
+		return nil
+	}
+
+	if instance, found := forge.InstanceNameFromLabels(machine.GetLabels()); found {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: machine.GetNamespace(), Name: instance}}}
+	}
+
+	return nil
+}
+
+// cleanupResource tears down the cluster environments, clean file and release visualizer afer deleting instance.
+// It returns whether the cleanup has been completed, hence the finalizer can be removed.
+func (r *InstanceReconciler) cleanupResource(ctx context.Context) (bool, error) {
 	log := ctrl.LoggerFrom(ctx)
 	instance := clctx.InstanceFrom(ctx)
-	path := fmt.Sprintf("./kubeconfigs/%s-instance.kubeconfig", instance.Name)
 
+	if completed, err := r.EnforceClusterTeardown(ctx); err != nil || !completed {
+		return false, err
+	}
+
+	path := fmt.Sprintf("./kubeconfigs/%s-instance.kubeconfig", instance.Name)
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		log.Error(err, "failed to delete file", "path", path)
-		return err
+		return false, err
 	}
 	log.Info("Successful cleaned up file", "path", path)
-	return nil
+
+	// cluster network allocations are cluster-scoped, hence not garbage collected through owner references
+	return true, r.releaseClusterNetworkAllocations(ctx)
 }
@@ -110,9 +110,6 @@ func (r *InstanceReconciler) enforceInstanceExpositionPresence(ctx context.Conte
 	// Enforce the ingress to access the environment GUI
 
 	host := forge.HostName(r.ServiceUrls.WebsiteBaseURL, environment.Mode)
-	fmt.Println("host:", host)
-	fmt.Println("service.GetName:", service.GetName())
-	fmt.Println("forge.IngressGUIPath:", forge.IngressGUIPath(instance, environment))
 	// cluster uses passthrough mode not ingress which will terminate in inress side.
 	if environment.EnvironmentType == clv1alpha2.ClassCluster {
 		configMap := v1.ConfigMap{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.IngressGUIName(environment))}
@@ -188,6 +185,12 @@ func (r *InstanceReconciler) enforceInstanceExpositionAbsence(ctx context.Contex
 		return err
 	}
 
+	// Enforce view-only ingress absence
+	ingressViewer := netv1.Ingress{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.ViewerIngressNameSuffix)}
+	if err := utils.EnforceObjectAbsence(ctx, r.Client, &ingressViewer, "ingress"); err != nil {
+		return err
+	}
+
 	// Enforce configmap absence
 	configMap := v1.ConfigMap{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.IngressdashboardPathSuffix)}
 	if err := utils.EnforceObjectAbsence(ctx, r.Client, &configMap, "configmap"); err != nil {
@@ -15,8 +15,18 @@
 package instctrl
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
 )
 
 const (
@@ -25,6 +35,21 @@ const (
 	metricInitialReadyTimesLabelEnvironment = "environment"
 	metricInitialReadyTimesLabelType        = "type"
 	metricInitialReadyTimesLabelPersistent  = "persistent"
+
+	metricClusterLabelStage        = "stage"
+	metricClusterLabelControlPlane = "control_plane"
+	metricClusterLabelCNI          = "cni"
+	metricClusterLabelAddon        = "addon"
+	metricClusterLabelOutcome      = "outcome"
+	metricClusterLabelPhase        = "phase"
+
+	metricClusterAddonCNI       = "cni"
+	metricClusterAddonContent   = "content"
+	metricClusterOutcomeSuccess = "success"
+	metricClusterOutcomeFailure = "failure"
+
+	// metricClusterInstancesTimeout -> the maximum time to retrieve the instances when collecting the cluster instances metric.
+	metricClusterInstancesTimeout = 5 * time.Second
 )
 
 var (
@@ -42,9 +67,164 @@ var (
 			metricInitialReadyTimesLabelPersistent,
 		},
 	)
+
+	metricClusterStageDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "instance_cluster_stage_duration_seconds",
+		Help: "The number of seconds required by the cluster environments to complete each provisioning stage, since the completion of the previous one",
+		// Buckets (upper bound in seconds): 10, 20, .., 50, 60, 90, 120, .., 570, 600, 900, 1200, .., 3300, 3600
+		Buckets: append(append(prometheus.LinearBuckets(10, 10, 5), prometheus.LinearBuckets(60, 30, 19)...), prometheus.LinearBuckets(900, 300, 10)...),
+	},
+		[]string{metricClusterLabelStage, metricClusterLabelControlPlane, metricClusterLabelCNI},
+	)
+
+	metricClusterStageFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "instance_cluster_stage_failures_total",
+		Help: "The number of failures encountered while enforcing the objects associated with each provisioning stage of the cluster environments",
+	},
+		[]string{metricClusterLabelStage, metricClusterLabelControlPlane, metricClusterLabelCNI},
+	)
+
+	metricClusterAddonInstalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "instance_cluster_addon_installs_total",
+		Help: "The number of installations of the add-ons (i.e., the CNI and the lab content) of the cluster environments, by outcome",
+	},
+		[]string{metricClusterLabelAddon, metricClusterLabelOutcome, metricClusterLabelControlPlane, metricClusterLabelCNI},
+	)
 )
 
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(metricInitialReadyTimes)
+	metrics.Registry.MustRegister(metricClusterStageDurations)
+	metrics.Registry.MustRegister(metricClusterStageFailures)
+	metrics.Registry.MustRegister(metricClusterAddonInstalls)
+}
+
+// registerClusterInstancesCollector registers the collector of the cluster instances metric, backed by the given reader.
+func registerClusterInstancesCollector(reader client.Reader) error {
+	err := metrics.Registry.Register(newClusterInstancesCollector(reader))
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+// clusterMetricLabels returns the labels identifying the configuration of the given cluster environment in the metrics.
+func clusterMetricLabels(environment *clv1alpha2.Environment) prometheus.Labels {
+	return prometheus.Labels{
+		metricClusterLabelControlPlane: string(environment.Cluster.ControlPlane.Provider),
+		metricClusterLabelCNI:          string(environment.Cluster.ClusterNet.Cni),
+	}
+}
+
+// observeClusterStageFailure accounts for a failure in the enforcement of the given provisioning stage, and returns the error.
+func observeClusterStageFailure(environment *clv1alpha2.Environment, stage clv1alpha2.ClusterStage, err error) error {
+	labels := clusterMetricLabels(environment)
+	labels[metricClusterLabelStage] = string(stage)
+	metricClusterStageFailures.With(labels).Inc()
+	return err
+}
+
+// observeClusterAddonInstall accounts for the outcome of the installation of the given add-on. Failures are accounted
+// only once until the installation succeeds, as tracked by the ClusterAddonFailed condition of the instance, given that
+// failed installations are retried at every reconciliation.
+func observeClusterAddonInstall(instance *clv1alpha2.Instance, environment *clv1alpha2.Environment, addon string, err error) {
+	reason := clusterAddonFailureReason(addon)
+	failing := meta.FindStatusCondition(instance.Status.Conditions, clv1alpha2.InstanceConditionClusterAddonFailed)
+	alreadyFailing := failing != nil && failing.Status == metav1.ConditionTrue && failing.Reason == reason
+
+	if err == nil {
+		if alreadyFailing {
+			meta.RemoveStatusCondition(&instance.Status.Conditions, clv1alpha2.InstanceConditionClusterAddonFailed)
+		}
+	} else {
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               clv1alpha2.InstanceConditionClusterAddonFailed,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            err.Error(),
+			ObservedGeneration: instance.Generation,
+		})
+		if alreadyFailing {
+			return
+		}
+	}
+
+	labels := clusterMetricLabels(environment)
+	labels[metricClusterLabelAddon] = addon
+	labels[metricClusterLabelOutcome] = metricClusterOutcomeSuccess
+	if err != nil {
+		labels[metricClusterLabelOutcome] = metricClusterOutcomeFailure
+	}
+	metricClusterAddonInstalls.With(labels).Inc()
+}
+
+// clusterAddonFailureReason returns the reason of the ClusterAddonFailed condition for the given add-on (e.g. CniInstallFailed).
+func clusterAddonFailureReason(addon string) string {
+	return strings.ToUpper(addon[:1]) + addon[1:] + "InstallFailed"
+}
+
+// clusterInstancesCollector exports the number of cluster instances per phase, control plane provider and CNI,
+// computed at collection time from the instances and the templates retrieved through the given reader.
+type clusterInstancesCollector struct {
+	reader client.Reader
+	desc   *prometheus.Desc
+}
+
+// newClusterInstancesCollector returns a new collector of the cluster instances metric.
+func newClusterInstancesCollector(reader client.Reader) *clusterInstancesCollector {
+	return &clusterInstancesCollector{
+		reader: reader,
+		desc: prometheus.NewDesc("instance_cluster_instances",
+			"The number of Instances of cluster environments, by phase",
+			[]string{metricClusterLabelPhase, metricClusterLabelControlPlane, metricClusterLabelCNI}, nil),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *clusterInstancesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *clusterInstancesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricClusterInstancesTimeout)
+	defer cancel()
+
+	var templates clv1alpha2.TemplateList
+	if err := c.reader.List(ctx, &templates); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	var instances clv1alpha2.InstanceList
+	if err := c.reader.List(ctx, &instances); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	clusters := make(map[clv1alpha2.GenericRef]*clv1alpha2.Environment)
+	for i := range templates.Items {
+		template := &templates.Items[i]
+		for j := range template.Spec.EnvironmentList {
+			if environment := &template.Spec.EnvironmentList[j]; environment.Cluster != nil {
+				clusters[clv1alpha2.GenericRef{Name: template.Name, Namespace: template.Namespace}] = environment
+				break
+			}
+		}
+	}
+
+	counts := make(map[[3]string]int)
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		environment, found := clusters[instance.Spec.Template]
+		if !found {
+			continue
+		}
+		labels := clusterMetricLabels(environment)
+		counts[[3]string{string(instance.Status.Phase), labels[metricClusterLabelControlPlane], labels[metricClusterLabelCNI]}]++
+	}
+
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), labels[:]...)
+	}
 }
@@ -0,0 +1,451 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	virtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+const (
+	// DefaultNodeClusterCNIImage -> the default image of the Cilium CLI, used to install the CNI in the VM-based clusters.
+	DefaultNodeClusterCNIImage = "quay.io/cilium/cilium-cli:v0.18.3"
+
+	// nodeClusterPollInterval is the interval between two checks of the nodes of a VM-based cluster, while it is not yet ready.
+	nodeClusterPollInterval = 30 * time.Second
+	// nodeClusterInfoTimeout is the maximum time granted to retrieve the cluster-info of a VM-based cluster.
+	nodeClusterInfoTimeout = 5 * time.Second
+)
+
+// ClusterInfoRetriever returns the (publicly accessible) cluster-info ConfigMap published by the API server at the given endpoint.
+type ClusterInfoRetriever func(ctx context.Context, endpoint string) (*corev1.ConfigMap, error)
+
+// EnforceNodeClusterEnvironments implements the logic to bootstrap the VM-based kubeadm cluster composed of the
+// environments of the template, without relying on the Cluster API. It creates one VM per node environment,
+// configured through cloud-init to either initialize or join the cluster, installs the CNI once the control
+// plane is reachable and reports the nodes in the instance status. It returns whether the cluster is ready.
+// The joining nodes are created only once the cluster has been initialized, as they validate it through its CA.
+func (r *InstanceReconciler) EnforceNodeClusterEnvironments(ctx context.Context) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	template := clctx.TemplateFrom(ctx)
+
+	environments := template.Spec.EnvironmentList
+	clusterName := forge.NodeClusterName(instance, &environments[0])
+
+	bootstrap, err := r.enforceNodeClusterBootstrapSecret(ctx, clusterName)
+	if err != nil {
+		return false, err
+	}
+	initialized, err := r.enforceNodeClusterCACert(ctx, clusterName, bootstrap)
+	if err != nil {
+		return false, err
+	}
+	if initialized {
+		if err := r.enforceNodeClusterKubeconfigSecret(ctx, clusterName, bootstrap); err != nil {
+			return false, err
+		}
+	}
+
+	publicKeys, err := r.GetPublicKeys(ctx)
+	if err != nil {
+		log.Error(err, "unable to get public keys")
+		return false, err
+	}
+
+	phase := clv1alpha2.EnvironmentPhaseReady
+	nodes := make([]clv1alpha2.InstanceClusterNode, 0, len(environments))
+	for i := range environments {
+		ctx, _ := clctx.EnvironmentInto(ctx, &environments[i])
+		if forge.IsNodeClusterInitializer(&environments[i]) {
+			if err := r.enforceNodeClusterEndpointService(ctx, clusterName); err != nil {
+				return false, err
+			}
+		} else if !initialized {
+			nodes = append(nodes, clv1alpha2.InstanceClusterNode{
+				Name: forge.NodeClusterNodeName(instance, &environments[i]), Role: environments[i].ClusterRole})
+			phase = nodeClusterPhase(phase, clv1alpha2.EnvironmentPhaseStarting)
+			continue
+		}
+
+		node, nodePhase, err := r.enforceNodeClusterNode(ctx, bootstrap, publicKeys)
+		if err != nil {
+			return false, err
+		}
+		nodes = append(nodes, node)
+		phase = nodeClusterPhase(phase, nodePhase)
+	}
+
+	// The nodes of the cluster can be inspected only once the VMs are up and running, and the cluster has been initialized.
+	switch {
+	case phase == clv1alpha2.EnvironmentPhaseReady && !initialized:
+		phase = clv1alpha2.EnvironmentPhaseRunning
+	case phase == clv1alpha2.EnvironmentPhaseReady:
+		ready, err := r.enforceNodeClusterReadiness(ctx, clusterName, nodes)
+		if err != nil {
+			return false, err
+		}
+		if !ready {
+			phase = clv1alpha2.EnvironmentPhaseRunning
+		}
+	}
+
+	instance.Status.ClusterNodes = forge.NodeClusterNodes(nodes)
+	if phase != instance.Status.Phase {
+		log.Info("phase changed", "cluster", clusterName, "previous", string(instance.Status.Phase), "current", string(phase))
+		instance.Status.Phase = phase
+	}
+	return phase == clv1alpha2.EnvironmentPhaseReady, nil
+}
+
+// enforceNodeClusterBootstrapSecret enforces the secret holding the bootstrap material of the cluster, which
+// is generated only once, as it is embedded in the cloud-init configuration of the nodes, and returns it.
+func (r *InstanceReconciler) enforceNodeClusterBootstrapSecret(ctx context.Context, clusterName string) (map[string][]byte, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	secret := corev1.Secret{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterBootstrapSecretName(clusterName)})}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		if len(secret.Data[forge.NodeClusterTokenKey]) == 0 {
+			data, err := forge.NodeClusterBootstrapData()
+			if err != nil {
+				return err
+			}
+			secret.Data = data
+		}
+		secret.SetLabels(forge.ClusterCredentialsLabels(secret.GetLabels(), instance))
+		secret.Type = corev1.SecretTypeOpaque
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce the cluster bootstrap secret", "secret", klog.KObj(&secret))
+		return nil, err
+	}
+	log.V(utils.FromResult(res)).Info("cluster bootstrap secret enforced", "secret", klog.KObj(&secret), "result", res)
+	return secret.Data, nil
+}
+
+// enforceNodeClusterCACert discovers the certificate of the cluster CA, generated by kubeadm while initializing the cluster,
+// and stores it in the bootstrap secret (as well as in the given bootstrap data). It returns whether the cluster has been
+// initialized (i.e., the CA is known), considering it not yet initialized (rather than failing) as long as the discovery fails.
+func (r *InstanceReconciler) enforceNodeClusterCACert(ctx context.Context, clusterName string, bootstrap map[string][]byte) (bool, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", clusterName)
+	instance := clctx.InstanceFrom(ctx)
+
+	if len(bootstrap[forge.NodeClusterCACertKey]) > 0 {
+		return true, nil
+	}
+
+	retrieve := r.ClusterInfoRetriever
+	if retrieve == nil {
+		retrieve = retrieveClusterInfo
+	}
+	endpoint := fmt.Sprintf("https://%s:%d", forge.NodeClusterEndpoint(instance, clusterName), forge.NodeClusterAPIServerPort)
+	clusterInfo, err := retrieve(ctx, endpoint)
+	if err != nil {
+		log.V(utils.LogDebugLevel).Info("cluster not yet initialized", "reason", err)
+		return false, nil
+	}
+	caCert, err := forge.NodeClusterCACertFromClusterInfo(clusterInfo, string(bootstrap[forge.NodeClusterTokenKey]))
+	if err != nil {
+		log.V(utils.LogDebugLevel).Info("cluster CA not yet discoverable", "reason", err)
+		return false, nil
+	}
+
+	secret := corev1.Secret{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterBootstrapSecretName(clusterName)})}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&secret), &secret); err != nil {
+		log.Error(err, "failed to retrieve the cluster bootstrap secret", "secret", klog.KObj(&secret))
+		return false, err
+	}
+	original := secret.DeepCopy()
+	secret.Data[forge.NodeClusterCACertKey] = caCert
+	if err := r.Patch(ctx, &secret, client.MergeFrom(original)); err != nil {
+		log.Error(err, "failed to store the cluster CA certificate", "secret", klog.KObj(&secret))
+		return false, err
+	}
+
+	bootstrap[forge.NodeClusterCACertKey] = caCert
+	log.Info("cluster CA certificate discovered")
+	return true, nil
+}
+
+// retrieveClusterInfo retrieves the cluster-info ConfigMap through an anonymous request to the API server at the given endpoint.
+// The certificate of the API server cannot be verified at this stage, as the CA is not yet known: the content of the
+// ConfigMap is trusted only once its signature is verified through the bootstrap token.
+func retrieveClusterInfo(ctx context.Context, endpoint string) (*corev1.ConfigMap, error) {
+	clientset, err := kubernetes.NewForConfig(&rest.Config{
+		Host:            endpoint,
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+		Timeout:         nodeClusterInfoTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientset.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(ctx, bootstrapapi.ConfigMapClusterInfo, metav1.GetOptions{})
+}
+
+// enforceNodeClusterKubeconfigSecret enforces the secret holding the kubeconfig to interact with the cluster,
+// named consistently with the ones generated by the Cluster API to be retrieved in the same way.
+func (r *InstanceReconciler) enforceNodeClusterKubeconfigSecret(ctx context.Context, clusterName string, bootstrap map[string][]byte) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	secret := corev1.Secret{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterKubeconfigSecretName(clusterName)})}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		if len(secret.Data[forge.NodeClusterKubeconfigKey]) == 0 {
+			kubeconfig, err := forge.NodeClusterKubeconfig(instance, clusterName, bootstrap)
+			if err != nil {
+				return err
+			}
+			secret.Data = map[string][]byte{forge.NodeClusterKubeconfigKey: kubeconfig}
+		}
+		secret.SetLabels(forge.ClusterCredentialsLabels(secret.GetLabels(), instance))
+		secret.Type = corev1.SecretTypeOpaque
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce the cluster kubeconfig secret", "secret", klog.KObj(&secret))
+		return err
+	}
+	log.V(utils.FromResult(res)).Info("cluster kubeconfig secret enforced", "secret", klog.KObj(&secret), "result", res)
+	return nil
+}
+
+// enforceNodeClusterEndpointService enforces the service exposing the API server of the cluster to the nodes and the operator.
+func (r *InstanceReconciler) enforceNodeClusterEndpointService(ctx context.Context, clusterName string) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	service := corev1.Service{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterEndpointServiceName(clusterName)})}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &service, func() error {
+		// The ClusterIP is immutable, hence only the relevant fields are updated.
+		spec := forge.NodeClusterEndpointServiceSpec(instance, environment)
+		service.Spec.Type, service.Spec.Selector, service.Spec.Ports = spec.Type, spec.Selector, spec.Ports
+		service.SetLabels(forge.InstanceObjectLabels(service.GetLabels(), instance))
+		return ctrl.SetControllerReference(instance, &service, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce the cluster endpoint service", "service", klog.KObj(&service))
+		return err
+	}
+	log.V(utils.FromResult(res)).Info("cluster endpoint service enforced", "service", klog.KObj(&service), "result", res)
+	return nil
+}
+
+// enforceNodeClusterNode enforces the cloud-init secret and the VirtualMachine backing the node of the current environment,
+// and returns the corresponding status entry along with the phase of the VM.
+func (r *InstanceReconciler) enforceNodeClusterNode(ctx context.Context, bootstrap map[string][]byte,
+	publicKeys []string) (clv1alpha2.InstanceClusterNode, clv1alpha2.EnvironmentPhase, error) {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	nodeName := forge.NodeClusterNodeName(instance, environment)
+	node := clv1alpha2.InstanceClusterNode{Name: nodeName, Role: environment.ClusterRole}
+	meta := forge.NamespacedNameToObjectMeta(types.NamespacedName{Namespace: instance.Namespace, Name: nodeName})
+
+	mountInfos, err := r.cloudInitMountInfos(ctx)
+	if err != nil {
+		return node, "", err
+	}
+	userdata, err := forge.NodeClusterCloudInitUserData(instance, environment, bootstrap, publicKeys, mountInfos)
+	if err != nil {
+		log.Error(err, "unable to marshal secret content")
+		return node, "", err
+	}
+
+	secret := corev1.Secret{ObjectMeta: meta}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		secret.SetLabels(forge.ClusterCredentialsLabels(secret.GetLabels(), instance))
+		secret.Data = map[string][]byte{UserDataKey: userdata}
+		secret.Type = corev1.SecretTypeOpaque
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce cloud-init secret", "secret", klog.KObj(&secret))
+		return node, "", err
+	}
+	log.V(utils.FromResult(res)).Info("cloud-init secret enforced", "secret", klog.KObj(&secret), "result", res)
+
+	vm := virtv1.VirtualMachine{ObjectMeta: meta}
+	res, err = ctrl.CreateOrUpdate(ctx, r.Client, &vm, func() error {
+		// As for the other VMs, the specifications are forged only at creation time, and later only the running flag is configured.
+		if vm.CreationTimestamp.IsZero() {
+			vm.Spec = forge.NodeClusterVirtualMachineSpec(instance, environment)
+		}
+		vm.Spec.Running = ptr.To(instance.Spec.Running)
+		vm.SetLabels(forge.InstanceObjectLabels(vm.GetLabels(), instance))
+		return ctrl.SetControllerReference(instance, &vm, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce virtualmachine", "virtualmachine", klog.KObj(&vm))
+		return node, "", err
+	}
+	log.V(utils.FromResult(res)).Info("virtualmachine enforced", "virtualmachine", klog.KObj(&vm), "result", res)
+
+	vmi := virtv1.VirtualMachineInstance{ObjectMeta: meta}
+	if err = r.Get(ctx, client.ObjectKeyFromObject(&vmi), &vmi); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "failed to retrieve virtualmachineinstance", "virtualmachineinstance", klog.KObj(&vmi))
+		return node, "", err
+	}
+	if len(vmi.Status.Interfaces) > 0 {
+		node.IP = vmi.Status.Interfaces[0].IP
+	}
+
+	return node, r.RetrievePhaseFromVM(&vm, &vmi), nil
+}
+
+// enforceNodeClusterReadiness installs the CNI in the cluster, in case it is not yet present, and marks the nodes which
+// joined the cluster and are ready. It returns whether all nodes are ready, considering the cluster not ready
+// (rather than failing) as long as the control plane is not reachable, since it may still be initializing.
+func (r *InstanceReconciler) enforceNodeClusterReadiness(ctx context.Context, clusterName string, nodes []clv1alpha2.InstanceClusterNode) (bool, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", clusterName)
+	instance := clctx.InstanceFrom(ctx)
+
+	newClient := r.WorkloadClusterClient
+	if newClient == nil {
+		newClient = r.workloadClusterClient
+	}
+	workloadClient, err := newClient(ctx, instance.Namespace, clusterName)
+	if err != nil {
+		log.Error(err, "failed to create the workload cluster client")
+		return false, err
+	}
+
+	var clusterNodes corev1.NodeList
+	if err := workloadClient.List(ctx, &clusterNodes); err != nil {
+		log.V(utils.LogDebugLevel).Info("control plane not yet reachable", "reason", err)
+		return false, nil
+	}
+	if len(clusterNodes.Items) == 0 {
+		return false, nil
+	}
+
+	if err := r.enforceNodeClusterCNI(ctx, workloadClient, clusterName); err != nil {
+		return false, err
+	}
+
+	readiness := make(map[string]bool, len(clusterNodes.Items))
+	for i := range clusterNodes.Items {
+		readiness[clusterNodes.Items[i].Name] = forge.IsNodeReady(&clusterNodes.Items[i])
+	}
+
+	ready := true
+	for i := range nodes {
+		nodes[i].Ready = readiness[nodes[i].Name]
+		ready = ready && nodes[i].Ready
+	}
+	return ready, nil
+}
+
+// enforceNodeClusterCNI installs the CNI (i.e., Cilium) in the cluster, in case it is not yet present, through a job
+// running the Cilium CLI. Failed jobs are removed, to be retried at the next reconciliation.
+func (r *InstanceReconciler) enforceNodeClusterCNI(ctx context.Context, workloadClient client.Client, clusterName string) error {
+	log := ctrl.LoggerFrom(ctx, "cluster", clusterName)
+	instance := clctx.InstanceFrom(ctx)
+
+	if r.SkipClusterAddons {
+		return nil
+	}
+
+	job := batchv1.Job{ObjectMeta: forge.NamespacedNameToObjectMeta(
+		types.NamespacedName{Namespace: instance.Namespace, Name: forge.NodeClusterCNIJobName(clusterName)})}
+	err := r.Get(ctx, client.ObjectKeyFromObject(&job), &job)
+	switch {
+	case err == nil && job.Status.Succeeded > 0:
+		r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvNodeClusterCNIInstalled, EvNodeClusterCNIInstalledMsg, clusterName)
+		log.Info("CNI installed")
+		return utils.EnforceObjectAbsence(ctx, r.Client, &job, "job", client.PropagationPolicy(metav1.DeletePropagationBackground))
+	case err == nil && nodeClusterJobFailed(&job):
+		log.Info("CNI installation failed, retrying", "job", klog.KObj(&job))
+		return utils.EnforceObjectAbsence(ctx, r.Client, &job, "job", client.PropagationPolicy(metav1.DeletePropagationBackground))
+	case err == nil:
+		log.V(utils.LogDebugLevel).Info("CNI installation in progress", "job", klog.KObj(&job))
+		return nil
+	case !kerrors.IsNotFound(err):
+		log.Error(err, "failed to retrieve the CNI installation job", "job", klog.KObj(&job))
+		return err
+	}
+
+	var daemonset appsv1.DaemonSet
+	err = workloadClient.Get(ctx, types.NamespacedName{Namespace: "kube-system", Name: "cilium"}, &daemonset)
+	if err == nil {
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		log.Error(err, "failed to retrieve the CNI daemonset")
+		return err
+	}
+
+	image := r.NodeClusterCNIImage
+	if image == "" {
+		image = DefaultNodeClusterCNIImage
+	}
+	job.Spec = forge.NodeClusterCNIJobSpec(clusterName, image)
+	job.SetLabels(forge.InstanceObjectLabels(nil, instance))
+	if err := ctrl.SetControllerReference(instance, &job, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, &job); err != nil {
+		log.Error(err, "failed to create the CNI installation job", "job", klog.KObj(&job))
+		return err
+	}
+	log.Info("CNI installation started", "job", klog.KObj(&job))
+	return nil
+}
+
+// nodeClusterJobFailed returns whether the given job has failed.
+func nodeClusterJobFailed(job *batchv1.Job) bool {
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == batchv1.JobFailed && job.Status.Conditions[i].Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeClusterPhase returns the phase of a cluster given the current one and the phase of one of its nodes:
+// the cluster is considered in the phase of the least advanced node, i.e., ready only if all nodes are ready.
+func nodeClusterPhase(current, node clv1alpha2.EnvironmentPhase) clv1alpha2.EnvironmentPhase {
+	if current == clv1alpha2.EnvironmentPhaseReady {
+		return node
+	}
+	return current
+}
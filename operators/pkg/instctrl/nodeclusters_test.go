@@ -0,0 +1,394 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+	jws "k8s.io/cluster-bootstrap/token/jws"
+	virtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+)
+
+var _ = Describe("Generation of the VM-based clusters", func() {
+	var (
+		ctx           context.Context
+		clientBuilder fake.ClientBuilder
+		reconciler    instctrl.InstanceReconciler
+
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+		tenant   clv1alpha2.Tenant
+
+		workloadObjects []client.Object
+		clusterInfo     *corev1.ConfigMap
+		recorder        *record.FakeRecorder
+
+		ready bool
+		err   error
+	)
+
+	const (
+		instanceName      = "kubeadm-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "kubeadm"
+		templateNamespace = "workspace-netgroup"
+		tenantName        = "tester"
+
+		clusterName      = "kubeadm-0000-lab"
+		controlPlaneName = "kubeadm-0000-lab-controlplane-0"
+		workerName       = "kubeadm-0000-lab-worker-0"
+
+		token = "abcdef.0123456789abcdef"
+	)
+
+	caCert := func() []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := x509.Certificate{
+			SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "kubernetes"},
+			IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}()
+
+	signedClusterInfo := func() *corev1.ConfigMap {
+		config := clientcmdapi.NewConfig()
+		config.Clusters[""] = &clientcmdapi.Cluster{Server: "https://10.0.0.10:6443", CertificateAuthorityData: caCert}
+		kubeconfig, err := clientcmd.Write(*config)
+		Expect(err).ToNot(HaveOccurred())
+
+		tokenID, tokenSecret, _ := strings.Cut(token, ".")
+		signature, err := jws.ComputeDetachedSignature(string(kubeconfig), tokenID, tokenSecret)
+		Expect(err).ToNot(HaveOccurred())
+		return &corev1.ConfigMap{Data: map[string]string{"kubeconfig": string(kubeconfig), "jws-kubeconfig-" + tokenID: signature}}
+	}
+
+	bootstrapSecret := func(data map[string][]byte) client.Object {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: clusterName + "-kubeadm", Namespace: instanceNamespace}, Data: data}
+	}
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: instanceNamespace, Name: name}
+	}
+
+	node := func(role clv1alpha2.ClusterNodeRole) clv1alpha2.Environment {
+		return clv1alpha2.Environment{
+			Name:            string(role),
+			Image:           "internal/registry/node:v1.30",
+			EnvironmentType: clv1alpha2.ClassVM,
+			Mode:            clv1alpha2.ModeStandard,
+			Resources: clv1alpha2.EnvironmentResources{
+				CPU:                   2,
+				ReservedCPUPercentage: 50,
+				Memory:                resource.MustParse("4G"),
+			},
+			IsClusterNode: true,
+			ClusterName:   "lab",
+			ClusterRole:   role,
+		}
+	}
+
+	runningVM := func(name, ip string) []client.Object {
+		meta := metav1.ObjectMeta{Name: name, Namespace: instanceNamespace}
+		return []client.Object{
+			&virtv1.VirtualMachine{ObjectMeta: meta, Status: virtv1.VirtualMachineStatus{
+				PrintableStatus: virtv1.VirtualMachineStatusRunning, Ready: true,
+			}},
+			&virtv1.VirtualMachineInstance{ObjectMeta: meta, Status: virtv1.VirtualMachineInstanceStatus{
+				Interfaces: []virtv1.VirtualMachineInstanceNetworkInterface{{IP: ip}},
+			}},
+		}
+	}
+
+	clusterNode := func(name string, ready corev1.ConditionStatus) client.Object {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+		workloadObjects = nil
+		clusterInfo = nil
+		recorder = record.NewFakeRecorder(1024)
+		clientBuilder = *fake.NewClientBuilder().WithScheme(scheme.Scheme)
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+			Spec: clv1alpha2.InstanceSpec{
+				Running:  true,
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: tenantName},
+			},
+		}
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec: clv1alpha2.TemplateSpec{
+				EnvironmentList: []clv1alpha2.Environment{node(clv1alpha2.ClusterNodeControlPlane), node(clv1alpha2.ClusterNodeWorker)},
+			},
+		}
+		tenant = clv1alpha2.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: tenantName},
+			Spec:       clv1alpha2.TenantSpec{PublicKeys: []string{"ssh-ed25519 AAAA tester@crownlabs"}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instctrl.InstanceReconciler{
+			Client:         clientBuilder.Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: recorder,
+			WorkloadClusterClient: func(_ context.Context, namespace, name string) (client.Client, error) {
+				Expect(namespace).To(Equal(instanceNamespace))
+				Expect(name).To(Equal(clusterName))
+				return fake.NewClientBuilder().WithObjects(workloadObjects...).Build(), nil
+			},
+			ClusterInfoRetriever: func(_ context.Context, endpoint string) (*corev1.ConfigMap, error) {
+				Expect(endpoint).To(Equal("https://" + clusterName + "-api." + instanceNamespace + ".svc:6443"))
+				if clusterInfo == nil {
+					return nil, errors.New("connection refused")
+				}
+				return clusterInfo, nil
+			},
+		}
+
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		ctx, _ = clctx.TemplateInto(ctx, &template)
+		ctx, _ = clctx.EnvironmentInto(ctx, &template.Spec.EnvironmentList[0])
+		ctx, _ = clctx.TenantInto(ctx, &tenant)
+		ready, err = reconciler.EnforceNodeClusterEnvironments(ctx)
+	})
+
+	When("the cluster is being created", func() {
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+		It("Should not report the cluster as ready", func() { Expect(ready).To(BeFalse()) })
+
+		It("Should generate the bootstrap material, without the CA", func() {
+			var bootstrap corev1.Secret
+			Expect(reconciler.Get(ctx, key(clusterName+"-kubeadm"), &bootstrap)).To(Succeed())
+			Expect(bootstrap.Data).To(HaveKey(forge.NodeClusterTokenKey))
+			Expect(bootstrap.Data).To(HaveKey(forge.NodeClusterOperatorTokenKey))
+			Expect(bootstrap.Data).ToNot(HaveKey(forge.NodeClusterCACertKey))
+			Expect(bootstrap.GetOwnerReferences()).To(HaveLen(1))
+		})
+
+		It("Should not generate the kubeconfig of the cluster yet", func() {
+			err := reconciler.Get(ctx, key(clusterName+"-kubeconfig"), &corev1.Secret{})
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Should expose the API server of the node initializing the cluster", func() {
+			var service corev1.Service
+			Expect(reconciler.Get(ctx, key(clusterName+"-api"), &service)).To(Succeed())
+			Expect(service.Spec.Selector).To(Equal(forge.NodeClusterNodeLabels(&instance, controlPlaneName)))
+		})
+
+		It("Should create the VM initializing the cluster only", func() {
+			var vm virtv1.VirtualMachine
+			var secret corev1.Secret
+			Expect(reconciler.Get(ctx, key(controlPlaneName), &vm)).To(Succeed())
+			Expect(*vm.Spec.Running).To(BeTrue())
+			Expect(reconciler.Get(ctx, key(controlPlaneName), &secret)).To(Succeed())
+			Expect(string(secret.Data[instctrl.UserDataKey])).To(ContainSubstring("kubeadm init"))
+			Expect(string(secret.Data[instctrl.UserDataKey])).ToNot(ContainSubstring("ca.key"))
+
+			err := reconciler.Get(ctx, key(workerName), &vm)
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Should report the nodes in the status", func() {
+			Expect(instance.Status.ClusterNodes).To(Equal([]clv1alpha2.InstanceClusterNode{
+				{Name: controlPlaneName, Role: clv1alpha2.ClusterNodeControlPlane},
+				{Name: workerName, Role: clv1alpha2.ClusterNodeWorker},
+			}))
+		})
+	})
+
+	When("the bootstrap material has already been generated", func() {
+		BeforeEach(func() {
+			clientBuilder.WithObjects(bootstrapSecret(map[string][]byte{forge.NodeClusterTokenKey: []byte(token)}))
+		})
+
+		It("Should preserve it", func() {
+			var bootstrap corev1.Secret
+			Expect(reconciler.Get(ctx, key(clusterName+"-kubeadm"), &bootstrap)).To(Succeed())
+			Expect(bootstrap.Data).To(HaveKeyWithValue(forge.NodeClusterTokenKey, []byte(token)))
+		})
+
+		When("the cluster has been initialized", func() {
+			BeforeEach(func() { clusterInfo = signedClusterInfo() })
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+			It("Should store the CA discovered from the cluster-info", func() {
+				var bootstrap corev1.Secret
+				Expect(reconciler.Get(ctx, key(clusterName+"-kubeadm"), &bootstrap)).To(Succeed())
+				Expect(bootstrap.Data).To(HaveKeyWithValue(forge.NodeClusterCACertKey, caCert))
+			})
+
+			It("Should generate the kubeconfig of the cluster", func() {
+				var kubeconfig corev1.Secret
+				Expect(reconciler.Get(ctx, key(clusterName+"-kubeconfig"), &kubeconfig)).To(Succeed())
+				Expect(kubeconfig.Data).To(HaveKey(forge.NodeClusterKubeconfigKey))
+			})
+
+			It("Should create the VMs joining the cluster", func() {
+				var secret corev1.Secret
+				Expect(reconciler.Get(ctx, key(workerName), &virtv1.VirtualMachine{})).To(Succeed())
+				Expect(reconciler.Get(ctx, key(workerName), &secret)).To(Succeed())
+				Expect(string(secret.Data[instctrl.UserDataKey])).To(ContainSubstring("kubeadm join"))
+			})
+		})
+
+		When("the cluster-info is not signed by the bootstrap token", func() {
+			BeforeEach(func() {
+				clusterInfo = signedClusterInfo()
+				clusterInfo.Data["kubeconfig"] += "\n"
+			})
+
+			It("Should not trust it", func() {
+				var bootstrap corev1.Secret
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reconciler.Get(ctx, key(clusterName+"-kubeadm"), &bootstrap)).To(Succeed())
+				Expect(bootstrap.Data).ToNot(HaveKey(forge.NodeClusterCACertKey))
+				Expect(reconciler.Get(ctx, key(workerName), &virtv1.VirtualMachine{})).ToNot(Succeed())
+			})
+		})
+	})
+
+	When("the VMs are running", func() {
+		BeforeEach(func() {
+			clientBuilder.WithObjects(bootstrapSecret(map[string][]byte{
+				forge.NodeClusterTokenKey: []byte(token), forge.NodeClusterCACertKey: caCert,
+			}))
+			clientBuilder.WithObjects(runningVM(controlPlaneName, "10.0.0.10")...)
+			clientBuilder.WithObjects(runningVM(workerName, "10.0.0.11")...)
+			workloadObjects = []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "cilium", Namespace: "kube-system"}},
+				clusterNode(controlPlaneName, corev1.ConditionTrue),
+			}
+		})
+
+		When("all nodes joined the cluster and are ready", func() {
+			BeforeEach(func() { workloadObjects = append(workloadObjects, clusterNode(workerName, corev1.ConditionTrue)) })
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("Should report the cluster as ready", func() {
+				Expect(ready).To(BeTrue())
+				Expect(instance.Status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseReady))
+			})
+			It("Should report the ready nodes in the status", func() {
+				Expect(instance.Status.ClusterNodes).To(Equal([]clv1alpha2.InstanceClusterNode{
+					{Name: controlPlaneName, Role: clv1alpha2.ClusterNodeControlPlane, IP: "10.0.0.10", Ready: true},
+					{Name: workerName, Role: clv1alpha2.ClusterNodeWorker, IP: "10.0.0.11", Ready: true},
+				}))
+			})
+			It("Should not install the CNI", func() {
+				err := reconciler.Get(ctx, key(clusterName+"-cni"), &batchv1.Job{})
+				Expect(kerrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		When("a node is not yet ready", func() {
+			BeforeEach(func() { workloadObjects = append(workloadObjects, clusterNode(workerName, corev1.ConditionFalse)) })
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("Should report the cluster as running, but not ready", func() {
+				Expect(ready).To(BeFalse())
+				Expect(instance.Status.Phase).To(Equal(clv1alpha2.EnvironmentPhaseRunning))
+				Expect(instance.Status.ClusterNodes[0].Ready).To(BeTrue())
+				Expect(instance.Status.ClusterNodes[1].Ready).To(BeFalse())
+			})
+		})
+
+		When("the CNI is not installed", func() {
+			BeforeEach(func() { workloadObjects = workloadObjects[1:] })
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+			It("Should start a job installing it", func() {
+				var job batchv1.Job
+				Expect(reconciler.Get(ctx, key(clusterName+"-cni"), &job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal(instctrl.DefaultNodeClusterCNIImage))
+				Expect(job.GetOwnerReferences()).To(HaveLen(1))
+			})
+
+			When("the installation job succeeded", func() {
+				BeforeEach(func() {
+					clientBuilder.WithObjects(&batchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{Name: clusterName + "-cni", Namespace: instanceNamespace},
+						Status:     batchv1.JobStatus{Succeeded: 1},
+					})
+				})
+
+				It("Should remove the job and emit the corresponding event", func() {
+					err := reconciler.Get(ctx, key(clusterName+"-cni"), &batchv1.Job{})
+					Expect(kerrors.IsNotFound(err)).To(BeTrue())
+					Expect(recorder.Events).To(Receive(ContainSubstring(instctrl.EvNodeClusterCNIInstalled)))
+				})
+			})
+
+			When("the installation job failed", func() {
+				BeforeEach(func() {
+					clientBuilder.WithObjects(&batchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{Name: clusterName + "-cni", Namespace: instanceNamespace},
+						Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+							{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+						}},
+					})
+				})
+
+				It("Should remove the job, to be retried", func() {
+					err := reconciler.Get(ctx, key(clusterName+"-cni"), &batchv1.Job{})
+					Expect(kerrors.IsNotFound(err)).To(BeTrue())
+					Expect(recorder.Events).ToNot(Receive())
+				})
+			})
+		})
+	})
+})
@@ -18,6 +18,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	kamajiv1alpha1 "github.com/clastix/cluster-api-control-plane-provider-kamaji/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,6 +27,10 @@ import (
 	"k8s.io/klog/v2/textlogger"
 	virtv1 "kubevirt.io/api/core/v1"
 	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -69,6 +74,11 @@ var _ = BeforeSuite(func() {
 	Expect(clv1alpha1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
 	Expect(virtv1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
 	Expect(cdiv1beta1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
+	Expect(capiv1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
+	Expect(infrav1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
+	Expect(bootstrapv1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
+	Expect(controlplanev1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
+	Expect(kamajiv1alpha1.AddToScheme(scheme.Scheme)).NotTo(HaveOccurred())
 
 	ctrl.SetLogger(textlogger.NewLogger(textlogger.NewConfig()))
 
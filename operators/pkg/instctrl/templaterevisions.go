@@ -0,0 +1,138 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+// EnforceTemplateRevision pins the Instance to a revision of its Template, and replaces the specification of the Template
+// in the context with the pinned one, so that the environments are enforced from it. The Instance is pinned to the current
+// specification of the Template the first time it is reconciled, as well as when an upgrade is requested through the
+// corresponding annotation; otherwise, the changes which would be applied by an upgrade are reported in its status.
+func (r *InstanceReconciler) EnforceTemplateRevision(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	template := clctx.TemplateFrom(ctx)
+
+	latest, err := forge.TemplateRevisionDigest(&template.Spec)
+	if err != nil {
+		log.Error(err, "failed to compute the template revision")
+		return err
+	}
+
+	pinned := instance.Status.TemplateRevision
+	upgrade := forge.TemplateUpgradeRequested(instance)
+	if pinned == nil || (upgrade && pinned.Revision != latest) {
+		name, err := r.enforceTemplateRevisionPresence(ctx, template, latest)
+		if err != nil {
+			return err
+		}
+
+		if pinned != nil {
+			log.Info("instance upgraded to the current template revision", "previous", pinned.Revision, "current", latest)
+			r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvTemplateUpgraded, EvTemplateUpgradedMsg, pinned.Revision, latest)
+		}
+		instance.Status.TemplateRevision = &clv1alpha2.InstanceTemplateRevisionStatus{Name: name, Revision: latest}
+		pinned = instance.Status.TemplateRevision
+	}
+
+	if upgrade {
+		if err := r.clearTemplateUpgradeRequest(ctx); err != nil {
+			return err
+		}
+	}
+
+	if pinned.Revision == latest {
+		pinned.LatestRevision = ""
+		pinned.PendingChanges = nil
+		return nil
+	}
+
+	var revision clv1alpha2.TemplateRevision
+	revisionName := types.NamespacedName{Namespace: template.Namespace, Name: pinned.Name}
+	if err := r.Get(ctx, revisionName, &revision); err != nil {
+		log.Error(err, "failed to retrieve the pinned template revision", "revision", revisionName)
+		if kerrors.IsNotFound(err) {
+			r.EventsRecorder.Eventf(instance, corev1.EventTypeWarning, EvTemplateRevisionNotFound, EvTemplateRevisionNotFoundMsg, pinned.Name)
+		}
+		return err
+	}
+
+	changes, err := forge.TemplateSpecChanges(&revision.Spec.Template, &template.Spec)
+	if err != nil {
+		log.Error(err, "failed to compute the changes of the current template revision")
+		return err
+	}
+	pinned.LatestRevision = latest
+	pinned.PendingChanges = changes
+	template.Spec = revision.Spec.Template
+	return nil
+}
+
+// enforceTemplateRevisionPresence creates the revision of the given template identified by the given digest, in case it
+// does not already exist, and returns its name. Revisions are immutable, hence existing ones are never updated.
+func (r *InstanceReconciler) enforceTemplateRevisionPresence(ctx context.Context, template *clv1alpha2.Template, digest string) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	revision := clv1alpha2.TemplateRevision{
+		ObjectMeta: forge.TemplateRevisionObjectMeta(template, digest),
+		Spec:       forge.TemplateRevisionSpec(template, digest),
+	}
+	// The revisions are garbage collected together with the template, without blocking its deletion.
+	if err := controllerutil.SetOwnerReference(template, &revision, r.Scheme); err != nil {
+		log.Error(err, "failed to configure the owner of the template revision")
+		return "", err
+	}
+
+	if err := r.Create(ctx, &revision); err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return revision.Name, nil
+		}
+		log.Error(err, "failed to create the template revision", "revision", klog.KObj(&revision))
+		return "", err
+	}
+	log.Info("template revision created", "revision", klog.KObj(&revision))
+	return revision.Name, nil
+}
+
+// clearTemplateUpgradeRequest removes the annotation requesting the upgrade of the instance, once it has been handled.
+func (r *InstanceReconciler) clearTemplateUpgradeRequest(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	// The patch is performed on a copy, to preserve the changes to the status not yet persisted.
+	original, updated := instance.DeepCopy(), instance.DeepCopy()
+	delete(updated.Annotations, forge.TemplateUpgradeAnnotation)
+	if err := r.Patch(ctx, updated, client.MergeFrom(original)); err != nil {
+		log.Error(err, "failed to remove the template upgrade annotation")
+		return err
+	}
+	instance.SetAnnotations(updated.GetAnnotations())
+	instance.SetResourceVersion(updated.GetResourceVersion())
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+)
+
+var _ = Describe("Template revisions", func() {
+	var (
+		ctx        context.Context
+		objects    []client.Object
+		reconciler instctrl.InstanceReconciler
+		recorder   *record.FakeRecorder
+
+		instance clv1alpha2.Instance
+		template clv1alpha2.Template
+		previous clv1alpha2.TemplateSpec
+
+		latest         string
+		previousDigest string
+		err            error
+	)
+
+	const (
+		instanceName      = "kubernetes-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "kubernetes"
+		templateNamespace = "workspace-netgroup"
+	)
+
+	events := func() []string {
+		var received []string
+		for len(recorder.Events) > 0 {
+			received = append(received, <-recorder.Events)
+		}
+		return received
+	}
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace},
+			Spec: clv1alpha2.InstanceSpec{
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: "tester"},
+			},
+		}
+		template = clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: templateNamespace},
+			Spec: clv1alpha2.TemplateSpec{EnvironmentList: []clv1alpha2.Environment{{
+				Name:            "cluster",
+				EnvironmentType: clv1alpha2.ClassCluster,
+				Cluster: &clv1alpha2.ClusterTemplate{
+					Name: "demo", Version: "v1.31.0",
+					MachineDeploy: clv1alpha2.MachineDeployment{Replicas: 2},
+				},
+			}}},
+		}
+
+		previous = *template.Spec.DeepCopy()
+		previous.EnvironmentList[0].Cluster.Version = "v1.30.0"
+		previous.EnvironmentList[0].Cluster.MachineDeploy.Replicas = 1
+
+		latest, err = forge.TemplateRevisionDigest(&template.Spec)
+		Expect(err).ToNot(HaveOccurred())
+		previousDigest, err = forge.TemplateRevisionDigest(&previous)
+		Expect(err).ToNot(HaveOccurred())
+
+		previousTemplate := template.DeepCopy()
+		previousTemplate.Spec = previous
+		objects = []client.Object{&clv1alpha2.TemplateRevision{
+			ObjectMeta: forge.TemplateRevisionObjectMeta(previousTemplate, previousDigest),
+			Spec:       forge.TemplateRevisionSpec(previousTemplate, previousDigest),
+		}}
+	})
+
+	JustBeforeEach(func() {
+		recorder = record.NewFakeRecorder(1024)
+		reconciler = instctrl.InstanceReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(append(objects, &instance, &template)...).
+				WithStatusSubresource(&instance, &template).Build(),
+			Scheme:         scheme.Scheme,
+			EventsRecorder: recorder,
+		}
+
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: instanceNamespace, Name: instanceName}, &instance)).To(Succeed())
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		ctx, _ = clctx.TemplateInto(ctx, &template)
+		err = reconciler.EnforceTemplateRevision(ctx)
+	})
+
+	When("the instance is not yet pinned to any revision", func() {
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should create the revision of the current specification", func() {
+			var revision clv1alpha2.TemplateRevision
+			name := types.NamespacedName{Namespace: templateNamespace, Name: forge.TemplateRevisionName(&template, latest)}
+			Expect(reconciler.Get(ctx, name, &revision)).To(Succeed())
+			Expect(revision.Spec.Revision).To(Equal(latest))
+			Expect(revision.Spec.Template.EnvironmentList[0].Cluster.Version).To(Equal("v1.31.0"))
+			Expect(revision.OwnerReferences).To(HaveLen(1))
+			Expect(revision.OwnerReferences[0].Name).To(Equal(templateName))
+		})
+
+		It("Should pin the instance to the current revision", func() {
+			Expect(instance.Status.TemplateRevision).To(Equal(&clv1alpha2.InstanceTemplateRevisionStatus{
+				Name: forge.TemplateRevisionName(&template, latest), Revision: latest,
+			}))
+		})
+
+		It("Should enforce the current specification", func() {
+			Expect(template.Spec.EnvironmentList[0].Cluster.Version).To(Equal("v1.31.0"))
+		})
+	})
+
+	When("the instance is pinned to a previous revision", func() {
+		BeforeEach(func() {
+			instance.Status.TemplateRevision = &clv1alpha2.InstanceTemplateRevisionStatus{
+				Name: forge.TemplateRevisionName(&template, previousDigest), Revision: previousDigest,
+			}
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should enforce the pinned specification", func() {
+			Expect(template.Spec.EnvironmentList[0].Cluster.Version).To(Equal("v1.30.0"))
+			Expect(template.Spec.EnvironmentList[0].Cluster.MachineDeploy.Replicas).To(BeEquivalentTo(1))
+		})
+
+		It("Should report the pending changes", func() {
+			Expect(instance.Status.TemplateRevision.Revision).To(Equal(previousDigest))
+			Expect(instance.Status.TemplateRevision.LatestRevision).To(Equal(latest))
+			Expect(instance.Status.TemplateRevision.PendingChanges).To(ConsistOf(
+				`environmentList[0].cluster.machineDeployment.replicas: 1 -> 2`,
+				`environmentList[0].cluster.version: "v1.30.0" -> "v1.31.0"`,
+			))
+		})
+
+		When("an upgrade is requested", func() {
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{forge.TemplateUpgradeAnnotation: "true"})
+			})
+
+			It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+			It("Should pin the instance to the current revision", func() {
+				Expect(instance.Status.TemplateRevision.Revision).To(Equal(latest))
+				Expect(instance.Status.TemplateRevision.LatestRevision).To(BeEmpty())
+				Expect(instance.Status.TemplateRevision.PendingChanges).To(BeEmpty())
+			})
+
+			It("Should enforce the current specification", func() {
+				Expect(template.Spec.EnvironmentList[0].Cluster.Version).To(Equal("v1.31.0"))
+			})
+
+			It("Should remove the upgrade annotation", func() {
+				var updated clv1alpha2.Instance
+				Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: instanceNamespace, Name: instanceName}, &updated)).To(Succeed())
+				Expect(updated.GetAnnotations()).ToNot(HaveKey(forge.TemplateUpgradeAnnotation))
+				Expect(instance.GetAnnotations()).ToNot(HaveKey(forge.TemplateUpgradeAnnotation))
+			})
+
+			It("Should emit the upgrade event", func() {
+				Expect(events()).To(ConsistOf(ContainSubstring(instctrl.EvTemplateUpgraded)))
+			})
+		})
+
+		When("the pinned revision does not exist", func() {
+			BeforeEach(func() { objects = nil })
+
+			It("Should return an error", func() { Expect(kerrors.IsNotFound(err)).To(BeTrue()) })
+
+			It("Should emit a warning event", func() {
+				Expect(events()).To(ConsistOf(ContainSubstring(instctrl.EvTemplateRevisionNotFound)))
+			})
+		})
+	})
+})
@@ -0,0 +1,113 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// enforceViewerSharing enforces the secret holding the key to sign the view-only share links of the Instance,
+// which is generated once and never rotated (unless the secret is deleted, revoking all the links), as well as
+// the ingress exposing the view-only sessions, and generates a new share link in case it is requested through
+// the corresponding annotation.
+func (r *InstanceReconciler) enforceViewerSharing(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+
+	secret := corev1.Secret{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.ViewerSecretNameSuffix)}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		if len(secret.Data[forge.ViewerKeyName]) == 0 {
+			key := make([]byte, forge.ViewerKeyLength)
+			if _, err := rand.Read(key); err != nil {
+				return err
+			}
+			secret.Data = map[string][]byte{forge.ViewerKeyName: key}
+		}
+		secret.SetLabels(forge.InstanceObjectLabels(secret.GetLabels(), instance))
+		return ctrl.SetControllerReference(instance, &secret, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce object", "secret", klog.KObj(&secret))
+		return err
+	}
+	log.V(utils.FromResult(res)).Info("object enforced", "secret", klog.KObj(&secret), "result", res)
+
+	// The ingress is removed, along with the other exposition objects, when the instance is not running.
+	if !instance.Spec.Running {
+		return nil
+	}
+	if err := r.enforceViewerIngress(ctx, secret.Data[forge.ViewerKeyName]); err != nil {
+		return err
+	}
+
+	// The share link can be generated only once the instance is exposed.
+	if !forge.ViewerShareRequired(instance) || instance.Status.URL == "" {
+		return nil
+	}
+
+	request := instance.GetAnnotations()[forge.ViewerShareAnnotation]
+	status, err := forge.ViewerShareStatus(instance, secret.Data[forge.ViewerKeyName], r.ViewerShareMaxValidity, time.Now())
+	if err != nil {
+		// The request is marked as handled anyway, as retrying would not change the outcome.
+		log.Info("invalid viewer share request", "request", request, "reason", err)
+		r.EventsRecorder.Eventf(instance, corev1.EventTypeWarning, EvViewerShareInvalid, EvViewerShareInvalidMsg, request, err)
+		instance.Status.ViewerShare = &clv1alpha2.InstanceViewerShareStatus{ObservedRequest: request}
+		return nil
+	}
+
+	instance.Status.ViewerShare = status
+	log.Info("viewer share link generated", "expiration", status.ExpirationTime)
+	r.EventsRecorder.Eventf(instance, corev1.EventTypeNormal, EvViewerShared, EvViewerSharedMsg, status.ExpirationTime.Format(time.RFC3339))
+	return nil
+}
+
+// enforceViewerIngress enforces the ingress exposing the view-only sessions of the Instance, on a path derived from
+// the key to sign the share links. Differently from the ingress of the GUI, it is not authenticated (the share links
+// are meant also for external viewers), and it never grants full control, since websockify only serves view-only
+// sessions on this path.
+func (r *InstanceReconciler) enforceViewerIngress(ctx context.Context, key []byte) error {
+	log := ctrl.LoggerFrom(ctx)
+	instance := clctx.InstanceFrom(ctx)
+	environment := clctx.EnvironmentFrom(ctx)
+
+	host := forge.HostName(r.ServiceUrls.WebsiteBaseURL, environment.Mode)
+	ingress := netv1.Ingress{ObjectMeta: forge.ObjectMetaWithSuffix(instance, forge.ViewerIngressNameSuffix)}
+	res, err := ctrl.CreateOrUpdate(ctx, r.Client, &ingress, func() error {
+		// The specifications are always enforced, as the path changes in case the key is rotated.
+		ingress.Spec = forge.IngressSpec(host, forge.ViewerPath(key), forge.IngressDefaultCertificateName,
+			forge.ObjectMeta(instance).Name, forge.GUIPortName)
+		ingress.SetLabels(forge.InstanceObjectLabels(ingress.GetLabels(), instance))
+		ingress.SetAnnotations(forge.IngressGUIAnnotations(environment, ingress.GetAnnotations()))
+		return ctrl.SetControllerReference(instance, &ingress, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "failed to enforce object", "ingress", klog.KObj(&ingress))
+		return err
+	}
+	log.V(utils.FromResult(res)).Info("object enforced", "ingress", klog.KObj(&ingress), "result", res)
+	return nil
+}
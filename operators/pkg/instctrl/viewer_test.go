@@ -0,0 +1,191 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instctrl_test
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+	. "github.com/netgroup-polito/CrownLabs/operators/pkg/utils/tests"
+)
+
+var _ = Describe("View-only sharing of the container based instances", func() {
+	var (
+		ctx        context.Context
+		reconciler instctrl.InstanceReconciler
+		recorder   *record.FakeRecorder
+
+		instance    clv1alpha2.Instance
+		environment clv1alpha2.Environment
+		secret      corev1.Secret
+		secretName  types.NamespacedName
+
+		err error
+	)
+
+	const (
+		instanceName      = "desktop-0000"
+		instanceNamespace = "tenant-tester"
+		templateName      = "desktop"
+		templateNamespace = "workspace-netgroup"
+	)
+
+	BeforeEach(func() {
+		ctx = ctrl.LoggerInto(context.Background(), logr.Discard())
+		recorder = record.NewFakeRecorder(10)
+
+		instance = clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName, Namespace: instanceNamespace, UID: "e3d2c1b0"},
+			Spec: clv1alpha2.InstanceSpec{
+				Running:  true,
+				Template: clv1alpha2.GenericRef{Name: templateName, Namespace: templateNamespace},
+				Tenant:   clv1alpha2.GenericRef{Name: "tester"},
+			},
+		}
+		environment = clv1alpha2.Environment{
+			Name:            "desktop",
+			EnvironmentType: clv1alpha2.ClassContainer,
+			GuiEnabled:      true,
+			Image:           "internal/registry/image:v1.0",
+			Resources: clv1alpha2.EnvironmentResources{
+				CPU:    1,
+				Memory: resource.MustParse("1G"),
+			},
+		}
+
+		secret = corev1.Secret{}
+		secretName = types.NamespacedName{Namespace: instanceNamespace, Name: forge.ViewerSecretName(&instance)}
+	})
+
+	JustBeforeEach(func() {
+		reconciler = instctrl.InstanceReconciler{
+			Client:                 fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+			Scheme:                 scheme.Scheme,
+			EventsRecorder:         recorder,
+			ServiceUrls:            instctrl.ServiceUrls{WebsiteBaseURL: "crownlabs.example.com"},
+			ViewerShareMaxValidity: time.Hour,
+		}
+
+		ctx, _ = clctx.InstanceInto(ctx, &instance)
+		ctx, _ = clctx.EnvironmentInto(ctx, &environment)
+		err = reconciler.EnforceContainerEnvironment(ctx)
+	})
+
+	It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+	It("Should generate the key to sign the share links", func() {
+		Expect(reconciler.Get(ctx, secretName, &secret)).To(Succeed())
+		Expect(secret.Data[forge.ViewerKeyName]).To(HaveLen(forge.ViewerKeyLength))
+		Expect(secret.GetOwnerReferences()).To(HaveLen(1))
+	})
+
+	It("Should expose the view-only sessions on the path derived from the key", func() {
+		Expect(reconciler.Get(ctx, secretName, &secret)).To(Succeed())
+
+		var ingress netv1.Ingress
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: instanceNamespace, Name: instanceName + "-" + forge.ViewerIngressNameSuffix}, &ingress)).To(Succeed())
+		Expect(ingress.Spec.Rules).To(HaveLen(1))
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("crownlabs.example.com"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths).To(HaveLen(1))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal(forge.ViewerPath(secret.Data[forge.ViewerKeyName])))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).ToNot(ContainSubstring(string(instance.UID)))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal(instanceName))
+		Expect(ingress.GetOwnerReferences()).To(HaveLen(1))
+	})
+
+	It("Should not generate any share link", func() {
+		Expect(instance.Status.ViewerShare).To(BeNil())
+	})
+
+	It("Should preserve the key across reconciliations", func() {
+		Expect(reconciler.Get(ctx, secretName, &secret)).To(Succeed())
+		Expect(reconciler.EnforceContainerEnvironment(ctx)).To(Succeed())
+
+		var updated corev1.Secret
+		Expect(reconciler.Get(ctx, secretName, &updated)).To(Succeed())
+		Expect(updated.Data).To(Equal(secret.Data))
+	})
+
+	When("a share link is requested", func() {
+		var expiration time.Time
+
+		BeforeEach(func() {
+			expiration = time.Now().Add(30 * time.Minute).Truncate(time.Second)
+			instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: expiration.Format(time.RFC3339)})
+		})
+
+		It("Should generate a share link signed with the key", func() {
+			Expect(reconciler.Get(ctx, secretName, &secret)).To(Succeed())
+			Expect(instance.Status.ViewerShare).ToNot(BeNil())
+			Expect(instance.Status.ViewerShare.ExpirationTime.Time).To(BeTemporally("==", expiration))
+
+			link, err := url.Parse(instance.Status.ViewerShare.URL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(link.Host).To(Equal("crownlabs.example.com"))
+			Expect(link.Path).To(Equal(forge.ViewerPath(secret.Data[forge.ViewerKeyName]) + "/"))
+			Expect(link.Query().Get(forge.ViewerTokenQueryParam)).To(Equal(forge.ViewerToken(secret.Data[forge.ViewerKeyName], expiration)))
+			Expect(recorder.Events).To(Receive(ContainSubstring(instctrl.EvViewerShared)))
+		})
+	})
+
+	When("an invalid share link is requested", func() {
+		BeforeEach(func() {
+			instance.SetAnnotations(map[string]string{forge.ViewerShareAnnotation: "tomorrow"})
+		})
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should mark the request as handled without generating a link", func() {
+			Expect(instance.Status.ViewerShare).To(HaveValue(Equal(clv1alpha2.InstanceViewerShareStatus{ObservedRequest: "tomorrow"})))
+			Expect(recorder.Events).To(Receive(ContainSubstring(instctrl.EvViewerShareInvalid)))
+		})
+	})
+
+	When("the instance is not running", func() {
+		BeforeEach(func() { instance.Spec.Running = false })
+
+		It("Should not expose the view-only sessions", func() {
+			var ingress netv1.Ingress
+			Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: instanceNamespace, Name: instanceName + "-" + forge.ViewerIngressNameSuffix}, &ingress)).
+				To(FailBecauseNotFound())
+		})
+	})
+
+	When("the environment is not graphical", func() {
+		BeforeEach(func() { environment.GuiEnabled = false })
+
+		It("Should not generate the key to sign the share links", func() {
+			Expect(reconciler.Get(ctx, secretName, &secret)).To(FailBecauseNotFound())
+		})
+	})
+})
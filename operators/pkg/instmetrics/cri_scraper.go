@@ -33,22 +33,22 @@ func (s CRIMetricsScraper) getStats(ctx context.Context, containers []*criapi.Co
 	tracer := trace.New("cri-metrics-scraper")
 
 	var containerStatsList []ContainerStats
+	// The stats of the pod sandboxes, retrieved once per sandbox, as shared by the containers belonging to the same pod.
+	sandboxStats := make(map[string]*criapi.PodSandboxStats)
 
 	for _, container := range containers {
 		containerID := container.GetId()
-
-		log = log.WithValues("containerId", containerID)
+		containerLog := log.WithValues("containerId", containerID)
 
 		filter := &criapi.ContainerStatsFilter{
 			Id: containerID,
 		}
-		ctx = clctx.LoggerIntoContext(ctx, log.WithName("list-containers-stats"))
-		containerStatsResponse, err := s.RuntimeClient.ListContainerStats(ctx, filter)
+		containerStatsResponse, err := s.RuntimeClient.ListContainerStats(clctx.LoggerIntoContext(ctx, containerLog.WithName("list-containers-stats")), filter)
 		if err != nil {
 			return nil, err
 		}
 
-		log.V(3).Info("ListContainerStats obtained")
+		containerLog.V(3).Info("ListContainerStats obtained")
 
 		// If no stats are found for containerID, Pod may no longer be running
 		if len(containerStatsResponse.GetStats()) == 0 {
@@ -57,13 +57,38 @@ func (s CRIMetricsScraper) getStats(ctx context.Context, containers []*criapi.Co
 		}
 
 		containerStats := containerStatsResponse.GetStats()[0]
-		containerStatsList = append(containerStatsList, ContainerStats{
+		ioPressure := containerStats.GetIo().GetPsi().GetSome()
+		stats := ContainerStats{
 			CPUTimestamp:         containerStats.GetCpu().GetTimestamp(),
 			UsageCoreNanoSeconds: containerStats.GetCpu().GetUsageCoreNanoSeconds().GetValue(),
 			MemoryUsageInBytes:   containerStats.GetMemory().GetWorkingSetBytes().GetValue(),
 			DiskUsageInBytes:     containerStats.GetWritableLayer().GetUsedBytes().GetValue(),
+			IOStallNanoSeconds:   ioPressure.GetTotal(),
+			IOPressurePerc:       float32(ioPressure.GetAvg10()),
+			RestartCount:         container.GetMetadata().GetAttempt(),
 			container:            container,
-			runningContainer:     true})
+			runningContainer:     true,
+		}
+
+		// Network counters are only available at the pod sandbox level. Not all the
+		// runtimes implement this call, hence failures do not prevent the other metrics
+		// from being exposed.
+		sandboxID := container.GetPodSandboxId()
+		podStats, found := sandboxStats[sandboxID]
+		if !found {
+			podStats, err = s.RuntimeClient.PodSandboxStats(clctx.LoggerIntoContext(ctx, containerLog.WithName("pod-sandbox-stats")), sandboxID)
+			if err != nil {
+				containerLog.V(2).Info("Unable to retrieve PodSandboxStats, network metrics not available", "error", err)
+			}
+			// Failures are cached as well, to avoid retrying for each container of the same pod.
+			sandboxStats[sandboxID] = podStats
+		}
+
+		network := podStats.GetLinux().GetNetwork().GetDefaultInterface()
+		stats.NetworkRxBytes = network.GetRxBytes().GetValue()
+		stats.NetworkTxBytes = network.GetTxBytes().GetValue()
+
+		containerStatsList = append(containerStatsList, stats)
 	}
 
 	tracer.Log()
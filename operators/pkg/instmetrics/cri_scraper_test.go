@@ -0,0 +1,168 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	clctx "github.com/netgroup-polito/CrownLabs/operators/pkg/context"
+)
+
+// fakeRuntimeServiceClient is a fake implementation of the CRI runtime service, returning the configured stats.
+type fakeRuntimeServiceClient struct {
+	criapi.RuntimeServiceClient
+
+	pods           []*criapi.PodSandbox
+	containers     []*criapi.Container
+	containerStats map[string]*criapi.ContainerStats
+	sandboxStats   map[string]*criapi.PodSandboxStats
+	sandboxErr     error
+	sandboxCalls   map[string]int
+}
+
+func (f *fakeRuntimeServiceClient) ListPodSandbox(_ context.Context, _ *criapi.ListPodSandboxRequest,
+	_ ...grpc.CallOption) (*criapi.ListPodSandboxResponse, error) {
+	return &criapi.ListPodSandboxResponse{Items: f.pods}, nil
+}
+
+func (f *fakeRuntimeServiceClient) ListContainers(_ context.Context, _ *criapi.ListContainersRequest,
+	_ ...grpc.CallOption) (*criapi.ListContainersResponse, error) {
+	return &criapi.ListContainersResponse{Containers: f.containers}, nil
+}
+
+func (f *fakeRuntimeServiceClient) ListContainerStats(_ context.Context, in *criapi.ListContainerStatsRequest,
+	_ ...grpc.CallOption) (*criapi.ListContainerStatsResponse, error) {
+	response := &criapi.ListContainerStatsResponse{}
+	if stats, found := f.containerStats[in.GetFilter().GetId()]; found {
+		response.Stats = []*criapi.ContainerStats{stats}
+	}
+	return response, nil
+}
+
+func (f *fakeRuntimeServiceClient) PodSandboxStats(_ context.Context, in *criapi.PodSandboxStatsRequest,
+	_ ...grpc.CallOption) (*criapi.PodSandboxStatsResponse, error) {
+	f.sandboxCalls[in.GetPodSandboxId()]++
+	if f.sandboxErr != nil {
+		return nil, f.sandboxErr
+	}
+	return &criapi.PodSandboxStatsResponse{Stats: f.sandboxStats[in.GetPodSandboxId()]}, nil
+}
+
+var _ = Describe("The CRI metrics scraper", func() {
+	var (
+		runtime    *fakeRuntimeServiceClient
+		containers []*criapi.Container
+
+		stats []ContainerStats
+		err   error
+	)
+
+	container := func(id, sandbox string, attempt uint32) *criapi.Container {
+		return &criapi.Container{Id: id, PodSandboxId: sandbox, Metadata: &criapi.ContainerMetadata{Name: id, Attempt: attempt}}
+	}
+
+	containerStats := func(cpu, memory uint64) *criapi.ContainerStats {
+		return &criapi.ContainerStats{
+			Cpu:           &criapi.CpuUsage{Timestamp: 42, UsageCoreNanoSeconds: &criapi.UInt64Value{Value: cpu}},
+			Memory:        &criapi.MemoryUsage{WorkingSetBytes: &criapi.UInt64Value{Value: memory}},
+			WritableLayer: &criapi.FilesystemUsage{UsedBytes: &criapi.UInt64Value{Value: 1024}},
+		}
+	}
+
+	BeforeEach(func() {
+		runtime = &fakeRuntimeServiceClient{
+			containerStats: map[string]*criapi.ContainerStats{
+				"app":     containerStats(100, 200),
+				"sidecar": containerStats(300, 400),
+			},
+			sandboxStats: map[string]*criapi.PodSandboxStats{
+				"pod": {Linux: &criapi.LinuxPodSandboxStats{Network: &criapi.NetworkUsage{
+					DefaultInterface: &criapi.NetworkInterfaceUsage{RxBytes: &criapi.UInt64Value{Value: 10}, TxBytes: &criapi.UInt64Value{Value: 20}},
+				}}},
+			},
+			sandboxCalls: make(map[string]int),
+		}
+		containers = []*criapi.Container{container("app", "pod", 2), container("sidecar", "pod", 0)}
+	})
+
+	JustBeforeEach(func() {
+		ctx := clctx.LoggerIntoContext(context.Background(), GinkgoLogr)
+		scraper := CRIMetricsScraper{RuntimeClient: &RemoteRuntimeServiceClient{runtimeClient: runtime}}
+		stats, err = scraper.getStats(ctx, containers)
+	})
+
+	It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+	It("Should return the stats of each container", func() {
+		Expect(stats).To(HaveLen(2))
+		Expect(stats[0].UsageCoreNanoSeconds).To(BeNumerically("==", 100))
+		Expect(stats[0].MemoryUsageInBytes).To(BeNumerically("==", 200))
+		Expect(stats[0].DiskUsageInBytes).To(BeNumerically("==", 1024))
+		Expect(stats[0].RestartCount).To(BeNumerically("==", 2))
+		Expect(stats[1].UsageCoreNanoSeconds).To(BeNumerically("==", 300))
+		Expect(stats[1].RestartCount).To(BeZero())
+	})
+
+	It("Should report the network counters of the pod", func() {
+		for i := range stats {
+			Expect(stats[i].NetworkRxBytes).To(BeNumerically("==", 10))
+			Expect(stats[i].NetworkTxBytes).To(BeNumerically("==", 20))
+		}
+	})
+
+	It("Should retrieve the stats of each pod sandbox once", func() {
+		Expect(runtime.sandboxCalls).To(Equal(map[string]int{"pod": 1}))
+	})
+
+	When("the containers belong to different pods", func() {
+		BeforeEach(func() { containers[1] = container("sidecar", "other", 0) })
+
+		It("Should retrieve the stats of each pod sandbox", func() {
+			Expect(runtime.sandboxCalls).To(Equal(map[string]int{"pod": 1, "other": 1}))
+		})
+	})
+
+	When("the runtime does not expose the pod sandbox stats", func() {
+		BeforeEach(func() { runtime.sandboxErr = errors.New("not implemented") })
+
+		It("Should not return an error", func() { Expect(err).ToNot(HaveOccurred()) })
+
+		It("Should return the container stats, without network counters", func() {
+			Expect(stats).To(HaveLen(2))
+			Expect(stats[0].MemoryUsageInBytes).To(BeNumerically("==", 200))
+			Expect(stats[0].NetworkRxBytes).To(BeZero())
+		})
+
+		It("Should not retry for each container of the same pod", func() {
+			Expect(runtime.sandboxCalls).To(Equal(map[string]int{"pod": 1}))
+		})
+	})
+
+	When("a container is no longer running", func() {
+		BeforeEach(func() { containers = append(containers, container("terminated", "pod", 0)) })
+
+		It("Should report it as not running", func() {
+			Expect(stats).To(HaveLen(3))
+			Expect(stats[2].runningContainer).To(BeFalse())
+			Expect(stats[2].container.GetId()).To(Equal("terminated"))
+		})
+	})
+})
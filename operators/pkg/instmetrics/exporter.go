@@ -0,0 +1,130 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricLabelNamespace       = "namespace"
+	metricLabelPod             = "pod"
+	metricLabelInstance        = "instance"
+	metricLabelTenant          = "tenant"
+	metricLabelWorkspace       = "workspace"
+	metricLabelTemplate        = "template"
+	metricLabelEnvironmentType = "environment_type"
+)
+
+// podLabelsToMetricLabels maps the labels forged for the pods of the instances to the labels of the exported metrics.
+var podLabelsToMetricLabels = []struct{ podLabel, metricLabel string }{
+	{instanceLabel, metricLabelInstance},
+	{"crownlabs.polito.it/tenant", metricLabelTenant},
+	{"crownlabs.polito.it/template", metricLabelTemplate},
+}
+
+// metricsCollector exports the cached metrics of the instances running on the node, labelled by instance,
+// tenant, workspace, template and environment type. Series are computed at collection time from the cache,
+// hence they disappear as soon as the corresponding pod is removed from it. The workspace and the environment
+// type are not reflected in the labels of the pods, hence they are empty if the instance attributes are not available.
+type metricsCollector struct {
+	scraper *MetricsScraper
+
+	cpu           *prometheus.Desc
+	memory        *prometheus.Desc
+	disk          *prometheus.Desc
+	networkRx     *prometheus.Desc
+	networkTx     *prometheus.Desc
+	ioWait        *prometheus.Desc
+	ioPressure    *prometheus.Desc
+	restartsCount *prometheus.Desc
+}
+
+// newMetricsCollector returns a new collector of the metrics cached by the given scraper.
+func newMetricsCollector(scraper *MetricsScraper) *metricsCollector {
+	labels := []string{metricLabelNamespace, metricLabelPod}
+	for _, mapping := range podLabelsToMetricLabels {
+		labels = append(labels, mapping.metricLabel)
+	}
+	labels = append(labels, metricLabelWorkspace, metricLabelEnvironmentType)
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, labels, nil)
+	}
+
+	return &metricsCollector{
+		scraper: scraper,
+
+		cpu:           desc("instance_cpu_usage_percent", "The CPU usage of the instance, as percentage of a single core"),
+		memory:        desc("instance_memory_usage_bytes", "The memory working set of the instance"),
+		disk:          desc("instance_disk_usage_bytes", "The disk space used by the instance"),
+		networkRx:     desc("instance_network_receive_bytes_total", "The bytes received by the instance on its default interface"),
+		networkTx:     desc("instance_network_transmit_bytes_total", "The bytes transmitted by the instance on its default interface"),
+		ioWait:        desc("instance_io_wait_seconds_total", "The time some tasks of the instance have been stalled waiting for IO"),
+		ioPressure:    desc("instance_io_pressure_percent", "The percentage of time some tasks of the instance have been stalled waiting for IO over the last 10 seconds"),
+		restartsCount: desc("instance_restarts_total", "The number of restarts of the application container of the instance"),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.cpu
+	ch <- c.memory
+	ch <- c.disk
+	ch <- c.networkRx
+	ch <- c.networkTx
+	ch <- c.ioWait
+	ch <- c.ioPressure
+	ch <- c.restartsCount
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.scraper.cachedMetricsMutex.RLock()
+	defer c.scraper.cachedMetricsMutex.RUnlock()
+
+	for podName, metrics := range c.scraper.cachedMetrics {
+		if metrics.pod == nil {
+			continue
+		}
+
+		podLabels := metrics.pod.GetLabels()
+		labels := []string{metrics.pod.GetMetadata().GetNamespace(), podName}
+		for _, mapping := range podLabelsToMetricLabels {
+			labels = append(labels, podLabels[mapping.podLabel])
+		}
+		var info InstanceInfo
+		if metrics.info != nil {
+			info = *metrics.info
+		}
+		labels = append(labels, info.Workspace, string(info.EnvironmentType))
+
+		// The metrics which could not be retrieved are omitted, rather than exported as zero.
+		if metrics.unavailable&metricCPU == 0 {
+			ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.GaugeValue, float64(metrics.CPUPerc), labels...)
+		}
+		if metrics.unavailable&metricMemory == 0 {
+			ch <- prometheus.MustNewConstMetric(c.memory, prometheus.GaugeValue, float64(metrics.MemBytes), labels...)
+		}
+		if metrics.unavailable&metricDisk == 0 {
+			ch <- prometheus.MustNewConstMetric(c.disk, prometheus.GaugeValue, float64(metrics.DiskBytes), labels...)
+		}
+		ch <- prometheus.MustNewConstMetric(c.networkRx, prometheus.CounterValue, float64(metrics.NetRxBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(c.networkTx, prometheus.CounterValue, float64(metrics.NetTxBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(c.ioWait, prometheus.CounterValue, float64(metrics.IOWaitNs)/1e9, labels...)
+		ch <- prometheus.MustNewConstMetric(c.ioPressure, prometheus.GaugeValue, float64(metrics.IOPressurePerc), labels...)
+		ch <- prometheus.MustNewConstMetric(c.restartsCount, prometheus.CounterValue, float64(metrics.RestartCount), labels...)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+var _ = Describe("The metrics exporter", func() {
+	var (
+		scraper  *MetricsScraper
+		families map[string]*dto.MetricFamily
+	)
+
+	pod := func(namespace, instance string) *criapi.PodSandbox {
+		return &criapi.PodSandbox{
+			Metadata: &criapi.PodSandboxMetadata{Namespace: namespace},
+			Labels: map[string]string{
+				instanceLabel:                  instance,
+				"crownlabs.polito.it/tenant":   "tester",
+				"crownlabs.polito.it/template": "kubernetes",
+				"unrelated":                    "label",
+			},
+		}
+	}
+
+	labels := func(metric *dto.Metric) map[string]string {
+		labels := map[string]string{}
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		return labels
+	}
+
+	BeforeEach(func() {
+		scraper = &MetricsScraper{cachedMetrics: map[string]*CustomMetrics{
+			"virt-launcher-vm": {
+				CPUPerc: 25, MemBytes: 1 << 30, DiskBytes: 2048, NetRxBytes: 10, NetTxBytes: 20,
+				IOWaitNs: 15e8, IOPressurePerc: 5, RestartCount: 1, pod: pod("tenant-tester", "kubernetes-0000"),
+				info: &InstanceInfo{Workspace: "netgroup", EnvironmentType: clv1alpha2.ClassVM},
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		registry := prometheus.NewPedanticRegistry()
+		Expect(registry.Register(newMetricsCollector(scraper))).To(Succeed())
+
+		gathered, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+		families = map[string]*dto.MetricFamily{}
+		for _, family := range gathered {
+			families[family.GetName()] = family
+		}
+	})
+
+	It("Should export one series per metric of each instance", func() {
+		Expect(families).To(HaveLen(8))
+		for _, family := range families {
+			Expect(family.GetMetric()).To(HaveLen(1))
+		}
+	})
+
+	It("Should export the cached values", func() {
+		Expect(families["instance_cpu_usage_percent"].GetMetric()[0].GetGauge().GetValue()).To(BeNumerically("==", 25))
+		Expect(families["instance_memory_usage_bytes"].GetMetric()[0].GetGauge().GetValue()).To(BeNumerically("==", 1<<30))
+		Expect(families["instance_disk_usage_bytes"].GetMetric()[0].GetGauge().GetValue()).To(BeNumerically("==", 2048))
+		Expect(families["instance_network_receive_bytes_total"].GetMetric()[0].GetCounter().GetValue()).To(BeNumerically("==", 10))
+		Expect(families["instance_network_transmit_bytes_total"].GetMetric()[0].GetCounter().GetValue()).To(BeNumerically("==", 20))
+		Expect(families["instance_io_wait_seconds_total"].GetMetric()[0].GetCounter().GetValue()).To(BeNumerically("==", 1.5))
+		Expect(families["instance_io_pressure_percent"].GetMetric()[0].GetGauge().GetValue()).To(BeNumerically("==", 5))
+		Expect(families["instance_restarts_total"].GetMetric()[0].GetCounter().GetValue()).To(BeNumerically("==", 1))
+	})
+
+	It("Should label the series by instance, derived from the labels of the pod and the instance attributes", func() {
+		for _, family := range families {
+			Expect(labels(family.GetMetric()[0])).To(Equal(map[string]string{
+				"namespace":        "tenant-tester",
+				"pod":              "virt-launcher-vm",
+				"instance":         "kubernetes-0000",
+				"tenant":           "tester",
+				"workspace":        "netgroup",
+				"template":         "kubernetes",
+				"environment_type": "VirtualMachine",
+			}))
+		}
+	})
+
+	When("the instance attributes are not available", func() {
+		BeforeEach(func() { scraper.cachedMetrics["virt-launcher-vm"].info = nil })
+
+		It("Should leave the corresponding labels empty", func() {
+			for _, family := range families {
+				Expect(labels(family.GetMetric()[0])).To(HaveKeyWithValue("workspace", ""))
+				Expect(labels(family.GetMetric()[0])).To(HaveKeyWithValue("environment_type", ""))
+				Expect(labels(family.GetMetric()[0])).To(HaveKeyWithValue("instance", "kubernetes-0000"))
+			}
+		})
+	})
+
+	When("some metrics are not available", func() {
+		BeforeEach(func() { scraper.cachedMetrics["virt-launcher-vm"].unavailable = metricCPU | metricDisk })
+
+		It("Should omit the corresponding series", func() {
+			Expect(families).ToNot(HaveKey("instance_cpu_usage_percent"))
+			Expect(families).ToNot(HaveKey("instance_disk_usage_bytes"))
+			Expect(families).To(HaveKey("instance_memory_usage_bytes"))
+		})
+	})
+
+	When("multiple instances are cached", func() {
+		BeforeEach(func() {
+			scraper.cachedMetrics["container-pod"] = &CustomMetrics{MemBytes: 100, pod: pod("tenant-other", "kubernetes-0001")}
+			// Metrics not associated with any pod cannot be labelled, hence they are not exported.
+			scraper.cachedMetrics["unknown"] = &CustomMetrics{MemBytes: 100}
+		})
+
+		It("Should export one series per instance", func() {
+			Expect(families["instance_memory_usage_bytes"].GetMetric()).To(HaveLen(2))
+		})
+	})
+
+	When("no instances are cached", func() {
+		BeforeEach(func() { scraper.cachedMetrics = map[string]*CustomMetrics{} })
+
+		It("Should not export any series", func() { Expect(families).To(BeEmpty()) })
+	})
+})
@@ -0,0 +1,264 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	virtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// virtHandlerSelector is the label selector identifying the virt-handler pods.
+	virtHandlerSelector = "kubevirt.io=virt-handler"
+	// virtHandlerMetricsPort is the port the virt-handler pods expose the metrics of the VMs on.
+	virtHandlerMetricsPort = 8443
+
+	// vmiVCPUSecondsMetric is the virt-handler metric exposing the time spent by each vCPU of a VM.
+	vmiVCPUSecondsMetric = "kubevirt_vmi_vcpu_seconds_total"
+	// vmiMemoryUsedMetric is the virt-handler metric exposing the memory used by the guest of a VM.
+	vmiMemoryUsedMetric = "kubevirt_vmi_memory_used_bytes"
+)
+
+// GuestAgentClient retrieves the metrics of KubeVirt virtual machines which can only be observed from the guest.
+type GuestAgentClient interface {
+	// FilesystemUsage returns the bytes used on the filesystems of the given VMI.
+	FilesystemUsage(ctx context.Context, namespace, name string) (uint64, error)
+	// GuestUsage returns the CPU and memory usage of the given VMI, as observed from the guest.
+	GuestUsage(ctx context.Context, namespace, name string) (*GuestUsage, error)
+}
+
+// GuestUsage is the CPU and memory usage of a VM, as observed from the guest.
+type GuestUsage struct {
+	// Timestamp in nanoseconds at which the information were collected.
+	Timestamp int64
+	// Cumulative time spent by the vCPUs of the guest.
+	CPUUsageNanoSeconds uint64
+	// Memory used by the guest, excluding the one not in use and the caches.
+	MemoryUsedBytes uint64
+}
+
+// KubeVirtGuestAgentClient implements GuestAgentClient through the guest agent subresources exposed by the KubeVirt API,
+// and the metrics exposed by the virt-handler running on the node (accessed through the proxy of the Kubernetes API).
+type KubeVirtGuestAgentClient struct {
+	restClient rest.Interface
+	clientset  kubernetes.Interface
+	// RefreshPeriod is the minimum interval between two queries to the guest agent of the same VMI.
+	RefreshPeriod time.Duration
+	// UsageRefreshPeriod is the minimum interval between two queries to the virt-handler metrics.
+	UsageRefreshPeriod time.Duration
+	// NodeName is the name of the node whose virt-handler is queried.
+	NodeName string
+	// VirtHandlerNamespace is the namespace the virt-handler pods run in.
+	VirtHandlerNamespace string
+
+	cache      map[string]guestFilesystemUsage
+	cacheMutex sync.Mutex
+
+	usage      map[string]*GuestUsage
+	usageErr   error
+	usageTime  time.Time
+	usageMutex sync.Mutex
+}
+
+// guestFilesystemUsage is the cached result of a query to the guest agent, including failures (e.g., because the
+// guest agent is not installed), so that they are not retried before the refresh period elapses.
+type guestFilesystemUsage struct {
+	usedBytes uint64
+	err       error
+	timestamp time.Time
+}
+
+// NewKubeVirtGuestAgentClient creates a new KubeVirtGuestAgentClient from the given configuration.
+func NewKubeVirtGuestAgentClient(config *rest.Config, refreshPeriod, usageRefreshPeriod time.Duration,
+	nodeName, virtHandlerNamespace string) (*KubeVirtGuestAgentClient, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating the kubernetes client: %w", err)
+	}
+
+	return &KubeVirtGuestAgentClient{
+		restClient:           clientset.Discovery().RESTClient(),
+		clientset:            clientset,
+		RefreshPeriod:        refreshPeriod,
+		UsageRefreshPeriod:   usageRefreshPeriod,
+		NodeName:             nodeName,
+		VirtHandlerNamespace: virtHandlerNamespace,
+		cache:                make(map[string]guestFilesystemUsage),
+	}, nil
+}
+
+// FilesystemUsage returns the bytes used on the filesystems of the given VMI, as reported by the guest agent.
+// Filesystems mounted multiple times are accounted only once.
+func (c *KubeVirtGuestAgentClient) FilesystemUsage(ctx context.Context, namespace, name string) (uint64, error) {
+	key := InstanceKey(namespace, name)
+
+	c.cacheMutex.Lock()
+	cached, ok := c.cache[key]
+	c.evictStaleEntries()
+	c.cacheMutex.Unlock()
+	if ok && time.Since(cached.timestamp) < c.RefreshPeriod {
+		return cached.usedBytes, cached.err
+	}
+
+	usedBytes, err := c.retrieveFilesystemUsage(ctx, namespace, name)
+
+	c.cacheMutex.Lock()
+	c.cache[key] = guestFilesystemUsage{usedBytes: usedBytes, err: err, timestamp: time.Now()}
+	c.cacheMutex.Unlock()
+
+	return usedBytes, err
+}
+
+// retrieveFilesystemUsage queries the guest agent of the given VMI for the bytes used on its filesystems.
+func (c *KubeVirtGuestAgentClient) retrieveFilesystemUsage(ctx context.Context, namespace, name string) (uint64, error) {
+	key := InstanceKey(namespace, name)
+
+	raw, err := c.restClient.Get().
+		AbsPath("/apis/subresources.kubevirt.io/v1/namespaces", namespace, "virtualmachineinstances", name, "filesystemlist").
+		DoRaw(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed retrieving the filesystem list of VMI %v: %w", key, err)
+	}
+
+	var filesystems virtv1.VirtualMachineInstanceFileSystemList
+	if err := json.Unmarshal(raw, &filesystems); err != nil {
+		return 0, fmt.Errorf("failed decoding the filesystem list of VMI %v: %w", key, err)
+	}
+
+	return filesystemsUsedBytes(filesystems.Items), nil
+}
+
+// filesystemsUsedBytes returns the bytes used on the given filesystems, accounting only once those mounted multiple times.
+func filesystemsUsedBytes(filesystems []virtv1.VirtualMachineInstanceFileSystem) uint64 {
+	var usedBytes uint64
+	disks := map[string]struct{}{}
+	for i := range filesystems {
+		filesystem := &filesystems[i]
+		if _, found := disks[filesystem.DiskName]; found {
+			continue
+		}
+		disks[filesystem.DiskName] = struct{}{}
+		usedBytes += uint64(max(filesystem.UsedBytes, 0))
+	}
+	return usedBytes
+}
+
+// evictStaleEntries removes the entries no longer refreshed, e.g., because the VMI has been deleted.
+// It must be called while holding the cache mutex.
+func (c *KubeVirtGuestAgentClient) evictStaleEntries() {
+	for key, cached := range c.cache {
+		if time.Since(cached.timestamp) > 2*c.RefreshPeriod {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// GuestUsage returns the CPU and memory usage of the given VMI, as exposed by the virt-handler running on the node.
+// The metrics of all the VMIs of the node are retrieved at once, and cached for the usage refresh period.
+func (c *KubeVirtGuestAgentClient) GuestUsage(ctx context.Context, namespace, name string) (*GuestUsage, error) {
+	c.usageMutex.Lock()
+	defer c.usageMutex.Unlock()
+
+	if c.usageTime.IsZero() || time.Since(c.usageTime) >= c.UsageRefreshPeriod {
+		c.usage, c.usageErr = c.retrieveGuestUsage(ctx)
+		c.usageTime = time.Now()
+	}
+
+	if c.usageErr != nil {
+		return nil, c.usageErr
+	}
+
+	usage, found := c.usage[InstanceKey(namespace, name)]
+	if !found {
+		return nil, fmt.Errorf("no guest metrics exposed for VMI %v", InstanceKey(namespace, name))
+	}
+	return usage, nil
+}
+
+// retrieveGuestUsage retrieves the metrics exposed by the virt-handler running on the node.
+func (c *KubeVirtGuestAgentClient) retrieveGuestUsage(ctx context.Context) (map[string]*GuestUsage, error) {
+	pods, err := c.clientset.CoreV1().Pods(c.VirtHandlerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: virtHandlerSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", c.NodeName).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving the virt-handler of node %v: %w", c.NodeName, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no virt-handler found on node %v", c.NodeName)
+	}
+
+	raw, err := c.clientset.CoreV1().Pods(c.VirtHandlerNamespace).
+		ProxyGet("https", pods.Items[0].Name, fmt.Sprint(virtHandlerMetricsPort), "metrics", nil).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving the metrics of virt-handler %v: %w", pods.Items[0].Name, err)
+	}
+
+	return parseGuestUsage(raw, time.Now())
+}
+
+// parseGuestUsage extracts the CPU and memory usage of the VMIs from the given metrics, in the Prometheus text format.
+func parseGuestUsage(raw []byte, timestamp time.Time) (map[string]*GuestUsage, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing the virt-handler metrics: %w", err)
+	}
+
+	// The VMIs the metrics refer to are identified by the namespace and name labels.
+	vmiKey := func(metric *dto.Metric) string {
+		var namespace, name string
+		for _, label := range metric.GetLabel() {
+			switch label.GetName() {
+			case "namespace":
+				namespace = label.GetValue()
+			case "name":
+				name = label.GetValue()
+			}
+		}
+		return InstanceKey(namespace, name)
+	}
+
+	usage := map[string]*GuestUsage{}
+	for _, metric := range families[vmiMemoryUsedMetric].GetMetric() {
+		usage[vmiKey(metric)] = &GuestUsage{
+			Timestamp:       timestamp.UnixNano(),
+			MemoryUsedBytes: uint64(max(metric.GetGauge().GetValue(), 0)),
+		}
+	}
+
+	// The time spent by each vCPU is exposed as a separate series, which are summed up. The VMIs whose memory usage
+	// is not exposed (e.g., because the memory balloon is disabled) are not reported, hence their metrics are omitted.
+	for _, metric := range families[vmiVCPUSecondsMetric].GetMetric() {
+		if guest, found := usage[vmiKey(metric)]; found {
+			guest.CPUUsageNanoSeconds += uint64(max(metric.GetCounter().GetValue(), 0) * float64(time.Second))
+		}
+	}
+
+	return usage, nil
+}
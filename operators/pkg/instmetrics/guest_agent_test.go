@@ -0,0 +1,146 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	virtv1 "kubevirt.io/api/core/v1"
+)
+
+var _ = Describe("The KubeVirt guest agent client", func() {
+	const (
+		namespace   = "tenant-tester"
+		virtHandler = "virt-handler-abcde"
+		metrics     = `# TYPE kubevirt_vmi_vcpu_seconds_total counter
+kubevirt_vmi_vcpu_seconds_total{id="0",name="vm",namespace="tenant-tester",state="running"} 1.5
+kubevirt_vmi_vcpu_seconds_total{id="1",name="vm",namespace="tenant-tester",state="running"} 2.5
+kubevirt_vmi_vcpu_seconds_total{id="0",name="no-balloon",namespace="tenant-tester",state="running"} 3
+# TYPE kubevirt_vmi_memory_used_bytes gauge
+kubevirt_vmi_memory_used_bytes{name="vm",namespace="tenant-tester"} 1.048576e+06
+`
+	)
+
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		requests map[string]int
+		client   *KubeVirtGuestAgentClient
+	)
+
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		Expect(json.NewEncoder(w).Encode(obj)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		requests = map[string]int{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests[r.URL.Path]++
+			switch r.URL.Path {
+			case "/apis/subresources.kubevirt.io/v1/namespaces/tenant-tester/virtualmachineinstances/vm/filesystemlist":
+				writeJSON(w, virtv1.VirtualMachineInstanceFileSystemList{Items: []virtv1.VirtualMachineInstanceFileSystem{
+					{DiskName: "vda1", MountPoint: "/", UsedBytes: 1000},
+					{DiskName: "vda1", MountPoint: "/snap", UsedBytes: 1000},
+					{DiskName: "vdb", MountPoint: "/data", UsedBytes: 500},
+				}})
+			case "/api/v1/namespaces/kubevirt/pods":
+				pods := corev1.PodList{}
+				if r.URL.Query().Get("fieldSelector") == "spec.nodeName=node-1" {
+					pods.Items = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: virtHandler, Namespace: "kubevirt"}}}
+				}
+				writeJSON(w, pods)
+			case "/api/v1/namespaces/kubevirt/pods/https:" + virtHandler + ":8443/proxy/metrics":
+				_, _ = w.Write([]byte(metrics))
+			default:
+				// e.g., the guest agent is not installed in the VM.
+				http.Error(w, "guest agent not connected", http.StatusServiceUnavailable)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		var err error
+		client, err = NewKubeVirtGuestAgentClient(&rest.Config{Host: server.URL}, time.Minute, time.Minute, "node-1", "kubevirt")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("The retrieval of the filesystem usage", func() {
+		It("Should account the filesystems mounted multiple times only once", func() {
+			Expect(client.FilesystemUsage(ctx, namespace, "vm")).To(BeNumerically("==", 1500))
+		})
+
+		It("Should cache the result", func() {
+			for range 3 {
+				Expect(client.FilesystemUsage(ctx, namespace, "vm")).To(BeNumerically("==", 1500))
+			}
+			Expect(requests).To(HaveKeyWithValue(HaveSuffix("/vm/filesystemlist"), 1))
+		})
+
+		It("Should cache the failures as well", func() {
+			for range 3 {
+				_, err := client.FilesystemUsage(ctx, namespace, "no-agent")
+				Expect(err).To(HaveOccurred())
+			}
+			Expect(requests).To(HaveKeyWithValue(HaveSuffix("/no-agent/filesystemlist"), 1))
+		})
+	})
+
+	Describe("The retrieval of the CPU and memory usage", func() {
+		It("Should sum up the time spent by the vCPUs", func() {
+			usage, err := client.GuestUsage(ctx, namespace, "vm")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usage.CPUUsageNanoSeconds).To(BeNumerically("==", 4*time.Second))
+			Expect(usage.MemoryUsedBytes).To(BeNumerically("==", 1<<20))
+			Expect(usage.Timestamp).ToNot(BeZero())
+		})
+
+		It("Should return an error if the memory usage of the VM is not exposed", func() {
+			_, err := client.GuestUsage(ctx, namespace, "no-balloon")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should return an error if the VM is not known by the virt-handler", func() {
+			_, err := client.GuestUsage(ctx, namespace, "other")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should retrieve the metrics of all the VMs at once", func() {
+			for _, name := range []string{"vm", "no-balloon", "other", "vm"} {
+				_, _ = client.GuestUsage(ctx, namespace, name)
+			}
+			Expect(requests).To(HaveKeyWithValue(HaveSuffix("/proxy/metrics"), 1))
+		})
+
+		When("the virt-handler cannot be found", func() {
+			BeforeEach(func() { client.NodeName = "node-2" })
+
+			It("Should return an error", func() {
+				_, err := client.GuestUsage(ctx, namespace, "vm")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
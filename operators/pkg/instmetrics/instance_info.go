@@ -0,0 +1,133 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instautoctrl"
+)
+
+// InstanceInfo are the attributes of an instance which are not reflected in the labels of the corresponding pods.
+type InstanceInfo struct {
+	// Workspace is the name of the workspace the template of the instance belongs to.
+	Workspace string
+	// EnvironmentType is the type of the environment of the instance.
+	EnvironmentType clv1alpha2.EnvironmentType
+}
+
+// InstanceInfoClient retrieves the attributes of the instances which are not reflected in the labels of the corresponding pods.
+type InstanceInfoClient interface {
+	// InstanceInfo returns the attributes of the given instance.
+	InstanceInfo(ctx context.Context, namespace, name string) (*InstanceInfo, error)
+}
+
+// KubernetesInstanceInfoClient implements InstanceInfoClient retrieving the Instance and its Template from the Kubernetes API.
+// The attributes do not change during the lifetime of an instance, hence they are cached, and refreshed only periodically.
+type KubernetesInstanceInfoClient struct {
+	client client.Client
+	// RefreshPeriod is the minimum interval between two queries for the same instance.
+	RefreshPeriod time.Duration
+
+	cache      map[string]cachedInstanceInfo
+	cacheMutex sync.Mutex
+}
+
+// cachedInstanceInfo is the cached result of a query for the attributes of an instance, including failures
+// (e.g., because the instance has already been deleted), so that they are not retried before the refresh period elapses.
+type cachedInstanceInfo struct {
+	info      *InstanceInfo
+	err       error
+	timestamp time.Time
+}
+
+// NewKubernetesInstanceInfoClient creates a new KubernetesInstanceInfoClient from the given configuration.
+func NewKubernetesInstanceInfoClient(config *rest.Config, refreshPeriod time.Duration) (*KubernetesInstanceInfoClient, error) {
+	scheme := runtime.NewScheme()
+	if err := clv1alpha2.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed configuring the scheme: %w", err)
+	}
+
+	cl, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed creating the kubernetes client: %w", err)
+	}
+
+	return &KubernetesInstanceInfoClient{
+		client:        cl,
+		RefreshPeriod: refreshPeriod,
+		cache:         make(map[string]cachedInstanceInfo),
+	}, nil
+}
+
+// InstanceInfo returns the attributes of the given instance, as derived from the Instance and (the revision
+// it is pinned to of) its Template. The environment type is the one of the first environment of the template.
+func (c *KubernetesInstanceInfoClient) InstanceInfo(ctx context.Context, namespace, name string) (*InstanceInfo, error) {
+	key := InstanceKey(namespace, name)
+
+	c.cacheMutex.Lock()
+	cached, ok := c.cache[key]
+	c.evictStaleEntries()
+	c.cacheMutex.Unlock()
+	if ok && time.Since(cached.timestamp) < c.RefreshPeriod {
+		return cached.info, cached.err
+	}
+
+	info, err := c.retrieveInstanceInfo(ctx, namespace, name)
+
+	c.cacheMutex.Lock()
+	c.cache[key] = cachedInstanceInfo{info: info, err: err, timestamp: time.Now()}
+	c.cacheMutex.Unlock()
+
+	return info, err
+}
+
+// retrieveInstanceInfo retrieves the attributes of the given instance from the Kubernetes API.
+func (c *KubernetesInstanceInfoClient) retrieveInstanceInfo(ctx context.Context, namespace, name string) (*InstanceInfo, error) {
+	var instance clv1alpha2.Instance
+	if err := c.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &instance); err != nil {
+		return nil, fmt.Errorf("failed retrieving instance %s: %w", InstanceKey(namespace, name), err)
+	}
+
+	template, err := instautoctrl.RetrieveTemplate(ctx, c.client, &instance)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &InstanceInfo{Workspace: template.Spec.WorkspaceRef.Name}
+	if len(template.Spec.EnvironmentList) > 0 {
+		info.EnvironmentType = template.Spec.EnvironmentList[0].EnvironmentType
+	}
+	return info, nil
+}
+
+// evictStaleEntries removes the entries no longer refreshed, e.g., because the instance has been deleted.
+// It must be called while holding the cache mutex.
+func (c *KubernetesInstanceInfoClient) evictStaleEntries() {
+	for key, cached := range c.cache {
+		if time.Since(cached.timestamp) > 2*c.RefreshPeriod {
+			delete(c.cache, key)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+var _ = Describe("The Kubernetes instance info client", func() {
+	const (
+		namespace = "tenant-tester"
+		name      = "kubernetes-0000"
+	)
+
+	var (
+		ctx      context.Context
+		objects  []client.Object
+		instance *clv1alpha2.Instance
+		template *clv1alpha2.Template
+		c        *KubernetesInstanceInfoClient
+
+		info *InstanceInfo
+		err  error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		instance = &clv1alpha2.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: clv1alpha2.InstanceSpec{
+				Template: clv1alpha2.GenericRef{Name: "kubernetes", Namespace: "workspace-netgroup"},
+				Tenant:   clv1alpha2.GenericRef{Name: "tester"},
+			},
+		}
+		template = &clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "workspace-netgroup"},
+			Spec: clv1alpha2.TemplateSpec{
+				WorkspaceRef:    clv1alpha2.GenericRef{Name: "netgroup"},
+				EnvironmentList: []clv1alpha2.Environment{{Name: "vm", EnvironmentType: clv1alpha2.ClassVM}},
+			},
+		}
+		objects = []client.Object{instance, template}
+	})
+
+	JustBeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clv1alpha2.AddToScheme(scheme)).To(Succeed())
+
+		c = &KubernetesInstanceInfoClient{
+			client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+			RefreshPeriod: time.Minute,
+			cache:         map[string]cachedInstanceInfo{},
+		}
+		info, err = c.InstanceInfo(ctx, namespace, name)
+	})
+
+	It("Should derive the attributes from the instance and its template", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info).To(Equal(&InstanceInfo{Workspace: "netgroup", EnvironmentType: clv1alpha2.ClassVM}))
+	})
+
+	It("Should cache the attributes until the refresh period elapses", func() {
+		Expect(c.client.Delete(ctx, instance)).To(Succeed())
+		Expect(c.InstanceInfo(ctx, namespace, name)).To(Equal(info))
+	})
+
+	When("the instance does not exist", func() {
+		BeforeEach(func() { objects = []client.Object{template} })
+
+		It("Should return an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(info).To(BeNil())
+		})
+	})
+
+	When("the template does not exist", func() {
+		BeforeEach(func() { objects = []client.Object{instance} })
+
+		It("Should return an error", func() { Expect(err).To(HaveOccurred()) })
+	})
+})
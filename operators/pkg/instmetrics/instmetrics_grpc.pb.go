@@ -24,8 +24,12 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type InstanceMetricsClient interface {
 	// ContainerMetrics returns metrics of the "application container" related to the required PodName.
+	// For VM based instances, the metrics refer to the guest, as observed by the hypervisor and the guest agent.
 	// If the container does not exist, the call returns an error.
 	ContainerMetrics(ctx context.Context, in *ContainerMetricsRequest, opts ...grpc.CallOption) (*ContainerMetricsResponse, error)
+	// WatchContainerMetrics streams the metrics of the "application container" related to the required PodName,
+	// sending an update each time they are refreshed by the metrics scraper.
+	WatchContainerMetrics(ctx context.Context, in *ContainerMetricsRequest, opts ...grpc.CallOption) (InstanceMetrics_WatchContainerMetricsClient, error)
 }
 
 type instanceMetricsClient struct {
@@ -45,13 +49,49 @@ func (c *instanceMetricsClient) ContainerMetrics(ctx context.Context, in *Contai
 	return out, nil
 }
 
+func (c *instanceMetricsClient) WatchContainerMetrics(ctx context.Context, in *ContainerMetricsRequest, opts ...grpc.CallOption) (InstanceMetrics_WatchContainerMetricsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &InstanceMetrics_ServiceDesc.Streams[0], "/instmetrics.InstanceMetrics/WatchContainerMetrics", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &instanceMetricsWatchContainerMetricsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type InstanceMetrics_WatchContainerMetricsClient interface {
+	Recv() (*ContainerMetricsResponse, error)
+	grpc.ClientStream
+}
+
+type instanceMetricsWatchContainerMetricsClient struct {
+	grpc.ClientStream
+}
+
+func (x *instanceMetricsWatchContainerMetricsClient) Recv() (*ContainerMetricsResponse, error) {
+	m := new(ContainerMetricsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // InstanceMetricsServer is the server API for InstanceMetrics service.
 // All implementations must embed UnimplementedInstanceMetricsServer
 // for forward compatibility
 type InstanceMetricsServer interface {
 	// ContainerMetrics returns metrics of the "application container" related to the required PodName.
+	// For VM based instances, the metrics refer to the guest, as observed by the hypervisor and the guest agent.
 	// If the container does not exist, the call returns an error.
 	ContainerMetrics(context.Context, *ContainerMetricsRequest) (*ContainerMetricsResponse, error)
+	// WatchContainerMetrics streams the metrics of the "application container" related to the required PodName,
+	// sending an update each time they are refreshed by the metrics scraper.
+	WatchContainerMetrics(*ContainerMetricsRequest, InstanceMetrics_WatchContainerMetricsServer) error
 	mustEmbedUnimplementedInstanceMetricsServer()
 }
 
@@ -62,6 +102,9 @@ type UnimplementedInstanceMetricsServer struct {
 func (UnimplementedInstanceMetricsServer) ContainerMetrics(context.Context, *ContainerMetricsRequest) (*ContainerMetricsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ContainerMetrics not implemented")
 }
+func (UnimplementedInstanceMetricsServer) WatchContainerMetrics(*ContainerMetricsRequest, InstanceMetrics_WatchContainerMetricsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchContainerMetrics not implemented")
+}
 func (UnimplementedInstanceMetricsServer) mustEmbedUnimplementedInstanceMetricsServer() {}
 
 // UnsafeInstanceMetricsServer may be embedded to opt out of forward compatibility for this service.
@@ -93,6 +136,27 @@ func _InstanceMetrics_ContainerMetrics_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InstanceMetrics_WatchContainerMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ContainerMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InstanceMetricsServer).WatchContainerMetrics(m, &instanceMetricsWatchContainerMetricsServer{stream})
+}
+
+type InstanceMetrics_WatchContainerMetricsServer interface {
+	Send(*ContainerMetricsResponse) error
+	grpc.ServerStream
+}
+
+type instanceMetricsWatchContainerMetricsServer struct {
+	grpc.ServerStream
+}
+
+func (x *instanceMetricsWatchContainerMetricsServer) Send(m *ContainerMetricsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // InstanceMetrics_ServiceDesc is the grpc.ServiceDesc for InstanceMetrics service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +169,12 @@ var InstanceMetrics_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _InstanceMetrics_ContainerMetrics_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchContainerMetrics",
+			Handler:       _InstanceMetrics_WatchContainerMetrics_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "instmetrics.proto",
 }
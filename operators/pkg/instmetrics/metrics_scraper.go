@@ -29,6 +29,26 @@ import (
 	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
 )
 
+const (
+	// instanceLabel is the label identifying the pods belonging to a CrownLabs instance.
+	instanceLabel = "crownlabs.polito.it/instance"
+	// virtLauncherLabel is the label identifying the virt-launcher pods hosting KubeVirt VMs.
+	virtLauncherLabel = "kubevirt.io"
+	// vmiNameLabel is the label storing the name of the VMI hosted by a virt-launcher pod.
+	vmiNameLabel = "vm.kubevirt.io/name"
+	// computeContainerName is the name of the virt-launcher container running the hypervisor.
+	computeContainerName = "compute"
+)
+
+// metricSet is a set of metrics, identified by the corresponding flags.
+type metricSet uint8
+
+const (
+	metricCPU metricSet = 1 << iota
+	metricMemory
+	metricDisk
+)
+
 // StatsScraper interface.
 type StatsScraper interface {
 	getStats(ctx context.Context, containers []*criapi.Container) ([]ContainerStats, error)
@@ -46,19 +66,56 @@ type ContainerStats struct {
 	// This may differ from the total bytes used on the filesystem and may not
 	// equal CapacityBytes - AvailableBytes.
 	DiskUsageInBytes uint64
+	// Cumulative bytes received and transmitted on the default interface of the pod.
+	NetworkRxBytes uint64
+	NetworkTxBytes uint64
+	// Cumulative time some tasks have been stalled waiting for IO.
+	IOStallNanoSeconds uint64
+	// Percentage of time some tasks have been stalled waiting for IO over the last 10 seconds.
+	IOPressurePerc float32
+	// Number of times the container has been restarted.
+	RestartCount     uint32
 	container        *criapi.Container
 	runningContainer bool
+	// The metrics which could not be retrieved, e.g., because the guest of a VM cannot be queried.
+	unavailable metricSet
 }
 
 // CustomMetrics stores desired metrics of a container.
 type CustomMetrics struct {
-	CPUPerc   float32 `json:"cpu"`
-	MemBytes  uint64  `json:"mem"`
-	DiskBytes uint64  `json:"disk"`
+	CPUPerc        float32 `json:"cpu"`
+	MemBytes       uint64  `json:"mem"`
+	DiskBytes      uint64  `json:"disk"`
+	NetRxBytes     uint64  `json:"netRx"`
+	NetTxBytes     uint64  `json:"netTx"`
+	IOWaitNs       uint64  `json:"ioWait"`
+	IOPressurePerc float32 `json:"ioPressure"`
+	RestartCount   uint32  `json:"restarts"`
+	// The pod the metrics refer to, whose labels identify the instance.
+	pod *criapi.PodSandbox
+	// The attributes of the instance not reflected in the labels of the pod, if available.
+	info *InstanceInfo
+	// The metrics which could not be retrieved, hence are not exported (and reported as zero through gRPC).
+	unavailable metricSet
 }
 
 func (c CustomMetrics) String() string {
-	return fmt.Sprintf("[CPU] %v \t[MEM] %v \t[DISK] %v", c.CPUPerc, c.MemBytes, c.DiskBytes)
+	return fmt.Sprintf("[CPU] %v \t[MEM] %v \t[DISK] %v \t[NET] %v/%v \t[IO] %v \t[RESTARTS] %v",
+		c.CPUPerc, c.MemBytes, c.DiskBytes, c.NetRxBytes, c.NetTxBytes, c.IOPressurePerc, c.RestartCount)
+}
+
+// response converts the metrics into the corresponding gRPC message.
+func (c *CustomMetrics) response() *ContainerMetricsResponse {
+	return &ContainerMetricsResponse{
+		CpuPerc:        c.CPUPerc,
+		MemBytes:       c.MemBytes,
+		DiskBytes:      c.DiskBytes,
+		NetRxBytes:     c.NetRxBytes,
+		NetTxBytes:     c.NetTxBytes,
+		IoWaitNs:       c.IOWaitNs,
+		IoPressurePerc: c.IOPressurePerc,
+		RestartCount:   c.RestartCount,
+	}
 }
 
 // MetricsScraper interface.
@@ -73,9 +130,22 @@ type MetricsScraper struct {
 	oldStats map[string]*ContainerStats
 	// Stats used to evaluate resource usages changes
 	// <key=podName, val=CustomMetrics>
-	cachedMetrics      map[string]*CustomMetrics
+	cachedMetrics map[string]*CustomMetrics
+	// Pods hosting the instances, used to look metrics up by instance
+	// <key=namespace/instanceName, val=podName>
+	instancePods       map[string]string
 	cachedMetricsMutex sync.RWMutex
 	scraper            StatsScraper
+	// GuestAgent retrieves the guest metrics of VMs, if configured.
+	GuestAgent GuestAgentClient
+	// InstanceInfo retrieves the attributes of the instances not reflected in the labels of the pods, if configured.
+	InstanceInfo InstanceInfoClient
+	// Pods whose application containers are watched
+	// <key=podSandboxId, val=PodSandbox>
+	watchedPods map[string]*criapi.PodSandbox
+	// Channels notified each time the cached metrics are refreshed.
+	subscribers      map[chan struct{}]struct{}
+	subscribersMutex sync.Mutex
 }
 
 // Start scraping metrics.
@@ -86,6 +156,7 @@ func (ms *MetricsScraper) Start(ctx context.Context) {
 		forge.ContentDownloaderName,
 	}
 	ms.oldStats = map[string]*ContainerStats{}
+	ms.watchedPods = map[string]*criapi.PodSandbox{}
 
 	// Periodically scrape updated metrics for watched containers.
 	ticker := time.NewTicker(ms.UpdatePeriod)
@@ -96,6 +167,7 @@ func (ms *MetricsScraper) Start(ctx context.Context) {
 		if err := ms.fillCachedMetrics(ctx); err != nil {
 			ms.Log.Error(err, "Error retrieving containerStats", "timeout", ms.UpdatePeriod)
 		}
+		ms.notifySubscribers()
 
 		// Operation running the context completed
 		cancel()
@@ -121,8 +193,12 @@ func (ms *MetricsScraper) fillCachedMetrics(ctx context.Context) error {
 		return err
 	}
 
-	for i, containerStats := range containerStatsList {
+	scrapedPods := map[string]struct{}{}
+	for i := range containerStatsList {
+		containerStats := &containerStatsList[i]
 		podName := containerStats.container.GetLabels()["io.kubernetes.pod.name"]
+		pod := ms.watchedPods[containerStats.container.GetPodSandboxId()]
+		scrapedPods[podName] = struct{}{}
 
 		// If no stats are found for containerID, Pod may no longer be running,
 		// related cache information must be removed.
@@ -132,28 +208,100 @@ func (ms *MetricsScraper) fillCachedMetrics(ctx context.Context) error {
 			continue
 		}
 
-		metrics := &CustomMetrics{}
+		// The resource usage of the hypervisor container is not representative of the one of the VM,
+		// which is retrieved from the guest instead.
+		if isVirtLauncherPod(pod) {
+			ms.guestStats(ctx, pod, containerStats)
+		}
+
+		metrics := &CustomMetrics{pod: pod, info: ms.instanceInfo(ctx, pod), unavailable: containerStats.unavailable}
 		metrics.MemBytes = containerStats.MemoryUsageInBytes
 		metrics.DiskBytes = containerStats.DiskUsageInBytes
-
-		if oldCPU, ok := ms.oldStats[podName]; ok {
-			duration := containerStats.CPUTimestamp - oldCPU.CPUTimestamp
-			newNs := containerStats.UsageCoreNanoSeconds
-			oldNs := oldCPU.UsageCoreNanoSeconds
-			cpuPerc := float32(newNs-oldNs) * 100 / float32(duration)
-			metrics.CPUPerc = cpuPerc
+		metrics.NetRxBytes = containerStats.NetworkRxBytes
+		metrics.NetTxBytes = containerStats.NetworkTxBytes
+		metrics.IOWaitNs = containerStats.IOStallNanoSeconds
+		metrics.IOPressurePerc = containerStats.IOPressurePerc
+		metrics.RestartCount = containerStats.RestartCount
+
+		// The CPU usage is computed only if both samples are available, and refer to different instants.
+		metrics.unavailable |= metricCPU
+		if oldCPU, ok := ms.oldStats[podName]; ok && (oldCPU.unavailable|containerStats.unavailable)&metricCPU == 0 {
+			if duration := containerStats.CPUTimestamp - oldCPU.CPUTimestamp; duration > 0 {
+				newNs := containerStats.UsageCoreNanoSeconds
+				oldNs := oldCPU.UsageCoreNanoSeconds
+				metrics.CPUPerc = float32(newNs-oldNs) * 100 / float32(duration)
+				metrics.unavailable &^= metricCPU
+			}
 		}
 
-		ms.oldStats[podName] = &containerStatsList[i]
+		ms.oldStats[podName] = containerStats
 
 		ms.cachedMetricsMutex.Lock()
 		ms.cachedMetrics[podName] = metrics
+		if pod != nil {
+			ms.instancePods[InstanceKey(pod.GetMetadata().GetNamespace(), pod.GetLabels()[instanceLabel])] = podName
+		}
 		ms.cachedMetricsMutex.Unlock()
 	}
 
+	// Pods no longer running any application container (e.g., because they have been deleted)
+	// are removed as well, to prevent the cache (and the exported series) from growing indefinitely.
+	for podName := range ms.oldStats {
+		if _, ok := scrapedPods[podName]; !ok {
+			log.V(2).Info("Removing cached metrics of no longer existing pod", "pod", podName)
+			ms.removeCachedMetric(podName)
+		}
+	}
+
 	return nil
 }
 
+// guestStats replaces the stats of the hypervisor container of the given virt-launcher pod with the ones observed from the
+// guest, marking as unavailable those which cannot be retrieved. If no guest agent client is configured, the CPU and memory
+// usage of the hypervisor container are preserved as an approximation of the guest ones, while the disk usage is omitted.
+func (ms *MetricsScraper) guestStats(ctx context.Context, pod *criapi.PodSandbox, stats *ContainerStats) {
+	log := clctx.LoggerFromContext(ctx).WithValues("pod", pod.GetMetadata().GetName())
+
+	stats.DiskUsageInBytes = 0
+	stats.unavailable |= metricDisk
+	if ms.GuestAgent == nil {
+		return
+	}
+
+	namespace, name := pod.GetMetadata().GetNamespace(), pod.GetLabels()[vmiNameLabel]
+	if diskBytes, err := ms.GuestAgent.FilesystemUsage(ctx, namespace, name); err != nil {
+		log.V(2).Info("Unable to retrieve guest filesystem usage", "error", err)
+	} else {
+		stats.DiskUsageInBytes = diskBytes
+		stats.unavailable &^= metricDisk
+	}
+
+	usage, err := ms.GuestAgent.GuestUsage(ctx, namespace, name)
+	if err != nil {
+		log.V(2).Info("Unable to retrieve guest CPU and memory usage", "error", err)
+		stats.CPUTimestamp, stats.UsageCoreNanoSeconds, stats.MemoryUsageInBytes = 0, 0, 0
+		stats.unavailable |= metricCPU | metricMemory
+		return
+	}
+	stats.CPUTimestamp = usage.Timestamp
+	stats.UsageCoreNanoSeconds = usage.CPUUsageNanoSeconds
+	stats.MemoryUsageInBytes = usage.MemoryUsedBytes
+}
+
+// instanceInfo returns the attributes of the instance the given pod belongs to, or nil if they cannot be retrieved.
+func (ms *MetricsScraper) instanceInfo(ctx context.Context, pod *criapi.PodSandbox) *InstanceInfo {
+	if ms.InstanceInfo == nil || pod == nil {
+		return nil
+	}
+
+	info, err := ms.InstanceInfo.InstanceInfo(ctx, pod.GetMetadata().GetNamespace(), pod.GetLabels()[instanceLabel])
+	if err != nil {
+		clctx.LoggerFromContext(ctx).V(2).Info("Unable to retrieve the instance attributes", "pod", pod.GetMetadata().GetName(), "error", err)
+		return nil
+	}
+	return info
+}
+
 // findApplicationContainerIDs retrieves a list of running application containers.
 func (ms *MetricsScraper) findApplicationContainerIDs(ctx context.Context) ([]*criapi.Container, error) {
 	log := ms.Log.WithName("find-application-container-ids")
@@ -171,16 +319,17 @@ func (ms *MetricsScraper) findApplicationContainerIDs(ctx context.Context) ([]*c
 		return nil, err
 	}
 
-	instancePodIDs := []string{}
+	watchedPods := map[string]*criapi.PodSandbox{}
 	for _, pod := range pods {
-		if _, ok := pod.Labels["crownlabs.polito.it/instance"]; !ok {
+		if _, ok := pod.Labels[instanceLabel]; !ok {
 			continue
 		}
-		if _, ok := pod.Labels["kubevirt.io"]; ok {
+		if _, ok := pod.Labels[virtLauncherLabel]; ok && !isVirtLauncherPod(pod) {
 			continue
 		}
-		instancePodIDs = append(instancePodIDs, pod.Id)
+		watchedPods[pod.Id] = pod
 	}
+	ms.watchedPods = watchedPods
 
 	// get application container ids.
 	ctxc := clctx.LoggerIntoContext(ctx, log.WithName("list-containers"))
@@ -197,7 +346,21 @@ func (ms *MetricsScraper) findApplicationContainerIDs(ctx context.Context) ([]*c
 
 	watchedContainers := []*criapi.Container{}
 	for _, container := range containers {
-		if utils.Contains(instancePodIDs, container.PodSandboxId) && !utils.Contains(ms.ignoredContainerNames, container.Metadata.Name) {
+		pod, ok := watchedPods[container.PodSandboxId]
+		if !ok {
+			continue
+		}
+
+		// For VMs, the application container is the one running the hypervisor, whose
+		// resource usage corresponds to the one of the guest.
+		if isVirtLauncherPod(pod) {
+			if container.Metadata.Name == computeContainerName {
+				watchedContainers = append(watchedContainers, container)
+			}
+			continue
+		}
+
+		if !utils.Contains(ms.ignoredContainerNames, container.Metadata.Name) {
 			watchedContainers = append(watchedContainers, container)
 		}
 	}
@@ -210,5 +373,67 @@ func (ms *MetricsScraper) removeCachedMetric(podName string) {
 	delete(ms.oldStats, podName)
 	ms.cachedMetricsMutex.Lock()
 	delete(ms.cachedMetrics, podName)
+	for instance, pod := range ms.instancePods {
+		if pod == podName {
+			delete(ms.instancePods, instance)
+		}
+	}
 	ms.cachedMetricsMutex.Unlock()
 }
+
+// cachedMetric returns the cached metrics of the application container targeted by the request, if any.
+func (ms *MetricsScraper) cachedMetric(in *ContainerMetricsRequest) (*CustomMetrics, bool) {
+	ms.cachedMetricsMutex.RLock()
+	defer ms.cachedMetricsMutex.RUnlock()
+
+	podName := in.GetPodName()
+	if podName == "" {
+		podName = ms.instancePods[InstanceKey(in.GetInstanceNamespace(), in.GetInstanceName())]
+	}
+
+	metrics, ok := ms.cachedMetrics[podName]
+	return metrics, ok
+}
+
+// subscribe returns a channel notified each time the cached metrics are refreshed,
+// together with the function to be called to stop receiving the notifications.
+func (ms *MetricsScraper) subscribe() (updates <-chan struct{}, unsubscribe func()) {
+	// The channel is buffered so that a slow subscriber coalesces the missed updates,
+	// without blocking the scraping loop.
+	ch := make(chan struct{}, 1)
+
+	ms.subscribersMutex.Lock()
+	if ms.subscribers == nil {
+		ms.subscribers = make(map[chan struct{}]struct{})
+	}
+	ms.subscribers[ch] = struct{}{}
+	ms.subscribersMutex.Unlock()
+
+	return ch, func() {
+		ms.subscribersMutex.Lock()
+		delete(ms.subscribers, ch)
+		ms.subscribersMutex.Unlock()
+	}
+}
+
+func (ms *MetricsScraper) notifySubscribers() {
+	ms.subscribersMutex.Lock()
+	defer ms.subscribersMutex.Unlock()
+
+	for ch := range ms.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// InstanceKey returns the key identifying an instance in the metrics cache.
+func InstanceKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// isVirtLauncherPod returns whether the given pod hosts a KubeVirt VM.
+func isVirtLauncherPod(pod *criapi.PodSandbox) bool {
+	return pod.GetLabels()[virtLauncherLabel] == "virt-launcher" && pod.GetLabels()[vmiNameLabel] != ""
+}
@@ -0,0 +1,196 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instmetrics
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+)
+
+// fakeGuestAgentClient is a fake implementation of the GuestAgentClient, returning the configured metrics.
+type fakeGuestAgentClient struct {
+	diskBytes map[string]uint64
+	usage     map[string]*GuestUsage
+}
+
+func (f *fakeGuestAgentClient) FilesystemUsage(_ context.Context, namespace, name string) (uint64, error) {
+	if diskBytes, found := f.diskBytes[InstanceKey(namespace, name)]; found {
+		return diskBytes, nil
+	}
+	return 0, errors.New("guest agent not connected")
+}
+
+func (f *fakeGuestAgentClient) GuestUsage(_ context.Context, namespace, name string) (*GuestUsage, error) {
+	if usage, found := f.usage[InstanceKey(namespace, name)]; found {
+		return usage, nil
+	}
+	return nil, errors.New("guest metrics not available")
+}
+
+var _ = Describe("The metrics scraper", func() {
+	const namespace = "tenant-tester"
+
+	var (
+		runtime *fakeRuntimeServiceClient
+		guest   *fakeGuestAgentClient
+		scraper *MetricsScraper
+	)
+
+	pod := func(id, instance string, labels map[string]string) *criapi.PodSandbox {
+		podLabels := map[string]string{instanceLabel: instance, "crownlabs.polito.it/tenant": "tester"}
+		for key, value := range labels {
+			podLabels[key] = value
+		}
+		return &criapi.PodSandbox{Id: id, Labels: podLabels, Metadata: &criapi.PodSandboxMetadata{Name: id, Namespace: namespace}}
+	}
+
+	container := func(id, name, sandbox string) *criapi.Container {
+		return &criapi.Container{Id: id, PodSandboxId: sandbox, Metadata: &criapi.ContainerMetadata{Name: name},
+			Labels: map[string]string{"io.kubernetes.pod.name": sandbox}}
+	}
+
+	stats := func(timestamp int64, cpu, memory uint64) *criapi.ContainerStats {
+		return &criapi.ContainerStats{
+			Cpu:           &criapi.CpuUsage{Timestamp: timestamp, UsageCoreNanoSeconds: &criapi.UInt64Value{Value: cpu}},
+			Memory:        &criapi.MemoryUsage{WorkingSetBytes: &criapi.UInt64Value{Value: memory}},
+			WritableLayer: &criapi.FilesystemUsage{UsedBytes: &criapi.UInt64Value{Value: 4096}},
+		}
+	}
+
+	BeforeEach(func() {
+		runtime = &fakeRuntimeServiceClient{
+			pods: []*criapi.PodSandbox{
+				pod("container-pod", "container-instance", nil),
+				pod("virt-launcher-vm", "vm-instance", map[string]string{virtLauncherLabel: "virt-launcher", vmiNameLabel: "vm"}),
+			},
+			containers: []*criapi.Container{
+				container("app", "application", "container-pod"),
+				container("vnc", forge.XVncName, "container-pod"),
+				container("compute", computeContainerName, "virt-launcher-vm"),
+			},
+			containerStats: map[string]*criapi.ContainerStats{
+				"app":     stats(1e9, 1e9, 100),
+				"vnc":     stats(1e9, 1e9, 100),
+				"compute": stats(1e9, 1e9, 8<<30),
+			},
+			sandboxCalls: map[string]int{},
+		}
+		guest = &fakeGuestAgentClient{
+			diskBytes: map[string]uint64{InstanceKey(namespace, "vm"): 2048},
+			usage:     map[string]*GuestUsage{InstanceKey(namespace, "vm"): {Timestamp: 1e9, CPUUsageNanoSeconds: 1e9, MemoryUsedBytes: 1 << 30}},
+		}
+
+		client := &RemoteRuntimeServiceClient{runtimeClient: runtime}
+		scraper = &MetricsScraper{
+			Log:                   GinkgoLogr,
+			RuntimeClient:         client,
+			scraper:               CRIMetricsScraper{RuntimeClient: client},
+			ignoredContainerNames: []string{forge.XVncName, forge.WebsockifyName, forge.ContentDownloaderName},
+			oldStats:              map[string]*ContainerStats{},
+			cachedMetrics:         map[string]*CustomMetrics{},
+			instancePods:          map[string]string{},
+			GuestAgent:            guest,
+		}
+	})
+
+	// tick performs a scraping round, after advancing the counters of the given containers and VMs by one second.
+	tick := func() {
+		GinkgoHelper()
+		for _, s := range runtime.containerStats {
+			s.Cpu.Timestamp += 1e9
+			s.Cpu.UsageCoreNanoSeconds.Value += 5e8
+		}
+		for _, usage := range guest.usage {
+			usage.Timestamp += 1e9
+			usage.CPUUsageNanoSeconds += 25e7
+		}
+		Expect(scraper.fillCachedMetrics(context.Background())).To(Succeed())
+	}
+
+	metrics := func(instance string) *CustomMetrics {
+		GinkgoHelper()
+		metrics, found := scraper.cachedMetric(&ContainerMetricsRequest{InstanceName: instance, InstanceNamespace: namespace})
+		Expect(found).To(BeTrue())
+		return metrics
+	}
+
+	Context("The instance is container-based", func() {
+		It("Should report the metrics of the application container, ignoring the sidecars", func() {
+			tick()
+			Expect(metrics("container-instance").MemBytes).To(BeNumerically("==", 100))
+			Expect(metrics("container-instance").DiskBytes).To(BeNumerically("==", 4096))
+			Expect(metrics("container-instance").unavailable).To(Equal(metricCPU))
+		})
+
+		It("Should compute the CPU usage from two consecutive samples", func() {
+			tick()
+			tick()
+			Expect(metrics("container-instance").CPUPerc).To(BeNumerically("~", 50))
+			Expect(metrics("container-instance").unavailable).To(BeZero())
+		})
+	})
+
+	Context("The instance is VM-based", func() {
+		It("Should report the metrics observed from the guest", func() {
+			tick()
+			tick()
+			Expect(metrics("vm-instance").MemBytes).To(BeNumerically("==", 1<<30))
+			Expect(metrics("vm-instance").DiskBytes).To(BeNumerically("==", 2048))
+			Expect(metrics("vm-instance").CPUPerc).To(BeNumerically("~", 25))
+			Expect(metrics("vm-instance").unavailable).To(BeZero())
+		})
+
+		When("the guest agent cannot be queried", func() {
+			BeforeEach(func() { guest.diskBytes = nil })
+
+			It("Should mark the disk usage as unavailable", func() {
+				tick()
+				tick()
+				Expect(metrics("vm-instance").DiskBytes).To(BeZero())
+				Expect(metrics("vm-instance").unavailable).To(Equal(metricDisk))
+			})
+		})
+
+		When("the guest CPU and memory usage cannot be retrieved", func() {
+			BeforeEach(func() { guest.usage = nil })
+
+			It("Should mark them as unavailable, rather than reporting the ones of the hypervisor", func() {
+				tick()
+				tick()
+				Expect(metrics("vm-instance").MemBytes).To(BeZero())
+				Expect(metrics("vm-instance").CPUPerc).To(BeZero())
+				Expect(metrics("vm-instance").unavailable).To(Equal(metricCPU | metricMemory))
+			})
+		})
+
+		When("no guest agent client is configured", func() {
+			BeforeEach(func() { scraper.GuestAgent = nil })
+
+			It("Should approximate the CPU and memory usage with the ones of the hypervisor", func() {
+				tick()
+				tick()
+				Expect(metrics("vm-instance").MemBytes).To(BeNumerically("==", 8<<30))
+				Expect(metrics("vm-instance").CPUPerc).To(BeNumerically("~", 50))
+				Expect(metrics("vm-instance").unavailable).To(Equal(metricDisk))
+			})
+		})
+	})
+})
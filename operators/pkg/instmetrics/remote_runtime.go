@@ -123,6 +123,21 @@ func (r *RemoteRuntimeServiceClient) ListContainerStats(ctx context.Context, fil
 	return resp, nil
 }
 
+// PodSandboxStats returns the stats of the given PodSandbox.
+func (r *RemoteRuntimeServiceClient) PodSandboxStats(ctx context.Context, podSandboxID string) (*criapi.PodSandboxStats, error) {
+	log := clctx.LoggerFromContext(ctx).WithValues("PodSandboxId", podSandboxID)
+
+	resp, err := r.runtimeClient.PodSandboxStats(ctx, &criapi.PodSandboxStatsRequest{
+		PodSandboxId: podSandboxID,
+	})
+	if err != nil {
+		log.Error(err, "Remote runtime call failed")
+		return nil, err
+	}
+	log.V(5).Info("Remote runtime call succeeded", "PodSandboxStatsResponse", resp.GetStats())
+	return resp.GetStats(), nil
+}
+
 func dial(ctx context.Context, addr string) (net.Conn, error) {
 	return (&net.Dialer{}).DialContext(ctx, unixProtocol, addr)
 }
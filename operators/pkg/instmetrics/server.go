@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 )
 
@@ -34,8 +35,14 @@ type Server struct {
 	Log                  logr.Logger
 	RuntimeClient        *RemoteRuntimeServiceClient
 	StatsScraper         *StatsScraper
-	metricsScraper       *MetricsScraper
-	grpcServer           *grpc.Server
+	// GuestAgent retrieves the guest metrics of VMs, if configured.
+	GuestAgent GuestAgentClient
+	// InstanceInfo retrieves the attributes of the instances the exported metrics are labelled by, if configured.
+	InstanceInfo InstanceInfoClient
+	// MetricsRegisterer is used to export the metrics in the Prometheus format, if configured.
+	MetricsRegisterer prometheus.Registerer
+	metricsScraper    *MetricsScraper
+	grpcServer        *grpc.Server
 	UnimplementedInstanceMetricsServer
 }
 
@@ -56,10 +63,20 @@ func (instmetrics *Server) Start(ctx context.Context) error {
 		UpdatePeriod:  instmetrics.MetricsScraperPeriod,
 		RuntimeClient: instmetrics.RuntimeClient,
 		cachedMetrics: make(map[string]*CustomMetrics),
+		instancePods:  make(map[string]string),
+		GuestAgent:    instmetrics.GuestAgent,
+		InstanceInfo:  instmetrics.InstanceInfo,
 		scraper:       *instmetrics.StatsScraper,
 	}
 	go instmetrics.metricsScraper.Start(ctx)
 
+	if instmetrics.MetricsRegisterer != nil {
+		if err := instmetrics.MetricsRegisterer.Register(newMetricsCollector(instmetrics.metricsScraper)); err != nil {
+			instmetrics.Log.Error(err, "Unable to register the metrics collector")
+			return err
+		}
+	}
+
 	if err := instmetrics.grpcServer.Serve(lis); err != nil {
 		instmetrics.Log.Error(err, "Unable to start gRPC server")
 		return err
@@ -68,25 +85,64 @@ func (instmetrics *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-// ContainerMetrics receives the PodName and returns a ContainerMetricsResponse with resource utilization.
+// ContainerMetrics receives the PodName (or the instance) and returns a ContainerMetricsResponse with resource utilization.
 func (instmetrics *Server) ContainerMetrics(_ context.Context, in *ContainerMetricsRequest) (*ContainerMetricsResponse, error) {
-	if in == nil || in.PodName == "" {
-		return nil, fmt.Errorf("wrong request: valid CustomMetricsRequest required")
+	if err := validateRequest(in); err != nil {
+		return nil, err
 	}
 
 	// Retrieve metrics from metricsScraper cache.
-	instmetrics.metricsScraper.cachedMetricsMutex.RLock()
-	metrics, ok := instmetrics.metricsScraper.cachedMetrics[in.GetPodName()]
-	instmetrics.metricsScraper.cachedMetricsMutex.RUnlock()
+	metrics, ok := instmetrics.metricsScraper.cachedMetric(in)
 	if !ok {
-		return nil, fmt.Errorf("no existing application container for requested PodName %v", in.GetPodName())
+		return nil, fmt.Errorf("no existing application container for requested %v", requestTarget(in))
+	}
+
+	return metrics.response(), nil
+}
+
+// WatchContainerMetrics receives the PodName (or the instance) and streams a ContainerMetricsResponse each time
+// the resource utilization is refreshed, until the client closes the stream.
+// Updates are not sent while no application container exists for the requested target.
+func (instmetrics *Server) WatchContainerMetrics(in *ContainerMetricsRequest, stream InstanceMetrics_WatchContainerMetricsServer) error {
+	if err := validateRequest(in); err != nil {
+		return err
+	}
+
+	log := instmetrics.Log.WithValues("target", requestTarget(in))
+	log.V(2).Info("Metrics watch started")
+	defer log.V(2).Info("Metrics watch terminated")
+
+	updates, unsubscribe := instmetrics.metricsScraper.subscribe()
+	defer unsubscribe()
+
+	// Send the currently cached metrics first, not to wait a whole scraping period.
+	for {
+		if metrics, ok := instmetrics.metricsScraper.cachedMetric(in); ok {
+			if err := stream.Send(metrics.response()); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-updates:
+		}
 	}
+}
 
-	response := &ContainerMetricsResponse{
-		CpuPerc:   metrics.CPUPerc,
-		MemBytes:  metrics.MemBytes,
-		DiskBytes: metrics.DiskBytes,
+// validateRequest checks that the request identifies either a pod or an instance.
+func validateRequest(in *ContainerMetricsRequest) error {
+	if in == nil || (in.GetPodName() == "" && (in.GetInstanceName() == "" || in.GetInstanceNamespace() == "")) {
+		return fmt.Errorf("wrong request: valid CustomMetricsRequest required, specifying either the PodName or the instance name and namespace")
 	}
+	return nil
+}
 
-	return response, nil
+// requestTarget returns a human readable description of the target of the request.
+func requestTarget(in *ContainerMetricsRequest) string {
+	if in.GetPodName() != "" {
+		return fmt.Sprintf("PodName %v", in.GetPodName())
+	}
+	return fmt.Sprintf("instance %v", InstanceKey(in.GetInstanceNamespace(), in.GetInstanceName()))
 }
@@ -0,0 +1,136 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Diff compares two renders (as produced by Encode) and returns a unified-like textual description of the
+// objects which have been added, removed or modified, one object at a time. The result is empty if the renders match.
+func Diff(previous, current []byte) (string, error) {
+	previousObjects, err := decodeUnstructured(previous)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the previous render: %w", err)
+	}
+	currentObjects, err := decodeUnstructured(current)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the current render: %w", err)
+	}
+
+	keys := make([]string, 0, len(previousObjects)+len(currentObjects))
+	for key := range previousObjects {
+		keys = append(keys, key)
+	}
+	for key := range currentObjects {
+		if _, found := previousObjects[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		before, after := previousObjects[key], currentObjects[key]
+		if before == after {
+			continue
+		}
+		fmt.Fprintf(&builder, "--- %s\n+++ %s\n", key, key)
+		for _, line := range diffLines(splitLines(before), splitLines(after)) {
+			builder.WriteString(line)
+			builder.WriteByte('\n')
+		}
+	}
+	return builder.String(), nil
+}
+
+// decodeUnstructured decodes the given render, returning the YAML encoding of each object indexed by its key.
+func decodeUnstructured(data []byte) (map[string]string, error) {
+	documents, err := splitDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer := json.NewSerializerWithOptions(json.DefaultMetaFactory, nil, nil, json.SerializerOptions{Yaml: true})
+	encoded := make(map[string]string, len(documents))
+	for _, document := range documents {
+		content, err := utilyaml.ToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(content); err != nil {
+			return nil, err
+		}
+
+		var builder strings.Builder
+		if err := serializer.Encode(obj, &builder); err != nil {
+			return nil, err
+		}
+		encoded[objectKey(obj)] = builder.String()
+	}
+	return encoded, nil
+}
+
+// splitLines splits the given text into lines, returning no lines for an empty text.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the line by line difference between before and after, based on their longest common subsequence.
+// Lines are prefixed by "-" if removed, "+" if added and " " if unchanged.
+func diffLines(before, after []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:].
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(before)+len(after))
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, " "+before[i])
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, "+"+after[j])
+			j++
+		default:
+			lines = append(lines, "-"+before[i])
+			i++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,307 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package instrender renders offline the objects forged by the instance operator for a given Instance.
+package instrender
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+	tntctrl "github.com/netgroup-polito/CrownLabs/operators/pkg/tenant-controller"
+)
+
+const (
+	// PlaceholderNFSServer is the NFS server of the MyDrive volume, in case the corresponding secret is not provided.
+	PlaceholderNFSServer = "mydrive.nfs.example.com"
+	// PlaceholderNFSPath is the NFS export path of the MyDrive volume, in case the corresponding secret is not provided.
+	PlaceholderNFSPath = "/mydrive"
+	// PlaceholderUID is the UID of the instance, in case it is not specified (as it is assigned by the API server).
+	PlaceholderUID types.UID = "00000000-0000-0000-0000-000000000000"
+	// PlaceholderViewerKey replaces the randomly generated key to sign the view-only share links, for the renders to be stable.
+	PlaceholderViewerKey = "placeholder"
+	// PlaceholderNodeClusterToken replaces the randomly generated bootstrap token of VM-based clusters, for the renders to be stable.
+	PlaceholderNodeClusterToken = "abcdef.0123456789abcdef"
+	// PlaceholderNodeClusterOperatorToken replaces the randomly generated operator token of VM-based clusters, for the renders to be stable.
+	PlaceholderNodeClusterOperatorToken = "ghijkl.0123456789abcdef"
+	// PlaceholderNodeClusterCertificateKey replaces the randomly generated key encrypting the control plane certificates of VM-based clusters.
+	PlaceholderNodeClusterCertificateKey = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+// nodeClusterPlaceholders are the placeholders replacing the bootstrap material of VM-based clusters. They are replaced
+// at creation time, rather than once rendered, as they are also embedded in the cloud-init configuration of the nodes.
+var nodeClusterPlaceholders = map[string]string{
+	forge.NodeClusterTokenKey:          PlaceholderNodeClusterToken,
+	forge.NodeClusterOperatorTokenKey:  PlaceholderNodeClusterOperatorToken,
+	forge.NodeClusterCertificateKeyKey: PlaceholderNodeClusterCertificateKey,
+}
+
+// errOffline is returned when the reconciliation attempts to interact with a workload cluster.
+var errOffline = errors.New("workload clusters are not reachable while rendering offline")
+
+// Options configures the instance operator whose behavior is rendered, consistently with the corresponding flags.
+type Options struct {
+	ServiceUrls         instctrl.ServiceUrls
+	ContainerEnvOpts    forge.ContainerEnvOpts
+	ClusterNetworkPools instctrl.ClusterNetworkPools
+}
+
+// Render runs the reconciliation of the Instance among the given objects (which must also include the corresponding
+// Template and Tenant) against an in-memory client, and returns the objects created by the instance operator, in
+// creation order. The objects the Instance depends on but which are created by other operators (i.e., the namespace
+// and the MyDrive secret) are replaced by placeholders, unless provided, as well as the randomly generated credentials
+// (i.e., the key signing the view-only share links and the bootstrap material of VM-based clusters). Cluster add-ons are never installed.
+func Render(ctx context.Context, scheme *runtime.Scheme, objects []client.Object, opts *Options) ([]*unstructured.Unstructured, error) {
+	instance, err := instanceFrom(objects)
+	if err != nil {
+		return nil, err
+	}
+	if instance.UID == "" {
+		instance.UID = PlaceholderUID
+	}
+	objects = append(objects, placeholders(objects, instance)...)
+
+	bootstrapSecretName := nodeClusterBootstrapSecretName(instance, objects)
+
+	var created []client.Object
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&clv1alpha2.Instance{}, &clv1alpha2.Template{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if secret, ok := obj.(*corev1.Secret); ok && bootstrapSecretName != "" && secret.Name == bootstrapSecretName {
+					for key, placeholder := range nodeClusterPlaceholders {
+						secret.Data[key] = []byte(placeholder)
+					}
+				}
+				if secret, ok := obj.(*corev1.Secret); ok && secret.Name == forge.ViewerSecretName(instance) {
+					// Replaced at creation, as the path of the view-only sessions is derived from the key.
+					secret.Data[forge.ViewerKeyName] = []byte(PlaceholderViewerKey)
+				}
+				if err := c.Create(ctx, obj, opts...); err != nil {
+					return err
+				}
+				created = append(created, obj)
+				return nil
+			},
+		}).Build()
+
+	reconciler := instctrl.InstanceReconciler{
+		Client:              cl,
+		Scheme:              scheme,
+		EventsRecorder:      record.NewFakeRecorder(1024),
+		ServiceUrls:         opts.ServiceUrls,
+		ContainerEnvOpts:    opts.ContainerEnvOpts,
+		ClusterNetworkPools: opts.ClusterNetworkPools,
+		WorkloadClusterClient: func(context.Context, string, string) (client.Client, error) {
+			return nil, errOffline
+		},
+		ClusterInfoRetriever: func(context.Context, string) (*corev1.ConfigMap, error) {
+			return nil, errOffline
+		},
+		SkipClusterAddons: true,
+	}
+
+	if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(instance)}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile instance %s: %w", client.ObjectKeyFromObject(instance), err)
+	}
+
+	rendered := make([]*unstructured.Unstructured, 0, len(created))
+	for _, obj := range created {
+		// Objects are retrieved again, as they may have been updated after their creation.
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return nil, err
+		}
+		current, err := scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(obj), current.(client.Object)); err != nil {
+			return nil, err
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(current)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, normalize(&unstructured.Unstructured{Object: content}, gvk))
+	}
+	return rendered, nil
+}
+
+// Decode decodes the objects in the given (possibly multi-document) YAML or JSON manifests into the types registered in the scheme.
+func Decode(scheme *runtime.Scheme, data []byte) ([]client.Object, error) {
+	serializer := json.NewSerializerWithOptions(json.DefaultMetaFactory, scheme, scheme, json.SerializerOptions{Yaml: true})
+	documents, err := splitDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]client.Object, 0, len(documents))
+	for _, document := range documents {
+		decoded, _, err := serializer.Decode(document, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := decoded.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object of type %T", decoded)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// Encode encodes the given objects as a multi-document YAML manifest.
+func Encode(objects []*unstructured.Unstructured) ([]byte, error) {
+	serializer := json.NewSerializerWithOptions(json.DefaultMetaFactory, nil, nil, json.SerializerOptions{Yaml: true})
+
+	var buffer bytes.Buffer
+	for _, obj := range objects {
+		buffer.WriteString("---\n")
+		if err := serializer.Encode(obj, &buffer); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// splitDocuments splits the given (possibly multi-document) YAML or JSON manifests into the documents which are not empty.
+func splitDocuments(data []byte) ([][]byte, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if !isEmptyDocument(document) {
+			documents = append(documents, document)
+		}
+	}
+}
+
+// isEmptyDocument returns whether the given YAML document contains only comments and blank lines.
+func isEmptyDocument(document []byte) bool {
+	for _, line := range bytes.Split(document, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("#")) {
+			return false
+		}
+	}
+	return true
+}
+
+// instanceFrom returns the single Instance among the given objects.
+func instanceFrom(objects []client.Object) (*clv1alpha2.Instance, error) {
+	var instance *clv1alpha2.Instance
+	for _, obj := range objects {
+		if candidate, ok := obj.(*clv1alpha2.Instance); ok {
+			if instance != nil {
+				return nil, errors.New("exactly one instance must be provided, found multiple")
+			}
+			instance = candidate
+		}
+	}
+	if instance == nil {
+		return nil, errors.New("exactly one instance must be provided, found none")
+	}
+	return instance, nil
+}
+
+// nodeClusterBootstrapSecretName returns the name of the secret holding the bootstrap material of the VM-based cluster
+// of the instance, if its template (among the given objects) defines one.
+func nodeClusterBootstrapSecretName(instance *clv1alpha2.Instance, objects []client.Object) string {
+	for _, obj := range objects {
+		template, ok := obj.(*clv1alpha2.Template)
+		if ok && template.Name == instance.Spec.Template.Name && template.Namespace == instance.Spec.Template.Namespace &&
+			forge.IsNodeClusterTemplate(template) {
+			return forge.NodeClusterBootstrapSecretName(forge.NodeClusterName(instance, &template.Spec.EnvironmentList[0]))
+		}
+	}
+	return ""
+}
+
+// placeholders returns the objects the instance depends on, created by other operators, which are missing among the given ones.
+func placeholders(objects []client.Object, instance *clv1alpha2.Instance) []client.Object {
+	namespace := &corev1.Namespace{}
+	namespace.SetName(instance.Namespace)
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			tntctrl.NFSSecretServerNameKey: []byte(PlaceholderNFSServer),
+			tntctrl.NFSSecretPathKey:       []byte(PlaceholderNFSPath),
+		},
+	}
+	secret.SetNamespace(instance.Namespace)
+	secret.SetName(tntctrl.NFSSecretName)
+
+	var missing []client.Object
+	for _, placeholder := range []client.Object{namespace, secret} {
+		if !contains(objects, placeholder) {
+			missing = append(missing, placeholder)
+		}
+	}
+	return missing
+}
+
+// contains returns whether an object of the same type and with the same key as the given one is present among the objects.
+func contains(objects []client.Object, target client.Object) bool {
+	key := client.ObjectKeyFromObject(target)
+	for _, obj := range objects {
+		if fmt.Sprintf("%T", obj) == fmt.Sprintf("%T", target) && client.ObjectKeyFromObject(obj) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize sets the type information of the given object and removes the fields set by the in-memory client, to obtain stable renders.
+func normalize(obj *unstructured.Unstructured, gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj.SetGroupVersionKind(gvk)
+	unstructured.RemoveNestedField(obj.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	if status, found, _ := unstructured.NestedMap(obj.Object, "status"); found && len(status) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "status")
+	}
+	return obj
+}
+
+// objectKey returns the key identifying the given object in a render.
+func objectKey(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s %s", obj.GroupVersionKind().GroupKind(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+}
@@ -0,0 +1,320 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrender_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/forge"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instctrl"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/instrender"
+)
+
+const manifests = `
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Tenant
+metadata:
+  name: tester
+spec:
+  firstName: Test
+  lastName: Tester
+  email: tester@example.com
+---
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Template
+metadata:
+  name: %[1]s
+  namespace: workspace-netgroup
+spec:
+  prettyName: Rendered template
+  description: A template rendered offline
+  workspace.crownlabs.polito.it/WorkspaceRef:
+    name: netgroup
+  environmentList:
+  - name: %[1]s
+    image: %[2]s
+    environmentType: %[1]s
+    guiEnabled: true
+    mode: Standard
+    persistent: %[3]s
+    mountMyDriveVolume: true
+    resources:
+      cpu: 1
+      reservedCPUPercentage: 50
+      memory: 1Gi
+      disk: 10Gi
+---
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Instance
+metadata:
+  name: rendered
+  namespace: tenant-tester
+spec:
+  template.crownlabs.polito.it/TemplateRef:
+    name: %[1]s
+    namespace: workspace-netgroup
+  tenant.crownlabs.polito.it/TenantRef:
+    name: tester
+  running: true
+`
+
+const clusterManifests = `
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Tenant
+metadata:
+  name: tester
+spec:
+  firstName: Test
+  lastName: Tester
+  email: tester@example.com
+  publicKeys:
+  - ssh-ed25519 AAAA tester@example.com
+---
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Template
+metadata:
+  name: cluster
+  namespace: workspace-netgroup
+spec:
+  prettyName: Rendered cluster
+  description: A cluster template rendered offline
+  workspace.crownlabs.polito.it/WorkspaceRef:
+    name: netgroup
+  environmentList:
+  - name: cluster
+    image: registry/capk/ubuntu-2204-container-disk:v1.30.3
+    environmentType: Cluster
+    mode: Standard
+    persistent: true
+    resources:
+      cpu: 2
+      reservedCPUPercentage: 50
+      memory: 4G
+    cluster:
+      name: demo
+      version: v1.30.2
+      serviceType: ClusterIP
+      clusterNet:
+        pods: 10.80.0.0/16
+        services: 10.95.0.0/16
+        cni: cilium
+      controlPlane:
+        provider: kamaji
+        replicas: 1
+      machineDeployment:
+        replicas: 1
+---
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Template
+metadata:
+  name: kubeadm
+  namespace: workspace-netgroup
+spec:
+  prettyName: Rendered VM-based cluster
+  description: A VM-based cluster template rendered offline
+  workspace.crownlabs.polito.it/WorkspaceRef:
+    name: netgroup
+  environmentList:
+  - name: control-plane
+    image: registry/capk/ubuntu-2204-container-disk:v1.30.3
+    environmentType: VirtualMachine
+    mode: Standard
+    isClusterNode: true
+    clusterName: lab
+    clusterRole: ControlPlane
+    resources:
+      cpu: 2
+      reservedCPUPercentage: 50
+      memory: 4G
+  - name: worker
+    image: registry/capk/ubuntu-2204-container-disk:v1.30.3
+    environmentType: VirtualMachine
+    mode: Standard
+    isClusterNode: true
+    clusterName: lab
+    clusterRole: Worker
+    resources:
+      cpu: 2
+      reservedCPUPercentage: 50
+      memory: 2G
+---
+apiVersion: crownlabs.polito.it/v1alpha2
+kind: Instance
+metadata:
+  name: rendered
+  namespace: tenant-tester
+spec:
+  template.crownlabs.polito.it/TemplateRef:
+    name: %[1]s
+    namespace: workspace-netgroup
+  tenant.crownlabs.polito.it/TenantRef:
+    name: tester
+  running: true
+`
+
+var _ = Describe("Offline rendering", func() {
+	var (
+		scheme  *runtime.Scheme
+		options instrender.Options
+	)
+
+	manifestsFor := func(environmentType, image, persistent string) []byte {
+		return []byte(strings.NewReplacer("%[1]s", environmentType, "%[2]s", image, "%[3]s", persistent).Replace(manifests))
+	}
+
+	clusterManifestsFor := func(template string) []byte {
+		return []byte(strings.ReplaceAll(clusterManifests, "%[1]s", template))
+	}
+
+	render := func(data []byte) []*unstructured.Unstructured {
+		objects, err := instrender.Decode(scheme, data)
+		Expect(err).ToNot(HaveOccurred())
+		rendered, err := instrender.Render(context.Background(), scheme, objects, &options)
+		Expect(err).ToNot(HaveOccurred())
+		return rendered
+	}
+
+	kinds := func(objects []*unstructured.Unstructured) []string {
+		var result []string
+		for _, obj := range objects {
+			result = append(result, obj.GetKind())
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		scheme = instrender.NewScheme()
+		options = instrender.Options{
+			ServiceUrls:      instctrl.ServiceUrls{WebsiteBaseURL: "crownlabs.example.com", InstancesAuthURL: "https://crownlabs.example.com/auth"},
+			ContainerEnvOpts: forge.ContainerEnvOpts{ImagesTag: "v1.0.0", XVncImg: "crownlabs/tigervnc", WebsockifyImg: "crownlabs/websockify"},
+		}
+	})
+
+	Describe("The instrender.Render function", func() {
+		It("Should render the objects of container environments", func() {
+			rendered := render(manifestsFor("Container", "crownlabs/pycharm", "false"))
+			Expect(kinds(rendered)).To(ConsistOf("TemplateRevision", "Service", "Ingress", "Secret", "Ingress", "Deployment"))
+		})
+
+		It("Should render the objects of persistent VM environments", func() {
+			rendered := render(manifestsFor("VirtualMachine", "registry/ubuntu:22.04", "true"))
+			Expect(kinds(rendered)).To(ConsistOf("TemplateRevision", "Secret", "Service", "Ingress", "VirtualMachine"))
+			for _, obj := range rendered {
+				Expect(obj.GetResourceVersion()).To(BeEmpty())
+				Expect(obj.GetAPIVersion()).ToNot(BeEmpty())
+			}
+		})
+
+		It("Should render the objects of cluster environments", func() {
+			rendered := render(clusterManifestsFor("cluster"))
+			Expect(kinds(rendered)).To(ConsistOf("TemplateRevision", "NetworkPolicy", "NetworkPolicy", "Cluster", "KubevirtCluster",
+				"KamajiControlPlane", "MachineDeployment", "KubevirtMachineTemplate", "KubeadmConfigTemplate"))
+		})
+
+		It("Should render the objects of VM-based clusters, replacing the bootstrap material with placeholders", func() {
+			rendered := render(clusterManifestsFor("kubeadm"))
+			Expect(kinds(rendered)).To(ConsistOf("TemplateRevision", "Secret", "Service", "Secret", "VirtualMachine"))
+
+			data := func(name, key string) string {
+				for _, obj := range rendered {
+					if obj.GetKind() == "Secret" && obj.GetName() == name {
+						value, _, err := unstructured.NestedString(obj.Object, "data", key)
+						Expect(err).ToNot(HaveOccurred())
+						decoded, err := base64.StdEncoding.DecodeString(value)
+						Expect(err).ToNot(HaveOccurred())
+						return string(decoded)
+					}
+				}
+				return ""
+			}
+
+			Expect(data("rendered-lab-kubeadm", forge.NodeClusterTokenKey)).To(Equal(instrender.PlaceholderNodeClusterToken))
+			Expect(data("rendered-lab-kubeadm", forge.NodeClusterOperatorTokenKey)).To(Equal(instrender.PlaceholderNodeClusterOperatorToken))
+			Expect(data("rendered-lab-controlplane-0", instctrl.UserDataKey)).To(
+				ContainSubstring("--token " + instrender.PlaceholderNodeClusterToken))
+		})
+
+		It("Should fail if no instance is provided", func() {
+			objects, err := instrender.Decode(scheme, manifestsFor("Container", "crownlabs/pycharm", "false"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = instrender.Render(context.Background(), scheme, objects[:2], &options)
+			Expect(err).To(MatchError(ContainSubstring("found none")))
+		})
+
+		It("Should produce stable renders", func() {
+			first, err := instrender.Encode(render(manifestsFor("Container", "crownlabs/pycharm", "false")))
+			Expect(err).ToNot(HaveOccurred())
+			second, err := instrender.Encode(render(manifestsFor("Container", "crownlabs/pycharm", "false")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(first)).To(Equal(string(second)))
+		})
+
+		DescribeTable("Should produce stable renders of cluster environments",
+			func(template string) {
+				first, err := instrender.Encode(render(clusterManifestsFor(template)))
+				Expect(err).ToNot(HaveOccurred())
+				second, err := instrender.Encode(render(clusterManifestsFor(template)))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(first)).To(Equal(string(second)))
+			},
+			Entry("Cluster API based clusters", "cluster"),
+			Entry("VM-based clusters", "kubeadm"),
+		)
+	})
+
+	Describe("The instrender.Diff function", func() {
+		var previous []byte
+
+		BeforeEach(func() {
+			var err error
+			previous, err = instrender.Encode(render(manifestsFor("Container", "crownlabs/pycharm", "false")))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should report no differences between identical renders", func() {
+			Expect(instrender.Diff(previous, previous)).To(BeEmpty())
+		})
+
+		It("Should report the modified lines of the changed objects", func() {
+			current, err := instrender.Encode(render(manifestsFor("Container", "crownlabs/vscode", "false")))
+			Expect(err).ToNot(HaveOccurred())
+
+			diff, err := instrender.Diff(previous, current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diff).To(ContainSubstring("--- Deployment.apps tenant-tester/rendered"))
+			Expect(diff).To(ContainSubstring("-        image: crownlabs/pycharm"))
+			Expect(diff).To(ContainSubstring("+        image: crownlabs/vscode"))
+			Expect(diff).ToNot(ContainSubstring("--- Service"))
+		})
+
+		It("Should report the added and removed objects", func() {
+			current, err := instrender.Encode(render(manifestsFor("VirtualMachine", "registry/ubuntu:22.04", "true")))
+			Expect(err).ToNot(HaveOccurred())
+
+			diff, err := instrender.Diff(previous, current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diff).To(ContainSubstring("--- Deployment.apps tenant-tester/rendered"))
+			Expect(diff).To(ContainSubstring("+kind: VirtualMachine"))
+			Expect(diff).To(ContainSubstring("-kind: Deployment"))
+		})
+	})
+})
@@ -0,0 +1,61 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatewh
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+var (
+	scheme *runtime.Scheme
+	ctx    = context.Background()
+
+	bypassGroups     = []string{"admins"}
+	testTemplateName = "test-template"
+)
+
+var _ = BeforeSuite(func() {
+	scheme = runtime.NewScheme()
+	Expect(clv1alpha2.AddToScheme(scheme)).To(Succeed())
+})
+
+func TestTemplateWebHooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Template Webhook Suite")
+}
+
+func serializeTemplate(t *clv1alpha2.Template) runtime.RawExtension {
+	data, err := json.Marshal(t)
+	Expect(err).ToNot(HaveOccurred())
+	return runtime.RawExtension{Raw: data}
+}
+
+func forgeRequest(op admissionv1.Operation, template *clv1alpha2.Template, groups ...string) admission.Request {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: op}}
+	req.Object = serializeTemplate(template)
+	req.Name = template.Name
+	req.UserInfo.Groups = groups
+	return req
+}
@@ -0,0 +1,284 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package templatewh groups the functionalities related to the Template webhook.
+package templatewh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+// packageNameRegex matches the valid names of the packages to be installed on the cluster nodes.
+var packageNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.+:=~_-]*$`)
+
+// TemplateValidator validates Templates.
+type TemplateValidator struct {
+	decoder      admission.Decoder
+	BypassGroups []string // current ns SAs group: system:serviceaccounts:NAMESPACE
+}
+
+// MakeTemplateValidator creates a new webhook handler suitable for controller runtime based on TemplateValidator.
+func MakeTemplateValidator(webhookBypassGroups []string, scheme *runtime.Scheme) *webhook.Admission {
+	return &webhook.Admission{Handler: &TemplateValidator{
+		BypassGroups: webhookBypassGroups,
+		decoder:      admission.NewDecoder(scheme),
+	}}
+}
+
+// Handle admits a template if the configuration of its environments is valid - this method is used by controller runtime.
+func (tv *TemplateValidator) Handle(ctx context.Context, req admission.Request) admission.Response { //nolint:gocritic // the signature of this method is imposed by controller runtime.
+	log := ctrl.LoggerFrom(ctx).WithName("validator").WithValues("username", req.UserInfo.Username, "template", req.Name)
+
+	if utils.MatchOneInStringSlices(tv.BypassGroups, req.UserInfo.Groups) {
+		log.Info("admitted: successful override")
+		return admission.Allowed("")
+	}
+
+	template, err := tv.DecodeTemplate(req.Object)
+	if err != nil {
+		log.Error(err, "template decode from request failed")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if errs := ValidateTemplate(template); len(errs) > 0 {
+		log.Info("denied: invalid template", "errors", errs.ToAggregate().Error())
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+
+	log.V(utils.LogDebugLevel).Info("allowed")
+	return admission.Allowed("")
+}
+
+// DecodeTemplate decodes the template from the incoming request.
+func (tv *TemplateValidator) DecodeTemplate(obj runtime.RawExtension) (*clv1alpha2.Template, error) {
+	if tv.decoder == nil {
+		return nil, errors.New("missing decoder")
+	}
+	template := &clv1alpha2.Template{}
+	err := tv.decoder.DecodeRaw(obj, template)
+	return template, err
+}
+
+// ValidateTemplate validates the configuration of the environments of the given template.
+func ValidateTemplate(template *clv1alpha2.Template) field.ErrorList {
+	errs := ValidateClusterNodes(template.Spec.EnvironmentList, field.NewPath("spec", "environmentList"))
+	for i := range template.Spec.EnvironmentList {
+		environment := &template.Spec.EnvironmentList[i]
+		envPath := field.NewPath("spec", "environmentList").Index(i)
+		errs = append(errs, ValidateSessionRecording(environment, envPath.Child("sessionRecording"))...)
+		if environment.Cluster == nil {
+			continue
+		}
+
+		clusterPath := envPath.Child("cluster")
+		errs = append(errs, ValidateNodeBootstrap(environment.Cluster.NodeBootstrap, clusterPath.Child("nodeBootstrap"))...)
+		errs = append(errs, ValidateAllowedEgress(environment.Cluster.AllowedEgress, clusterPath.Child("allowedEgress"))...)
+	}
+	return errs
+}
+
+// ValidateClusterNodes validates the environments representing the nodes of a VM-based cluster, if any. In this case, all the
+// environments must be VM nodes of the same cluster, uniquely identified by role and ordinal, and including the control plane
+// node with ordinal 0, which initializes the cluster.
+func ValidateClusterNodes(environments []clv1alpha2.Environment, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if !slices.ContainsFunc(environments, func(env clv1alpha2.Environment) bool { return env.IsClusterNode }) {
+		return errs
+	}
+
+	nodes := make(map[string]struct{}, len(environments))
+	initializer := false
+	for i := range environments {
+		environment := &environments[i]
+		envPath := fldPath.Index(i)
+
+		if !environment.IsClusterNode {
+			errs = append(errs, field.Invalid(envPath.Child("isClusterNode"), false, "all environments must be nodes of the same cluster"))
+			continue
+		}
+		if environment.EnvironmentType != clv1alpha2.ClassVM && environment.EnvironmentType != clv1alpha2.ClassCloudVM {
+			errs = append(errs, field.NotSupported(envPath.Child("environmentType"), environment.EnvironmentType,
+				[]string{string(clv1alpha2.ClassVM), string(clv1alpha2.ClassCloudVM)}))
+		}
+		for _, msg := range validation.IsDNS1123Label(environment.ClusterName) {
+			errs = append(errs, field.Invalid(envPath.Child("clusterName"), environment.ClusterName, msg))
+		}
+		if environment.ClusterName != environments[0].ClusterName {
+			errs = append(errs, field.Invalid(envPath.Child("clusterName"), environment.ClusterName, "all environments must be nodes of the same cluster"))
+		}
+		if environment.ClusterRole != clv1alpha2.ClusterNodeControlPlane && environment.ClusterRole != clv1alpha2.ClusterNodeWorker {
+			errs = append(errs, field.NotSupported(envPath.Child("clusterRole"), environment.ClusterRole,
+				[]string{string(clv1alpha2.ClusterNodeControlPlane), string(clv1alpha2.ClusterNodeWorker)}))
+		}
+
+		node := fmt.Sprintf("%s-%d", environment.ClusterRole, environment.Ordinal)
+		if _, found := nodes[node]; found {
+			errs = append(errs, field.Duplicate(envPath.Child("ordinal"), environment.Ordinal))
+		}
+		nodes[node] = struct{}{}
+		initializer = initializer || (environment.ClusterRole == clv1alpha2.ClusterNodeControlPlane && environment.Ordinal == 0)
+	}
+
+	if !initializer {
+		errs = append(errs, field.Required(fldPath, "a control plane node with ordinal 0 is required to initialize the cluster"))
+	}
+	return errs
+}
+
+// ValidateAllowedEgress validates the destinations the nodes of a cluster environment are allowed to reach.
+func ValidateAllowedEgress(rules []clv1alpha2.ClusterEgressRule, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i := range rules {
+		rulePath := fldPath.Index(i)
+		_, network, err := net.ParseCIDR(rules[i].CIDR)
+		if err != nil {
+			errs = append(errs, field.Invalid(rulePath.Child("cidr"), rules[i].CIDR, "must be a valid CIDR"))
+			continue
+		}
+
+		for j, except := range rules[i].Except {
+			ip, _, err := net.ParseCIDR(except)
+			if err != nil || !network.Contains(ip) {
+				errs = append(errs, field.Invalid(rulePath.Child("except").Index(j), except, "must be a valid CIDR within "+rules[i].CIDR))
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateSessionRecording validates the recording configuration of an environment, which is supported
+// only by graphical container environments and requires an HTTP(S) endpoint for the Upload (default) destination.
+func ValidateSessionRecording(environment *clv1alpha2.Environment, fldPath *field.Path) field.ErrorList {
+	recording := environment.SessionRecording
+	if recording == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if environment.EnvironmentType != clv1alpha2.ClassContainer || !environment.GuiEnabled {
+		errs = append(errs, field.Forbidden(fldPath, "session recording is supported only by graphical container environments"))
+	}
+
+	switch {
+	case recording.Destination != clv1alpha2.RecordingDestinationPersistentVolume && recording.UploadURL == "":
+		errs = append(errs, field.Required(fldPath.Child("uploadUrl"), "an upload URL is required for the Upload destination"))
+	case recording.UploadURL != "":
+		if u, err := url.Parse(recording.UploadURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(fldPath.Child("uploadUrl"), recording.UploadURL, "must be a valid HTTP(S) URL"))
+		}
+	}
+	return errs
+}
+
+// ValidateNodeBootstrap validates the node bootstrap customization of a cluster environment.
+func ValidateNodeBootstrap(bootstrap *clv1alpha2.NodeBootstrap, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if bootstrap == nil {
+		return errs
+	}
+
+	paths := make(map[string]struct{}, len(bootstrap.Files))
+	for i := range bootstrap.Files {
+		file := &bootstrap.Files[i]
+		filePath := fldPath.Child("files").Index(i)
+
+		if !path.IsAbs(file.Path) || path.Clean(file.Path) != file.Path {
+			errs = append(errs, field.Invalid(filePath.Child("path"), file.Path, "must be an absolute and clean path"))
+		}
+		if _, found := paths[file.Path]; found {
+			errs = append(errs, field.Duplicate(filePath.Child("path"), file.Path))
+		}
+		paths[file.Path] = struct{}{}
+
+		errs = append(errs, validateNodeFileContent(file, filePath)...)
+	}
+
+	for i, pkg := range bootstrap.Packages {
+		if !packageNameRegex.MatchString(pkg) {
+			errs = append(errs, field.Invalid(fldPath.Child("packages").Index(i), pkg, "must be a valid package name"))
+		}
+	}
+
+	errs = append(errs, validateCommands(bootstrap.PreKubeadmCommands, fldPath.Child("preKubeadmCommands"))...)
+	errs = append(errs, validateCommands(bootstrap.PostKubeadmCommands, fldPath.Child("postKubeadmCommands"))...)
+
+	if bootstrap.NTP != nil {
+		for i, server := range bootstrap.NTP.Servers {
+			if strings.TrimSpace(server) == "" {
+				errs = append(errs, field.Required(fldPath.Child("ntp", "servers").Index(i), "must not be empty"))
+			}
+		}
+	}
+
+	for key := range bootstrap.KubeletExtraArgs {
+		if key == "" || strings.HasPrefix(key, "-") {
+			errs = append(errs, field.Invalid(fldPath.Child("kubeletExtraArgs"), key, "must be a kubelet flag name, without the leading dashes"))
+		}
+	}
+
+	return errs
+}
+
+// validateNodeFileContent checks that the content of a file is specified exactly once.
+func validateNodeFileContent(file *clv1alpha2.NodeFile, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch {
+	case file.ContentFrom == nil:
+		return errs
+	case file.Content != "":
+		errs = append(errs, field.Forbidden(fldPath.Child("contentFrom"), "content and contentFrom are mutually exclusive"))
+	case (file.ContentFrom.ConfigMap == nil) == (file.ContentFrom.Secret == nil):
+		errs = append(errs, field.Invalid(fldPath.Child("contentFrom"), file.ContentFrom, "exactly one of configMap and secret must be specified"))
+	}
+
+	for _, ref := range []struct {
+		name string
+		ref  *clv1alpha2.NodeFileKeyRef
+	}{{"configMap", file.ContentFrom.ConfigMap}, {"secret", file.ContentFrom.Secret}} {
+		if ref.ref != nil && (ref.ref.Name == "" || ref.ref.Key == "") {
+			errs = append(errs, field.Required(fldPath.Child("contentFrom", ref.name), "name and key must be specified"))
+		}
+	}
+	return errs
+}
+
+// validateCommands checks that none of the given commands is empty.
+func validateCommands(commands []string, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			errs = append(errs, field.Required(fldPath.Index(i), fmt.Sprintf("command %d must not be empty", i)))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,347 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatewh
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clv1alpha2 "github.com/netgroup-polito/CrownLabs/operators/api/v1alpha2"
+)
+
+var _ = Describe("Template validating webhook", func() {
+	var (
+		validator *TemplateValidator
+		template  *clv1alpha2.Template
+		groups    []string
+		response  admission.Response
+	)
+
+	BeforeEach(func() {
+		validator = MakeTemplateValidator(bypassGroups, scheme).Handler.(*TemplateValidator)
+		groups = []string{"users"}
+		template = &clv1alpha2.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: testTemplateName},
+			Spec: clv1alpha2.TemplateSpec{
+				EnvironmentList: []clv1alpha2.Environment{{
+					Name: "cluster",
+					Cluster: &clv1alpha2.ClusterTemplate{
+						NodeBootstrap: &clv1alpha2.NodeBootstrap{
+							Files: []clv1alpha2.NodeFile{{Path: "/etc/motd", Content: "Welcome"}},
+						},
+					},
+				}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		response = validator.Handle(ctx, forgeRequest(admissionv1.Create, template, groups...))
+	})
+
+	When("the template is valid", func() {
+		It("Should be allowed", func() { Expect(response.Allowed).To(BeTrue()) })
+	})
+
+	When("the node bootstrap configuration is invalid", func() {
+		BeforeEach(func() { template.Spec.EnvironmentList[0].Cluster.NodeBootstrap.Files[0].Path = "/etc/./motd" })
+
+		It("Should be denied", func() {
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring("spec.environmentList[0].cluster.nodeBootstrap.files[0].path"))
+		})
+
+		When("the requester belongs to a bypass group", func() {
+			BeforeEach(func() { groups = bypassGroups })
+			It("Should be allowed", func() { Expect(response.Allowed).To(BeTrue()) })
+		})
+	})
+})
+
+var _ = Describe("Validation of the node bootstrap", func() {
+	var (
+		bootstrap *clv1alpha2.NodeBootstrap
+		errs      field.ErrorList
+	)
+
+	BeforeEach(func() {
+		bootstrap = &clv1alpha2.NodeBootstrap{
+			Files: []clv1alpha2.NodeFile{
+				{Path: "/etc/motd", Content: "Welcome to CrownLabs"},
+				{Path: "/etc/crownlabs/config", ContentFrom: &clv1alpha2.NodeFileSource{
+					ConfigMap: &clv1alpha2.NodeFileKeyRef{Name: "config", Key: "config"},
+				}},
+			},
+			Packages:            []string{"jq", "nfs-common=1:2.6.1"},
+			PreKubeadmCommands:  []string{"echo pre"},
+			PostKubeadmCommands: []string{"echo post"},
+			NTP:                 &clv1alpha2.NodeNTP{Servers: []string{"ntp.polito.it"}},
+			KubeletExtraArgs:    map[string]string{"max-pods": "50"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		errs = ValidateNodeBootstrap(bootstrap, field.NewPath("nodeBootstrap"))
+	})
+
+	When("the configuration is valid", func() {
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("the configuration is not specified", func() {
+		BeforeEach(func() { bootstrap = nil })
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("a file path is not clean", func() {
+		BeforeEach(func() { bootstrap.Files[0].Path = "/etc/../motd" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.files[0].path"))
+		})
+	})
+
+	When("two files have the same path", func() {
+		BeforeEach(func() { bootstrap.Files[1].Path = bootstrap.Files[0].Path })
+		It("Should return a duplicate error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Type).To(Equal(field.ErrorTypeDuplicate))
+		})
+	})
+
+	When("a file specifies both content and contentFrom", func() {
+		BeforeEach(func() { bootstrap.Files[1].Content = "inline" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.files[1].contentFrom"))
+		})
+	})
+
+	When("a file source specifies both a configmap and a secret", func() {
+		BeforeEach(func() {
+			bootstrap.Files[1].ContentFrom.Secret = &clv1alpha2.NodeFileKeyRef{Name: "secret", Key: "key"}
+		})
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.files[1].contentFrom"))
+		})
+	})
+
+	When("a package name is invalid", func() {
+		BeforeEach(func() { bootstrap.Packages = append(bootstrap.Packages, "jq; rm -rf /") })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.packages[2]"))
+		})
+	})
+
+	When("a command is empty", func() {
+		BeforeEach(func() { bootstrap.PostKubeadmCommands = append(bootstrap.PostKubeadmCommands, "  ") })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.postKubeadmCommands[1]"))
+		})
+	})
+
+	When("a kubelet argument includes the leading dashes", func() {
+		BeforeEach(func() { bootstrap.KubeletExtraArgs["--node-labels"] = "foo=bar" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("nodeBootstrap.kubeletExtraArgs"))
+		})
+	})
+})
+
+var _ = Describe("Validation of the allowed egress", func() {
+	var (
+		rules []clv1alpha2.ClusterEgressRule
+		errs  field.ErrorList
+	)
+
+	BeforeEach(func() {
+		rules = []clv1alpha2.ClusterEgressRule{
+			{CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8"}, Ports: []clv1alpha2.ClusterEgressPort{{Port: 443}}},
+			{CIDR: "2001:db8::/32"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		errs = ValidateAllowedEgress(rules, field.NewPath("allowedEgress"))
+	})
+
+	When("the configuration is valid", func() {
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("a CIDR is invalid", func() {
+		BeforeEach(func() { rules[1].CIDR = "2001:db8::" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("allowedEgress[1].cidr"))
+		})
+	})
+
+	When("an excepted CIDR is not within the allowed one", func() {
+		BeforeEach(func() { rules[0].CIDR = "192.168.0.0/16" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("allowedEgress[0].except[0]"))
+		})
+	})
+})
+
+var _ = Describe("Validation of the session recording", func() {
+	var (
+		environment clv1alpha2.Environment
+		errs        field.ErrorList
+	)
+
+	BeforeEach(func() {
+		environment = clv1alpha2.Environment{
+			EnvironmentType:  clv1alpha2.ClassContainer,
+			GuiEnabled:       true,
+			Mode:             clv1alpha2.ModeExam,
+			SessionRecording: &clv1alpha2.SessionRecording{Destination: clv1alpha2.RecordingDestinationPersistentVolume},
+		}
+	})
+
+	JustBeforeEach(func() {
+		errs = ValidateSessionRecording(&environment, field.NewPath("sessionRecording"))
+	})
+
+	When("the configuration is valid", func() {
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("the configuration is not specified", func() {
+		BeforeEach(func() { environment.SessionRecording = nil })
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("the environment is not a graphical container", func() {
+		BeforeEach(func() { environment.EnvironmentType = clv1alpha2.ClassVM })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("sessionRecording"))
+		})
+	})
+
+	When("the upload URL is missing", func() {
+		BeforeEach(func() { environment.SessionRecording.Destination = clv1alpha2.RecordingDestinationUpload })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("sessionRecording.uploadUrl"))
+		})
+	})
+
+	When("the upload URL is missing with the default destination", func() {
+		BeforeEach(func() { environment.SessionRecording.Destination = "" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("sessionRecording.uploadUrl"))
+		})
+	})
+
+	When("the upload URL is invalid", func() {
+		BeforeEach(func() {
+			environment.SessionRecording.Destination = clv1alpha2.RecordingDestinationUpload
+			environment.SessionRecording.UploadURL = "ftp://recordings.example.com/upload"
+		})
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("sessionRecording.uploadUrl"))
+		})
+	})
+})
+
+var _ = Describe("Validation of the cluster nodes", func() {
+	var (
+		environments []clv1alpha2.Environment
+		errs         field.ErrorList
+	)
+
+	node := func(role clv1alpha2.ClusterNodeRole, ordinal int) clv1alpha2.Environment {
+		return clv1alpha2.Environment{
+			EnvironmentType: clv1alpha2.ClassVM, IsClusterNode: true,
+			ClusterName: "lab", ClusterRole: role, Ordinal: ordinal,
+		}
+	}
+
+	BeforeEach(func() {
+		environments = []clv1alpha2.Environment{
+			node(clv1alpha2.ClusterNodeControlPlane, 0),
+			node(clv1alpha2.ClusterNodeWorker, 0),
+			node(clv1alpha2.ClusterNodeWorker, 1),
+		}
+	})
+
+	JustBeforeEach(func() {
+		errs = ValidateClusterNodes(environments, field.NewPath("environmentList"))
+	})
+
+	When("the configuration is valid", func() {
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("the environments are not cluster nodes", func() {
+		BeforeEach(func() { environments = []clv1alpha2.Environment{{EnvironmentType: clv1alpha2.ClassContainer}} })
+		It("Should not return errors", func() { Expect(errs).To(BeEmpty()) })
+	})
+
+	When("an environment is not a cluster node", func() {
+		BeforeEach(func() { environments[2].IsClusterNode = false })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("environmentList[2].isClusterNode"))
+		})
+	})
+
+	When("a node is not a VM", func() {
+		BeforeEach(func() { environments[1].EnvironmentType = clv1alpha2.ClassContainer })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("environmentList[1].environmentType"))
+		})
+	})
+
+	When("a node belongs to a different cluster", func() {
+		BeforeEach(func() { environments[1].ClusterName = "other" })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("environmentList[1].clusterName"))
+		})
+	})
+
+	When("two nodes have the same role and ordinal", func() {
+		BeforeEach(func() { environments[2].Ordinal = 0 })
+		It("Should return a duplicate error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Type).To(Equal(field.ErrorTypeDuplicate))
+			Expect(errs[0].Field).To(Equal("environmentList[2].ordinal"))
+		})
+	})
+
+	When("the control plane node with ordinal 0 is missing", func() {
+		BeforeEach(func() { environments[0].Ordinal = 1 })
+		It("Should return an error", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Type).To(Equal(field.ErrorTypeRequired))
+		})
+	})
+})
@@ -0,0 +1,92 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxExtractedFileSize bounds the size of each file extracted from an archive.
+const maxExtractedFileSize = 16 << 20
+
+// ExtractTarball extracts the given (possibly gzip compressed) tar archive into the given directory.
+// Only regular files and directories are extracted, and entries escaping the target directory are rejected.
+func ExtractTarball(data []byte, dir string) error {
+	var reader io.Reader = bytes.NewReader(data)
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress the archive: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the archive: %w", err)
+		}
+
+		target := filepath.Join(dir, filepath.Clean("/"+header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid archive entry %q", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o750); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractTarFile(tr, target, header.Size); err != nil {
+				return err
+			}
+		default:
+			// Symbolic links and other special files are ignored.
+		}
+	}
+}
+
+// extractTarFile writes the current entry of the given tar reader to the target path.
+func extractTarFile(tr *tar.Reader, target string, size int64) error {
+	if size > maxExtractedFileSize {
+		return fmt.Errorf("archive entry %q exceeds the maximum size", filepath.Base(target))
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, io.LimitReader(tr, maxExtractedFileSize))
+	return err
+}
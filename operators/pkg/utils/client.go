@@ -24,8 +24,8 @@ import (
 )
 
 // EnforceObjectAbsence deletes a Kubernetes object and prints the appropriate log messages, without failing if it does not exist.
-func EnforceObjectAbsence(ctx context.Context, c client.Client, obj client.Object, kind string) error {
-	if err := c.Delete(ctx, obj); err != nil {
+func EnforceObjectAbsence(ctx context.Context, c client.Client, obj client.Object, kind string, opts ...client.DeleteOption) error {
+	if err := c.Delete(ctx, obj, opts...); err != nil {
 		if !kerrors.IsNotFound(err) {
 			ctrl.LoggerFrom(ctx).Error(err, "failed to delete object", kind, klog.KObj(obj))
 			return err
@@ -0,0 +1,94 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// maxSubnetCandidates bounds the number of candidate subnets evaluated while looking for a free one.
+const maxSubnetCandidates = 1 << 16
+
+// NextFreeSubnet returns the first subnet with the given prefix length carved from the pool,
+// which does not overlap with any of the already allocated CIDRs.
+func NextFreeSubnet(pool string, prefixLength int, allocated []string) (string, error) {
+	parent, err := netip.ParsePrefix(pool)
+	if err != nil {
+		return "", fmt.Errorf("invalid pool %q: %w", pool, err)
+	}
+	parent = parent.Masked()
+
+	if prefixLength < parent.Bits() || prefixLength > parent.Addr().BitLen() {
+		return "", fmt.Errorf("prefix length /%d cannot be carved from pool %v", prefixLength, parent)
+	}
+
+	used := make([]netip.Prefix, 0, len(allocated))
+	for _, cidr := range allocated {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return "", fmt.Errorf("invalid allocated CIDR %q: %w", cidr, err)
+		}
+		used = append(used, prefix.Masked())
+	}
+
+	base := new(big.Int).SetBytes(parent.Addr().AsSlice())
+	step := new(big.Int).Lsh(big.NewInt(1), uint(parent.Addr().BitLen()-prefixLength))
+	count := new(big.Int).Lsh(big.NewInt(1), uint(prefixLength-parent.Bits()))
+	if count.Cmp(big.NewInt(maxSubnetCandidates)) > 0 {
+		count = big.NewInt(maxSubnetCandidates)
+	}
+
+	for i := int64(0); i < count.Int64(); i++ {
+		offset := new(big.Int).Mul(step, big.NewInt(i))
+		candidate := netip.PrefixFrom(addrFromBigInt(new(big.Int).Add(base, offset), parent.Addr().BitLen()), prefixLength)
+		if !overlapsAny(candidate, used) {
+			return candidate.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("pool %v exhausted for prefix length /%d", parent, prefixLength)
+}
+
+// SubnetsOverlap returns whether the two given CIDRs overlap.
+func SubnetsOverlap(first, second string) (bool, error) {
+	firstPrefix, err := netip.ParsePrefix(first)
+	if err != nil {
+		return false, fmt.Errorf("invalid CIDR %q: %w", first, err)
+	}
+	secondPrefix, err := netip.ParsePrefix(second)
+	if err != nil {
+		return false, fmt.Errorf("invalid CIDR %q: %w", second, err)
+	}
+	return firstPrefix.Masked().Overlaps(secondPrefix.Masked()), nil
+}
+
+// overlapsAny returns whether the candidate prefix overlaps with any of the given ones.
+func overlapsAny(candidate netip.Prefix, prefixes []netip.Prefix) bool {
+	for _, prefix := range prefixes {
+		if candidate.Overlaps(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addrFromBigInt converts an integer into the IP address of the given length (in bits).
+func addrFromBigInt(value *big.Int, bitLen int) netip.Addr {
+	bytes := value.FillBytes(make([]byte, bitLen/8))
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
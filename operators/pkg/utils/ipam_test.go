@@ -0,0 +1,109 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/netgroup-polito/CrownLabs/operators/pkg/utils"
+)
+
+var _ = Describe("The IPAM utility functions", func() {
+
+	Describe("The NextFreeSubnet function", func() {
+		type NextFreeSubnetCase struct {
+			Pool         string
+			PrefixLength int
+			Allocated    []string
+			Expected     string
+		}
+
+		DescribeTable("Returning the first free subnet",
+			func(c NextFreeSubnetCase) {
+				Expect(utils.NextFreeSubnet(c.Pool, c.PrefixLength, c.Allocated)).To(Equal(c.Expected))
+			},
+			Entry("When no subnet is allocated", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Expected: "10.0.0.0/24",
+			}),
+			Entry("When the first subnets are allocated", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Allocated: []string{"10.0.0.0/24", "10.0.1.0/24"}, Expected: "10.0.2.0/24",
+			}),
+			Entry("When a larger overlapping subnet is allocated", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Allocated: []string{"10.0.0.0/23"}, Expected: "10.0.2.0/24",
+			}),
+			Entry("When a smaller overlapping subnet is allocated", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Allocated: []string{"10.0.0.128/25"}, Expected: "10.0.1.0/24",
+			}),
+			Entry("When the pool is misaligned", NextFreeSubnetCase{
+				Pool: "10.0.3.7/16", PrefixLength: 24, Expected: "10.0.0.0/24",
+			}),
+			Entry("When the allocated subnets are misaligned", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Allocated: []string{"10.0.0.42/24"}, Expected: "10.0.1.0/24",
+			}),
+			Entry("When the pool is IPv6", NextFreeSubnetCase{
+				Pool: "fd00::/48", PrefixLength: 64, Allocated: []string{"fd00::/64"}, Expected: "fd00:0:0:1::/64",
+			}),
+			Entry("When the requested prefix matches the pool", NextFreeSubnetCase{
+				Pool: "10.0.0.0/24", PrefixLength: 24, Expected: "10.0.0.0/24",
+			}),
+		)
+
+		DescribeTable("Returning an error",
+			func(c NextFreeSubnetCase) {
+				_, err := utils.NextFreeSubnet(c.Pool, c.PrefixLength, c.Allocated)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("When the pool is exhausted", NextFreeSubnetCase{
+				Pool: "10.0.0.0/23", PrefixLength: 24, Allocated: []string{"10.0.0.0/24", "10.0.1.0/24"},
+			}),
+			Entry("When the pool is exhausted by a larger subnet", NextFreeSubnetCase{
+				Pool: "10.0.0.0/23", PrefixLength: 24, Allocated: []string{"10.0.0.0/16"},
+			}),
+			Entry("When the IPv6 pool is exhausted", NextFreeSubnetCase{
+				Pool: "fd00::/63", PrefixLength: 64, Allocated: []string{"fd00::/64", "fd00:0:0:1::/64"},
+			}),
+			Entry("When the prefix is shorter than the pool one", NextFreeSubnetCase{
+				Pool: "10.0.0.0/24", PrefixLength: 16,
+			}),
+			Entry("When the prefix is longer than the address", NextFreeSubnetCase{
+				Pool: "10.0.0.0/24", PrefixLength: 33,
+			}),
+			Entry("When the pool is invalid", NextFreeSubnetCase{
+				Pool: "invalid", PrefixLength: 24,
+			}),
+			Entry("When an allocated subnet is invalid", NextFreeSubnetCase{
+				Pool: "10.0.0.0/16", PrefixLength: 24, Allocated: []string{"invalid"},
+			}),
+		)
+	})
+
+	Describe("The SubnetsOverlap function", func() {
+		DescribeTable("Checking whether two subnets overlap",
+			func(first, second string, expected bool) {
+				Expect(utils.SubnetsOverlap(first, second)).To(Equal(expected))
+			},
+			Entry("When the subnets are identical", "10.0.0.0/24", "10.0.0.0/24", true),
+			Entry("When one subnet contains the other", "10.0.0.0/16", "10.0.4.0/24", true),
+			Entry("When the subnets are disjoint", "10.0.0.0/24", "10.0.1.0/24", false),
+			Entry("When the subnets belong to different families", "10.0.0.0/8", "fd00::/8", false),
+		)
+
+		It("Should return an error when a subnet is invalid", func() {
+			_, err := utils.SubnetsOverlap("10.0.0.0/24", "invalid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,40 @@
+// Copyright 2020-2025 Politecnico di Torino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WorkloadClusterConfig returns the REST configuration to interact with the given workload cluster,
+// based on the admin kubeconfig generated by the Cluster API in the namespace of the cluster.
+func WorkloadClusterConfig(ctx context.Context, c client.Reader, namespace, clusterName string) (*rest.Config, error) {
+	raw, err := kubeconfig.FromSecret(ctx, c, client.ObjectKey{Namespace: namespace, Name: clusterName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the kubeconfig of cluster %s/%s: %w", namespace, clusterName, err)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the kubeconfig of cluster %s/%s: %w", namespace, clusterName, err)
+	}
+	return config, nil
+}